@@ -164,6 +164,9 @@ type Client interface {
 	GetTorrentContents(infoHash string) ([]*TorrentContentFile, error)
 	PurgeCache()
 	GetStatus() (*Status, error)
+	// Return free disk space of the path (in client side), in bytes.
+	// Some clients can only report free space of the default save path.
+	GetFreeSpaceOnPath(path string) (int64, error)
 	GetName() string
 	GetClientConfig() *config.ClientConfigStruct
 	SetConfig(variable string, value string) error
@@ -474,6 +477,54 @@ func (torrent *Torrent) HasAnyTag(tags string) bool {
 	})
 }
 
+// Return the HnR (Hit and Run) requirements of torrent: required seeding time (seconds) and ratio.
+// hnr is false if torrent is not a HnR one (does not have the config.HR_TAG tag).
+// The requirements are read from "hnrst" & "hnrr" meta of torrent that are recorded when it's added;
+// if not exists, they are read from config of torrent's site.
+func (torrent *Torrent) GetHnRRequirements() (hnr bool, seedingTime int64, ratio float64) {
+	if !slices.Contains(torrent.Tags, config.HR_TAG) {
+		return false, 0, 0
+	}
+	meta := torrent.GetMetadataFromTags()
+	for key, value := range torrent.Meta {
+		meta[key] = value
+	}
+	if _, ok := meta["hnrst"]; ok {
+		return true, meta["hnrst"], float64(meta["hnrr"]) / 100
+	}
+	if siteConfig := config.GetSiteConfig(torrent.GetSiteFromTag()); siteConfig != nil {
+		return true, siteConfig.HnRSeedingTimeValue, siteConfig.HnRRatio
+	}
+	return true, config.DEFAULT_SITE_HNR_SEEDING_TIME, 0
+}
+
+// Return whether the HnR requirements of torrent are satisfied at now.
+// A torrent satisfies the requirements if it has been seeded (since completed) for the required time,
+// or it's uploaded / size ratio reaches the required ratio. Non-HnR torrents are always satisfied.
+// Incomplete torrents that have downloaded some data are unsatisfied, the remaining is the full required
+// seeding time (the seeding obligation starts when torrent is completed).
+// Incomplete torrents that have not downloaded anything yet are treated as satisfied.
+// remaining is the remaining seeding time (seconds) of unsatisfied torrent.
+func (torrent *Torrent) GetHnRStatus(now int64) (satisfied bool, remaining int64) {
+	hnr, seedingTime, ratio := torrent.GetHnRRequirements()
+	if !hnr {
+		return true, 0
+	}
+	if ratio > 0 && torrent.Size > 0 && float64(torrent.Uploaded)/float64(torrent.Size) >= ratio {
+		return true, 0
+	}
+	if torrent.Ctime <= 0 {
+		if torrent.Downloaded > 0 {
+			return false, seedingTime
+		}
+		return true, 0
+	}
+	if seeded := now - torrent.Ctime; seeded < seedingTime {
+		return false, seedingTime - seeded
+	}
+	return true, 0
+}
+
 // return index or -1
 func (trackers TorrentTrackers) FindIndex(hostOrUrl string) int {
 	for i, tracker := range trackers {
@@ -496,6 +547,22 @@ func GenerateTorrentTagFromMetadata(name string, value int64) string {
 	return "meta." + name + ":" + fmt.Sprint(value)
 }
 
+// Generate tags that record the HnR requirements of site torrent, which are the config.HR_TAG tag
+// and the meta tags of required seeding time (seconds) and ratio (in percent).
+func GenerateTorrentTagsFromHnR(siteConfig *config.SiteConfigStruct) []string {
+	seedingTime := config.DEFAULT_SITE_HNR_SEEDING_TIME
+	ratio := float64(0)
+	if siteConfig != nil {
+		seedingTime = siteConfig.HnRSeedingTimeValue
+		ratio = siteConfig.HnRRatio
+	}
+	return []string{
+		config.HR_TAG,
+		GenerateTorrentTagFromMetadata("hnrst", seedingTime),
+		GenerateTorrentTagFromMetadata("hnrr", int64(ratio*100)),
+	}
+}
+
 func IsSubstituteTag(tag string) bool {
 	return substituteTagRegex.MatchString(tag)
 }
@@ -699,34 +766,56 @@ func FilterTorrentsXseed(clientInstance Client, torrents []*Torrent) (
 	return
 }
 
+// Separate torrents into 2 groups: the ones that are deletable (non-HnR or HnR requirements satisfied)
+// and the ones that HnR requirements are NOT satisfied yet at now.
+func FilterTorrentsHnR(torrents []*Torrent, now int64) (torrentsDeletable, torrentsHnR []*Torrent) {
+	for _, t := range torrents {
+		if satisfied, _ := t.GetHnRStatus(now); satisfied {
+			torrentsDeletable = append(torrentsDeletable, t)
+		} else {
+			torrentsHnR = append(torrentsHnR, t)
+		}
+	}
+	return
+}
+
 // Delete torrents from client. If torrent has no other xseed torrent (with same content path),
 // delete files; Otherwise preserve files.
-func DeleteTorrentsAuto(clientInstance Client, infoHashes []string) (err error) {
+// Unless force is true, torrents that HnR requirements are not satisfied yet are skipped (not deleted),
+// their info hashes are returned as skipped. Skipping torrents is NOT an error: err is nil as long as
+// the deletion of other torrents succeeds, callers should report the skipped torrents themselves.
+// "ptool delete" follows the same contract.
+func DeleteTorrentsAuto(clientInstance Client, infoHashes []string, force bool) (skipped []string, err error) {
 	var torrents []*Torrent
 	for _, infoHash := range infoHashes {
 		if torrent, _ := clientInstance.GetTorrent(infoHash); torrent != nil {
 			torrents = append(torrents, torrent)
 		}
 	}
+	var torrentsHnR []*Torrent
+	if !force {
+		torrents, torrentsHnR = FilterTorrentsHnR(torrents, util.Now())
+	}
+	skipped = util.Map(torrentsHnR, func(t *Torrent) string { return t.InfoHash })
 	torrents, torrentsXseed, err := FilterTorrentsXseed(clientInstance, torrents)
 	if err != nil {
-		return err
+		return skipped, err
 	}
 	if len(torrentsXseed) > 0 {
 		infoHashes := util.Map(torrentsXseed, func(t *Torrent) string { return t.InfoHash })
 		err = clientInstance.DeleteTorrents(infoHashes, false)
 		if err != nil {
-			return fmt.Errorf("failed to delete torrents: %w", err)
+			return skipped, fmt.Errorf("failed to delete torrents: %w", err)
 		}
 	}
 	if len(torrents) > 0 {
 		infoHashes := util.Map(torrents, func(t *Torrent) string { return t.InfoHash })
 		err = clientInstance.DeleteTorrents(infoHashes, true)
 		if err != nil {
-			return fmt.Errorf("failed to delete torrents: %w", err)
+			return skipped, fmt.Errorf("failed to delete torrents: %w", err)
 		}
 	}
-	return nil
+	return skipped, nil
 }
 
 // Parse and return torrents that meet criterion.
@@ -0,0 +1,45 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+)
+
+func TestTorrentHnR(t *testing.T) {
+	now := int64(1700000000)
+	tags := client.GenerateTorrentTagsFromHnR(&config.SiteConfigStruct{HnRSeedingTimeValue: 3600, HnRRatio: 1.5})
+	torrent := &client.Torrent{Tags: tags, Size: 1000, Uploaded: 1000, Ctime: now - 600}
+	if hnr, seedingTime, ratio := torrent.GetHnRRequirements(); !hnr || seedingTime != 3600 || ratio != 1.5 {
+		t.Errorf("unexpected HnR requirements: hnr=%t, seedingTime=%d, ratio=%f", hnr, seedingTime, ratio)
+	}
+	if satisfied, remaining := torrent.GetHnRStatus(now); satisfied || remaining != 3000 {
+		t.Errorf("unexpected HnR status: satisfied=%t, remaining=%d", satisfied, remaining)
+	}
+	torrent.Uploaded = 1500
+	if satisfied, _ := torrent.GetHnRStatus(now); !satisfied {
+		t.Errorf("expect HnR satisfied by ratio")
+	}
+	torrent.Uploaded = 0
+	if satisfied, _ := torrent.GetHnRStatus(now + 3000); !satisfied {
+		t.Errorf("expect HnR satisfied by seeding time")
+	}
+	incomplete := &client.Torrent{Tags: tags, Size: 1000, Downloaded: 100}
+	if satisfied, remaining := incomplete.GetHnRStatus(now); satisfied || remaining != 3600 {
+		t.Errorf("unexpected incomplete HnR status: satisfied=%t, remaining=%d", satisfied, remaining)
+	}
+	notStarted := &client.Torrent{Tags: tags, Size: 1000}
+	if satisfied, _ := notStarted.GetHnRStatus(now); !satisfied {
+		t.Errorf("expect not started HnR torrent satisfied")
+	}
+	normal := &client.Torrent{Tags: []string{config.PRIVATE_TAG}}
+	if satisfied, _ := normal.GetHnRStatus(now); !satisfied {
+		t.Errorf("expect non-HnR torrent satisfied")
+	}
+
+	deletable, protected := client.FilterTorrentsHnR([]*client.Torrent{torrent, incomplete, notStarted, normal}, now)
+	if len(deletable) != 2 || len(protected) != 2 || protected[0] != torrent || protected[1] != incomplete {
+		t.Errorf("unexpected filter result: %d deletable, %d protected", len(deletable), len(protected))
+	}
+}
@@ -0,0 +1,232 @@
+package deluge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+)
+
+// Fields requested from Deluge when syncing torrents list.
+// "save_path" is deprecated in Deluge 2.x in favor of "download_location", request both for compatibility.
+var torrentFields = []string{
+	"hash", "name", "state", "save_path", "download_location", "total_size", "total_wanted", "total_done",
+	"progress", "upload_payload_rate", "download_payload_rate", "max_upload_speed", "max_download_speed",
+	"total_uploaded", "all_time_download", "time_added", "completed_time", "time_since_transfer",
+	"total_seeds", "total_peers", "tracker", "tracker_host", "tracker_status", "trackers", "label",
+	"is_finished", "paused",
+}
+
+type rpcRequest struct {
+	Method string `json:"method"`
+	Params []any  `json:"params"`
+	Id     int64  `json:"id"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	Id     int64           `json:"id"`
+}
+
+type rpcError struct {
+	Message string `json:"message"`
+	Code    int64  `json:"code"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("deluge rpc error %d: %s", e.Code, e.Message)
+}
+
+type apiTracker struct {
+	Url  string `json:"url"`
+	Tier int64  `json:"tier"`
+}
+
+type apiTorrentFile struct {
+	Index  int64  `json:"index"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+}
+
+type apiTorrentContents struct {
+	Files          []*apiTorrentFile `json:"files"`
+	FileProgress   []float64         `json:"file_progress"`   // [0, 1]
+	FilePriorities []int64           `json:"file_priorities"` // 0 - skip; 1 - low; 4 - normal; 7 - high
+}
+
+type apiSessionStats struct {
+	UploadRate   float64 `json:"upload_rate"`   // bytes/s
+	DownloadRate float64 `json:"download_rate"` // bytes/s
+	MaxUpload    float64 `json:"max_upload"`    // KiB/s, -1 means unlimited
+	MaxDownload  float64 `json:"max_download"`  // KiB/s, -1 means unlimited
+	FreeSpace    int64   `json:"free_space"`
+}
+
+type apiUpdateUi struct {
+	Connected bool                   `json:"connected"`
+	Torrents  map[string]*apiTorrent `json:"torrents"`
+	Stats     *apiSessionStats       `json:"stats"`
+}
+
+type apiTorrent struct {
+	Hash                string        `json:"hash"`
+	Name                string        `json:"name"`
+	State               string        `json:"state"` // Allocating|Checking|Downloading|Seeding|Paused|Error|Queued|Moving
+	SavePath            string        `json:"save_path"`
+	DownloadLocation    string        `json:"download_location"`
+	TotalSize           int64         `json:"total_size"`   // size of all files
+	TotalWanted         int64         `json:"total_wanted"` // size of selected files
+	TotalDone           int64         `json:"total_done"`
+	Progress            float64       `json:"progress"` // [0, 100]
+	UploadPayloadRate   float64       `json:"upload_payload_rate"`
+	DownloadPayloadRate float64       `json:"download_payload_rate"`
+	MaxUploadSpeed      float64       `json:"max_upload_speed"`   // KiB/s, -1 means unlimited
+	MaxDownloadSpeed    float64       `json:"max_download_speed"` // KiB/s, -1 means unlimited
+	TotalUploaded       int64         `json:"total_uploaded"`
+	AllTimeDownload     int64         `json:"all_time_download"`
+	TimeAdded           float64       `json:"time_added"`
+	CompletedTime       float64       `json:"completed_time"`      // Deluge 2.x only
+	TimeSinceTransfer   float64       `json:"time_since_transfer"` // Deluge 2.x only
+	TotalSeeds          int64         `json:"total_seeds"`
+	TotalPeers          int64         `json:"total_peers"`
+	Tracker             string        `json:"tracker"`
+	TrackerHost         string        `json:"tracker_host"`
+	TrackerStatus       string        `json:"tracker_status"` // e.g. "Announce OK", "Error: unregistered torrent"
+	Trackers            []*apiTracker `json:"trackers"`
+	Label               string        `json:"label"` // Label plugin
+	IsFinished          bool          `json:"is_finished"`
+	Paused              bool          `json:"paused"`
+}
+
+func (dt *apiTorrent) savePath() string {
+	if dt.DownloadLocation != "" {
+		return dt.DownloadLocation
+	}
+	return dt.SavePath
+}
+
+func (dt *apiTorrent) tracker() string {
+	if dt.Tracker != "" {
+		return dt.Tracker
+	}
+	if len(dt.Trackers) > 0 {
+		return dt.Trackers[0].Url
+	}
+	return ""
+}
+
+// Return path sep (either '/' or '\') of this torrent.
+func (dt *apiTorrent) sep() string {
+	if strings.Contains(dt.savePath(), `\`) {
+		return `\`
+	}
+	return `/`
+}
+
+func (dt *apiTorrent) contentPath() string {
+	return strings.TrimSuffix(dt.savePath(), dt.sep()) + dt.sep() + dt.Name
+}
+
+func (dt *apiTorrent) toTorrentState() string {
+	switch dt.State {
+	case "Downloading", "Allocating":
+		return "downloading"
+	case "Seeding":
+		return "seeding"
+	case "Paused":
+		if dt.IsFinished {
+			return "completed"
+		}
+		return "paused"
+	case "Queued":
+		if dt.IsFinished {
+			return "seeding"
+		}
+		return "downloading"
+	case "Checking", "Moving":
+		return "checking"
+	case "Error":
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+func (dt *apiTorrent) toTorrent(meta *torrentMeta) *client.Torrent {
+	tracker := dt.tracker()
+	activityTime := int64(0)
+	if dt.TimeSinceTransfer >= 0 {
+		activityTime = util.Now() - int64(dt.TimeSinceTransfer)
+	}
+	torrent := &client.Torrent{
+		InfoHash:           dt.Hash,
+		Name:               dt.Name,
+		TrackerDomain:      util.ParseUrlHostname(tracker),
+		TrackerBaseDomain:  util.GetUrlDomain(tracker),
+		Tracker:            tracker,
+		State:              dt.toTorrentState(),
+		LowLevelState:      dt.State,
+		Atime:              int64(dt.TimeAdded),
+		Ctime:              int64(dt.CompletedTime),
+		ActivityTime:       activityTime,
+		Category:           dt.Label,
+		SavePath:           dt.savePath(),
+		ContentPath:        dt.contentPath(),
+		Downloaded:         dt.AllTimeDownload,
+		DownloadSpeed:      int64(dt.DownloadPayloadRate),
+		DownloadSpeedLimit: kib2Bytes(dt.MaxDownloadSpeed),
+		Uploaded:           dt.TotalUploaded,
+		UploadSpeed:        int64(dt.UploadPayloadRate),
+		UploadedSpeedLimit: kib2Bytes(dt.MaxUploadSpeed),
+		Size:               dt.TotalWanted,
+		SizeTotal:          dt.TotalSize,
+		SizeCompleted:      dt.TotalDone,
+		Seeders:            dt.TotalSeeds,
+		Leechers:           dt.TotalPeers,
+		Tags:               []string{},
+		Meta:               map[string]int64{},
+	}
+	if meta != nil {
+		torrent.Tags = append(torrent.Tags, meta.Tags...)
+		for key, value := range meta.Meta {
+			torrent.Meta[key] = value
+		}
+	}
+	return torrent
+}
+
+// Convert Deluge speed value (KiB/s, -1 == unlimited) to bytes/s (-1 == unlimited).
+func kib2Bytes(value float64) int64 {
+	if value < 0 {
+		return -1
+	}
+	return int64(value * 1024)
+}
+
+// Convert ptool speed limit value (bytes/s, <= 0 == unlimited) to Deluge (KiB/s, -1 == unlimited).
+func bytes2Kib(value int64) float64 {
+	if value <= 0 {
+		return -1
+	}
+	return max(float64(value)/1024, 1)
+}
+
+// Map qBittorrent style file priority to Deluge priority.
+// qb: 0 - do not download; 1 - normal; 6 - high; 7 - maximal.
+// Deluge: 0 - skip; 1 - low; 4 - normal; 7 - high.
+func qbPriority2Deluge(priority int64) int64 {
+	switch {
+	case priority <= 0:
+		return 0
+	case priority == 1:
+		return 4
+	case priority >= 6:
+		return 7
+	default:
+		return priority
+	}
+}
@@ -0,0 +1,985 @@
+package deluge
+
+// Deluge Web UI JSON-RPC API: https://deluge.readthedocs.io/en/latest/reference/webapi.html .
+// Core RPC methods: https://deluge.readthedocs.io/en/latest/reference/api.html .
+// Requires Deluge 2.x with Web UI enabled. Label plugin is required for categories.
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+type Client struct {
+	Name                      string
+	ClientConfig              *config.ClientConfigStruct
+	Config                    *config.ConfigStruct
+	HttpClient                *http.Client
+	Logined                   bool
+	rpcUrl                    string
+	rpcId                     atomic.Int64
+	datatime                  int64
+	torrents                  map[string]*apiTorrent
+	stats                     *apiSessionStats
+	unfinishedSize            int64
+	unfinishedDownloadingSize int64
+	contentPathTorrents       map[string][]*apiTorrent
+	meta                      *metaStore
+}
+
+// Call a Deluge JSON-RPC method. If result is not nil, unmarshal rpc result into it.
+func (dclient *Client) call(result any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	req := &rpcRequest{
+		Method: method,
+		Params: params,
+		Id:     dclient.rpcId.Add(1),
+	}
+	var res rpcResponse
+	if err := util.PostAndFetchJson(dclient.rpcUrl, req, &res, nil, dclient.HttpClient); err != nil {
+		return fmt.Errorf("deluge rpc %s error: %w", method, err)
+	}
+	if res.Error != nil {
+		return fmt.Errorf("deluge rpc %s error: %w", method, res.Error)
+	}
+	if result != nil && res.Result != nil {
+		return json.Unmarshal(res.Result, result)
+	}
+	return nil
+}
+
+// Call rpc method after login.
+func (dclient *Client) apiCall(result any, method string, params ...any) error {
+	if err := dclient.login(); err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	return dclient.call(result, method, params...)
+}
+
+// Login Web UI and make sure it's connected to a daemon (the first one if not connected).
+func (dclient *Client) login() error {
+	if dclient.Logined {
+		return nil
+	}
+	password := dclient.ClientConfig.Password
+	if password == "" {
+		password = "deluge" // Deluge Web UI default password
+	}
+	ok := false
+	if err := dclient.call(&ok, "auth.login", password); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("incorrect password")
+	}
+	connected := false
+	if err := dclient.call(&connected, "web.connected"); err != nil {
+		return err
+	}
+	if !connected {
+		// [[id, host, port, username], ...]
+		var hosts [][]any
+		if err := dclient.call(&hosts, "web.get_hosts"); err != nil {
+			return err
+		}
+		if len(hosts) == 0 || len(hosts[0]) == 0 {
+			return fmt.Errorf("no deluge daemon host configured in Web UI")
+		}
+		if err := dclient.call(nil, "web.connect", hosts[0][0]); err != nil {
+			return fmt.Errorf("failed to connect to daemon: %w", err)
+		}
+	}
+	dclient.Logined = true
+	return nil
+}
+
+func (dclient *Client) Cached() bool {
+	return dclient.datatime > 0
+}
+
+func (dclient *Client) sync() error {
+	if dclient.datatime > 0 {
+		return nil
+	}
+	var data apiUpdateUi
+	if err := dclient.apiCall(&data, "web.update_ui", torrentFields, map[string]any{}); err != nil {
+		return err
+	}
+	if data.Torrents == nil {
+		data.Torrents = map[string]*apiTorrent{}
+	}
+	for hash, torrent := range data.Torrents {
+		torrent.Hash = hash
+	}
+	dclient.torrents = data.Torrents
+	dclient.stats = data.Stats
+	dclient.datatime = util.Now()
+	dclient.buildDerivative()
+	return nil
+}
+
+func (dclient *Client) buildDerivative() {
+	unfinishedSize := int64(0)
+	unfinishedDownloadingSize := int64(0)
+	contentPathTorrents := map[string][]*apiTorrent{}
+	for _, torrent := range dclient.torrents {
+		usize := torrent.TotalWanted - torrent.TotalDone
+		unfinishedSize += usize
+		if torrent.State != "Paused" {
+			unfinishedDownloadingSize += usize
+		}
+		contentPath := torrent.contentPath()
+		contentPathTorrents[contentPath] = append(contentPathTorrents[contentPath], torrent)
+	}
+	dclient.unfinishedSize = unfinishedSize
+	dclient.unfinishedDownloadingSize = unfinishedDownloadingSize
+	dclient.contentPathTorrents = contentPathTorrents
+}
+
+func (dclient *Client) toTorrent(dt *apiTorrent) *client.Torrent {
+	return dt.toTorrent(dclient.meta.get(dt.Hash))
+}
+
+func (dclient *Client) getAllInfoHashes() ([]string, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	return util.MapKeys(dclient.torrents), nil
+}
+
+func (dclient *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
+	// Deluge keeps .torrent files in "<config_dir>/state" dir.
+	if dclient.ClientConfig.LocalTorrentsPath != "" {
+		return os.ReadFile(filepath.Join(dclient.ClientConfig.LocalTorrentsPath, infoHash+".torrent"))
+	}
+	return nil, fmt.Errorf("unsupported")
+}
+
+func (dclient *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	dt := dclient.torrents[infoHash]
+	if dt == nil {
+		return nil, nil
+	}
+	return dclient.toTorrent(dt), nil
+}
+
+func (dclient *Client) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	torrents := []*client.Torrent{}
+	for _, dt := range dclient.torrents {
+		if category != "" {
+			if category == constants.NONE {
+				if dt.Label != "" {
+					continue
+				}
+			} else if normalizeLabel(category) != dt.Label {
+				continue
+			}
+		}
+		torrent := dclient.toTorrent(dt)
+		if !showAll && torrent.DownloadSpeed < 1024 && torrent.UploadSpeed < 1024 {
+			continue
+		}
+		if !torrent.MatchStateFilter(stateFilter) {
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+func (dclient *Client) GetTorrentsByContentPath(contentPath string) ([]*client.Torrent, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	var torrents []*client.Torrent
+	for _, dt := range dclient.contentPathTorrents[contentPath] {
+		torrents = append(torrents, dclient.toTorrent(dt))
+	}
+	return torrents, nil
+}
+
+func (dclient *Client) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
+	if option == nil {
+		option = &client.TorrentOption{}
+	}
+	// Deluge only supports changing the display name of torrent, which will break the content path detection,
+	// so option.Name is ignored.
+	options := map[string]any{
+		"add_paused":          option.Pause,
+		"seed_mode":           option.SkipChecking,
+		"sequential_download": option.SequentialDownload,
+		"max_upload_speed":    bytes2Kib(option.UploadSpeedLimit),
+		"max_download_speed":  bytes2Kib(option.DownloadSpeedLimit),
+	}
+	if option.SavePath != "" {
+		options["download_location"] = option.SavePath
+	}
+	if option.RatioLimit > 0 {
+		options["stop_at_ratio"] = true
+		options["stop_ratio"] = option.RatioLimit
+	}
+	var infoHash string
+	var err error
+	if torrentUrl := string(torrentContent); util.IsTorrentUrl(torrentUrl) {
+		if strings.HasPrefix(torrentUrl, "magnet:") {
+			err = dclient.apiCall(&infoHash, "core.add_torrent_magnet", torrentUrl, options)
+		} else {
+			err = dclient.apiCall(&infoHash, "core.add_torrent_url", torrentUrl, options, map[string]any{})
+		}
+	} else {
+		err = dclient.apiCall(&infoHash, "core.add_torrent_file", "file.torrent",
+			base64.StdEncoding.EncodeToString(torrentContent), options)
+	}
+	if err != nil {
+		return fmt.Errorf("add torrent error: %w", err)
+	}
+	if infoHash == "" {
+		return fmt.Errorf("add torrent error: torrent not added (already exists?)")
+	}
+	if option.Category != "" && option.Category != constants.NONE {
+		if err := dclient.setLabel([]string{infoHash}, option.Category); err != nil {
+			return err
+		}
+	}
+	if len(option.Tags) > 0 || len(meta) > 0 {
+		err := dclient.meta.update([]string{infoHash}, func(tm *torrentMeta) {
+			tm.Tags = util.UniqueSlice(append(tm.Tags, option.Tags...))
+			tm.Meta = util.CopyMap(meta, false)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOption, meta map[string]int64) error {
+	if option == nil {
+		option = &client.TorrentOption{}
+	}
+	if err := dclient.sync(); err != nil {
+		return err
+	}
+	dt := dclient.torrents[infoHash]
+	if dt == nil {
+		return fmt.Errorf("torrent not exists")
+	}
+	if option.Category != "" {
+		category := option.Category
+		if category == constants.NONE {
+			category = ""
+		}
+		if normalizeLabel(category) != dt.Label {
+			if err := dclient.setLabel([]string{infoHash}, category); err != nil {
+				return err
+			}
+		}
+	}
+	if len(option.Tags) > 0 || len(option.RemoveTags) > 0 || len(meta) > 0 {
+		err := dclient.meta.update([]string{infoHash}, func(tm *torrentMeta) {
+			tm.Tags = slices.DeleteFunc(tm.Tags, func(tag string) bool { return slices.Contains(option.RemoveTags, tag) })
+			tm.Tags = util.UniqueSlice(append(tm.Tags, option.Tags...))
+			if len(meta) > 0 {
+				tm.Meta = util.CopyMap(meta, false)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	options := map[string]any{}
+	if option.DownloadSpeedLimit != 0 && option.DownloadSpeedLimit != kib2Bytes(dt.MaxDownloadSpeed) {
+		options["max_download_speed"] = bytes2Kib(option.DownloadSpeedLimit)
+	}
+	if option.UploadSpeedLimit != 0 && option.UploadSpeedLimit != kib2Bytes(dt.MaxUploadSpeed) {
+		options["max_upload_speed"] = bytes2Kib(option.UploadSpeedLimit)
+	}
+	if option.RatioLimit != 0 {
+		options["stop_at_ratio"] = option.RatioLimit > 0
+		if option.RatioLimit > 0 {
+			options["stop_ratio"] = option.RatioLimit
+		}
+	}
+	if len(options) > 0 {
+		if err := dclient.apiCall(nil, "core.set_torrent_options", []string{infoHash}, options); err != nil {
+			return err
+		}
+	}
+	if option.SavePath != "" && option.SavePath != dt.savePath() {
+		if err := dclient.SetTorrentsSavePath([]string{infoHash}, option.SavePath); err != nil {
+			return err
+		}
+	}
+	if option.Pause {
+		return dclient.PauseTorrents([]string{infoHash})
+	} else if option.Resume {
+		return dclient.ResumeTorrents([]string{infoHash})
+	}
+	return nil
+}
+
+func (dclient *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	// return a list of [infoHash, errorMsg] of failed ones.
+	var errors [][]string
+	if err := dclient.apiCall(&errors, "core.remove_torrents", infoHashes, deleteFiles); err != nil {
+		return err
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to delete %d torrents: %v", len(errors), errors)
+	}
+	if err := dclient.meta.remove(infoHashes); err != nil {
+		log.Warnf("Failed to remove deluge torrents meta: %v", err)
+	}
+	if dclient.Cached() {
+		for _, infoHash := range infoHashes {
+			delete(dclient.torrents, infoHash)
+		}
+		dclient.buildDerivative()
+	}
+	return nil
+}
+
+func (dclient *Client) PauseTorrents(infoHashes []string) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	return dclient.apiCall(nil, "core.pause_torrents", infoHashes)
+}
+
+func (dclient *Client) ResumeTorrents(infoHashes []string) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	return dclient.apiCall(nil, "core.resume_torrents", infoHashes)
+}
+
+func (dclient *Client) RecheckTorrents(infoHashes []string) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	return dclient.apiCall(nil, "core.force_recheck", infoHashes)
+}
+
+func (dclient *Client) ReannounceTorrents(infoHashes []string) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	return dclient.apiCall(nil, "core.force_reannounce", infoHashes)
+}
+
+func (dclient *Client) AddTagsToTorrents(infoHashes []string, tags []string) error {
+	if len(infoHashes) == 0 || len(tags) == 0 {
+		return nil
+	}
+	return dclient.meta.update(infoHashes, func(tm *torrentMeta) {
+		tm.Tags = util.UniqueSlice(append(tm.Tags, tags...))
+	})
+}
+
+func (dclient *Client) RemoveTagsFromTorrents(infoHashes []string, tags []string) error {
+	if len(infoHashes) == 0 || len(tags) == 0 {
+		return nil
+	}
+	return dclient.meta.update(infoHashes, func(tm *torrentMeta) {
+		tm.Tags = slices.DeleteFunc(tm.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	})
+}
+
+func (dclient *Client) SetTorrentsSavePath(infoHashes []string, savePath string) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	savePath = strings.TrimSpace(savePath)
+	if savePath == "" {
+		return fmt.Errorf("savePath is empty")
+	}
+	return dclient.apiCall(nil, "core.move_storage", infoHashes, savePath)
+}
+
+func (dclient *Client) PauseAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.PauseTorrents(infoHashes)
+}
+
+func (dclient *Client) ResumeAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.ResumeTorrents(infoHashes)
+}
+
+func (dclient *Client) RecheckAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.RecheckTorrents(infoHashes)
+}
+
+func (dclient *Client) ReannounceAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.ReannounceTorrents(infoHashes)
+}
+
+func (dclient *Client) AddTagsToAllTorrents(tags []string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.AddTagsToTorrents(infoHashes, tags)
+}
+
+func (dclient *Client) RemoveTagsFromAllTorrents(tags []string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.RemoveTagsFromTorrents(infoHashes, tags)
+}
+
+func (dclient *Client) SetAllTorrentsSavePath(savePath string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.SetTorrentsSavePath(infoHashes, savePath)
+}
+
+func (dclient *Client) GetTags() ([]string, error) {
+	return dclient.meta.getTags()
+}
+
+func (dclient *Client) CreateTags(tags ...string) error {
+	return dclient.meta.createTags(tags...)
+}
+
+func (dclient *Client) DeleteTags(tags ...string) error {
+	return dclient.meta.deleteTags(tags...)
+}
+
+// Create a label if not exists. Deluge Label plugin only allows lowercase "[a-z0-9_-.]" chars in label name.
+func (dclient *Client) makeLabel(label string) error {
+	var labels []string
+	if err := dclient.apiCall(&labels, "label.get_labels"); err != nil {
+		return err
+	}
+	if slices.Contains(labels, label) {
+		return nil
+	}
+	return dclient.apiCall(nil, "label.add", label)
+}
+
+func (dclient *Client) setLabel(infoHashes []string, category string) error {
+	label := normalizeLabel(category)
+	if label != "" {
+		if err := dclient.makeLabel(label); err != nil {
+			return fmt.Errorf("failed to create label: %w", err)
+		}
+	}
+	for _, infoHash := range infoHashes {
+		if err := dclient.apiCall(nil, "label.set_torrent", infoHash, label); err != nil {
+			return err
+		}
+		if dclient.torrents[infoHash] != nil {
+			dclient.torrents[infoHash].Label = label
+		}
+	}
+	return nil
+}
+
+// Deluge has no concept of category save path. Use "move completed" option of label to simulate it.
+func (dclient *Client) MakeCategory(category string, savePath string) error {
+	label := normalizeLabel(category)
+	if err := dclient.makeLabel(label); err != nil {
+		return err
+	}
+	if savePath != constants.NONE {
+		return dclient.apiCall(nil, "label.set_options", label, map[string]any{
+			"apply_move_completed": savePath != "",
+			"move_completed":       savePath != "",
+			"move_completed_path":  savePath,
+		})
+	}
+	return nil
+}
+
+func (dclient *Client) DeleteCategories(categories []string) error {
+	for _, category := range categories {
+		if err := dclient.apiCall(nil, "label.remove", normalizeLabel(category)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
+	var labels []string
+	if err := dclient.apiCall(&labels, "label.get_labels"); err != nil {
+		return nil, err
+	}
+	cats := []*client.TorrentCategory{}
+	for _, label := range labels {
+		var options struct {
+			MoveCompleted     bool   `json:"move_completed"`
+			MoveCompletedPath string `json:"move_completed_path"`
+		}
+		if err := dclient.apiCall(&options, "label.get_options", label); err != nil {
+			return nil, err
+		}
+		cat := &client.TorrentCategory{Name: label}
+		if options.MoveCompleted {
+			cat.SavePath = options.MoveCompletedPath
+		}
+		cats = append(cats, cat)
+	}
+	return cats, nil
+}
+
+func (dclient *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	if category == constants.NONE {
+		category = ""
+	}
+	return dclient.setLabel(infoHashes, category)
+}
+
+func (dclient *Client) SetAllTorrentsCatetory(category string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.SetTorrentsCatetory(infoHashes, category)
+}
+
+func (dclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	// Deluge does not support seeding time limit
+	options := map[string]any{
+		"stop_at_ratio": ratioLimit > 0,
+	}
+	if ratioLimit > 0 {
+		options["stop_ratio"] = ratioLimit
+	}
+	return dclient.apiCall(nil, "core.set_torrent_options", infoHashes, options)
+}
+
+func (dclient *Client) SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.SetTorrentsShareLimits(infoHashes, ratioLimit, seedingTimeLimit)
+}
+
+func (dclient *Client) TorrentRootPathExists(rootFolder string) bool {
+	if rootFolder == "" {
+		return false
+	}
+	if err := dclient.sync(); err != nil {
+		return false
+	}
+	for _, torrent := range dclient.torrents {
+		if torrent.Name == rootFolder {
+			return true
+		}
+	}
+	return false
+}
+
+func (dclient *Client) getTorrentContents(infoHash string) (*apiTorrentContents, error) {
+	var contents apiTorrentContents
+	err := dclient.apiCall(&contents, "core.get_torrent_status", infoHash,
+		[]string{"files", "file_progress", "file_priorities"})
+	if err != nil {
+		return nil, err
+	}
+	if len(contents.FileProgress) != len(contents.Files) || len(contents.FilePriorities) != len(contents.Files) {
+		return nil, fmt.Errorf("torrent not found or invalid files data")
+	}
+	return &contents, nil
+}
+
+func (dclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentContentFile, error) {
+	contents, err := dclient.getTorrentContents(infoHash)
+	if err != nil {
+		return nil, err
+	}
+	files := []*client.TorrentContentFile{}
+	for i, file := range contents.Files {
+		files = append(files, &client.TorrentContentFile{
+			Index:    file.Index,
+			Path:     strings.ReplaceAll(file.Path, `\`, "/"),
+			Size:     file.Size,
+			Ignored:  contents.FilePriorities[i] == 0,
+			Complete: contents.FileProgress[i] >= 1,
+			Progress: contents.FileProgress[i],
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Index < files[j].Index
+	})
+	return files, nil
+}
+
+func (dclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
+	if len(fileIndexes) == 0 {
+		return fmt.Errorf("must provide at least fileIndex")
+	}
+	contents, err := dclient.getTorrentContents(infoHash)
+	if err != nil {
+		return err
+	}
+	priorities := contents.FilePriorities
+	for _, index := range fileIndexes {
+		if index < 0 || index >= int64(len(priorities)) {
+			return fmt.Errorf("invalid file index %d", index)
+		}
+		priorities[index] = qbPriority2Deluge(priority)
+	}
+	return dclient.apiCall(nil, "core.set_torrent_options", []string{infoHash}, map[string]any{
+		"file_priorities": priorities,
+	})
+}
+
+func (dclient *Client) GetFreeSpaceOnPath(path string) (int64, error) {
+	var freeSpace int64
+	if err := dclient.apiCall(&freeSpace, "core.get_free_space", path); err != nil {
+		return 0, err
+	}
+	return freeSpace, nil
+}
+
+func (dclient *Client) PurgeCache() {
+	dclient.datatime = 0
+	dclient.torrents = nil
+	dclient.stats = nil
+	dclient.unfinishedSize = 0
+	dclient.unfinishedDownloadingSize = 0
+	dclient.contentPathTorrents = nil
+	dclient.meta.purge()
+}
+
+func (dclient *Client) GetStatus() (*client.Status, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	status := &client.Status{
+		FreeSpaceOnDisk:           -1,
+		UnfinishedSize:            dclient.unfinishedSize,
+		UnfinishedDownloadingSize: dclient.unfinishedDownloadingSize,
+	}
+	if dclient.stats != nil {
+		status.DownloadSpeed = int64(dclient.stats.DownloadRate)
+		status.UploadSpeed = int64(dclient.stats.UploadRate)
+		status.DownloadSpeedLimit = max(kib2Bytes(dclient.stats.MaxDownload), 0)
+		status.UploadSpeedLimit = max(kib2Bytes(dclient.stats.MaxUpload), 0)
+		if dclient.stats.FreeSpace >= 0 {
+			status.FreeSpaceOnDisk = dclient.stats.FreeSpace
+		}
+	}
+	if tags, err := dclient.meta.getTags(); err == nil {
+		status.NoAdd = slices.Contains(tags, config.NOADD_TAG)
+		status.NoDel = slices.Contains(tags, config.NODEL_TAG)
+	}
+	return status, nil
+}
+
+func (dclient *Client) GetName() string {
+	return dclient.Name
+}
+
+func (dclient *Client) GetClientConfig() *config.ClientConfigStruct {
+	return dclient.ClientConfig
+}
+
+func (dclient *Client) SetConfig(variable string, value string) error {
+	if strings.HasPrefix(variable, "de_") && len(variable) > 3 {
+		v, _ := util.String2Any(value)
+		return dclient.apiCall(nil, "core.set_config", map[string]any{variable[3:]: v})
+	}
+	switch variable {
+	case "global_download_speed_limit":
+		return dclient.apiCall(nil, "core.set_config", map[string]any{
+			"max_download_speed": bytes2Kib(util.ParseInt(value)),
+		})
+	case "global_upload_speed_limit":
+		return dclient.apiCall(nil, "core.set_config", map[string]any{
+			"max_upload_speed": bytes2Kib(util.ParseInt(value)),
+		})
+	case "free_disk_space", "global_download_speed", "global_upload_speed":
+		return fmt.Errorf("%s is read-only", variable)
+	case "save_path":
+		return dclient.apiCall(nil, "core.set_config", map[string]any{"download_location": value})
+	default:
+		return nil
+	}
+}
+
+func (dclient *Client) GetConfig(variable string) (string, error) {
+	if strings.HasPrefix(variable, "de_") && len(variable) > 3 {
+		var value any
+		if err := dclient.apiCall(&value, "core.get_config_value", variable[3:]); err != nil {
+			return "", err
+		}
+		return fmt.Sprint(value), nil
+	}
+	switch variable {
+	case "global_download_speed_limit", "global_upload_speed_limit":
+		key := "max_download_speed"
+		if variable == "global_upload_speed_limit" {
+			key = "max_upload_speed"
+		}
+		var value float64
+		if err := dclient.apiCall(&value, "core.get_config_value", key); err != nil {
+			return "", err
+		}
+		return fmt.Sprint(max(kib2Bytes(value), 0)), nil
+	case "free_disk_space":
+		status, err := dclient.GetStatus()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(status.FreeSpaceOnDisk), nil
+	case "global_download_speed":
+		status, err := dclient.GetStatus()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(status.DownloadSpeed), nil
+	case "global_upload_speed":
+		status, err := dclient.GetStatus()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(status.UploadSpeed), nil
+	case "save_path":
+		var value string
+		err := dclient.apiCall(&value, "core.get_config_value", "download_location")
+		return value, err
+	default:
+		return "", nil
+	}
+}
+
+// Deluge only reports the status of current working tracker of a torrent.
+func (dclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
+	var dt apiTorrent
+	if err := dclient.apiCall(&dt, "core.get_torrent_status", infoHash,
+		[]string{"tracker", "tracker_status", "trackers"}); err != nil {
+		return nil, err
+	}
+	currentTracker := dt.tracker()
+	trackers := client.TorrentTrackers{}
+	for _, dtracker := range dt.Trackers {
+		tracker := client.TorrentTracker{
+			Url:    dtracker.Url,
+			Status: "notcontacted",
+		}
+		if dtracker.Url == currentTracker {
+			tracker.Status, tracker.Msg = parseTrackerStatus(dt.TrackerStatus)
+		}
+		trackers = append(trackers, tracker)
+	}
+	return trackers, nil
+}
+
+func (dclient *Client) getTrackers(infoHash string) ([]*apiTracker, error) {
+	var dt apiTorrent
+	if err := dclient.apiCall(&dt, "core.get_torrent_status", infoHash, []string{"trackers"}); err != nil {
+		return nil, err
+	}
+	return dt.Trackers, nil
+}
+
+func (dclient *Client) setTrackers(infoHash string, trackers []*apiTracker) error {
+	return dclient.apiCall(nil, "core.set_torrent_trackers", infoHash, trackers)
+}
+
+func (dclient *Client) EditTorrentTracker(infoHash string, oldTracker string,
+	newTracker string, replaceHost bool) error {
+	trackers, err := dclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	index := -1
+	newTrackerUrl := newTracker
+	for i, tracker := range trackers {
+		if replaceHost {
+			if !util.MatchUrlWithHostOrUrl(tracker.Url, oldTracker) {
+				continue
+			}
+			if !util.IsUrl(newTracker) {
+				urlObj, err := url.Parse(tracker.Url)
+				if err != nil {
+					continue
+				}
+				urlObj.Host = newTracker
+				newTrackerUrl = urlObj.String()
+			}
+		} else if tracker.Url != oldTracker {
+			continue
+		}
+		index = i
+		break
+	}
+	if index == -1 {
+		return fmt.Errorf("torrent %s old tracker %s does NOT exist", infoHash, oldTracker)
+	}
+	if trackers[index].Url == newTrackerUrl {
+		return nil
+	}
+	trackers[index].Url = newTrackerUrl
+	return dclient.setTrackers(infoHash, trackers)
+}
+
+func (dclient *Client) AddTorrentTrackers(infoHash string, trackers []string,
+	oldTracker string, removeExisting bool) error {
+	dtrackers, err := dclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	if oldTracker != "" && !slices.ContainsFunc(dtrackers, func(t *apiTracker) bool {
+		return util.MatchUrlWithHostOrUrl(t.Url, oldTracker)
+	}) {
+		return nil
+	}
+	tier := int64(0)
+	if removeExisting {
+		dtrackers = nil
+	} else {
+		trackers = util.Filter(trackers, func(tracker string) bool {
+			return !slices.ContainsFunc(dtrackers, func(t *apiTracker) bool { return t.Url == tracker })
+		})
+		for _, t := range dtrackers {
+			tier = max(tier, t.Tier+1)
+		}
+	}
+	if len(trackers) == 0 {
+		return nil
+	}
+	for _, tracker := range trackers {
+		dtrackers = append(dtrackers, &apiTracker{Url: tracker, Tier: tier})
+		tier++
+	}
+	return dclient.setTrackers(infoHash, dtrackers)
+}
+
+func (dclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string) error {
+	dtrackers, err := dclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	newTrackers := util.Filter(dtrackers, func(t *apiTracker) bool {
+		return !slices.Contains(trackers, t.Url)
+	})
+	if len(newTrackers) == len(dtrackers) {
+		return nil
+	}
+	return dclient.setTrackers(infoHash, newTrackers)
+}
+
+func (dclient *Client) Close() {
+	dclient.PurgeCache()
+	if dclient.Logined {
+		dclient.Logined = false
+		dclient.call(nil, "auth.delete_session")
+	}
+}
+
+// Parse Deluge tracker status text, e.g. "Announce OK", "Error: unregistered torrent".
+func parseTrackerStatus(trackerStatus string) (status string, msg string) {
+	prefix, msg, found := strings.Cut(trackerStatus, ":")
+	msg = strings.TrimSpace(msg)
+	if !found {
+		prefix = trackerStatus
+	}
+	switch prefix {
+	case "":
+		return "notcontacted", ""
+	case "Announce OK":
+		return "working", msg
+	case "Announce Sent":
+		return "updating", msg
+	case "Error", "Warning":
+		return "error", msg
+	default:
+		return "unknown", trackerStatus
+	}
+}
+
+// Deluge Label plugin only allows lowercase labels.
+func normalizeLabel(category string) string {
+	return strings.ToLower(category)
+}
+
+func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
+	client.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	urlObj, err := url.Parse(clientConfig.Url)
+	if err != nil || (urlObj.Scheme != "http" && urlObj.Scheme != "https") || urlObj.Host == "" {
+		return nil, fmt.Errorf("invalid deluge url: %s", clientConfig.Url)
+	}
+	client := &Client{
+		Name:         name,
+		ClientConfig: clientConfig,
+		Config:       config,
+		HttpClient: &http.Client{
+			Jar: jar,
+		},
+		rpcUrl: strings.TrimSuffix(clientConfig.Url, "/") + "/json",
+		meta:   newMetaStore(name),
+	}
+	return client, nil
+}
+
+func init() {
+	client.Register(&client.RegInfo{
+		Name:    "deluge",
+		Creator: NewClient,
+	})
+}
+
+var (
+	_ client.Client = (*Client)(nil)
+)
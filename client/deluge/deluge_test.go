@@ -0,0 +1,130 @@
+package deluge_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/deluge"
+	"github.com/sagan/ptool/config"
+)
+
+const testInfoHash = "0123456789abcdef0123456789abcdef01234567"
+
+// A minimal stand-in of Deluge Web UI JSON-RPC server.
+func newTestServer(t *testing.T) *httptest.Server {
+	labels := map[string]string{}
+	added := false
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/json" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+			Id     int64  `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid rpc request: %v", err)
+			return
+		}
+		var result any
+		switch req.Method {
+		case "auth.login":
+			result = req.Params[0] == "deluge"
+		case "web.connected":
+			result = true
+		case "web.update_ui":
+			torrents := map[string]any{}
+			if added {
+				torrents[testInfoHash] = map[string]any{
+					"name":                  "foo",
+					"state":                 "Seeding",
+					"download_location":     "/downloads",
+					"total_size":            2048,
+					"total_wanted":          1024,
+					"total_done":            1024,
+					"upload_payload_rate":   4096,
+					"download_payload_rate": 0,
+					"max_upload_speed":      -1,
+					"max_download_speed":    10,
+					"time_added":            1700000000.5,
+					"time_since_transfer":   -1,
+					"trackers":              []any{map[string]any{"url": "https://tracker.example.com/announce", "tier": 0}},
+					"label":                 labels[testInfoHash],
+					"is_finished":           true,
+				}
+			}
+			result = map[string]any{
+				"connected": true,
+				"torrents":  torrents,
+				"stats": map[string]any{
+					"upload_rate": 4096, "download_rate": 0, "max_upload": -1, "max_download": 100, "free_space": 12345,
+				},
+			}
+		case "core.add_torrent_file":
+			added = true
+			result = testInfoHash
+		case "label.get_labels":
+			result = []string{}
+		case "label.add":
+		case "label.set_torrent":
+			labels[req.Params[0].(string)] = req.Params[1].(string)
+		default:
+			w.Write([]byte(`{"id":1,"result":null,"error":{"message":"unknown method","code":2}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": req.Id, "result": result, "error": nil})
+	}))
+}
+
+func TestDelugeClient(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	config.ConfigDir = t.TempDir()
+	clientConfig := &config.ClientConfigStruct{Type: "deluge", Name: "de", Url: server.URL + "/"}
+	clientInstance, err := deluge.NewClient("de", clientConfig, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer clientInstance.Close()
+
+	err = clientInstance.AddTorrent([]byte("d4:infod4:name3:fooee"), &client.TorrentOption{
+		Category: config.BRUSH_CAT,
+		Tags:     []string{"site:foo"},
+	}, map[string]int64{"dcet": 100})
+	if err != nil {
+		t.Fatalf("failed to add torrent: %v", err)
+	}
+	torrents, err := clientInstance.GetTorrents("", config.BRUSH_CAT, true)
+	if err != nil {
+		t.Fatalf("failed to get torrents: %v", err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("expect 1 torrent, got %d", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.InfoHash != testInfoHash || torrent.State != "seeding" || torrent.ContentPath != "/downloads/foo" ||
+		torrent.Size != 1024 || torrent.SizeTotal != 2048 || torrent.TrackerDomain != "tracker.example.com" ||
+		torrent.DownloadSpeedLimit != 10*1024 || torrent.UploadedSpeedLimit != -1 {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	if !slices.Equal(torrent.Tags, []string{"site:foo"}) || torrent.Meta["dcet"] != 100 {
+		t.Errorf("unexpected torrent tags / meta: %v / %v", torrent.Tags, torrent.Meta)
+	}
+	status, err := clientInstance.GetStatus()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status.FreeSpaceOnDisk != 12345 || status.UploadSpeed != 4096 || status.DownloadSpeedLimit != 100*1024 ||
+		status.UploadSpeedLimit != 0 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if _, err = clientInstance.GetTorrentContents(testInfoHash); err == nil {
+		t.Errorf("expect rpc error for unknown method")
+	}
+}
@@ -0,0 +1,168 @@
+package deluge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/natefinch/atomic"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+)
+
+// Deluge (and it's Label plugin) has no concept of torrent tags, and a torrent can have only one label,
+// which is used as category. Tags & meta of torrents are stored in a local file in config dir instead.
+const META_FILE = "deluge-%s.json" // %s: client name
+
+type torrentMeta struct {
+	Tags []string         `json:"tags,omitempty"`
+	Meta map[string]int64 `json:"meta,omitempty"`
+}
+
+type metaStore struct {
+	Tags     []string                `json:"tags"` // all created tags
+	Torrents map[string]*torrentMeta `json:"torrents"`
+	filename string
+	loaded   bool
+	mu       sync.Mutex
+}
+
+func newMetaStore(clientName string) *metaStore {
+	return &metaStore{
+		filename: filepath.Join(config.ConfigDir, fmt.Sprintf(META_FILE, clientName)),
+	}
+}
+
+func (ms *metaStore) load() error {
+	if ms.loaded {
+		return nil
+	}
+	ms.Torrents = map[string]*torrentMeta{}
+	contents, err := os.ReadFile(ms.filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read deluge meta file: %w", err)
+		}
+	} else if err = json.Unmarshal(contents, ms); err != nil {
+		return fmt.Errorf("failed to parse deluge meta file: %w", err)
+	}
+	if ms.Torrents == nil {
+		ms.Torrents = map[string]*torrentMeta{}
+	}
+	ms.loaded = true
+	return nil
+}
+
+func (ms *metaStore) save() error {
+	contents, err := json.Marshal(ms)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ms.filename), constants.PERM_DIR); err != nil {
+		return err
+	}
+	return atomic.WriteFile(ms.filename, bytes.NewReader(contents))
+}
+
+// Get tags & meta of a torrent. Return nil if not exists.
+func (ms *metaStore) get(infoHash string) *torrentMeta {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.load(); err != nil {
+		return nil
+	}
+	return ms.Torrents[infoHash]
+}
+
+// Update tags & meta of torrents in store and persist it.
+// The updater is called with the existing torrent meta (never nil).
+func (ms *metaStore) update(infoHashes []string, updater func(tm *torrentMeta)) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.load(); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		tm := ms.Torrents[infoHash]
+		if tm == nil {
+			tm = &torrentMeta{}
+		}
+		updater(tm)
+		for _, tag := range tm.Tags {
+			if !slices.Contains(ms.Tags, tag) {
+				ms.Tags = append(ms.Tags, tag)
+			}
+		}
+		if len(tm.Tags) == 0 && len(tm.Meta) == 0 {
+			delete(ms.Torrents, infoHash)
+		} else {
+			ms.Torrents[infoHash] = tm
+		}
+	}
+	return ms.save()
+}
+
+func (ms *metaStore) remove(infoHashes []string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.load(); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		delete(ms.Torrents, infoHash)
+	}
+	return ms.save()
+}
+
+func (ms *metaStore) getTags() ([]string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.load(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(ms.Tags), nil
+}
+
+func (ms *metaStore) createTags(tags ...string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.load(); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if !slices.Contains(ms.Tags, tag) {
+			ms.Tags = append(ms.Tags, tag)
+		}
+	}
+	return ms.save()
+}
+
+// Delete tags, also remove them from all torrents.
+func (ms *metaStore) deleteTags(tags ...string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.load(); err != nil {
+		return err
+	}
+	ms.Tags = slices.DeleteFunc(ms.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	for infoHash, tm := range ms.Torrents {
+		tm.Tags = slices.DeleteFunc(tm.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+		if len(tm.Tags) == 0 && len(tm.Meta) == 0 {
+			delete(ms.Torrents, infoHash)
+		}
+	}
+	return ms.save()
+}
+
+func (ms *metaStore) purge() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.loaded = false
+	ms.Tags = nil
+	ms.Torrents = nil
+}
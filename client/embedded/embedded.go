@@ -0,0 +1,1433 @@
+package embedded
+
+// Embedded BitTorrent client, powered by anacrolix/torrent: https://github.com/anacrolix/torrent .
+// The engine runs inside the ptool process. It's started on first use and stopped when client is closed,
+// so torrents are only seeded / downloaded while ptool is running (e.g. "ptool daemon").
+// All state (torrents, tags, categories & pieces completion) is persisted in "<config_dir>/embedded-<name>/" dir.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	alog "github.com/anacrolix/log"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/gofrs/flock"
+	"github.com/natefinch/atomic"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/osutil"
+)
+
+const (
+	SAMPLE_INTERVAL = 3 * time.Second  // interval of calculating speeds & enforcing share limits
+	SAVE_INTERVAL   = 60 * time.Second // interval of persisting transfer stats of torrents
+	LIMITER_BURST   = 256 * 1024
+)
+
+// A torrent that is loaded in engine.
+type liveTorrent struct {
+	t              *torrent.Torrent
+	baseUploaded   int64 // uploaded bytes of previous sessions
+	baseDownloaded int64
+	lastUploaded   int64 // uploaded bytes of current session, at last sampling
+	lastDownloaded int64
+	uploadSpeed    int64
+	downloadSpeed  int64
+	checking       bool
+}
+
+func (lt *liveTorrent) sessionStats() (uploaded int64, downloaded int64) {
+	stats := lt.t.Stats()
+	return stats.BytesWrittenData.Int64(), stats.BytesReadUsefulData.Int64()
+}
+
+// Return the size of selected (not ignored) files and the completed size of them.
+// Both are 0 if metadata of torrent is not available yet.
+func (lt *liveTorrent) selectedSize(ts *torrentState) (size int64, completed int64) {
+	if lt.t.Info() == nil {
+		return 0, 0
+	}
+	for i, file := range lt.t.Files() {
+		if filePriority(ts, i) > 0 {
+			size += file.Length()
+			completed += file.BytesCompleted()
+		}
+	}
+	return size, completed
+}
+
+func (lt *liveTorrent) isDone(ts *torrentState) bool {
+	if lt.t.Info() == nil {
+		return false
+	}
+	size, completed := lt.selectedSize(ts)
+	return completed >= size
+}
+
+func (lt *liveTorrent) trackers(ts *torrentState) [][]string {
+	if ts.Trackers != nil {
+		return ts.Trackers
+	}
+	mi := lt.t.Metainfo()
+	if len(mi.AnnounceList) > 0 {
+		return mi.AnnounceList
+	}
+	if mi.Announce != "" {
+		return [][]string{{mi.Announce}}
+	}
+	return nil
+}
+
+type Client struct {
+	Name            string
+	ClientConfig    *config.ClientConfigStruct
+	Config          *config.ConfigStruct
+	dir             string
+	mu              sync.Mutex
+	engine          *torrent.Client
+	lock            *flock.Flock
+	pieceCompletion storage.PieceCompletion
+	uploadLimiter   *rate.Limiter
+	downloadLimiter *rate.Limiter
+	state           *engineState
+	torrents        map[string]*liveTorrent
+	dirty           bool // transfer stats of torrents changed but not persisted yet
+	stop            chan struct{}
+	stopped         chan struct{}
+}
+
+// Start the engine and load all torrents, if not started yet. Must be called with mu held.
+func (ec *Client) start() (err error) {
+	if ec.engine != nil {
+		return nil
+	}
+	// Only one process can use the engine (listen port & data) at the same time.
+	lock, err := config.LockConfigDirFile(fmt.Sprintf(LOCK_FILE, ec.Name))
+	if err != nil {
+		return fmt.Errorf("embedded client is being used by another ptool process: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if ec.pieceCompletion != nil {
+				ec.pieceCompletion.Close()
+				ec.pieceCompletion = nil
+			}
+			lock.Unlock()
+		}
+	}()
+	if err = os.MkdirAll(filepath.Join(ec.dir, TORRENTS_DIR), constants.PERM_DIR); err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+	state, err := loadState(filepath.Join(ec.dir, STATE_FILE))
+	if err != nil {
+		return err
+	}
+	ec.state = state
+	if err = os.MkdirAll(ec.defaultSavePath(), constants.PERM_DIR); err != nil {
+		return fmt.Errorf("failed to create save path: %w", err)
+	}
+	// Use the pure Go bolt db, as sqlite requires cgo.
+	if ec.pieceCompletion, err = storage.NewBoltPieceCompletion(ec.dir); err != nil {
+		return fmt.Errorf("failed to open pieces completion db: %w", err)
+	}
+	ec.uploadLimiter = newLimiter(state.UploadSpeedLimit)
+	ec.downloadLimiter = newLimiter(state.DownloadSpeedLimit)
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = ec.dir
+	cfg.DefaultStorage = ec.newStorage(ec.defaultSavePath())
+	cfg.Seed = true
+	cfg.NoDHT = ec.ClientConfig.EmbeddedDisableDht
+	if ec.ClientConfig.EmbeddedListenPort > 0 {
+		cfg.ListenPort = int(ec.ClientConfig.EmbeddedListenPort)
+	} else if ec.ClientConfig.EmbeddedListenPort < 0 {
+		cfg.ListenPort = 0 // random port
+	}
+	cfg.UploadRateLimiter = ec.uploadLimiter
+	cfg.DownloadRateLimiter = ec.downloadLimiter
+	cfg.Logger = alog.Default.WithFilterLevel(alog.Critical)
+	if ec.engine, err = torrent.NewClient(cfg); err != nil {
+		return fmt.Errorf("failed to start engine: %w", err)
+	}
+	ec.torrents = map[string]*liveTorrent{}
+	for infoHash, ts := range state.Torrents {
+		if _, err := ec.load(infoHash, ts); err != nil {
+			log.Warnf("embedded client %s: failed to load torrent %s (%s): %v", ec.Name, infoHash, ts.Name, err)
+		}
+	}
+	ec.lock = lock
+	ec.stop = make(chan struct{})
+	ec.stopped = make(chan struct{})
+	go ec.sample()
+	log.Tracef("embedded client %s started, listen port %d", ec.Name, ec.engine.LocalPort())
+	return nil
+}
+
+// Acquire the client: lock it & make sure engine is started. Caller must call release() if no error.
+func (ec *Client) acquire() error {
+	ec.mu.Lock()
+	if err := ec.start(); err != nil {
+		ec.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (ec *Client) release() {
+	ec.mu.Unlock()
+}
+
+func (ec *Client) defaultSavePath() string {
+	if ec.state != nil && ec.state.SavePath != "" {
+		return ec.state.SavePath
+	}
+	if ec.ClientConfig.EmbeddedSavePath != "" {
+		return ec.ClientConfig.EmbeddedSavePath
+	}
+	return filepath.Join(ec.dir, DOWNLOAD_DIR)
+}
+
+func (ec *Client) newStorage(savePath string) storage.ClientImpl {
+	return storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   savePath,
+		PieceCompletion: ec.pieceCompletion,
+	})
+}
+
+func (ec *Client) torrentFilename(infoHash string) string {
+	return filepath.Join(ec.dir, TORRENTS_DIR, infoHash+".torrent")
+}
+
+func (ec *Client) save() error {
+	ec.dirty = false
+	return ec.state.save(filepath.Join(ec.dir, STATE_FILE))
+}
+
+// Load a torrent into engine, from it's .torrent file in state dir, or magnet uri if metadata is not fetched yet.
+func (ec *Client) load(infoHash string, ts *torrentState) (*liveTorrent, error) {
+	var spec *torrent.TorrentSpec
+	if mi, err := metainfo.LoadFromFile(ec.torrentFilename(infoHash)); err == nil {
+		if spec, err = torrent.TorrentSpecFromMetaInfoErr(mi); err != nil {
+			return nil, err
+		}
+	} else if ts.Magnet != "" {
+		if spec, err = torrent.TorrentSpecFromMagnetUri(ts.Magnet); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("failed to load torrent file: %w", err)
+	}
+	if ts.Trackers != nil {
+		spec.Trackers = ts.Trackers
+	}
+	spec.Storage = ec.newStorage(ts.SavePath)
+	spec.DisallowDataDownload = ts.Paused
+	spec.DisallowDataUpload = ts.Paused
+	t, _, err := ec.engine.AddTorrentSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	lt := &liveTorrent{
+		t:              t,
+		baseUploaded:   ts.Uploaded,
+		baseDownloaded: ts.Downloaded,
+	}
+	ec.torrents[infoHash] = lt
+	go ec.onInfo(infoHash, lt)
+	return lt, nil
+}
+
+// Drop a torrent from engine and load it again, e.g. after trackers or save path changed.
+func (ec *Client) reload(infoHash string, ts *torrentState) error {
+	if lt := ec.torrents[infoHash]; lt != nil {
+		ec.unload(infoHash, ts)
+	}
+	_, err := ec.load(infoHash, ts)
+	return err
+}
+
+func (ec *Client) unload(infoHash string, ts *torrentState) {
+	lt := ec.torrents[infoHash]
+	if lt == nil {
+		return
+	}
+	updateStats(lt, ts)
+	delete(ec.torrents, infoHash)
+	lt.t.Drop()
+}
+
+// Wait for metadata of torrent, then apply file priorities to it.
+// For torrent added by magnet uri, also save the fetched metadata as .torrent file.
+func (ec *Client) onInfo(infoHash string, lt *liveTorrent) {
+	select {
+	case <-lt.t.GotInfo():
+	case <-lt.t.Closed():
+		return
+	}
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ts := ec.state.Torrents[infoHash]
+	if ec.torrents[infoHash] != lt || ts == nil {
+		return
+	}
+	applyFilePriorities(lt, ts)
+	if ts.Magnet != "" {
+		mi := lt.t.Metainfo()
+		buf := &bytes.Buffer{}
+		if err := mi.Write(buf); err != nil {
+			log.Warnf("embedded client %s: failed to encode metadata of torrent %s: %v", ec.Name, infoHash, err)
+			return
+		}
+		if err := atomic.WriteFile(ec.torrentFilename(infoHash), buf); err != nil {
+			log.Warnf("embedded client %s: failed to save metadata of torrent %s: %v", ec.Name, infoHash, err)
+			return
+		}
+		ts.Name = lt.t.Name()
+		ts.Magnet = ""
+		if err := ec.save(); err != nil {
+			log.Warnf("embedded client %s: failed to save state: %v", ec.Name, err)
+		}
+	}
+}
+
+// Periodically calculate speeds, update activity / completion time and enforce share limits of torrents.
+func (ec *Client) sample() {
+	defer close(ec.stopped)
+	ticker := time.NewTicker(SAMPLE_INTERVAL)
+	defer ticker.Stop()
+	lastSampled := time.Now()
+	lastSaved := lastSampled
+	for {
+		select {
+		case <-ec.stop:
+			return
+		case now := <-ticker.C:
+			ec.mu.Lock()
+			seconds := now.Sub(lastSampled).Seconds()
+			lastSampled = now
+			for infoHash, lt := range ec.torrents {
+				if ts := ec.state.Torrents[infoHash]; ts != nil {
+					ec.sampleTorrent(lt, ts, seconds)
+				}
+			}
+			if ec.dirty && now.Sub(lastSaved) >= SAVE_INTERVAL {
+				lastSaved = now
+				if err := ec.save(); err != nil {
+					log.Warnf("embedded client %s: failed to save state: %v", ec.Name, err)
+				}
+			}
+			ec.mu.Unlock()
+		}
+	}
+}
+
+func (ec *Client) sampleTorrent(lt *liveTorrent, ts *torrentState, seconds float64) {
+	now := util.Now()
+	uploaded, downloaded := lt.sessionStats()
+	if seconds > 0 {
+		lt.uploadSpeed = int64(float64(uploaded-lt.lastUploaded) / seconds)
+		lt.downloadSpeed = int64(float64(downloaded-lt.lastDownloaded) / seconds)
+	}
+	if uploaded != lt.lastUploaded || downloaded != lt.lastDownloaded {
+		lt.lastUploaded = uploaded
+		lt.lastDownloaded = downloaded
+		ts.Uploaded = lt.baseUploaded + uploaded
+		ts.Downloaded = lt.baseDownloaded + downloaded
+		ts.ActivityTime = now
+		ec.dirty = true
+	}
+	if ts.Ctime <= 0 && !lt.checking && lt.isDone(ts) {
+		ts.Ctime = now
+		ec.dirty = true
+	}
+	if ts.Paused || ts.Ctime <= 0 {
+		return
+	}
+	size, _ := lt.selectedSize(ts)
+	if ts.RatioLimit > 0 && size > 0 && float64(ts.Uploaded)/float64(size) >= ts.RatioLimit ||
+		ts.SeedingTimeLimit > 0 && now-ts.Ctime >= ts.SeedingTimeLimit {
+		log.Debugf("embedded client %s: torrent %s (%s) reached share limits, pause it", ec.Name, lt.t.InfoHash(), ts.Name)
+		ts.Paused = true
+		lt.t.DisallowDataDownload()
+		lt.t.DisallowDataUpload()
+		ec.dirty = true
+	}
+}
+
+func (ec *Client) toTorrent(infoHash string, ts *torrentState, lt *liveTorrent) *client.Torrent {
+	name := ts.Name
+	sizeTotal := int64(0)
+	if lt.t.Info() != nil {
+		name = lt.t.Name()
+		sizeTotal = lt.t.Length()
+	}
+	size, completed := lt.selectedSize(ts)
+	stats := lt.t.Stats()
+	tracker := ""
+	if trackers := lt.trackers(ts); len(trackers) > 0 && len(trackers[0]) > 0 {
+		tracker = trackers[0][0]
+	}
+	state, lowLevelState := "", ""
+	done := lt.isDone(ts)
+	switch {
+	case lt.checking:
+		state, lowLevelState = "checking", "checking"
+	case ts.Paused && done:
+		state, lowLevelState = "completed", "paused"
+	case ts.Paused:
+		state, lowLevelState = "paused", "paused"
+	case lt.t.Info() == nil:
+		state, lowLevelState = "downloading", "metadata"
+	case done:
+		state, lowLevelState = "seeding", "seeding"
+	default:
+		state, lowLevelState = "downloading", "downloading"
+	}
+	torrent := &client.Torrent{
+		InfoHash:           infoHash,
+		Name:               name,
+		TrackerDomain:      util.ParseUrlHostname(tracker),
+		TrackerBaseDomain:  util.GetUrlDomain(tracker),
+		Tracker:            tracker,
+		State:              state,
+		LowLevelState:      lowLevelState,
+		Atime:              ts.Atime,
+		Ctime:              ts.Ctime,
+		ActivityTime:       max(ts.ActivityTime, ts.Atime),
+		Category:           ts.Category,
+		SavePath:           ts.SavePath,
+		ContentPath:        filepath.Join(ts.SavePath, name),
+		Tags:               slices.Clone(ts.Tags),
+		Meta:               util.CopyMap(ts.Meta, false),
+		Downloaded:         lt.baseDownloaded + stats.BytesReadUsefulData.Int64(),
+		DownloadSpeed:      lt.downloadSpeed,
+		DownloadSpeedLimit: -1, // only global speed limits are supported
+		Uploaded:           lt.baseUploaded + stats.BytesWrittenData.Int64(),
+		UploadSpeed:        lt.uploadSpeed,
+		UploadedSpeedLimit: -1,
+		Size:               size,
+		SizeTotal:          sizeTotal,
+		SizeCompleted:      completed,
+		// The engine does not expose the scrape results of trackers, use the connected peers instead.
+		Seeders:  int64(stats.ConnectedSeeders),
+		Leechers: int64(max(stats.ActivePeers-stats.ConnectedSeeders, 0)),
+	}
+	if torrent.Meta == nil {
+		torrent.Meta = map[string]int64{}
+	}
+	return torrent
+}
+
+func (ec *Client) getAllInfoHashes() ([]string, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	return util.MapKeys(ec.state.Torrents), nil
+}
+
+// Call fn on each existing torrent of infoHashes, with client acquired, then persist the state.
+func (ec *Client) updateTorrents(infoHashes []string, fn func(infoHash string, ts *torrentState) error) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	var errs []error
+	for _, infoHash := range infoHashes {
+		ts := ec.state.Torrents[infoHash]
+		if ts == nil {
+			continue
+		}
+		if err := fn(infoHash, ts); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", infoHash, err))
+		}
+	}
+	if err := ec.save(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d errors: %v", len(errs), errs)
+	}
+	return nil
+}
+
+func (ec *Client) Cached() bool {
+	return ec.engine != nil
+}
+
+func (ec *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	if ec.state.Torrents[infoHash] == nil {
+		return nil, fmt.Errorf("torrent not exists")
+	}
+	return os.ReadFile(ec.torrentFilename(infoHash))
+}
+
+func (ec *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	ts := ec.state.Torrents[infoHash]
+	lt := ec.torrents[infoHash]
+	if ts == nil || lt == nil {
+		return nil, nil
+	}
+	return ec.toTorrent(infoHash, ts, lt), nil
+}
+
+func (ec *Client) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	torrents := []*client.Torrent{}
+	for infoHash, ts := range ec.state.Torrents {
+		lt := ec.torrents[infoHash]
+		if lt == nil {
+			continue
+		}
+		if category != "" {
+			if category == constants.NONE {
+				if ts.Category != "" {
+					continue
+				}
+			} else if category != ts.Category {
+				continue
+			}
+		}
+		torrent := ec.toTorrent(infoHash, ts, lt)
+		if !showAll && torrent.DownloadSpeed < 1024 && torrent.UploadSpeed < 1024 {
+			continue
+		}
+		if !torrent.MatchStateFilter(stateFilter) {
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+func (ec *Client) GetTorrentsByContentPath(contentPath string) ([]*client.Torrent, error) {
+	torrents, err := ec.GetTorrents("", "", true)
+	if err != nil {
+		return nil, err
+	}
+	return util.Filter(torrents, func(torrent *client.Torrent) bool {
+		return torrent.ContentPath == contentPath
+	}), nil
+}
+
+func (ec *Client) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
+	if option == nil {
+		option = &client.TorrentOption{}
+	}
+	torrentUrl := string(torrentContent)
+	if util.IsUrl(torrentUrl) {
+		res, _, err := util.FetchUrl(torrentUrl, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch torrent: %w", err)
+		}
+		torrentContent, err = io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to fetch torrent: %w", err)
+		}
+		torrentUrl = ""
+	}
+	var infoHash, name, magnet string
+	var info *metainfo.Info
+	if strings.HasPrefix(torrentUrl, "magnet:") {
+		m, err := metainfo.ParseMagnetUri(torrentUrl)
+		if err != nil {
+			return fmt.Errorf("invalid magnet uri: %w", err)
+		}
+		infoHash, name, magnet = m.InfoHash.HexString(), m.DisplayName, torrentUrl
+	} else {
+		mi, err := metainfo.Load(bytes.NewReader(torrentContent))
+		if err != nil {
+			return fmt.Errorf("invalid torrent: %w", err)
+		}
+		torrentInfo, err := mi.UnmarshalInfo()
+		if err != nil {
+			return fmt.Errorf("invalid torrent info: %w", err)
+		}
+		info = &torrentInfo
+		if !info.HasV1() {
+			return fmt.Errorf("v2-only torrent is not supported")
+		}
+		infoHash, name = mi.HashInfoBytes().HexString(), info.BestName()
+	}
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	if ec.state.Torrents[infoHash] != nil {
+		return fmt.Errorf("torrent %s already exists", infoHash)
+	}
+	// Only the display name of torrent could be changed, which will break the content path detection,
+	// so option.Name is ignored. Per-torrent speed limits and sequential download are NOT supported.
+	category := option.Category
+	if category == constants.NONE {
+		category = ""
+	}
+	savePath := option.SavePath
+	if savePath == "" && category != "" {
+		savePath = ec.state.Categories[category]
+	}
+	if savePath == "" {
+		savePath = ec.defaultSavePath()
+	}
+	if category != "" {
+		if _, ok := ec.state.Categories[category]; !ok {
+			ec.state.Categories[category] = ""
+		}
+	}
+	ts := &torrentState{
+		Name:             name,
+		Magnet:           magnet,
+		SavePath:         savePath,
+		Category:         category,
+		Tags:             util.UniqueSlice(option.Tags),
+		Meta:             util.CopyMap(meta, false),
+		Paused:           option.Pause,
+		Atime:            util.Now(),
+		RatioLimit:       option.RatioLimit,
+		SeedingTimeLimit: option.SeedingTimeLimit,
+	}
+	ec.addTags(ts.Tags...)
+	if magnet == "" {
+		if err := atomic.WriteFile(ec.torrentFilename(infoHash), bytes.NewReader(torrentContent)); err != nil {
+			return fmt.Errorf("failed to save torrent file: %w", err)
+		}
+		if option.SkipChecking {
+			// Mark all pieces as complete so the engine will not check them.
+			hash := metainfo.NewHashFromHex(infoHash)
+			for i := range info.NumPieces() {
+				ec.pieceCompletion.Set(metainfo.PieceKey{InfoHash: hash, Index: i}, true)
+			}
+		}
+	}
+	if _, err := ec.load(infoHash, ts); err != nil {
+		os.Remove(ec.torrentFilename(infoHash))
+		return fmt.Errorf("failed to add torrent: %w", err)
+	}
+	ec.state.Torrents[infoHash] = ts
+	return ec.save()
+}
+
+func (ec *Client) ModifyTorrent(infoHash string, option *client.TorrentOption, meta map[string]int64) error {
+	if option == nil {
+		option = &client.TorrentOption{}
+	}
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	ts := ec.state.Torrents[infoHash]
+	if ts == nil {
+		return fmt.Errorf("torrent not exists")
+	}
+	if option.Category != "" {
+		ec.setCategory(ts, option.Category)
+	}
+	if len(option.Tags) > 0 || len(option.RemoveTags) > 0 {
+		ts.Tags = slices.DeleteFunc(ts.Tags, func(tag string) bool { return slices.Contains(option.RemoveTags, tag) })
+		ts.Tags = util.UniqueSlice(append(ts.Tags, option.Tags...))
+		ec.addTags(ts.Tags...)
+	}
+	if len(meta) > 0 {
+		ts.Meta = util.CopyMap(meta, false)
+	}
+	if option.RatioLimit != 0 {
+		ts.RatioLimit = max(option.RatioLimit, 0)
+	}
+	if option.SeedingTimeLimit != 0 {
+		ts.SeedingTimeLimit = max(option.SeedingTimeLimit, 0)
+	}
+	if option.SavePath != "" && option.SavePath != ts.SavePath {
+		if err := ec.setSavePath(infoHash, ts, option.SavePath); err != nil {
+			return err
+		}
+	}
+	if option.Pause {
+		ec.pause(infoHash, ts)
+	} else if option.Resume {
+		ec.resume(infoHash, ts)
+	}
+	return ec.save()
+}
+
+func (ec *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) error {
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		var info *metainfo.Info
+		if lt := ec.torrents[infoHash]; lt != nil {
+			info = lt.t.Info()
+			ec.unload(infoHash, ts)
+		}
+		delete(ec.state.Torrents, infoHash)
+		os.Remove(ec.torrentFilename(infoHash))
+		if info == nil {
+			return nil
+		}
+		// Forget pieces completion, in case the torrent is added again later.
+		hash := metainfo.NewHashFromHex(infoHash)
+		for i := range info.NumPieces() {
+			ec.pieceCompletion.Set(metainfo.PieceKey{InfoHash: hash, Index: i}, false)
+		}
+		if deleteFiles && info.BestName() != "" {
+			return os.RemoveAll(filepath.Join(ts.SavePath, info.BestName()))
+		}
+		return nil
+	})
+}
+
+func (ec *Client) pause(infoHash string, ts *torrentState) {
+	ts.Paused = true
+	if lt := ec.torrents[infoHash]; lt != nil {
+		lt.t.DisallowDataDownload()
+		lt.t.DisallowDataUpload()
+	}
+}
+
+func (ec *Client) resume(infoHash string, ts *torrentState) {
+	ts.Paused = false
+	if lt := ec.torrents[infoHash]; lt != nil {
+		lt.t.AllowDataDownload()
+		lt.t.AllowDataUpload()
+	}
+}
+
+func (ec *Client) PauseTorrents(infoHashes []string) error {
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		ec.pause(infoHash, ts)
+		return nil
+	})
+}
+
+func (ec *Client) ResumeTorrents(infoHashes []string) error {
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		ec.resume(infoHash, ts)
+		return nil
+	})
+}
+
+// Re-hash all pieces of torrents in background.
+func (ec *Client) RecheckTorrents(infoHashes []string) error {
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		lt := ec.torrents[infoHash]
+		if lt == nil || lt.t.Info() == nil || lt.checking {
+			return nil
+		}
+		lt.checking = true
+		ts.Ctime = 0
+		go func() {
+			lt.t.VerifyData()
+			ec.mu.Lock()
+			lt.checking = false
+			ec.mu.Unlock()
+		}()
+		return nil
+	})
+}
+
+// The engine does not support forcing announce, reload the torrents instead, which announces to trackers.
+func (ec *Client) ReannounceTorrents(infoHashes []string) error {
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		return ec.reload(infoHash, ts)
+	})
+}
+
+func (ec *Client) AddTagsToTorrents(infoHashes []string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		ts.Tags = util.UniqueSlice(append(ts.Tags, tags...))
+		ec.addTags(tags...)
+		return nil
+	})
+}
+
+func (ec *Client) RemoveTagsFromTorrents(infoHashes []string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		ts.Tags = slices.DeleteFunc(ts.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+		return nil
+	})
+}
+
+// Move contents of torrent to new save path. The torrent is unloaded from engine during moving.
+func (ec *Client) setSavePath(infoHash string, ts *torrentState, savePath string) error {
+	lt := ec.torrents[infoHash]
+	if lt == nil || lt.t.Info() == nil {
+		ts.SavePath = savePath
+		return ec.reload(infoHash, ts)
+	}
+	name := lt.t.Info().BestName()
+	ec.unload(infoHash, ts)
+	oldContentPath := filepath.Join(ts.SavePath, name)
+	var err error
+	if _, err = os.Stat(oldContentPath); err == nil {
+		if err = os.MkdirAll(savePath, constants.PERM_DIR); err == nil {
+			err = os.Rename(oldContentPath, filepath.Join(savePath, name))
+		}
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err == nil {
+		ts.SavePath = savePath
+	}
+	if _, loadErr := ec.load(infoHash, ts); loadErr != nil && err == nil {
+		err = loadErr
+	}
+	return err
+}
+
+func (ec *Client) SetTorrentsSavePath(infoHashes []string, savePath string) error {
+	savePath = strings.TrimSpace(savePath)
+	if savePath == "" {
+		return fmt.Errorf("savePath is empty")
+	}
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		if ts.SavePath == savePath {
+			return nil
+		}
+		return ec.setSavePath(infoHash, ts, savePath)
+	})
+}
+
+func (ec *Client) PauseAllTorrents() error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.PauseTorrents(infoHashes)
+}
+
+func (ec *Client) ResumeAllTorrents() error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.ResumeTorrents(infoHashes)
+}
+
+func (ec *Client) RecheckAllTorrents() error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.RecheckTorrents(infoHashes)
+}
+
+func (ec *Client) ReannounceAllTorrents() error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.ReannounceTorrents(infoHashes)
+}
+
+func (ec *Client) AddTagsToAllTorrents(tags []string) error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.AddTagsToTorrents(infoHashes, tags)
+}
+
+func (ec *Client) RemoveTagsFromAllTorrents(tags []string) error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.RemoveTagsFromTorrents(infoHashes, tags)
+}
+
+func (ec *Client) SetAllTorrentsSavePath(savePath string) error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.SetTorrentsSavePath(infoHashes, savePath)
+}
+
+func (ec *Client) addTags(tags ...string) {
+	for _, tag := range tags {
+		if !slices.Contains(ec.state.Tags, tag) {
+			ec.state.Tags = append(ec.state.Tags, tag)
+		}
+	}
+}
+
+func (ec *Client) GetTags() ([]string, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	return slices.Clone(ec.state.Tags), nil
+}
+
+func (ec *Client) CreateTags(tags ...string) error {
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	ec.addTags(tags...)
+	return ec.save()
+}
+
+// Delete tags, also remove them from all torrents.
+func (ec *Client) DeleteTags(tags ...string) error {
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	ec.state.Tags = slices.DeleteFunc(ec.state.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	for _, ts := range ec.state.Torrents {
+		ts.Tags = slices.DeleteFunc(ts.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	}
+	return ec.save()
+}
+
+// The save path of category is only used as the default save path of newly added torrents.
+func (ec *Client) MakeCategory(category string, savePath string) error {
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	if savePath == constants.NONE {
+		savePath = ec.state.Categories[category]
+	}
+	ec.state.Categories[category] = savePath
+	return ec.save()
+}
+
+// Delete categories, torrents of them become uncategorized.
+func (ec *Client) DeleteCategories(categories []string) error {
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	for _, category := range categories {
+		delete(ec.state.Categories, category)
+	}
+	for _, ts := range ec.state.Torrents {
+		if slices.Contains(categories, ts.Category) {
+			ts.Category = ""
+		}
+	}
+	return ec.save()
+}
+
+func (ec *Client) GetCategories() ([]*client.TorrentCategory, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	cats := []*client.TorrentCategory{}
+	for name, savePath := range ec.state.Categories {
+		cats = append(cats, &client.TorrentCategory{Name: name, SavePath: savePath})
+	}
+	slices.SortFunc(cats, func(a, b *client.TorrentCategory) int { return strings.Compare(a.Name, b.Name) })
+	return cats, nil
+}
+
+func (ec *Client) setCategory(ts *torrentState, category string) {
+	if category == constants.NONE {
+		category = ""
+	}
+	if category != "" {
+		if _, ok := ec.state.Categories[category]; !ok {
+			ec.state.Categories[category] = ""
+		}
+	}
+	ts.Category = category
+}
+
+func (ec *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		ec.setCategory(ts, category)
+		return nil
+	})
+}
+
+func (ec *Client) SetAllTorrentsCatetory(category string) error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.SetTorrentsCatetory(infoHashes, category)
+}
+
+// Share limits are enforced by ptool: torrent is paused once it reaches any of the limits.
+func (ec *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error {
+	return ec.updateTorrents(infoHashes, func(infoHash string, ts *torrentState) error {
+		ts.RatioLimit = max(ratioLimit, 0)
+		ts.SeedingTimeLimit = max(seedingTimeLimit, 0)
+		return nil
+	})
+}
+
+func (ec *Client) SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error {
+	infoHashes, err := ec.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return ec.SetTorrentsShareLimits(infoHashes, ratioLimit, seedingTimeLimit)
+}
+
+func (ec *Client) TorrentRootPathExists(rootFolder string) bool {
+	if rootFolder == "" {
+		return false
+	}
+	if err := ec.acquire(); err != nil {
+		return false
+	}
+	defer ec.release()
+	for _, ts := range ec.state.Torrents {
+		if ts.Name == rootFolder {
+			return true
+		}
+	}
+	return false
+}
+
+func (ec *Client) GetTorrentContents(infoHash string) ([]*client.TorrentContentFile, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	ts := ec.state.Torrents[infoHash]
+	lt := ec.torrents[infoHash]
+	if ts == nil || lt == nil {
+		return nil, fmt.Errorf("torrent not exists")
+	}
+	if lt.t.Info() == nil {
+		return nil, fmt.Errorf("metadata of torrent is not available yet")
+	}
+	files := []*client.TorrentContentFile{}
+	for i, file := range lt.t.Files() {
+		completed := file.BytesCompleted()
+		progress := float64(1)
+		if file.Length() > 0 {
+			progress = float64(completed) / float64(file.Length())
+		}
+		files = append(files, &client.TorrentContentFile{
+			Index:    int64(i),
+			Path:     file.Path(),
+			Size:     file.Length(),
+			Progress: progress,
+			Ignored:  filePriority(ts, i) == 0,
+			Complete: completed >= file.Length(),
+		})
+	}
+	return files, nil
+}
+
+func (ec *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
+	if len(fileIndexes) == 0 {
+		return fmt.Errorf("must provide at least fileIndex")
+	}
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	ts := ec.state.Torrents[infoHash]
+	lt := ec.torrents[infoHash]
+	if ts == nil || lt == nil {
+		return fmt.Errorf("torrent not exists")
+	}
+	if lt.t.Info() == nil {
+		return fmt.Errorf("metadata of torrent is not available yet")
+	}
+	for _, index := range fileIndexes {
+		if index < 0 || index >= int64(len(lt.t.Files())) {
+			return fmt.Errorf("invalid file index %d", index)
+		}
+	}
+	if ts.FilePriorities == nil {
+		ts.FilePriorities = map[int]int64{}
+	}
+	for _, index := range fileIndexes {
+		if priority == 1 {
+			delete(ts.FilePriorities, int(index))
+		} else {
+			ts.FilePriorities[int(index)] = priority
+		}
+	}
+	applyFilePriorities(lt, ts)
+	if ts.Ctime > 0 && !lt.isDone(ts) {
+		ts.Ctime = 0
+	}
+	return ec.save()
+}
+
+// The data of embedded client is always live, no cache to purge.
+func (ec *Client) PurgeCache() {
+}
+
+func (ec *Client) GetFreeSpaceOnPath(path string) (int64, error) {
+	return osutil.GetFreeDiskSpace(path)
+}
+
+func (ec *Client) GetStatus() (*client.Status, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	status := &client.Status{
+		FreeSpaceOnDisk:    -1,
+		DownloadSpeedLimit: ec.state.DownloadSpeedLimit,
+		UploadSpeedLimit:   ec.state.UploadSpeedLimit,
+		NoAdd:              slices.Contains(ec.state.Tags, config.NOADD_TAG),
+		NoDel:              slices.Contains(ec.state.Tags, config.NODEL_TAG),
+	}
+	if freeSpace, err := osutil.GetFreeDiskSpace(ec.defaultSavePath()); err == nil {
+		status.FreeSpaceOnDisk = freeSpace
+	}
+	for infoHash, lt := range ec.torrents {
+		ts := ec.state.Torrents[infoHash]
+		if ts == nil {
+			continue
+		}
+		status.DownloadSpeed += lt.downloadSpeed
+		status.UploadSpeed += lt.uploadSpeed
+		size, completed := lt.selectedSize(ts)
+		status.UnfinishedSize += size - completed
+		if !ts.Paused {
+			status.UnfinishedDownloadingSize += size - completed
+		}
+	}
+	return status, nil
+}
+
+func (ec *Client) GetName() string {
+	return ec.Name
+}
+
+func (ec *Client) GetClientConfig() *config.ClientConfigStruct {
+	return ec.ClientConfig
+}
+
+func (ec *Client) SetConfig(variable string, value string) error {
+	switch variable {
+	case "free_disk_space", "global_download_speed", "global_upload_speed":
+		return fmt.Errorf("%s is read-only", variable)
+	case "global_download_speed_limit", "global_upload_speed_limit", "save_path":
+	default:
+		return nil
+	}
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	switch variable {
+	case "global_download_speed_limit":
+		ec.state.DownloadSpeedLimit = max(util.ParseInt(value), 0)
+		setLimiter(ec.downloadLimiter, ec.state.DownloadSpeedLimit)
+	case "global_upload_speed_limit":
+		ec.state.UploadSpeedLimit = max(util.ParseInt(value), 0)
+		setLimiter(ec.uploadLimiter, ec.state.UploadSpeedLimit)
+	case "save_path":
+		if value != "" {
+			if err := os.MkdirAll(value, constants.PERM_DIR); err != nil {
+				return err
+			}
+		}
+		ec.state.SavePath = value
+	}
+	return ec.save()
+}
+
+func (ec *Client) GetConfig(variable string) (string, error) {
+	switch variable {
+	case "global_download_speed_limit", "global_upload_speed_limit", "free_disk_space",
+		"global_download_speed", "global_upload_speed":
+		status, err := ec.GetStatus()
+		if err != nil {
+			return "", err
+		}
+		switch variable {
+		case "global_download_speed_limit":
+			return fmt.Sprint(status.DownloadSpeedLimit), nil
+		case "global_upload_speed_limit":
+			return fmt.Sprint(status.UploadSpeedLimit), nil
+		case "free_disk_space":
+			return fmt.Sprint(status.FreeSpaceOnDisk), nil
+		case "global_download_speed":
+			return fmt.Sprint(status.DownloadSpeed), nil
+		default:
+			return fmt.Sprint(status.UploadSpeed), nil
+		}
+	case "save_path":
+		if err := ec.acquire(); err != nil {
+			return "", err
+		}
+		defer ec.release()
+		return ec.defaultSavePath(), nil
+	default:
+		return "", nil
+	}
+}
+
+// The engine does not expose the announce results of trackers, so the status is always "unknown".
+func (ec *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
+	if err := ec.acquire(); err != nil {
+		return nil, err
+	}
+	defer ec.release()
+	ts := ec.state.Torrents[infoHash]
+	lt := ec.torrents[infoHash]
+	if ts == nil || lt == nil {
+		return nil, fmt.Errorf("torrent not exists")
+	}
+	trackers := client.TorrentTrackers{}
+	for _, tier := range lt.trackers(ts) {
+		for _, tracker := range tier {
+			trackers = append(trackers, client.TorrentTracker{
+				Url:    tracker,
+				Status: "unknown",
+			})
+		}
+	}
+	return trackers, nil
+}
+
+// Replace trackers of a torrent and reload it. The engine can not update trackers of a running torrent.
+func (ec *Client) setTrackers(infoHash string, updater func(trackers [][]string) ([][]string, error)) error {
+	if err := ec.acquire(); err != nil {
+		return err
+	}
+	defer ec.release()
+	ts := ec.state.Torrents[infoHash]
+	lt := ec.torrents[infoHash]
+	if ts == nil || lt == nil {
+		return fmt.Errorf("torrent not exists")
+	}
+	oldTrackers := lt.trackers(ts)
+	trackers, err := updater(slices.Clone(oldTrackers))
+	if err != nil || trackers == nil {
+		return err
+	}
+	ts.Trackers = util.Filter(trackers, func(tier []string) bool { return len(tier) > 0 })
+	if ts.Trackers == nil {
+		ts.Trackers = [][]string{}
+	}
+	if err := ec.reload(infoHash, ts); err != nil {
+		return err
+	}
+	return ec.save()
+}
+
+func (ec *Client) EditTorrentTracker(infoHash string, oldTracker string,
+	newTracker string, replaceHost bool) error {
+	return ec.setTrackers(infoHash, func(trackers [][]string) ([][]string, error) {
+		for i, tier := range trackers {
+			for j, tracker := range tier {
+				newTrackerUrl := newTracker
+				if replaceHost {
+					if !util.MatchUrlWithHostOrUrl(tracker, oldTracker) {
+						continue
+					}
+					if !util.IsUrl(newTracker) {
+						urlObj, err := url.Parse(tracker)
+						if err != nil {
+							continue
+						}
+						urlObj.Host = newTracker
+						newTrackerUrl = urlObj.String()
+					}
+				} else if tracker != oldTracker {
+					continue
+				}
+				if tracker == newTrackerUrl {
+					return nil, nil
+				}
+				trackers[i] = slices.Clone(tier)
+				trackers[i][j] = newTrackerUrl
+				return trackers, nil
+			}
+		}
+		return nil, fmt.Errorf("torrent %s old tracker %s does NOT exist", infoHash, oldTracker)
+	})
+}
+
+// Each added tracker is put in it's own tier.
+func (ec *Client) AddTorrentTrackers(infoHash string, trackers []string,
+	oldTracker string, removeExisting bool) error {
+	return ec.setTrackers(infoHash, func(existingTrackers [][]string) ([][]string, error) {
+		if oldTracker != "" && !slices.ContainsFunc(existingTrackers, func(tier []string) bool {
+			return slices.ContainsFunc(tier, func(tracker string) bool {
+				return util.MatchUrlWithHostOrUrl(tracker, oldTracker)
+			})
+		}) {
+			return nil, nil
+		}
+		if removeExisting {
+			existingTrackers = nil
+		} else {
+			trackers = util.Filter(trackers, func(tracker string) bool {
+				return !slices.ContainsFunc(existingTrackers, func(tier []string) bool {
+					return slices.Contains(tier, tracker)
+				})
+			})
+		}
+		if len(trackers) == 0 {
+			return nil, nil
+		}
+		for _, tracker := range trackers {
+			existingTrackers = append(existingTrackers, []string{tracker})
+		}
+		return existingTrackers, nil
+	})
+}
+
+func (ec *Client) RemoveTorrentTrackers(infoHash string, trackers []string) error {
+	return ec.setTrackers(infoHash, func(existingTrackers [][]string) ([][]string, error) {
+		removed := false
+		for i, tier := range existingTrackers {
+			newTier := util.Filter(tier, func(tracker string) bool { return !slices.Contains(trackers, tracker) })
+			if len(newTier) != len(tier) {
+				existingTrackers[i] = newTier
+				removed = true
+			}
+		}
+		if !removed {
+			return nil, nil
+		}
+		return existingTrackers, nil
+	})
+}
+
+// Stop the engine and persist state. The client will be started again if used later.
+func (ec *Client) Close() {
+	ec.mu.Lock()
+	if ec.engine == nil {
+		ec.mu.Unlock()
+		return
+	}
+	stop, stopped := ec.stop, ec.stopped
+	ec.mu.Unlock()
+	close(stop)
+	<-stopped
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	for infoHash, lt := range ec.torrents {
+		if ts := ec.state.Torrents[infoHash]; ts != nil {
+			updateStats(lt, ts)
+		}
+	}
+	if err := ec.save(); err != nil {
+		log.Errorf("embedded client %s: failed to save state: %v", ec.Name, err)
+	}
+	ec.engine.Close()
+	ec.pieceCompletion.Close()
+	ec.lock.Unlock()
+	ec.engine = nil
+	ec.pieceCompletion = nil
+	ec.lock = nil
+	ec.torrents = nil
+	ec.state = nil
+}
+
+// Update the transfer stats & completion time of torrent state from engine.
+func updateStats(lt *liveTorrent, ts *torrentState) {
+	uploaded, downloaded := lt.sessionStats()
+	ts.Uploaded = lt.baseUploaded + uploaded
+	ts.Downloaded = lt.baseDownloaded + downloaded
+	if ts.Ctime <= 0 && !lt.checking && lt.isDone(ts) {
+		ts.Ctime = util.Now()
+	}
+}
+
+// Return the qb style priority of the file of index.
+func filePriority(ts *torrentState, index int) int64 {
+	if priority, ok := ts.FilePriorities[index]; ok {
+		return priority
+	}
+	return 1
+}
+
+func applyFilePriorities(lt *liveTorrent, ts *torrentState) {
+	for i, file := range lt.t.Files() {
+		file.SetPriority(qbPriority2Piece(filePriority(ts, i)))
+	}
+}
+
+// Map qBittorrent style file priority to engine piece priority.
+// qb: 0 - do not download; 1 - normal; 6 - high; 7 - maximal.
+func qbPriority2Piece(priority int64) torrent.PiecePriority {
+	switch {
+	case priority <= 0:
+		return torrent.PiecePriorityNone
+	case priority >= 6:
+		return torrent.PiecePriorityHigh
+	default:
+		return torrent.PiecePriorityNormal
+	}
+}
+
+func newLimiter(limit int64) *rate.Limiter {
+	limiter := rate.NewLimiter(rate.Inf, LIMITER_BURST)
+	setLimiter(limiter, limit)
+	return limiter
+}
+
+// limit <= 0 means no limit.
+func setLimiter(limiter *rate.Limiter, limit int64) {
+	if limit > 0 {
+		limiter.SetLimit(rate.Limit(limit))
+	} else {
+		limiter.SetLimit(rate.Inf)
+	}
+}
+
+func stateDir(name string) string {
+	return filepath.Join(config.ConfigDir, fmt.Sprintf(STATE_DIR, name))
+}
+
+func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
+	client.Client, error) {
+	client := &Client{
+		Name:         name,
+		ClientConfig: clientConfig,
+		Config:       config,
+		dir:          stateDir(name),
+	}
+	return client, nil
+}
+
+func init() {
+	client.Register(&client.RegInfo{
+		Name:    "embedded",
+		Creator: NewClient,
+	})
+}
+
+var (
+	_ client.Client = (*Client)(nil)
+)
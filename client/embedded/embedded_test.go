@@ -0,0 +1,108 @@
+package embedded_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/embedded"
+	"github.com/sagan/ptool/config"
+)
+
+// Create a single-file torrent of a local file, return .torrent contents and info-hash.
+func createTorrent(t *testing.T, filename string) ([]byte, string) {
+	info := metainfo.Info{PieceLength: 16 * 1024}
+	if err := info.BuildFromFilePath(filename); err != nil {
+		t.Fatalf("failed to build torrent info: %v", err)
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatalf("failed to encode torrent info: %v", err)
+	}
+	mi := &metainfo.MetaInfo{InfoBytes: infoBytes, Announce: "https://tracker.example.com/announce"}
+	buf := &bytes.Buffer{}
+	if err := mi.Write(buf); err != nil {
+		t.Fatalf("failed to encode torrent: %v", err)
+	}
+	return buf.Bytes(), mi.HashInfoBytes().HexString()
+}
+
+func newClient(t *testing.T) client.Client {
+	clientInstance, err := embedded.NewClient("local", &config.ClientConfigStruct{
+		Type:               "embedded",
+		Name:               "local",
+		EmbeddedListenPort: -1,
+		EmbeddedDisableDht: true,
+	}, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return clientInstance
+}
+
+func TestEmbeddedClient(t *testing.T) {
+	config.ConfigDir = t.TempDir()
+	savePath := t.TempDir()
+	filename := filepath.Join(savePath, "foo.bin")
+	if err := os.WriteFile(filename, bytes.Repeat([]byte("ptool"), 20000), 0644); err != nil {
+		t.Fatalf("failed to create data file: %v", err)
+	}
+	torrentContent, infoHash := createTorrent(t, filename)
+
+	clientInstance := newClient(t)
+	err := clientInstance.AddTorrent(torrentContent, &client.TorrentOption{
+		Category: "my cat",
+		SavePath: savePath,
+		Tags:     []string{"site:foo"},
+	}, map[string]int64{"dcet": 100})
+	if err != nil {
+		t.Fatalf("failed to add torrent: %v", err)
+	}
+	// The existing data is checked by the engine, then the torrent starts seeding.
+	var torrent *client.Torrent
+	for i := 0; i < 100; i++ {
+		if torrent, err = clientInstance.GetTorrent(infoHash); err != nil || torrent == nil {
+			t.Fatalf("failed to get torrent: %v", err)
+		}
+		if torrent.State == "seeding" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if torrent.State != "seeding" || torrent.SizeCompleted != 100000 || torrent.ContentPath != filename {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	if err = clientInstance.PauseTorrents([]string{infoHash}); err != nil {
+		t.Fatalf("failed to pause torrent: %v", err)
+	}
+	clientInstance.Close()
+
+	// All state is persisted and restored by a new client instance.
+	clientInstance = newClient(t)
+	defer clientInstance.Close()
+	torrents, err := clientInstance.GetTorrents("", "my cat", true)
+	if err != nil {
+		t.Fatalf("failed to get torrents: %v", err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("expect 1 torrent, got %d", len(torrents))
+	}
+	torrent = torrents[0]
+	if torrent.InfoHash != infoHash || torrent.State != "completed" || torrent.SavePath != savePath ||
+		torrent.TrackerDomain != "tracker.example.com" || torrent.Ctime <= 0 {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	if !slices.Equal(torrent.Tags, []string{"site:foo"}) || torrent.Meta["dcet"] != 100 {
+		t.Errorf("unexpected torrent tags / meta: %v / %v", torrent.Tags, torrent.Meta)
+	}
+	if exported, err := clientInstance.ExportTorrentFile(infoHash); err != nil || !bytes.Equal(exported, torrentContent) {
+		t.Errorf("failed to export torrent: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package embedded
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/natefinch/atomic"
+
+	"github.com/sagan/ptool/constants"
+)
+
+// Files in the state dir ("<config_dir>/embedded-<name>/") of an embedded client.
+const (
+	STATE_DIR    = "embedded-%s" // %s: client name
+	STATE_FILE   = "state.json"
+	TORRENTS_DIR = "torrents" // <info-hash>.torrent files of all torrents
+	DOWNLOAD_DIR = "downloads"
+	LOCK_FILE    = "embedded-%s.lock" // %s: client name. In config dir
+)
+
+// Persisted state of a torrent. The engine itself only knows the data (pieces completion) of torrents,
+// all other properties are maintained by ptool.
+type torrentState struct {
+	Name             string           `json:"name"`
+	Magnet           string           `json:"magnet,omitempty"` // magnet uri, if metadata is not fetched yet
+	SavePath         string           `json:"savePath"`
+	Category         string           `json:"category,omitempty"`
+	Tags             []string         `json:"tags,omitempty"`
+	Meta             map[string]int64 `json:"meta,omitempty"`
+	Paused           bool             `json:"paused,omitempty"`
+	Atime            int64            `json:"atime"`
+	Ctime            int64            `json:"ctime,omitempty"`
+	ActivityTime     int64            `json:"activityTime,omitempty"`
+	Uploaded         int64            `json:"uploaded,omitempty"`
+	Downloaded       int64            `json:"downloaded,omitempty"`
+	FilePriorities   map[int]int64    `json:"filePriorities,omitempty"` // qb style priority, absent means normal (1)
+	Trackers         [][]string       `json:"trackers,omitempty"`       // if set, overrides trackers of .torrent
+	RatioLimit       float64          `json:"ratioLimit,omitempty"`
+	SeedingTimeLimit int64            `json:"seedingTimeLimit,omitempty"`
+}
+
+type engineState struct {
+	SavePath           string                   `json:"savePath,omitempty"` // default save path
+	DownloadSpeedLimit int64                    `json:"downloadSpeedLimit,omitempty"`
+	UploadSpeedLimit   int64                    `json:"uploadSpeedLimit,omitempty"`
+	Tags               []string                 `json:"tags"`       // all created tags
+	Categories         map[string]string        `json:"categories"` // name => savePath
+	Torrents           map[string]*torrentState `json:"torrents"`
+}
+
+func loadState(filename string) (*engineState, error) {
+	state := &engineState{}
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read embedded client state file: %w", err)
+		}
+	} else if err = json.Unmarshal(contents, state); err != nil {
+		return nil, fmt.Errorf("failed to parse embedded client state file: %w", err)
+	}
+	if state.Categories == nil {
+		state.Categories = map[string]string{}
+	}
+	if state.Torrents == nil {
+		state.Torrents = map[string]*torrentState{}
+	}
+	return state, nil
+}
+
+func (state *engineState) save(filename string) error {
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), constants.PERM_DIR); err != nil {
+		return err
+	}
+	return atomic.WriteFile(filename, bytes.NewReader(contents))
+}
@@ -13,6 +13,7 @@ import (
 	"net/textproto"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"slices"
@@ -688,6 +689,26 @@ func (qbclient *Client) TorrentRootPathExists(rootFolder string) bool {
 	return false
 }
 
+// qBittorrent Web API only reports free space of the disk of default save path.
+func (qbclient *Client) GetFreeSpaceOnPath(savePath string) (int64, error) {
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return 0, err
+	}
+	if path.Clean(util.ToSlash(savePath)) != path.Clean(util.ToSlash(preferences.Save_path)) {
+		return 0, fmt.Errorf("qBittorrent can only report free space of default save path %s",
+			preferences.Save_path)
+	}
+	status, err := qbclient.GetStatus()
+	if err != nil {
+		return 0, err
+	}
+	if status.FreeSpaceOnDisk < 0 {
+		return 0, fmt.Errorf("free space unknown")
+	}
+	return status.FreeSpaceOnDisk, nil
+}
+
 func (qbclient *Client) GetStatus() (*client.Status, error) {
 	err := qbclient.sync()
 	if err != nil {
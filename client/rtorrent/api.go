@@ -0,0 +1,233 @@
+package rtorrent
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+)
+
+// Custom field that stores torrent tags (and meta, as "meta.name:value" tags), url-encoded & comma separated.
+// ruTorrent compatible fields: "custom1" (label, used as category) and "addtime".
+const TAGS_FIELD = "ptool_tags"
+
+// Fields requested in d.multicall2 when syncing torrents list, the order matters.
+// The last one is the ruTorrent way to get all tracker urls of a torrent, separated by "#".
+var torrentFields = []string{
+	"d.hash=", "d.name=", "d.state=", "d.is_active=", "d.complete=", "d.hashing=", "d.is_multi_file=",
+	"d.directory=", "d.size_bytes=", "d.selected_size_bytes=", "d.completed_bytes=", "d.up.rate=", "d.down.rate=",
+	"d.up.total=", "d.down.total=", "d.timestamp.started=", "d.timestamp.finished=", "d.custom=addtime",
+	"d.custom1=", "d.custom=" + TAGS_FIELD, "d.peers_complete=", "d.peers_accounted=", "d.message=",
+	`cat="$t.multicall=d.hash=,t.url=,cat={#}"`,
+}
+
+type apiTorrent struct {
+	Hash              string // lowercase
+	Name              string
+	State             int64 // 0 - stopped; 1 - started
+	IsActive          bool  // false if paused
+	Complete          bool
+	Hashing           int64 // 0 - no hashing
+	IsMultiFile       bool
+	Directory         string // for multi-file torrent, it's the root folder of contents
+	SizeBytes         int64
+	SelectedSizeBytes int64
+	CompletedBytes    int64
+	UpRate            int64
+	DownRate          int64
+	UpTotal           int64
+	DownTotal         int64
+	TimestampStarted  int64
+	TimestampFinished int64
+	AddTime           int64 // ruTorrent "addtime" custom field
+	Category          string
+	Tags              []string // including substitute tags
+	PeersComplete     int64
+	PeersAccounted    int64
+	Message           string
+	Trackers          []string
+}
+
+func parseTorrent(row []any) *apiTorrent {
+	if len(row) < len(torrentFields) {
+		return nil
+	}
+	trackers := util.Filter(strings.Split(toString(row[23]), "#"), func(tracker string) bool {
+		return tracker != ""
+	})
+	return &apiTorrent{
+		Hash:              strings.ToLower(toString(row[0])),
+		Name:              toString(row[1]),
+		State:             toInt64(row[2]),
+		IsActive:          toInt64(row[3]) == 1,
+		Complete:          toInt64(row[4]) == 1,
+		Hashing:           toInt64(row[5]),
+		IsMultiFile:       toInt64(row[6]) == 1,
+		Directory:         toString(row[7]),
+		SizeBytes:         toInt64(row[8]),
+		SelectedSizeBytes: toInt64(row[9]),
+		CompletedBytes:    toInt64(row[10]),
+		UpRate:            toInt64(row[11]),
+		DownRate:          toInt64(row[12]),
+		UpTotal:           toInt64(row[13]),
+		DownTotal:         toInt64(row[14]),
+		TimestampStarted:  toInt64(row[15]),
+		TimestampFinished: toInt64(row[16]),
+		AddTime:           util.ParseInt(strings.TrimSpace(toString(row[17]))),
+		Category:          decodeField(toString(row[18])),
+		Tags:              decodeTags(toString(row[19])),
+		PeersComplete:     toInt64(row[20]),
+		PeersAccounted:    toInt64(row[21]),
+		Message:           toString(row[22]),
+		Trackers:          trackers,
+	}
+}
+
+// Return path sep (either '/' or '\') of this torrent.
+func (rt *apiTorrent) sep() string {
+	if strings.Contains(rt.Directory, `\`) {
+		return `\`
+	}
+	return `/`
+}
+
+// For multi-file torrent, d.directory is the root folder; otherwise it's the parent folder of the file.
+func (rt *apiTorrent) savePath() string {
+	if rt.IsMultiFile {
+		if i := strings.LastIndex(strings.TrimSuffix(rt.Directory, rt.sep()), rt.sep()); i >= 0 {
+			return rt.Directory[:i]
+		}
+	}
+	return rt.Directory
+}
+
+func (rt *apiTorrent) contentPath() string {
+	if rt.IsMultiFile {
+		return strings.TrimSuffix(rt.Directory, rt.sep())
+	}
+	return strings.TrimSuffix(rt.Directory, rt.sep()) + rt.sep() + rt.Name
+}
+
+func (rt *apiTorrent) toTorrentState() string {
+	switch {
+	case rt.Hashing > 0:
+		return "checking"
+	case rt.State == 0 || !rt.IsActive:
+		if rt.Complete {
+			return "completed"
+		}
+		return "paused"
+	case rt.Complete:
+		return "seeding"
+	default:
+		return "downloading"
+	}
+}
+
+func (rt *apiTorrent) toTorrent() *client.Torrent {
+	tracker := ""
+	if len(rt.Trackers) > 0 {
+		tracker = rt.Trackers[0]
+	}
+	atime := rt.AddTime
+	if atime <= 0 {
+		atime = rt.TimestampStarted
+	}
+	// rTorrent does not record the last activity time of torrent.
+	activityTime := max(atime, rt.TimestampFinished)
+	if rt.UpRate > 0 || rt.DownRate > 0 {
+		activityTime = util.Now()
+	}
+	torrent := &client.Torrent{
+		InfoHash:           rt.Hash,
+		Name:               rt.Name,
+		TrackerDomain:      util.ParseUrlHostname(tracker),
+		TrackerBaseDomain:  util.GetUrlDomain(tracker),
+		Tracker:            tracker,
+		State:              rt.toTorrentState(),
+		LowLevelState:      rt.lowLevelState(),
+		Atime:              atime,
+		Ctime:              rt.TimestampFinished,
+		ActivityTime:       activityTime,
+		Category:           rt.Category,
+		SavePath:           rt.savePath(),
+		ContentPath:        rt.contentPath(),
+		Tags:               rt.Tags,
+		Downloaded:         rt.DownTotal,
+		DownloadSpeed:      rt.DownRate,
+		DownloadSpeedLimit: -1, // rTorrent only supports throttle groups
+		Uploaded:           rt.UpTotal,
+		UploadSpeed:        rt.UpRate,
+		UploadedSpeedLimit: -1,
+		Size:               rt.SelectedSizeBytes,
+		SizeTotal:          rt.SizeBytes,
+		SizeCompleted:      rt.CompletedBytes,
+		Seeders:            rt.PeersComplete,
+		Leechers:           max(rt.PeersAccounted-rt.PeersComplete, 0),
+	}
+	torrent.Meta = torrent.GetMetadataFromTags()
+	torrent.RemoveSubstituteTags()
+	return torrent
+}
+
+func (rt *apiTorrent) lowLevelState() string {
+	state := "stopped"
+	if rt.State == 1 {
+		state = "started"
+		if !rt.IsActive {
+			state = "paused"
+		}
+	}
+	if rt.Hashing > 0 {
+		state += ",hashing"
+	}
+	if rt.Complete {
+		state += ",complete"
+	}
+	return state
+}
+
+// Field values are url-encoded, the same as ruTorrent, so they can be safely used in rTorrent commands.
+// Escape value to be stored in custom field. "," is also escaped,
+// as it's the separator of arguments in rTorrent command and of tags.
+func encodeField(value string) string {
+	return strings.ReplaceAll(url.PathEscape(value), ",", "%2C")
+}
+
+func decodeField(value string) string {
+	if decoded, err := url.PathUnescape(value); err == nil {
+		return decoded
+	}
+	return value
+}
+
+// Each tag is escaped individually before joined, so tags that contain "," are preserved.
+func encodeTags(tags []string) string {
+	return encodeField(strings.Join(util.Map(util.UniqueSlice(tags), encodeField), ","))
+}
+
+func decodeTags(value string) []string {
+	return util.Map(util.Filter(strings.Split(decodeField(value), ","), func(tag string) bool {
+		return tag != ""
+	}), decodeField)
+}
+
+// Quote value as a string argument of rTorrent command, e.g. the ones executed by load.* methods.
+func quoteCommandArg(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Map qBittorrent style file priority to rTorrent priority.
+// qb: 0 - do not download; 1 - normal; 6 - high; 7 - maximal.
+// rTorrent: 0 - off; 1 - normal; 2 - high.
+func qbPriority2Rtorrent(priority int64) int64 {
+	switch {
+	case priority <= 0:
+		return 0
+	case priority >= 6:
+		return 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,991 @@
+package rtorrent
+
+// rTorrent XML-RPC API: https://kannibalox.github.io/rtorrent-docs/cmd-ref.html .
+// Requires rTorrent 0.9.6+. Supported client urls:
+// - scgi://127.0.0.1:5000 : SCGI over TCP (rTorrent "network.scgi.open_port").
+// - scgi:///home/user/rtorrent/rpc.socket : SCGI over unix socket (rTorrent "network.scgi.open_local").
+// - http(s)://example.com/RPC2 : XML-RPC over HTTP, exposed by a web server.
+// - http(s)://example.com/rutorrent/plugins/rpc/rpc.php : through ruTorrent (rpc plugin required).
+// For http(s) urls, username & password are used in HTTP Basic Authorization.
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+type Client struct {
+	Name                      string
+	ClientConfig              *config.ClientConfigStruct
+	Config                    *config.ConfigStruct
+	transport                 transport
+	datatime                  int64
+	torrents                  map[string]*apiTorrent
+	unfinishedSize            int64
+	unfinishedDownloadingSize int64
+	contentPathTorrents       map[string][]*apiTorrent
+}
+
+// Call a rTorrent XML-RPC method.
+func (rtclient *Client) call(method string, params ...any) (any, error) {
+	body, err := encodeRequest(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := rtclient.transport.roundTrip(body)
+	if err != nil {
+		return nil, fmt.Errorf("rtorrent rpc %s error: %w", method, err)
+	}
+	result, err := decodeResponse(resBody)
+	if err != nil {
+		return nil, fmt.Errorf("rtorrent rpc %s error: %w", method, err)
+	}
+	return result, nil
+}
+
+// Call multiple methods in one request using "system.multicall". Each call is [methodName, params...].
+func (rtclient *Client) multicall(calls [][]any) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	structs := []any{}
+	for _, call := range calls {
+		structs = append(structs, map[string]any{
+			"methodName": call[0],
+			"params":     call[1:],
+		})
+	}
+	result, err := rtclient.call("system.multicall", structs)
+	if err != nil {
+		return err
+	}
+	// each result is either an array of one value, or a fault struct.
+	for i, item := range toSlice(result) {
+		if fault, ok := item.(map[string]any); ok {
+			return fmt.Errorf("rtorrent rpc %s error: %w", calls[i][0], &xmlrpcFault{
+				Code:    toInt64(fault["faultCode"]),
+				Message: toString(fault["faultString"]),
+			})
+		}
+	}
+	return nil
+}
+
+// Call a method on each of torrents.
+func (rtclient *Client) callTorrents(infoHashes []string, method string, params ...any) error {
+	calls := [][]any{}
+	for _, infoHash := range infoHashes {
+		calls = append(calls, append([]any{method, target(infoHash)}, params...))
+	}
+	return rtclient.multicall(calls)
+}
+
+func (rtclient *Client) Cached() bool {
+	return rtclient.datatime > 0
+}
+
+func (rtclient *Client) sync() error {
+	if rtclient.datatime > 0 {
+		return nil
+	}
+	result, err := rtclient.call("d.multicall2", append([]any{"", "main"}, util.Map(torrentFields,
+		func(field string) any { return field })...)...)
+	if err != nil {
+		return err
+	}
+	torrents := map[string]*apiTorrent{}
+	for _, row := range toSlice(result) {
+		if torrent := parseTorrent(toSlice(row)); torrent != nil {
+			torrents[torrent.Hash] = torrent
+		}
+	}
+	rtclient.torrents = torrents
+	rtclient.datatime = util.Now()
+	rtclient.buildDerivative()
+	return nil
+}
+
+func (rtclient *Client) buildDerivative() {
+	unfinishedSize := int64(0)
+	unfinishedDownloadingSize := int64(0)
+	contentPathTorrents := map[string][]*apiTorrent{}
+	for _, torrent := range rtclient.torrents {
+		usize := max(torrent.SelectedSizeBytes-torrent.CompletedBytes, 0)
+		unfinishedSize += usize
+		if torrent.State == 1 && torrent.IsActive {
+			unfinishedDownloadingSize += usize
+		}
+		contentPath := torrent.contentPath()
+		contentPathTorrents[contentPath] = append(contentPathTorrents[contentPath], torrent)
+	}
+	rtclient.unfinishedSize = unfinishedSize
+	rtclient.unfinishedDownloadingSize = unfinishedDownloadingSize
+	rtclient.contentPathTorrents = contentPathTorrents
+}
+
+func (rtclient *Client) getTorrent(infoHash string) (*apiTorrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	torrent := rtclient.torrents[infoHash]
+	if torrent == nil {
+		return nil, fmt.Errorf("torrent not exists")
+	}
+	return torrent, nil
+}
+
+func (rtclient *Client) getAllInfoHashes() ([]string, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	return util.MapKeys(rtclient.torrents), nil
+}
+
+func (rtclient *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
+	// rTorrent keeps .torrent files in it's session dir, with uppercase info-hash file names.
+	if rtclient.ClientConfig.LocalTorrentsPath != "" {
+		return os.ReadFile(filepath.Join(rtclient.ClientConfig.LocalTorrentsPath, target(infoHash)+".torrent"))
+	}
+	return nil, fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	torrent := rtclient.torrents[infoHash]
+	if torrent == nil {
+		return nil, nil
+	}
+	return torrent.toTorrent(), nil
+}
+
+func (rtclient *Client) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	torrents := []*client.Torrent{}
+	for _, rt := range rtclient.torrents {
+		if category != "" {
+			if category == constants.NONE {
+				if rt.Category != "" {
+					continue
+				}
+			} else if category != rt.Category {
+				continue
+			}
+		}
+		torrent := rt.toTorrent()
+		if !showAll && torrent.DownloadSpeed < 1024 && torrent.UploadSpeed < 1024 {
+			continue
+		}
+		if !torrent.MatchStateFilter(stateFilter) {
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+func (rtclient *Client) GetTorrentsByContentPath(contentPath string) ([]*client.Torrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	var torrents []*client.Torrent
+	for _, rt := range rtclient.contentPathTorrents[contentPath] {
+		torrents = append(torrents, rt.toTorrent())
+	}
+	return torrents, nil
+}
+
+// rTorrent does not support renaming torrent or per-torrent speed limits,
+// option.Name, option.DownloadSpeedLimit and option.UploadSpeedLimit are ignored.
+func (rtclient *Client) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
+	if option == nil {
+		option = &client.TorrentOption{}
+	}
+	// Commands executed on the new torrent after it's loaded.
+	commands := []any{"d.custom.set=addtime," + fmt.Sprint(util.Now())}
+	if option.SavePath != "" {
+		commands = append(commands, "d.directory.set="+quoteCommandArg(option.SavePath))
+	}
+	if option.Category != "" && option.Category != constants.NONE {
+		commands = append(commands, "d.custom1.set="+encodeField(option.Category))
+	}
+	tags := util.CopySlice(option.Tags)
+	for name, value := range meta {
+		tags = append(tags, client.GenerateTorrentTagFromMetadata(name, value))
+	}
+	if len(tags) > 0 {
+		commands = append(commands, "d.custom.set="+TAGS_FIELD+","+encodeTags(tags))
+	}
+	var err error
+	if torrentUrl := string(torrentContent); util.IsTorrentUrl(torrentUrl) {
+		method := "load.start"
+		if option.Pause {
+			method = "load.normal"
+		}
+		_, err = rtclient.call(method, append([]any{"", torrentUrl}, commands...)...)
+	} else {
+		method := "load.raw_start"
+		if option.Pause {
+			method = "load.raw"
+		}
+		_, err = rtclient.call(method, append([]any{"", torrentContent}, commands...)...)
+	}
+	if err != nil {
+		return fmt.Errorf("add torrent error: %w", err)
+	}
+	return nil
+}
+
+func (rtclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOption, meta map[string]int64) error {
+	if option == nil {
+		option = &client.TorrentOption{}
+	}
+	rt, err := rtclient.getTorrent(infoHash)
+	if err != nil {
+		return err
+	}
+	if option.Category != "" {
+		category := option.Category
+		if category == constants.NONE {
+			category = ""
+		}
+		if category != rt.Category {
+			if err := rtclient.setCategory([]string{infoHash}, category); err != nil {
+				return err
+			}
+		}
+	}
+	if len(option.Tags) > 0 || len(option.RemoveTags) > 0 || len(meta) > 0 {
+		tags := slices.DeleteFunc(slices.Clone(rt.Tags), func(tag string) bool {
+			return slices.Contains(option.RemoveTags, tag) || len(meta) > 0 && client.IsSubstituteTag(tag)
+		})
+		tags = append(tags, option.Tags...)
+		for name, value := range meta {
+			tags = append(tags, client.GenerateTorrentTagFromMetadata(name, value))
+		}
+		if err := rtclient.setTags(infoHash, tags); err != nil {
+			return err
+		}
+	}
+	if option.SavePath != "" && option.SavePath != rt.savePath() {
+		if err := rtclient.SetTorrentsSavePath([]string{infoHash}, option.SavePath); err != nil {
+			return err
+		}
+	}
+	if option.Pause {
+		return rtclient.PauseTorrents([]string{infoHash})
+	} else if option.Resume {
+		return rtclient.ResumeTorrents([]string{infoHash})
+	}
+	return nil
+}
+
+// rTorrent itself never deletes downloaded files. If deleteFiles is true,
+// contents are deleted by executing "rm" command on the rTorrent host.
+func (rtclient *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) error {
+	if err := rtclient.sync(); err != nil {
+		return err
+	}
+	infoHashes = util.Filter(infoHashes, func(infoHash string) bool {
+		return rtclient.torrents[infoHash] != nil
+	})
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	calls := [][]any{}
+	for _, infoHash := range infoHashes {
+		calls = append(calls, []any{"d.erase", target(infoHash)})
+		// only delete contents if no other torrent is using them.
+		contentPath := rtclient.torrents[infoHash].contentPath()
+		if deleteFiles && !slices.ContainsFunc(rtclient.contentPathTorrents[contentPath], func(rt *apiTorrent) bool {
+			return !slices.Contains(infoHashes, rt.Hash)
+		}) {
+			calls = append(calls, []any{"execute.throw", "", "rm", "-rf", "--", contentPath})
+		}
+	}
+	if err := rtclient.multicall(calls); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		delete(rtclient.torrents, infoHash)
+	}
+	rtclient.buildDerivative()
+	return nil
+}
+
+// "Stop" in ruTorrent: stop and close the torrent.
+func (rtclient *Client) PauseTorrents(infoHashes []string) error {
+	calls := [][]any{}
+	for _, infoHash := range infoHashes {
+		calls = append(calls, []any{"d.stop", target(infoHash)}, []any{"d.close", target(infoHash)})
+	}
+	return rtclient.multicall(calls)
+}
+
+func (rtclient *Client) ResumeTorrents(infoHashes []string) error {
+	calls := [][]any{}
+	for _, infoHash := range infoHashes {
+		calls = append(calls, []any{"d.open", target(infoHash)}, []any{"d.start", target(infoHash)})
+	}
+	return rtclient.multicall(calls)
+}
+
+func (rtclient *Client) RecheckTorrents(infoHashes []string) error {
+	return rtclient.callTorrents(infoHashes, "d.check_hash")
+}
+
+func (rtclient *Client) ReannounceTorrents(infoHashes []string) error {
+	return rtclient.callTorrents(infoHashes, "d.tracker_announce")
+}
+
+func (rtclient *Client) setCategory(infoHashes []string, category string) error {
+	if err := rtclient.callTorrents(infoHashes, "d.custom1.set", encodeField(category)); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		if rtclient.torrents[infoHash] != nil {
+			rtclient.torrents[infoHash].Category = category
+		}
+	}
+	return nil
+}
+
+func (rtclient *Client) setTags(infoHash string, tags []string) error {
+	if _, err := rtclient.call("d.custom.set", target(infoHash), TAGS_FIELD, encodeTags(tags)); err != nil {
+		return err
+	}
+	if rtclient.torrents[infoHash] != nil {
+		rtclient.torrents[infoHash].Tags = util.UniqueSlice(tags)
+	}
+	return nil
+}
+
+func (rtclient *Client) AddTagsToTorrents(infoHashes []string, tags []string) error {
+	if err := rtclient.sync(); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		rt := rtclient.torrents[infoHash]
+		if rt == nil || !slices.ContainsFunc(tags, func(tag string) bool { return !slices.Contains(rt.Tags, tag) }) {
+			continue
+		}
+		if err := rtclient.setTags(infoHash, append(slices.Clone(rt.Tags), tags...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rtclient *Client) RemoveTagsFromTorrents(infoHashes []string, tags []string) error {
+	if err := rtclient.sync(); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		rt := rtclient.torrents[infoHash]
+		if rt == nil || !slices.ContainsFunc(tags, func(tag string) bool { return slices.Contains(rt.Tags, tag) }) {
+			continue
+		}
+		err := rtclient.setTags(infoHash, util.Filter(rt.Tags, func(tag string) bool {
+			return !slices.Contains(tags, tag)
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rTorrent does not move downloaded files when changing directory of torrent.
+// Contents are moved by executing "mv" command on the rTorrent host.
+func (rtclient *Client) SetTorrentsSavePath(infoHashes []string, savePath string) error {
+	savePath = strings.TrimSpace(savePath)
+	if savePath == "" {
+		return fmt.Errorf("savePath is empty")
+	}
+	if err := rtclient.sync(); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		rt := rtclient.torrents[infoHash]
+		if rt == nil || rt.savePath() == savePath {
+			continue
+		}
+		calls := [][]any{
+			{"d.stop", target(infoHash)},
+			{"d.close", target(infoHash)},
+			{"execute.throw", "", "mkdir", "-p", "--", savePath},
+		}
+		if rt.CompletedBytes > 0 {
+			calls = append(calls, []any{"execute.throw", "", "mv", "--", rt.contentPath(), savePath})
+		}
+		calls = append(calls, []any{"d.directory.set", target(infoHash), savePath})
+		if rt.State == 1 {
+			calls = append(calls, []any{"d.open", target(infoHash)}, []any{"d.start", target(infoHash)})
+		}
+		if err := rtclient.multicall(calls); err != nil {
+			return err
+		}
+	}
+	rtclient.PurgeCache()
+	return nil
+}
+
+func (rtclient *Client) PauseAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.PauseTorrents(infoHashes)
+}
+
+func (rtclient *Client) ResumeAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.ResumeTorrents(infoHashes)
+}
+
+func (rtclient *Client) RecheckAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.RecheckTorrents(infoHashes)
+}
+
+func (rtclient *Client) ReannounceAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.ReannounceTorrents(infoHashes)
+}
+
+func (rtclient *Client) AddTagsToAllTorrents(tags []string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.AddTagsToTorrents(infoHashes, tags)
+}
+
+func (rtclient *Client) RemoveTagsFromAllTorrents(tags []string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.RemoveTagsFromTorrents(infoHashes, tags)
+}
+
+func (rtclient *Client) SetAllTorrentsSavePath(savePath string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.SetTorrentsSavePath(infoHashes, savePath)
+}
+
+func (rtclient *Client) GetTags() ([]string, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, rt := range rtclient.torrents {
+		for _, tag := range rt.Tags {
+			if !client.IsSubstituteTag(tag) && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+func (rtclient *Client) CreateTags(tags ...string) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) DeleteTags(tags ...string) error {
+	return rtclient.RemoveTagsFromAllTorrents(tags)
+}
+
+func (rtclient *Client) MakeCategory(category string, savePath string) error {
+	return fmt.Errorf("unsupported")
+}
+
+// Categories exist only as labels of torrents, so just unset category of torrents.
+func (rtclient *Client) DeleteCategories(categories []string) error {
+	if err := rtclient.sync(); err != nil {
+		return err
+	}
+	infoHashes := []string{}
+	for infoHash, rt := range rtclient.torrents {
+		if slices.Contains(categories, rt.Category) {
+			infoHashes = append(infoHashes, infoHash)
+		}
+	}
+	return rtclient.setCategory(infoHashes, "")
+}
+
+func (rtclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	cats := []*client.TorrentCategory{}
+	catsFlag := map[string]bool{}
+	for _, rt := range rtclient.torrents {
+		if rt.Category != "" && !catsFlag[rt.Category] {
+			cats = append(cats, &client.TorrentCategory{
+				Name: rt.Category,
+			})
+			catsFlag[rt.Category] = true
+		}
+	}
+	return cats, nil
+}
+
+func (rtclient *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
+	if category == constants.NONE {
+		category = ""
+	}
+	return rtclient.setCategory(infoHashes, category)
+}
+
+func (rtclient *Client) SetAllTorrentsCatetory(category string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.SetTorrentsCatetory(infoHashes, category)
+}
+
+func (rtclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) TorrentRootPathExists(rootFolder string) bool {
+	if rootFolder == "" {
+		return false
+	}
+	if err := rtclient.sync(); err != nil {
+		return false
+	}
+	for _, torrent := range rtclient.torrents {
+		if torrent.Name == rootFolder {
+			return true
+		}
+	}
+	return false
+}
+
+func (rtclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentContentFile, error) {
+	result, err := rtclient.call("f.multicall", target(infoHash), "",
+		"f.path=", "f.size_bytes=", "f.completed_chunks=", "f.size_chunks=", "f.priority=")
+	if err != nil {
+		return nil, err
+	}
+	files := []*client.TorrentContentFile{}
+	for i, item := range toSlice(result) {
+		row := toSlice(item)
+		if len(row) < 5 {
+			return nil, fmt.Errorf("invalid files data")
+		}
+		completedChunks := toInt64(row[2])
+		sizeChunks := toInt64(row[3])
+		progress := float64(1)
+		if sizeChunks > 0 {
+			progress = float64(completedChunks) / float64(sizeChunks)
+		}
+		files = append(files, &client.TorrentContentFile{
+			Index:    int64(i),
+			Path:     toString(row[0]),
+			Size:     toInt64(row[1]),
+			Ignored:  toInt64(row[4]) == 0,
+			Complete: completedChunks == sizeChunks,
+			Progress: progress,
+		})
+	}
+	return files, nil
+}
+
+func (rtclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
+	if len(fileIndexes) == 0 {
+		return fmt.Errorf("must provide at least fileIndex")
+	}
+	calls := [][]any{}
+	for _, index := range fileIndexes {
+		calls = append(calls, []any{"f.priority.set", fmt.Sprintf("%s:f%d", target(infoHash), index),
+			qbPriority2Rtorrent(priority)})
+	}
+	calls = append(calls, []any{"d.update_priorities", target(infoHash)})
+	return rtclient.multicall(calls)
+}
+
+func (rtclient *Client) PurgeCache() {
+	rtclient.datatime = 0
+	rtclient.torrents = nil
+	rtclient.unfinishedSize = 0
+	rtclient.unfinishedDownloadingSize = 0
+	rtclient.contentPathTorrents = nil
+}
+
+func (rtclient *Client) GetStatus() (*client.Status, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	status := &client.Status{
+		FreeSpaceOnDisk:           -1,
+		UnfinishedSize:            rtclient.unfinishedSize,
+		UnfinishedDownloadingSize: rtclient.unfinishedDownloadingSize,
+	}
+	values := map[string]*int64{
+		"throttle.global_down.rate":     &status.DownloadSpeed,
+		"throttle.global_up.rate":       &status.UploadSpeed,
+		"throttle.global_down.max_rate": &status.DownloadSpeedLimit,
+		"throttle.global_up.max_rate":   &status.UploadSpeedLimit,
+	}
+	for method, value := range values {
+		result, err := rtclient.call(method, "")
+		if err != nil {
+			return nil, err
+		}
+		*value = toInt64(result)
+	}
+	// rTorrent can only report free disk space of the dir of a torrent.
+	if freeSpace, err := rtclient.getFreeSpace(); err == nil {
+		status.FreeSpaceOnDisk = freeSpace
+	} else {
+		log.Debugf("Failed to get rtorrent free disk space: %v", err)
+	}
+	for _, rt := range rtclient.torrents {
+		if slices.Contains(rt.Tags, config.NOADD_TAG) {
+			status.NoAdd = true
+		}
+		if slices.Contains(rt.Tags, config.NODEL_TAG) {
+			status.NoDel = true
+		}
+	}
+	return status, nil
+}
+
+// Get free disk space of default download dir, using any torrent in that dir.
+func (rtclient *Client) getFreeSpace() (int64, error) {
+	result, err := rtclient.call("directory.default", "")
+	if err != nil {
+		return 0, err
+	}
+	return rtclient.getFreeSpaceOfDir(toString(result))
+}
+
+// rTorrent can only report free disk space of the dir of a torrent, so there must be a torrent in dir.
+func (rtclient *Client) getFreeSpaceOfDir(dir string) (int64, error) {
+	dir = strings.TrimSuffix(dir, "/")
+	for infoHash, rt := range rtclient.torrents {
+		if rt.savePath() == dir || strings.HasPrefix(rt.savePath(), dir+"/") {
+			result, err := rtclient.call("d.free_diskspace", target(infoHash))
+			if err != nil {
+				return 0, err
+			}
+			return toInt64(result), nil
+		}
+	}
+	return 0, fmt.Errorf("no torrent in dir %s", dir)
+}
+
+func (rtclient *Client) GetFreeSpaceOnPath(path string) (int64, error) {
+	if err := rtclient.sync(); err != nil {
+		return 0, err
+	}
+	return rtclient.getFreeSpaceOfDir(path)
+}
+
+func (rtclient *Client) GetName() string {
+	return rtclient.Name
+}
+
+func (rtclient *Client) GetClientConfig() *config.ClientConfigStruct {
+	return rtclient.ClientConfig
+}
+
+// "rt_*" variables are rTorrent commands, e.g. "rt_network.max_open_files".
+func (rtclient *Client) SetConfig(variable string, value string) error {
+	if strings.HasPrefix(variable, "rt_") && len(variable) > 3 {
+		v, _ := util.String2Any(value)
+		_, err := rtclient.call(variable[3:]+".set", "", v)
+		return err
+	}
+	var err error
+	switch variable {
+	case "global_download_speed_limit":
+		_, err = rtclient.call("throttle.global_down.max_rate.set", "", max(util.ParseInt(value), 0))
+	case "global_upload_speed_limit":
+		_, err = rtclient.call("throttle.global_up.max_rate.set", "", max(util.ParseInt(value), 0))
+	case "free_disk_space", "global_download_speed", "global_upload_speed":
+		return fmt.Errorf("%s is read-only", variable)
+	case "save_path":
+		_, err = rtclient.call("directory.default.set", "", value)
+	}
+	return err
+}
+
+func (rtclient *Client) GetConfig(variable string) (string, error) {
+	if strings.HasPrefix(variable, "rt_") && len(variable) > 3 {
+		value, err := rtclient.call(variable[3:], "")
+		if err != nil {
+			return "", err
+		}
+		return toString(value), nil
+	}
+	switch variable {
+	case "global_download_speed_limit", "global_upload_speed_limit", "free_disk_space",
+		"global_download_speed", "global_upload_speed":
+		status, err := rtclient.GetStatus()
+		if err != nil {
+			return "", err
+		}
+		switch variable {
+		case "global_download_speed_limit":
+			return fmt.Sprint(status.DownloadSpeedLimit), nil
+		case "global_upload_speed_limit":
+			return fmt.Sprint(status.UploadSpeedLimit), nil
+		case "free_disk_space":
+			return fmt.Sprint(status.FreeSpaceOnDisk), nil
+		case "global_download_speed":
+			return fmt.Sprint(status.DownloadSpeed), nil
+		default:
+			return fmt.Sprint(status.UploadSpeed), nil
+		}
+	case "save_path":
+		value, err := rtclient.call("directory.default", "")
+		return toString(value), err
+	default:
+		return "", nil
+	}
+}
+
+type apiTracker struct {
+	Url       string
+	Group     int64
+	Enabled   bool
+	Succeeded int64
+	Failed    int64
+}
+
+func (rtclient *Client) getTrackers(infoHash string) ([]*apiTracker, error) {
+	result, err := rtclient.call("t.multicall", target(infoHash), "",
+		"t.url=", "t.group=", "t.is_enabled=", "t.success_counter=", "t.failed_counter=")
+	if err != nil {
+		return nil, err
+	}
+	trackers := []*apiTracker{}
+	for _, item := range toSlice(result) {
+		row := toSlice(item)
+		if len(row) < 5 {
+			return nil, fmt.Errorf("invalid trackers data")
+		}
+		trackers = append(trackers, &apiTracker{
+			Url:       toString(row[0]),
+			Group:     toInt64(row[1]),
+			Enabled:   toInt64(row[2]) == 1,
+			Succeeded: toInt64(row[3]),
+			Failed:    toInt64(row[4]),
+		})
+	}
+	return trackers, nil
+}
+
+// rTorrent only keeps the latest tracker error message of a torrent in "d.message".
+// Disabled trackers are treated as removed.
+func (rtclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
+	rttrackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return nil, err
+	}
+	message, err := rtclient.call("d.message", target(infoHash))
+	if err != nil {
+		return nil, err
+	}
+	trackers := client.TorrentTrackers{}
+	for _, rttracker := range rttrackers {
+		if !rttracker.Enabled {
+			continue
+		}
+		tracker := client.TorrentTracker{
+			Url:    rttracker.Url,
+			Status: "notcontacted",
+		}
+		if rttracker.Failed > 0 {
+			tracker.Status = "error"
+			tracker.Msg = toString(message)
+		} else if rttracker.Succeeded > 0 {
+			tracker.Status = "working"
+		}
+		trackers = append(trackers, tracker)
+	}
+	return trackers, nil
+}
+
+// rTorrent can not modify or remove existing trackers of a torrent. Instead, the old tracker is disabled,
+// and the new tracker is inserted into the same group.
+func (rtclient *Client) EditTorrentTracker(infoHash string, oldTracker string,
+	newTracker string, replaceHost bool) error {
+	trackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	index := -1
+	newTrackerUrl := newTracker
+	for i, tracker := range trackers {
+		if !tracker.Enabled {
+			continue
+		}
+		if replaceHost {
+			if !util.MatchUrlWithHostOrUrl(tracker.Url, oldTracker) {
+				continue
+			}
+			if !util.IsUrl(newTracker) {
+				urlObj, err := url.Parse(tracker.Url)
+				if err != nil {
+					continue
+				}
+				urlObj.Host = newTracker
+				newTrackerUrl = urlObj.String()
+			}
+		} else if tracker.Url != oldTracker {
+			continue
+		}
+		index = i
+		break
+	}
+	if index == -1 {
+		return fmt.Errorf("torrent %s old tracker %s does NOT exist", infoHash, oldTracker)
+	}
+	if trackers[index].Url == newTrackerUrl {
+		return nil
+	}
+	return rtclient.multicall([][]any{
+		{"d.tracker.insert", target(infoHash), fmt.Sprint(trackers[index].Group), newTrackerUrl},
+		{"t.is_enabled.set", fmt.Sprintf("%s:t%d", target(infoHash), index), int64(0)},
+	})
+}
+
+func (rtclient *Client) AddTorrentTrackers(infoHash string, trackers []string,
+	oldTracker string, removeExisting bool) error {
+	rttrackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	if oldTracker != "" && !slices.ContainsFunc(rttrackers, func(t *apiTracker) bool {
+		return t.Enabled && util.MatchUrlWithHostOrUrl(t.Url, oldTracker)
+	}) {
+		return nil
+	}
+	calls := [][]any{}
+	group := int64(0)
+	for i, t := range rttrackers {
+		if removeExisting {
+			if t.Enabled && !slices.Contains(trackers, t.Url) {
+				calls = append(calls, []any{"t.is_enabled.set", fmt.Sprintf("%s:t%d", target(infoHash), i), int64(0)})
+			}
+		} else {
+			group = max(group, t.Group+1)
+		}
+	}
+	for _, tracker := range trackers {
+		if slices.ContainsFunc(rttrackers, func(t *apiTracker) bool { return t.Enabled && t.Url == tracker }) {
+			continue
+		}
+		calls = append(calls, []any{"d.tracker.insert", target(infoHash), fmt.Sprint(group), tracker})
+		group++
+	}
+	return rtclient.multicall(calls)
+}
+
+func (rtclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string) error {
+	rttrackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	calls := [][]any{}
+	for i, t := range rttrackers {
+		if t.Enabled && slices.Contains(trackers, t.Url) {
+			calls = append(calls, []any{"t.is_enabled.set", fmt.Sprintf("%s:t%d", target(infoHash), i), int64(0)})
+		}
+	}
+	return rtclient.multicall(calls)
+}
+
+func (rtclient *Client) Close() {
+	rtclient.PurgeCache()
+}
+
+// rTorrent uses uppercase info-hash as download target.
+func target(infoHash string) string {
+	return strings.ToUpper(infoHash)
+}
+
+func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
+	client.Client, error) {
+	urlObj, err := url.Parse(clientConfig.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rtorrent url: %s", clientConfig.Url)
+	}
+	var rtransport transport
+	switch urlObj.Scheme {
+	case "scgi":
+		if urlObj.Host != "" {
+			rtransport = &scgiTransport{network: "tcp", address: urlObj.Host, timeout: 60 * time.Second}
+		} else if urlObj.Path != "" {
+			rtransport = &scgiTransport{network: "unix", address: urlObj.Path, timeout: 60 * time.Second}
+		}
+	case "http", "https":
+		if urlObj.Host != "" {
+			rtransport = &httpTransport{
+				url:        clientConfig.Url,
+				username:   clientConfig.Username,
+				password:   clientConfig.Password,
+				httpClient: &http.Client{Timeout: 60 * time.Second},
+			}
+		}
+	}
+	if rtransport == nil {
+		return nil, fmt.Errorf("invalid rtorrent url: %s", clientConfig.Url)
+	}
+	client := &Client{
+		Name:         name,
+		ClientConfig: clientConfig,
+		Config:       config,
+		transport:    rtransport,
+	}
+	return client, nil
+}
+
+func init() {
+	client.Register(&client.RegInfo{
+		Name:    "rtorrent",
+		Creator: NewClient,
+	})
+}
+
+var (
+	_ client.Client = (*Client)(nil)
+)
@@ -0,0 +1,160 @@
+package rtorrent_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/rtorrent"
+	"github.com/sagan/ptool/config"
+)
+
+const testInfoHash = "0123456789abcdef0123456789abcdef01234567"
+
+// A minimal stand-in of rTorrent XML-RPC server (e.g. ruTorrent rpc.php endpoint).
+// The commands of added torrent are recorded in loadCommands.
+func newTestServer(t *testing.T) (server *httptest.Server, loadCommands *[]string) {
+	loadCommands = &[]string{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "admin" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			MethodName string `xml:"methodName"`
+			Params     []struct {
+				Value struct {
+					String string `xml:"string"`
+					Base64 string `xml:"base64"`
+				} `xml:"value"`
+			} `xml:"params>param"`
+		}
+		if err := xml.Unmarshal(body, &req); err != nil {
+			t.Errorf("invalid xmlrpc request: %v", err)
+			return
+		}
+		result := ""
+		switch req.MethodName {
+		case "load.raw_start":
+			if len(req.Params) < 2 || req.Params[1].Value.Base64 == "" {
+				t.Errorf("invalid load.raw_start params")
+			}
+			for _, param := range req.Params[2:] {
+				*loadCommands = append(*loadCommands, param.Value.String)
+			}
+			result = `<i4>0</i4>`
+		case "d.multicall2":
+			rows := ""
+			if len(*loadCommands) > 0 {
+				category, tags := "", ""
+				for _, command := range *loadCommands {
+					if value, found := strings.CutPrefix(command, "d.custom1.set="); found {
+						category = value
+					} else if value, found := strings.CutPrefix(command, "d.custom.set=ptool_tags,"); found {
+						tags = value
+					}
+				}
+				values := []string{
+					`<string>` + strings.ToUpper(testInfoHash) + `</string>`, `<string>foo</string>`, `<i8>1</i8>`,
+					`<i8>1</i8>`, `<i8>1</i8>`, `<i8>0</i8>`, `<i8>1</i8>`, `<string>/downloads/foo</string>`,
+					`<i8>2048</i8>`, `<i8>1024</i8>`, `<i8>1024</i8>`, `<i8>4096</i8>`, `<i8>0</i8>`, `<i8>100</i8>`,
+					`<i8>1024</i8>`, `<i8>1700000000</i8>`, `<i8>1700000100</i8>`, `<string>1699999999</string>`,
+					`<string>` + category + `</string>`, `<string>` + tags + `</string>`, `<i8>3</i8>`, `<i8>5</i8>`,
+					`<string></string>`, `<string>https://tracker.example.com/announce#</string>`,
+				}
+				rows = `<value><array><data><value>` + strings.Join(values, `</value><value>`) +
+					`</value></data></array></value>`
+			}
+			result = `<array><data>` + rows + `</data></array>`
+		case "throttle.global_down.rate", "throttle.global_up.max_rate":
+			result = `<i8>0</i8>`
+		case "throttle.global_up.rate":
+			result = `<i8>4096</i8>`
+		case "throttle.global_down.max_rate":
+			result = `<i8>102400</i8>`
+		case "directory.default":
+			result = `<string>/downloads</string>`
+		case "d.free_diskspace":
+			result = `<i8>12345</i8>`
+		default:
+			fmt.Fprint(w, `<?xml version="1.0"?><methodResponse><fault><value><struct>`+
+				`<member><name>faultCode</name><value><i4>-506</i4></value></member>`+
+				`<member><name>faultString</name><value><string>Method not defined</string></value></member>`+
+				`</struct></value></fault></methodResponse>`)
+			return
+		}
+		fmt.Fprint(w, `<?xml version="1.0"?><methodResponse><params><param><value>`+result+
+			`</value></param></params></methodResponse>`)
+	})), loadCommands
+}
+
+func TestRtorrentClient(t *testing.T) {
+	server, loadCommands := newTestServer(t)
+	defer server.Close()
+	clientConfig := &config.ClientConfigStruct{
+		Type:     "rtorrent",
+		Name:     "rt",
+		Url:      server.URL + "/rutorrent/plugins/rpc/rpc.php",
+		Username: "admin",
+		Password: "pass",
+	}
+	clientInstance, err := rtorrent.NewClient("rt", clientConfig, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer clientInstance.Close()
+
+	err = clientInstance.AddTorrent([]byte("d4:infod4:name3:fooee"), &client.TorrentOption{
+		Category: "my cat",
+		SavePath: `/downloads/my "dir"`,
+		Tags:     []string{"site:foo", "foo,bar"},
+	}, map[string]int64{"dcet": 100})
+	if err != nil {
+		t.Fatalf("failed to add torrent: %v", err)
+	}
+	// Each command of added torrent is a single string, "," must not appear in values unquoted.
+	if !slices.Contains(*loadCommands, `d.directory.set="/downloads/my \"dir\""`) {
+		t.Errorf("expect quoted save path in load commands, got %v", *loadCommands)
+	}
+	for _, command := range *loadCommands {
+		if value, found := strings.CutPrefix(command, "d.custom.set=ptool_tags,"); found &&
+			strings.Contains(value, ",") {
+			t.Errorf("expect no unescaped comma in tags command, got %s", command)
+		}
+	}
+	torrents, err := clientInstance.GetTorrents("", "my cat", true)
+	if err != nil {
+		t.Fatalf("failed to get torrents: %v", err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("expect 1 torrent, got %d", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.InfoHash != testInfoHash || torrent.State != "seeding" || torrent.SavePath != "/downloads" ||
+		torrent.ContentPath != "/downloads/foo" || torrent.Size != 1024 || torrent.SizeTotal != 2048 ||
+		torrent.TrackerDomain != "tracker.example.com" || torrent.Atime != 1699999999 || torrent.Leechers != 2 {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	if !slices.Contains(torrent.Tags, "site:foo") || !slices.Contains(torrent.Tags, "foo,bar") ||
+		len(torrent.Tags) != 2 || torrent.Meta["dcet"] != 100 {
+		t.Errorf("unexpected torrent tags / meta: %v / %v", torrent.Tags, torrent.Meta)
+	}
+	status, err := clientInstance.GetStatus()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status.FreeSpaceOnDisk != 12345 || status.UploadSpeed != 4096 || status.DownloadSpeedLimit != 102400 ||
+		status.UploadSpeedLimit != 0 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if _, err = clientInstance.GetTorrentContents(testInfoHash); err == nil {
+		t.Errorf("expect xmlrpc fault for unknown method")
+	}
+}
@@ -0,0 +1,318 @@
+package rtorrent
+
+// A minimal XML-RPC codec & transports (SCGI / HTTP) for rTorrent.
+// XML-RPC spec: http://xmlrpc.com/spec.md .
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type xmlrpcFault struct {
+	Code    int64
+	Message string
+}
+
+func (f *xmlrpcFault) Error() string {
+	return fmt.Sprintf("rtorrent xmlrpc fault %d: %s", f.Code, f.Message)
+}
+
+// A generic XML DOM node.
+type xmlNode struct {
+	XMLName xml.Name
+	Content string     `xml:",chardata"`
+	Nodes   []*xmlNode `xml:",any"`
+}
+
+func (node *xmlNode) child(name string) *xmlNode {
+	for _, child := range node.Nodes {
+		if child.XMLName.Local == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// Encode a XML-RPC methodCall request body.
+// Supported param types: string, bool, int, int64, float64, []byte, []string, []any, map[string]any.
+func encodeRequest(method string, params ...any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`<?xml version="1.0"?><methodCall><methodName>`)
+	xml.EscapeText(buf, []byte(method))
+	buf.WriteString(`</methodName><params>`)
+	for _, param := range params {
+		buf.WriteString(`<param>`)
+		if err := encodeValue(buf, param); err != nil {
+			return nil, err
+		}
+		buf.WriteString(`</param>`)
+	}
+	buf.WriteString(`</params></methodCall>`)
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, value any) error {
+	buf.WriteString(`<value>`)
+	switch v := value.(type) {
+	case string:
+		buf.WriteString(`<string>`)
+		xml.EscapeText(buf, []byte(v))
+		buf.WriteString(`</string>`)
+	case bool:
+		if v {
+			buf.WriteString(`<boolean>1</boolean>`)
+		} else {
+			buf.WriteString(`<boolean>0</boolean>`)
+		}
+	case int:
+		encodeInt(buf, int64(v))
+	case int64:
+		encodeInt(buf, v)
+	case float64:
+		buf.WriteString(`<double>` + strconv.FormatFloat(v, 'f', -1, 64) + `</double>`)
+	case []byte:
+		buf.WriteString(`<base64>` + base64.StdEncoding.EncodeToString(v) + `</base64>`)
+	case []string:
+		buf.WriteString(`<array><data>`)
+		for _, item := range v {
+			encodeValue(buf, item)
+		}
+		buf.WriteString(`</data></array>`)
+	case []any:
+		buf.WriteString(`<array><data>`)
+		for _, item := range v {
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(`</data></array>`)
+	case map[string]any:
+		buf.WriteString(`<struct>`)
+		for name, item := range v {
+			buf.WriteString(`<member><name>`)
+			xml.EscapeText(buf, []byte(name))
+			buf.WriteString(`</name>`)
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+			buf.WriteString(`</member>`)
+		}
+		buf.WriteString(`</struct>`)
+	default:
+		return fmt.Errorf("unsupported xmlrpc value type %T", value)
+	}
+	buf.WriteString(`</value>`)
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, value int64) {
+	if value >= math.MinInt32 && value <= math.MaxInt32 {
+		buf.WriteString(`<i4>` + strconv.FormatInt(value, 10) + `</i4>`)
+	} else {
+		buf.WriteString(`<i8>` + strconv.FormatInt(value, 10) + `</i8>`)
+	}
+}
+
+// Decode a XML-RPC methodResponse body. Return the (only) param value, or a *xmlrpcFault error.
+// Decoded value types: string, bool, int64, float64, []byte, []any, map[string]any or nil.
+func decodeResponse(body []byte) (any, error) {
+	var root xmlNode
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("invalid xmlrpc response: %w", err)
+	}
+	if root.XMLName.Local != "methodResponse" {
+		return nil, fmt.Errorf("invalid xmlrpc response: root element is %s", root.XMLName.Local)
+	}
+	if fault := root.child("fault"); fault != nil {
+		value, err := decodeValue(fault.child("value"))
+		if err != nil {
+			return nil, err
+		}
+		f := &xmlrpcFault{}
+		if faultStruct, ok := value.(map[string]any); ok {
+			f.Code = toInt64(faultStruct["faultCode"])
+			f.Message = toString(faultStruct["faultString"])
+		}
+		return nil, f
+	}
+	params := root.child("params")
+	if params == nil {
+		return nil, nil
+	}
+	param := params.child("param")
+	if param == nil {
+		return nil, nil
+	}
+	return decodeValue(param.child("value"))
+}
+
+func decodeValue(node *xmlNode) (any, error) {
+	if node == nil {
+		return nil, fmt.Errorf("invalid xmlrpc value: missing value element")
+	}
+	if len(node.Nodes) == 0 {
+		return node.Content, nil // string is the default type
+	}
+	typeNode := node.Nodes[0]
+	content := strings.TrimSpace(typeNode.Content)
+	switch typeNode.XMLName.Local {
+	case "string", "dateTime.iso8601":
+		return typeNode.Content, nil
+	case "i4", "i8", "int":
+		return strconv.ParseInt(content, 10, 64)
+	case "boolean":
+		return content == "1", nil
+	case "double":
+		return strconv.ParseFloat(content, 64)
+	case "base64":
+		return base64.StdEncoding.DecodeString(content)
+	case "nil":
+		return nil, nil
+	case "array":
+		values := []any{}
+		data := typeNode.child("data")
+		if data == nil {
+			return values, nil
+		}
+		for _, item := range data.Nodes {
+			value, err := decodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case "struct":
+		values := map[string]any{}
+		for _, member := range typeNode.Nodes {
+			name := member.child("name")
+			if name == nil {
+				continue
+			}
+			value, err := decodeValue(member.child("value"))
+			if err != nil {
+				return nil, err
+			}
+			values[name.Content] = value
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unsupported xmlrpc value type %s", typeNode.XMLName.Local)
+	}
+}
+
+func toInt64(value any) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	default:
+		return 0
+	}
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toSlice(value any) []any {
+	if v, ok := value.([]any); ok {
+		return v
+	}
+	return nil
+}
+
+// Send a raw XML-RPC request and return response body.
+type transport interface {
+	roundTrip(body []byte) ([]byte, error)
+}
+
+// SCGI transport. network: "tcp" or "unix".
+// SCGI protocol: https://python.ca/scgi/protocol.txt .
+type scgiTransport struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+func (st *scgiTransport) roundTrip(body []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(st.network, st.address, st.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(st.timeout))
+	headers := "CONTENT_LENGTH\x00" + strconv.Itoa(len(body)) + "\x00SCGI\x001\x00"
+	request := strconv.Itoa(len(headers)) + ":" + headers + "," + string(body)
+	if _, err = io.WriteString(conn, request); err != nil {
+		return nil, err
+	}
+	// The response is in CGI format: headers, a blank line, then body.
+	reader := bufio.NewReader(conn)
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scgi response: %w", err)
+	}
+	if status := header.Get("Status"); status != "" && !strings.HasPrefix(status, "200") {
+		return nil, fmt.Errorf("scgi response status: %s", status)
+	}
+	return io.ReadAll(reader)
+}
+
+// HTTP transport, for rTorrent XML-RPC exposed by a web server (e.g. "/RPC2"),
+// or ruTorrent's "plugins/rpc/rpc.php" endpoint.
+type httpTransport struct {
+	url        string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+func (ht *httpTransport) roundTrip(body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, ht.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if ht.username != "" || ht.password != "" {
+		req.SetBasicAuth(ht.username, ht.password)
+	}
+	res, err := ht.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %d", res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
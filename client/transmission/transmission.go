@@ -2,6 +2,9 @@ package transmission
 
 // use https://github.com/hekmon/transmissionrpc
 // protocol: https://github.com/transmission/transmission/blob/3.00/extras/rpc-spec.txt
+// Transmission 4.x (RPC v17): https://github.com/transmission/transmission/blob/4.0.0/docs/rpc-spec.md
+// On RPC v17+, torrent category is stored in it's bandwidth group ("group" field), instead of a "category:xxx" label;
+// and trackers are edited via "trackerList" field.
 
 import (
 	"context"
@@ -40,8 +43,12 @@ type Client struct {
 	unfinishedDownloadingSize int64
 	contentPathTorrents       map[string][]*transmissionrpc.Torrent
 	lastTorrent               *transmissionrpc.Torrent // a **really** simple cache with capacity of only one
+	rpcVersion                int64                    // server RPC version. 0 if not fetched yet
 }
 
+// The first RPC version (Transmission 4.0.0) that supports torrent "group", "trackerList" fields and labels in torrent-add.
+const RPC_VERSION_V4 = 17
+
 func (trclient *Client) GetTorrentsByContentPath(contentPath string) ([]*client.Torrent, error) {
 	if err := trclient.Sync(false); err != nil {
 		return nil, err
@@ -67,6 +74,19 @@ func (trclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit f
 	return ErrNotImplemented
 }
 
+// Return true if server RPC version >= 17 (Transmission 4.x).
+func (trclient *Client) isV4() bool {
+	if trclient.rpcVersion == 0 {
+		sessionArgs, err := trclient.client.SessionArgumentsGet(context.TODO(), []string{"rpc-version"})
+		if err != nil || sessionArgs.RPCVersion == nil {
+			log.Debugf("Failed to get tr rpc version: %v", err)
+			return false
+		}
+		trclient.rpcVersion = *sessionArgs.RPCVersion
+	}
+	return trclient.rpcVersion >= RPC_VERSION_V4
+}
+
 // get a torrent info from rpc. return error if torrent not found
 func (trclient *Client) getTorrent(infoHash string, full bool) (*transmissionrpc.Torrent, error) {
 	// If TrackerStats is present, it's a full info.
@@ -126,11 +146,15 @@ func (trclient *Client) Sync(full bool) (err error) {
 	if full {
 		torrents, err = transmissionbt.TorrentGetAll(context.TODO())
 	} else {
-		torrents, err = transmissionbt.TorrentGet(context.TODO(), []string{
+		fields := []string{
 			"activityDate", "addedDate", "doneDate", "downloadDir", "downloadedEver", "downloadLimit", "downloadLimited",
 			"hashString", "id", "labels", "name", "peersGettingFromUs", "peersSendingToUs", "percentDone", "rateDownload",
 			"rateUpload", "sizeWhenDone", "status", "trackers", "totalSize", "uploadedEver", "uploadLimit", "uploadLimited",
-		}, nil)
+		}
+		if trclient.isV4() {
+			fields = append(fields, "group")
+		}
+		torrents, err = transmissionbt.TorrentGet(context.TODO(), fields, nil)
 	}
 
 	if err != nil {
@@ -241,6 +265,7 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 	if option.SavePath != "" {
 		downloadDir = &option.SavePath
 	}
+	isV4 := trclient.isV4()
 	payload := transmissionrpc.TorrentAddPayload{
 		Paused:      &option.Pause,
 		DownloadDir: downloadDir,
@@ -252,6 +277,23 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		torrentContentB64 := base64.StdEncoding.EncodeToString(torrentContent)
 		payload.MetaInfo = &torrentContentB64
 	}
+	labels := util.CopySlice(option.Tags)
+	for name, value := range meta {
+		labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
+	}
+	var group *string
+	if option.Category != "" && option.Category != constants.NONE {
+		if isV4 {
+			group = &option.Category
+		} else {
+			// use label to simulate category
+			labels = append(labels, client.GenerateTorrentTagFromCategory(option.Category))
+		}
+	}
+	if isV4 && len(labels) > 0 {
+		payload.Labels = labels
+		labels = nil
+	}
 	// returned torrent will only have HashString, ID and Name fields set up.
 	torrent, err := transmissionbt.TorrentAdd(context.TODO(), payload)
 	if err != nil {
@@ -265,14 +307,6 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		log.Tracef("rename tr torrent name=%s err=%v", name, err)
 	}
 
-	labels := util.CopySlice(option.Tags)
-	if option.Category != "" && option.Category != constants.NONE {
-		// use label to simulate category
-		labels = append(labels, client.GenerateTorrentTagFromCategory(option.Category))
-	}
-	for name, value := range meta {
-		labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
-	}
 	uploadLimit := int64(0)
 	downloadLimit := int64(0)
 	uploadLimited := false
@@ -291,9 +325,10 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		}
 		downloadLimited = true
 	}
-	if len(labels) > 0 || uploadLimited || downloadLimited {
+	if len(labels) > 0 || group != nil || uploadLimited || downloadLimited {
 		err := transmissionbt.TorrentSet(context.TODO(), transmissionrpc.TorrentSetPayload{
 			IDs:             []int64{*torrent.ID},
+			Group:           group,
 			Labels:          labels,
 			UploadLimited:   &uploadLimited,
 			UploadLimit:     &uploadLimit,
@@ -325,42 +360,48 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 		IDs: []int64{*trtorrent.ID},
 	}
 
-	if (option.Category != "" && option.Category != constants.NONE) ||
-		len(option.Tags) > 0 || len(option.RemoveTags) > 0 ||
-		len(meta) > 0 || len(torrent.Meta) > 0 {
+	isV4 := trclient.isV4()
+	category := torrent.Category
+	if option.Category != "" {
+		category = option.Category
+		if category == constants.NONE {
+			category = ""
+		}
+	}
+	updateLabels := len(option.Tags) > 0 || len(option.RemoveTags) > 0 || len(meta) > 0
+	if category != torrent.Category {
+		updateLabels = true
+	}
+	// On v4, also migrate the category label (set by ptool on previous Transmission versions) to group.
+	if isV4 && (trtorrent.Group == nil || *trtorrent.Group != category) {
+		payload.Group = &category
+	}
+	if updateLabels {
 		labels := []string{}
-		if option.Category != "" && torrent.Category != option.Category {
-			categoryTag := client.GenerateTorrentTagFromCategory(option.Category)
-			labels = append(labels, categoryTag)
-		} else if torrent.Category != "" {
-			categoryTag := client.GenerateTorrentTagFromCategory(torrent.Category)
-			labels = append(labels, categoryTag)
+		if category != "" && !isV4 {
+			labels = append(labels, client.GenerateTorrentTagFromCategory(category))
+		}
+		for _, tag := range torrent.Tags {
+			if !slices.Contains(option.RemoveTags, tag) && !slices.Contains(option.Tags, tag) {
+				labels = append(labels, tag)
+			}
 		}
 		labels = append(labels, option.Tags...)
+		metaValues := torrent.Meta
 		if len(meta) > 0 {
-			for name, value := range meta {
-				labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
-			}
-		} else if len(torrent.Meta) > 0 {
-			for name, value := range torrent.Meta {
-				labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
-			}
+			metaValues = meta
 		}
-		if len(labels) > 0 || len(option.RemoveTags) > 0 {
-			for _, tag := range torrent.Tags {
-				if !slices.Contains(option.RemoveTags, tag) {
-					labels = append(labels, tag)
-				}
-			}
-			payload.Labels = labels
+		for name, value := range metaValues {
+			labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
 		}
+		payload.Labels = labels
 	}
 
 	if option.DownloadSpeedLimit != 0 && option.DownloadSpeedLimit != torrent.DownloadSpeedLimit {
 		downloadLimited := true
 		downloadLimit := int64(0)
 		if option.DownloadSpeedLimit > 0 {
-			downloadLimit := option.DownloadSpeedLimit / 1024
+			downloadLimit = option.DownloadSpeedLimit / 1024
 			if downloadLimit == 0 {
 				downloadLimit = 1
 			}
@@ -545,12 +586,32 @@ func (trclient *Client) DeleteTags(tags ...string) error {
 	return trclient.RemoveTagsFromAllTorrents(tags)
 }
 
+// On Transmission 4.x, category is a bandwidth group. Category save path is not supported,
+// savePath must be empty or constants.NONE.
 func (trclient *Client) MakeCategory(category string, savePath string) error {
-	return fmt.Errorf("unsupported")
+	if !trclient.isV4() || savePath != "" && savePath != constants.NONE {
+		return fmt.Errorf("unsupported")
+	}
+	return trclient.client.BandwidthGroupSet(context.TODO(), transmissionrpc.BandwidthGroup{
+		Name:                category,
+		HonorsSessionLimits: true,
+	})
 }
 
+// Transmission has no way to delete a bandwidth group, so only unset category of torrents.
 func (trclient *Client) DeleteCategories(categories []string) error {
-	return fmt.Errorf("unsupported")
+	if err := trclient.Sync(false); err != nil {
+		return err
+	}
+	for infoHash, trtorrent := range trclient.torrents {
+		if !slices.Contains(categories, tr2Torrent(trtorrent).Category) {
+			continue
+		}
+		if err := trclient.ModifyTorrent(infoHash, &client.TorrentOption{Category: constants.NONE}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (trclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
@@ -559,9 +620,22 @@ func (trclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
 	}
 	cats := []*client.TorrentCategory{}
 	catsFlag := map[string]bool{}
+	if trclient.isV4() {
+		groups, err := trclient.client.BandwidthGroupGet(context.TODO(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			if group.Name != "" && !catsFlag[group.Name] {
+				cats = append(cats, &client.TorrentCategory{
+					Name: group.Name,
+				})
+				catsFlag[group.Name] = true
+			}
+		}
+	}
 	for _, trtorrent := range trclient.torrents {
-		torrent := tr2Torrent(trtorrent)
-		cat := torrent.GetCategoryFromTag()
+		cat := tr2Torrent(trtorrent).Category
 		if cat != "" && !catsFlag[cat] {
 			cats = append(cats, &client.TorrentCategory{
 				Name: cat,
@@ -615,12 +689,17 @@ func (trclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentCo
 	}
 	files := []*client.TorrentContentFile{}
 	for i, trTorrentFile := range torrent.Files {
+		progress := float64(1)
+		if trTorrentFile.Length > 0 {
+			progress = float64(trTorrentFile.BytesCompleted) / float64(trTorrentFile.Length)
+		}
 		files = append(files, &client.TorrentContentFile{
+			Index:    int64(i),
 			Path:     trTorrentFile.Name,
 			Size:     trTorrentFile.Length,
 			Ignored:  !torrent.FileStats[i].Wanted,
 			Complete: trTorrentFile.BytesCompleted == trTorrentFile.Length,
-			Progress: float64(trTorrentFile.BytesCompleted) / float64(trTorrentFile.Length),
+			Progress: progress,
 		})
 	}
 	return files, nil
@@ -639,6 +718,14 @@ func (trclient *Client) PurgeCache() {
 	trclient.contentPathTorrents = nil
 }
 
+func (trclient *Client) GetFreeSpaceOnPath(path string) (int64, error) {
+	freeSpace, err := trclient.client.FreeSpace(context.TODO(), path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(freeSpace / 8), nil // tr freespace is in bits.
+}
+
 func (trclient *Client) GetStatus() (*client.Status, error) {
 	if err := trclient.syncMeta(); err != nil {
 		return nil, err
@@ -833,6 +920,7 @@ func (trclient *Client) EditTorrentTracker(infoHash string, oldTracker string, n
 			}
 		} else if tracker.Announce == oldTracker {
 			oldTrackerId = tracker.ID
+			oldTrackerUrl = tracker.Announce
 			break
 		}
 	}
@@ -842,6 +930,16 @@ func (trclient *Client) EditTorrentTracker(infoHash string, oldTracker string, n
 	if oldTrackerUrl == newTrackerUrl {
 		return nil
 	}
+	if trclient.isV4() {
+		// Match by url, as the trackers cached by setTrackerList do not have ids.
+		trackers := util.Map(trtorrent.Trackers, func(t *transmissionrpc.Tracker) *transmissionrpc.Tracker {
+			if t.Announce == oldTrackerUrl {
+				return &transmissionrpc.Tracker{Announce: newTrackerUrl, Tier: t.Tier}
+			}
+			return t
+		})
+		return trclient.setTrackerList(trtorrent, trackers)
+	}
 	// this is broken for now as transmission RPC expects trackerReplace to be
 	// a mixed types array of ids (integer) and urls(string)
 	// it's a problem of transmissionrpc library
@@ -872,6 +970,21 @@ func (trclient *Client) AddTorrentTrackers(infoHash string, trackers []string,
 		})
 	}
 	if len(trackers) > 0 {
+		if trclient.isV4() {
+			// add each new tracker as a new tier, the same as qBittorrent
+			tier := int64(0)
+			newTrackers := []*transmissionrpc.Tracker{}
+			if !removeExisting {
+				for _, t := range trtorrent.Trackers {
+					tier = max(tier, t.Tier+1)
+				}
+				newTrackers = append(newTrackers, trtorrent.Trackers...)
+			}
+			for i, tracker := range trackers {
+				newTrackers = append(newTrackers, &transmissionrpc.Tracker{Announce: tracker, Tier: tier + int64(i)})
+			}
+			return trclient.setTrackerList(trtorrent, newTrackers)
+		}
 		payload := transmissionrpc.TorrentSetPayload{
 			IDs:        []int64{*trtorrent.ID},
 			TrackerAdd: trackers,
@@ -896,6 +1009,11 @@ func (trclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string
 		}
 	}
 	if len(trackerIds) > 0 {
+		if trclient.isV4() {
+			return trclient.setTrackerList(trtorrent, util.Filter(trtorrent.Trackers, func(t *transmissionrpc.Tracker) bool {
+				return !slices.Contains(trackers, t.Announce)
+			}))
+		}
 		return trclient.client.TorrentSet(context.TODO(), transmissionrpc.TorrentSetPayload{
 			IDs:           []int64{*trtorrent.ID},
 			TrackerRemove: trackerIds,
@@ -904,8 +1022,43 @@ func (trclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string
 	return nil
 }
 
+// Replace all trackers of torrent using "trackerList" field (RPC v17+).
+func (trclient *Client) setTrackerList(trtorrent *transmissionrpc.Torrent, trackers []*transmissionrpc.Tracker) error {
+	trackerList := generateTrackerList(trackers)
+	err := trclient.client.TorrentSet(context.TODO(), transmissionrpc.TorrentSetPayload{
+		IDs:         []int64{*trtorrent.ID},
+		TrackerList: &trackerList,
+	})
+	if err == nil {
+		trclient.lastTorrent = nil
+		if trclient.torrents[*trtorrent.HashString] != nil {
+			trclient.torrents[*trtorrent.HashString].Trackers = trackers
+		}
+	}
+	return err
+}
+
+// Priority is in qBittorrent style: 0 - do not download; 1 - normal; 6 - high; 7 - maximal.
 func (trclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
-	return ErrNotImplemented
+	trtorrent, err := trclient.getTorrent(infoHash, false)
+	if err != nil {
+		return err
+	}
+	payload := transmissionrpc.TorrentSetPayload{
+		IDs: []int64{*trtorrent.ID},
+	}
+	if priority <= 0 {
+		payload.FilesUnwanted = fileIndexes
+	} else {
+		payload.FilesWanted = fileIndexes
+		if priority >= 6 {
+			payload.PriorityHigh = fileIndexes
+		} else {
+			payload.PriorityNormal = fileIndexes
+		}
+	}
+	trclient.lastTorrent = nil
+	return trclient.client.TorrentSet(context.TODO(), payload)
 }
 
 func (trclient *Client) Close() {
@@ -992,6 +1145,26 @@ func tr2State(trtorrent *transmissionrpc.Torrent) string {
 	}
 }
 
+// Generate "trackerList" value: announce urls, one per line, with a blank line between tiers.
+func generateTrackerList(trackers []*transmissionrpc.Tracker) string {
+	trackers = slices.Clone(trackers)
+	slices.SortStableFunc(trackers, func(a, b *transmissionrpc.Tracker) int {
+		return int(a.Tier - b.Tier)
+	})
+	trackerList := ""
+	for i, tracker := range trackers {
+		if i > 0 {
+			if tracker.Tier != trackers[i-1].Tier {
+				trackerList += "\n\n"
+			} else {
+				trackerList += "\n"
+			}
+		}
+		trackerList += tracker.Announce
+	}
+	return trackerList
+}
+
 func getContentPath(trtorrent *transmissionrpc.Torrent) string {
 	sep := "/"
 	if strings.Contains(*trtorrent.DownloadDir, `\`) {
@@ -1042,7 +1215,11 @@ func tr2Torrent(trtorrent *transmissionrpc.Torrent) *client.Torrent {
 		Meta:               nil,
 	}
 	torrent.Meta = torrent.GetMetadataFromTags()
-	torrent.Category = torrent.GetCategoryFromTag()
+	if trtorrent.Group != nil && *trtorrent.Group != "" {
+		torrent.Category = *trtorrent.Group
+	} else {
+		torrent.Category = torrent.GetCategoryFromTag()
+	}
 	torrent.RemoveSubstituteTags()
 	return torrent
 }
@@ -0,0 +1,221 @@
+package transmission_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/transmission"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+)
+
+const testInfoHash = "0123456789abcdef0123456789abcdef01234567"
+
+// A minimal stand-in of Transmission RPC server of the rpcVersion.
+// The arguments of all received requests are recorded in requests (method => arguments list).
+type testServer struct {
+	*httptest.Server
+	rpcVersion int64
+	requests   map[string][]map[string]any
+	torrent    map[string]any // nil if not added yet
+}
+
+func newTestServer(t *testing.T, rpcVersion int64) *testServer {
+	server := &testServer{rpcVersion: rpcVersion, requests: map[string][]map[string]any{}}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transmission/rpc" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Method    string         `json:"method"`
+			Arguments map[string]any `json:"arguments"`
+			Tag       int            `json:"tag"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid rpc request: %v", err)
+			return
+		}
+		server.requests[req.Method] = append(server.requests[req.Method], req.Arguments)
+		result := map[string]any{}
+		switch req.Method {
+		case "session-get":
+			result["rpc-version"] = server.rpcVersion
+			result["rpc-version-minimum"] = 1
+			result["download-dir"] = "/downloads"
+		case "torrent-add":
+			server.torrent = map[string]any{
+				"activityDate": 1700000100, "addedDate": 1700000000, "doneDate": 1700000050, "downloadDir": "/downloads",
+				"downloadedEver": 1024, "downloadLimit": 0, "downloadLimited": false, "hashString": testInfoHash,
+				"id": 1, "labels": req.Arguments["labels"], "name": "foo", "peersGettingFromUs": 0,
+				"peersSendingToUs": 0, "percentDone": 1, "rateDownload": 0, "rateUpload": 0, "sizeWhenDone": 1024,
+				"status": 6, "totalSize": 1024, "uploadedEver": 0, "uploadLimit": 0, "uploadLimited": false,
+				"trackers": []any{map[string]any{"announce": "https://tracker.example.com/announce", "id": 0, "tier": 0}},
+			}
+			result["torrent-added"] = map[string]any{"hashString": testInfoHash, "id": 1, "name": "foo"}
+		case "torrent-set":
+			for _, key := range []string{"labels", "group"} {
+				if value, ok := req.Arguments[key]; ok {
+					server.torrent[key] = value
+				}
+			}
+		case "torrent-get":
+			torrents := []any{}
+			if server.torrent != nil {
+				torrents = append(torrents, server.torrent)
+			}
+			result["torrents"] = torrents
+		case "group-get":
+			result["group"] = []any{map[string]any{"name": "other group"}}
+		case "group-set":
+		default:
+			t.Errorf("unexpected rpc method %s", req.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"arguments": result, "result": "success", "tag": req.Tag})
+	}))
+	return server
+}
+
+func newClient(t *testing.T, server *testServer) client.Client {
+	clientInstance, err := transmission.NewClient("tr", &config.ClientConfigStruct{
+		Type: "transmission",
+		Name: "tr",
+		Url:  server.URL,
+	}, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return clientInstance
+}
+
+// Add a torrent with category & tags, then get it back.
+func addAndGetTorrent(t *testing.T, clientInstance client.Client) *client.Torrent {
+	err := clientInstance.AddTorrent([]byte("d4:infod4:name3:fooee"), &client.TorrentOption{
+		Category: "my cat",
+		Tags:     []string{"site:foo"},
+	}, map[string]int64{"dcet": 100})
+	if err != nil {
+		t.Fatalf("failed to add torrent: %v", err)
+	}
+	torrents, err := clientInstance.GetTorrents("", "my cat", true)
+	if err != nil {
+		t.Fatalf("failed to get torrents: %v", err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("expect 1 torrent, got %d", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.InfoHash != testInfoHash || torrent.Category != "my cat" ||
+		!slices.Equal(torrent.Tags, []string{"site:foo"}) || torrent.Meta["dcet"] != 100 ||
+		torrent.State != "seeding" || torrent.ContentPath != "/downloads/foo" {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	return torrent
+}
+
+func TestTransmissionV4(t *testing.T) {
+	server := newTestServer(t, 17)
+	defer server.Close()
+	clientInstance := newClient(t, server)
+
+	addAndGetTorrent(t, clientInstance)
+	// labels are set in torrent-add; category is set as bandwidth group.
+	if labels := server.requests["torrent-add"][0]["labels"]; labels == nil ||
+		!slices.Contains(labels.([]any), "site:foo") || !slices.Contains(labels.([]any), "meta.dcet:100") {
+		t.Errorf("expect labels in torrent-add, got %v", labels)
+	}
+	if set := server.requests["torrent-set"][0]; set["group"] != "my cat" || set["labels"] != nil {
+		t.Errorf("expect only group in torrent-set, got %v", set)
+	}
+	if fields := server.requests["torrent-get"][0]["fields"].([]any); !slices.Contains(fields, "group") {
+		t.Errorf("expect group field in torrent-get, got %v", fields)
+	}
+	// rpc version is detected only once.
+	if cnt := len(server.requests["session-get"]); cnt != 1 {
+		t.Errorf("expect rpc version fetched once, got %d", cnt)
+	}
+
+	categories, err := clientInstance.GetCategories()
+	if err != nil || len(categories) != 2 {
+		t.Errorf("expect 2 categories (groups and torrent category), got %v (%v)", categories, err)
+	}
+	if err = clientInstance.MakeCategory("new cat", ""); err != nil {
+		t.Errorf("failed to make category: %v", err)
+	}
+	if err = clientInstance.MakeCategory("new cat 2", constants.NONE); err != nil {
+		t.Errorf("failed to make category with none save path: %v", err)
+	}
+	if err = clientInstance.MakeCategory("new cat 3", "/downloads/cat"); err == nil {
+		t.Errorf("expect error of making category with save path")
+	}
+	if group := server.requests["group-set"]; len(group) != 2 || group[0]["name"] != "new cat" ||
+		group[1]["name"] != "new cat 2" {
+		t.Errorf("unexpected group-set request: %v", group)
+	}
+
+	if err = clientInstance.AddTorrentTrackers(testInfoHash, []string{"https://tracker2.example.com/announce"},
+		"", false); err != nil {
+		t.Fatalf("failed to add trackers: %v", err)
+	}
+	set := server.requests["torrent-set"][len(server.requests["torrent-set"])-1]
+	if set["trackerList"] != "https://tracker.example.com/announce\n\nhttps://tracker2.example.com/announce" ||
+		set["trackerAdd"] != nil {
+		t.Errorf("unexpected torrent-set request of adding trackers: %v", set)
+	}
+	if err = clientInstance.EditTorrentTracker(testInfoHash, "tracker.example.com", "tracker3.example.com",
+		true); err != nil {
+		t.Fatalf("failed to edit tracker: %v", err)
+	}
+	set = server.requests["torrent-set"][len(server.requests["torrent-set"])-1]
+	if set["trackerList"] != "https://tracker3.example.com/announce\n\nhttps://tracker2.example.com/announce" {
+		t.Errorf("unexpected torrent-set request of editing tracker: %v", set)
+	}
+	if err = clientInstance.RemoveTorrentTrackers(testInfoHash,
+		[]string{"https://tracker2.example.com/announce"}); err != nil {
+		t.Fatalf("failed to remove tracker: %v", err)
+	}
+	set = server.requests["torrent-set"][len(server.requests["torrent-set"])-1]
+	if set["trackerList"] != "https://tracker3.example.com/announce" || set["trackerRemove"] != nil {
+		t.Errorf("unexpected torrent-set request of removing tracker: %v", set)
+	}
+}
+
+func TestTransmissionV3(t *testing.T) {
+	server := newTestServer(t, 16)
+	defer server.Close()
+	clientInstance := newClient(t, server)
+
+	addAndGetTorrent(t, clientInstance)
+	// category is simulated by "category:xxx" label, all labels are set in torrent-set.
+	if labels := server.requests["torrent-add"][0]["labels"]; labels != nil {
+		t.Errorf("expect no labels in torrent-add, got %v", labels)
+	}
+	set := server.requests["torrent-set"][0]
+	if labels, _ := set["labels"].([]any); set["group"] != nil || !slices.Contains(labels, "category:my cat") ||
+		!slices.Contains(labels, "site:foo") {
+		t.Errorf("expect category label in torrent-set, got %v", set)
+	}
+	if fields := server.requests["torrent-get"][0]["fields"].([]any); slices.Contains(fields, "group") {
+		t.Errorf("expect no group field in torrent-get, got %v", fields)
+	}
+	if err := clientInstance.MakeCategory("new cat", ""); err == nil {
+		t.Errorf("expect error of making category")
+	}
+	if len(server.requests["group-get"]) > 0 || len(server.requests["group-set"]) > 0 {
+		t.Errorf("expect no group requests")
+	}
+
+	if err := clientInstance.AddTorrentTrackers(testInfoHash, []string{"https://tracker2.example.com/announce"},
+		"", false); err != nil {
+		t.Fatalf("failed to add trackers: %v", err)
+	}
+	set = server.requests["torrent-set"][len(server.requests["torrent-set"])-1]
+	if trackerAdd, _ := set["trackerAdd"].([]any); set["trackerList"] != nil ||
+		!slices.Equal(trackerAdd, []any{"https://tracker2.example.com/announce"}) {
+		t.Errorf("unexpected torrent-set request of adding trackers: %v", set)
+	}
+}
@@ -272,7 +272,7 @@ func add(cmd *cobra.Command, args []string) error {
 				option.Tags = append(option.Tags, client.GenerateTorrentTagFromSite(sitename))
 			}
 			if hr {
-				option.Tags = append(option.Tags, config.HR_TAG)
+				option.Tags = append(option.Tags, client.GenerateTorrentTagsFromHnR(siteInstance.GetSiteConfig())...)
 			}
 			option.Tags = append(option.Tags, fixedTags...)
 			if renameTemplate != nil {
@@ -6,6 +6,7 @@ import (
 	_ "github.com/sagan/ptool/cmd/addtags"
 	_ "github.com/sagan/ptool/cmd/addtrackers"
 	_ "github.com/sagan/ptool/cmd/alias"
+	_ "github.com/sagan/ptool/cmd/attendance"
 	_ "github.com/sagan/ptool/cmd/batchdl"
 	_ "github.com/sagan/ptool/cmd/brush"
 	_ "github.com/sagan/ptool/cmd/checktag"
@@ -14,6 +15,7 @@ import (
 	_ "github.com/sagan/ptool/cmd/cookiecloud/all"
 	_ "github.com/sagan/ptool/cmd/createcategory"
 	_ "github.com/sagan/ptool/cmd/createtags"
+	_ "github.com/sagan/ptool/cmd/daemon"
 	_ "github.com/sagan/ptool/cmd/delete"
 	_ "github.com/sagan/ptool/cmd/deletecategories"
 	_ "github.com/sagan/ptool/cmd/deletetags"
@@ -26,9 +28,11 @@ import (
 	_ "github.com/sagan/ptool/cmd/getcategories"
 	_ "github.com/sagan/ptool/cmd/gettags"
 	_ "github.com/sagan/ptool/cmd/hardlink/all"
+	_ "github.com/sagan/ptool/cmd/hnr"
 	_ "github.com/sagan/ptool/cmd/iyuu/all"
 	_ "github.com/sagan/ptool/cmd/maketorrent"
 	_ "github.com/sagan/ptool/cmd/markinvalidtracker"
+	_ "github.com/sagan/ptool/cmd/metrics/all"
 	_ "github.com/sagan/ptool/cmd/modifytorrent"
 	_ "github.com/sagan/ptool/cmd/movesavepath"
 	_ "github.com/sagan/ptool/cmd/parsetorrent"
@@ -49,13 +53,16 @@ import (
 	_ "github.com/sagan/ptool/cmd/shell"
 	_ "github.com/sagan/ptool/cmd/show"
 	_ "github.com/sagan/ptool/cmd/sites/all"
+	_ "github.com/sagan/ptool/cmd/sitestats/all"
 	_ "github.com/sagan/ptool/cmd/skipchecking"
 	_ "github.com/sagan/ptool/cmd/statscmd"
 	_ "github.com/sagan/ptool/cmd/status"
 	_ "github.com/sagan/ptool/cmd/tidyup"
+	_ "github.com/sagan/ptool/cmd/torrentinfo"
 	_ "github.com/sagan/ptool/cmd/transfertorrent"
 	_ "github.com/sagan/ptool/cmd/verifytorrent"
 	_ "github.com/sagan/ptool/cmd/versioncmd"
+	_ "github.com/sagan/ptool/cmd/xseed/all"
 	_ "github.com/sagan/ptool/cmd/xseedadd"
 	_ "github.com/sagan/ptool/cmd/xseedcheck"
 )
@@ -0,0 +1,120 @@
+package attendance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "attendance {site | group}... [-a]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "attendance"},
+	Short:       "Do daily attendance (check-in, 签到) of sites.",
+	Long: `Do daily attendance (check-in, 签到) of sites.
+Args is site or group names. If "-a" flag is set, all enabled (not dead nor hidden) sites are used.
+
+Currently only NexusPHP sites are supported. By default, it uses the attendance link in site page
+(usually "attendance.php"), and skips the site if there is no such link. The behavior can be customized
+by "attendanceUrl", "attendanceMethod", "attendancePayload", "selectorAttendanceLink"
+and "selectorAttendanceMessage" site configs.
+
+It displays the result of each site: the reward (bonus points got), streak (consecutive days)
+and total times of attendance, if site provides them. Sites that don't support attendance are skipped.
+It's suitable to be run periodically, e.g. in a daily cron job or a [[jobs]] task of "ptool daemon".`,
+	RunE: attendance,
+}
+
+var (
+	all = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&all, "all", "a", false, "Do attendance of all sites")
+	cmd.RootCmd.AddCommand(command)
+}
+
+type attendanceResult struct {
+	sitename string
+	result   *site.AttendanceResult
+	err      error
+}
+
+func attendance(cmd *cobra.Command, args []string) error {
+	sitenames := config.ParseGroupAndOtherNames(args...)
+	if all {
+		if len(args) > 0 {
+			return fmt.Errorf("--all flag cann't be used with site names")
+		}
+		for _, siteConfig := range config.Get().SitesEnabled {
+			if siteConfig.Dead || siteConfig.Hidden {
+				continue
+			}
+			sitenames = append(sitenames, siteConfig.GetName())
+		}
+	}
+	if len(sitenames) == 0 {
+		return fmt.Errorf("no sites provided")
+	}
+	ch := make(chan *attendanceResult, len(sitenames))
+	for _, sitename := range sitenames {
+		// site.CreateSite is NOT safe for concurrent use, so create site instance before starting goroutine.
+		siteInstance, err := site.CreateSite(sitename)
+		if err != nil {
+			ch <- &attendanceResult{sitename: sitename, err: err}
+			continue
+		}
+		go func() {
+			result, err := siteInstance.Attendance()
+			ch <- &attendanceResult{sitename: sitename, result: result, err: err}
+		}()
+	}
+	results := []*attendanceResult{}
+	for range sitenames {
+		results = append(results, <-ch)
+	}
+	slices.SortStableFunc(results, func(a, b *attendanceResult) int {
+		return slices.Index(sitenames, a.sitename) - slices.Index(sitenames, b.sitename)
+	})
+
+	errorCnt := int64(0)
+	fmt.Printf("%-15s  %-10s  %-10s  %-6s  %-6s  %s\n", "Site", "Result", "Reward", "Streak", "Total", "Message")
+	for _, result := range results {
+		if result.err != nil {
+			status := "✕ error"
+			if errors.Is(result.err, site.ErrAttendanceUnsupported) {
+				status = "- skipped"
+			} else {
+				errorCnt++
+			}
+			fmt.Printf("%-15s  %-10s  %-10s  %-6s  %-6s  %v\n", result.sitename, status, "-", "-", "-", result.err)
+			continue
+		}
+		status := "✓ done"
+		if result.result.AlreadyAttended {
+			status = "✓ already"
+		}
+		fmt.Printf("%-15s  %-10s  %-10s  %-6s  %-6s  %s\n", result.sitename, status,
+			formatNumber(result.result.Reward), formatNumber(float64(result.result.Streak)),
+			formatNumber(float64(result.result.Total)), util.StringPrefixInBytes(result.result.Message, 100))
+	}
+	if errorCnt > 0 {
+		fmt.Fprintf(os.Stderr, "%d sites failed\n", errorCnt)
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+func formatNumber(value float64) string {
+	if value == 0 {
+		return "-"
+	}
+	return fmt.Sprint(value)
+}
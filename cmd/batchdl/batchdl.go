@@ -67,6 +67,12 @@ The template supports the following variables:
 * torrentInfo : The parsed "TorrentMeta" struct of torrent. See help of "parsetorrent" cmd
 E.g. '--rename "{{.site}}.{{.id}} - {{.name128}}.torrent"'
 
+If "--rss" flag is set, it fetches torrents from the RSS feed of site (the "rssUrl" site config) instead,
+which is a single page of latest torrents. Feed torrents usually lack discount / HnR / peers info,
+which will be fetched from site (by searching the torrent) only when needed, that is,
+if any of "--free", "--no-hr", "--no-paid", "--no-neutral", "--only-downloaded", "--max-seeders"
+or (explicitly set) "--min-seeders" flag is set. Otherwise these criterion are not checked.
+
 It will output the summary of downloads result in the end:
 * Torrents : Torrents downloaded
 * AllTorrents : All torrents fetched, including not-downloaded (skipped)
@@ -99,6 +105,7 @@ var (
 	latestFlag         = false
 	newestFlag         = false
 	saveAppend         = false
+	useRss             = false
 	maxTorrents        = int64(0)
 	minSeeders         = int64(0)
 	maxSeeders         = int64(0)
@@ -167,6 +174,8 @@ func init() {
 		"Only display or download torrent that had been downloaded before")
 	command.Flags().BoolVarP(&addCategoryAuto, "add-category-auto", "", false,
 		"Automatically set category of added torrent to corresponding sitename")
+	command.Flags().BoolVarP(&useRss, "rss", "", false,
+		`Fetch torrents from the RSS feed of site ("rssUrl" site config) instead of torrents list pages`)
 	command.Flags().BoolVarP(&saveAppend, "save-append", "", false,
 		`Used with "--save-*" flags, write to those files in append mode`)
 	command.Flags().Int64VarP(&maxTorrents, "max-torrents", "", -1,
@@ -263,6 +272,21 @@ func batchdl(command *cobra.Command, args []string) error {
 	if util.CountNonZeroVariables(skipExisting, rename) > 1 {
 		return fmt.Errorf("--skip-existing and --rename flags are NOT compatible")
 	}
+	enrich := false
+	if useRss {
+		if siteInstance.GetSiteConfig().RssUrl == "" {
+			return fmt.Errorf("site %s does not have rssUrl configured", sitename)
+		}
+		if largestFlag || newestFlag || (sortFlag != "" && sortFlag != constants.NONE) || startPage != "" ||
+			baseUrl != "" {
+			return fmt.Errorf("--rss flag is NOT compatible with --largest, --newest, --sort, --start-page and " +
+				"--base-url flags")
+		}
+		if !command.Flags().Changed("min-seeders") {
+			minSeeders = -1
+		}
+		enrich = freeOnly || nohr || noPaid || noNeutral || onlyDownloaded || minSeeders >= 0 || maxSeeders >= 0
+	}
 	if largestFlag {
 		sortFlag = "size"
 		orderFlag = "desc"
@@ -325,6 +349,10 @@ func batchdl(command *cobra.Command, args []string) error {
 			log.Warnf("Client has _noadd flag and --add-respect-noadd flag is set. Abort task")
 			return nil
 		}
+		if err := site.CheckAccountThreshold(siteInstance); err != nil {
+			return fmt.Errorf("site %s account check failed, refuse to add torrents to client: %w",
+				siteInstance.GetName(), err)
+		}
 		clientAddTorrentOption = &client.TorrentOption{
 			Pause:    addPaused,
 			SavePath: addSavePath,
@@ -413,7 +441,12 @@ mainloop:
 		now := util.Now()
 		lastMarker = marker
 		log.Printf("Get torrents with page parker '%s'", marker)
-		torrents, marker, err = siteInstance.GetAllTorrents(sortFlag, desc, marker, baseUrl)
+		if useRss {
+			torrents, err = site.GetRssTorrents(siteInstance)
+			marker = ""
+		} else {
+			torrents, marker, err = siteInstance.GetAllTorrents(sortFlag, desc, marker, baseUrl)
+		}
 		cntTorrentsThisPage := 0
 
 		if err != nil {
@@ -451,6 +484,28 @@ mainloop:
 					continue
 				}
 			}
+			if filter != "" && !torrent.MatchFilter(filter) {
+				log.Debugf("Skip torrent %s due to filter %s does NOT match", torrent.Name, filter)
+				continue
+			}
+			if len(tagList) > 0 && !torrent.HasAnyTag(tagList) {
+				log.Debugf("Skip torrent %s due to it does not contain any tag of %v", torrent.Name, tagList)
+				continue
+			}
+			if torrent.MatchFiltersOr(excludesList) {
+				log.Debugf("Skip torrent %s due to excludes matches", torrent.Name)
+				continue
+			}
+			if !torrent.MatchFiltersAndOr(includesList) {
+				log.Debugf("Skip torrent %s due to includes does NOT match", torrent.Name)
+				continue
+			}
+			if enrich && torrent.InfoIncomplete {
+				if err := site.EnrichTorrent(siteInstance, torrent); err != nil {
+					log.Warnf("Skip torrent %s which info can not be fetched from site: %v", torrent.Name, err)
+					continue
+				}
+			}
 			if !onlyDownloaded && !includeDownloaded && torrent.IsActive {
 				log.Debugf("Skip active torrent %s", torrent.Name)
 				continue
@@ -475,22 +530,6 @@ mainloop:
 					continue
 				}
 			}
-			if filter != "" && !torrent.MatchFilter(filter) {
-				log.Debugf("Skip torrent %s due to filter %s does NOT match", torrent.Name, filter)
-				continue
-			}
-			if len(tagList) > 0 && !torrent.HasAnyTag(tagList) {
-				log.Debugf("Skip torrent %s due to it does not contain any tag of %v", torrent.Name, tagList)
-				continue
-			}
-			if torrent.MatchFiltersOr(excludesList) {
-				log.Debugf("Skip torrent %s due to excludes matches", torrent.Name)
-				continue
-			}
-			if !torrent.MatchFiltersAndOr(includesList) {
-				log.Debugf("Skip torrent %s due to includes does NOT match", torrent.Name)
-				continue
-			}
 			if freeOnly {
 				if torrent.DownloadMultiplier != 0 {
 					log.Debugf("Skip non-free torrent %s", torrent.Name)
@@ -621,7 +660,7 @@ mainloop:
 							ratioLimit = config.Get().PublicTorrentRatioLimit
 						}
 						if torrent.HasHnR || siteInstance.GetSiteConfig().GlobalHnR {
-							tags = append(tags, config.HR_TAG)
+							tags = append(tags, client.GenerateTorrentTagsFromHnR(siteInstance.GetSiteConfig())...)
 						}
 						clientAddTorrentOption.Tags = tags
 						clientAddTorrentOption.RatioLimit = ratioLimit
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -11,7 +13,9 @@ import (
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/notify"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/stats"
 	"github.com/sagan/ptool/util"
@@ -19,12 +23,22 @@ import (
 )
 
 var command = &cobra.Command{
-	Use:         "brush {client} {site | group}...",
+	Use:         "brush {client | group}[,client...] {site | group}...",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "brush"},
 	Short:       "Brush sites using client.",
-	Long:        `Brush sites using client.`,
-	Args:        cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
-	RunE:        brush,
+	Long: `Brush sites using client.
+
+The first arg can be a client, several clients separated by comma (e.g. "box1,box2"),
+or a group with "clients" config. If multiple clients are provided, each site's new torrents are fetched once,
+rated by brush strategy, then each chosen torrent is placed to the client with the most free disk space,
+upload headroom and free "brushMaxTorrents" slots. The same torrent is never added to two clients.
+
+If "brushMinSiteRatio" or "brushMinSiteBuffer" (uploaded - downloaded) config of a site is set,
+it checks the user account status of site before fetching new torrents. If the account is below the threshold
+(or the status can not be got), the site is brushed in "delete-only" mode: no new torrent will be added,
+but existing brush torrents of client are still checked and deleted as normal.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: brush,
 }
 
 var (
@@ -32,6 +46,7 @@ var (
 	addPaused = false
 	ordered   = false
 	force     = false
+	useRss    = false
 	maxSites  = int64(0)
 )
 
@@ -40,25 +55,46 @@ func init() {
 	command.Flags().BoolVarP(&addPaused, "add-paused", "", false, "Add torrents to client in paused state")
 	command.Flags().BoolVarP(&ordered, "ordered", "", false, "Brush sites provided in order")
 	command.Flags().BoolVarP(&force, "force", "", false, `Force mode. Ignore "`+config.NOADD_TAG+`" flag tag in client`)
+	command.Flags().BoolVarP(&useRss, "rss", "", false,
+		"Fetch site new torrents from RSS feed (rssUrl config) instead of torrents list page, if configured")
 	command.Flags().Int64VarP(&maxSites, "max-sites", "", -1, "Allowed max succcess sites number, -1 == no limit")
 	cmd.RootCmd.AddCommand(command)
 }
 
+// A client being brushed, and its state of current site
+type brushClient struct {
+	instance      client.Client
+	status        *client.Status
+	strategy      strategy.Strategy
+	torrents      []*client.Torrent
+	siteOption    *strategy.BrushSiteOptionStruct
+	clientOption  *strategy.BrushClientOptionStruct
+	siteTorrents  []*site.Torrent
+	noadd         bool
+	bandwidthFull bool
+}
+
 func brush(cmd *cobra.Command, args []string) (err error) {
-	clientName := args[0]
+	clientNames := config.ParseGroupClients(strings.Split(args[0], ",")...)
 	sitenames := config.ParseGroupAndOtherNamesWithoutDeduplicate(args[1:]...)
-	clientInstance, err := client.CreateClient(clientName)
-	if err != nil {
-		return err
-	}
-	lock, err := config.LockConfigDirFile(fmt.Sprintf(config.CLIENT_LOCK_FILE, clientName))
-	if err != nil {
-		return err
-	}
-	defer lock.Unlock()
-	if clientInstance.GetClientConfig().Type == "transmission" {
-		log.Warnf("Warning: brush function of transmission client has NOT been tested")
+	var allClientInstances []client.Client
+	for _, clientName := range clientNames {
+		clientInstance, err := client.CreateClient(clientName)
+		if err != nil {
+			return err
+		}
+		lock, err := config.LockConfigDirFile(fmt.Sprintf(config.CLIENT_LOCK_FILE, clientName))
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+		if clientInstance.GetClientConfig().Type == "transmission" {
+			log.Warnf("Warning: brush function of transmission client has NOT been tested")
+		}
+		allClientInstances = append(allClientInstances, clientInstance)
 	}
+	// clients that are still being brushed. A client is removed from it if it can not add more torrents.
+	clientInstances := slices.Clone(allClientInstances)
 	if !ordered {
 		rand.Shuffle(len(sitenames), func(i, j int) { sitenames[i], sitenames[j] = sitenames[j], sitenames[i] })
 	}
@@ -67,13 +103,17 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 	cntSkipSite := int64(0)
 	cntAddTorrents := int64(0)
 	cntDeleteTorrents := int64(0)
+	var deleteOnlySites []string
 	var statDb *stats.StatDb
 	if config.Get().BrushEnableStats {
-		statDb, err = stats.NewDb(filepath.Join(config.ConfigDir, config.STATS_FILENAME))
+		statDb, err = stats.NewDb(filepath.Join(config.ConfigDir, config.STATS_FILENAME),
+			filepath.Join(config.ConfigDir, config.LEGACY_STATS_FILENAME))
 		if err != nil {
 			log.Warnf("Failed to create stats db: %v.", err)
 		}
 	}
+	// info-hashes of all torrents added in this run, to make sure a torrent is never added to two clients.
+	addedInfoHashes := map[string]bool{}
 
 	for i, sitename := range sitenames {
 		siteInstance, err := site.CreateSite(sitename)
@@ -81,230 +121,111 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			log.Errorf("Failed to get instance of site %s: %v", sitename, err)
 			continue
 		}
-		log.Printf("Brush client %s site %s", clientInstance.GetName(), sitename)
-		status, err := clientInstance.GetStatus()
-		if err != nil {
-			log.Printf("Failed to get client %s status: %v", clientInstance.GetName(), err)
-			continue
-		}
-		noadd := !force && status.NoAdd
-		var siteTorrents []*site.Torrent
-		if status.UploadSpeedLimit > 0 && (status.UploadSpeedLimit < strategy.SLOW_UPLOAD_SPEED ||
-			(float64(status.UploadSpeed)/float64(status.UploadSpeedLimit)) >= strategy.BANDWIDTH_FULL_PERCENT) {
-			log.Printf(
-				"Client %s upload bandwidth is already full (Up speed/limit: %s/s/%s/s). Do not fetch site new torrents\n",
-				clientInstance.GetName(),
-				util.BytesSize(float64(status.UploadSpeed)),
-				util.BytesSize(float64(status.UploadSpeedLimit)),
-			)
-		} else if !siteInstance.GetSiteConfig().BrushAllowHr && siteInstance.GetSiteConfig().GlobalHnR {
-			log.Printf("Site %s enforces global HnR. Do not fetch site new torrents", sitename)
-		} else if noadd {
-			log.Printf("Client %s in NoAdd status. Do not fetch site new torrents", clientInstance.GetName())
-		} else {
-			siteTorrents, err = siteInstance.GetLatestTorrents(true)
+		now := util.Now()
+		var brushClients []*brushClient
+		canAdd := false
+		for _, clientInstance := range clientInstances {
+			log.Printf("Brush client %s site %s", clientInstance.GetName(), sitename)
+			bc, err := prepareBrushClient(clientInstance, siteInstance, now, statDb)
 			if err != nil {
-				log.Printf("failed to fetch site %s torrents: %v", sitename, err)
-			}
-		}
-
-		clientTorrents, err := clientInstance.GetTorrents("", config.BRUSH_CAT, true)
-		if err != nil {
-			log.Printf("Failed to get client %s torrents: %v ", clientInstance.GetName(), err)
-			continue
-		}
-		brushSiteOption := strategy.GetBrushSiteOptions(siteInstance, util.Now())
-		brushMaxTorrents := clientInstance.GetClientConfig().BrushMaxTorrents
-		if siteInstance.GetSiteConfig().BrushAllowAddTorrentsPercent != 0 {
-			p := float64(siteInstance.GetSiteConfig().BrushAllowAddTorrentsPercent) / 100.0
-			brushMaxTorrents = int64(p * float64(clientInstance.GetClientConfig().BrushMaxTorrents))
-		}
-
-		currentTorrents := len(getTorrentsOfSite(clientTorrents, sitename))
-		brushSiteOption.AllowAddTorrents = brushMaxTorrents - int64(currentTorrents)
-		log.Printf("Site %s already have %d torrents, max %d, allow %d", sitename,
-			len(getTorrentsOfSite(clientTorrents, sitename)), brushMaxTorrents, brushSiteOption.AllowAddTorrents)
-		brushClientOption := strategy.GetBrushClientOptions(clientInstance)
-		log.Printf(
-			"Brush Options: minDiskSpace=%v, slowUploadSpeedTier=%v, torrentUploadSpeedLimit=%v/s,"+
-				" maxDownloadingTorrents=%d, maxTorrents=%d, minRatio=%f",
-			util.BytesSize(float64(brushClientOption.MinDiskSpace)),
-			util.BytesSize(float64(brushClientOption.SlowUploadSpeedTier)),
-			util.BytesSize(float64(brushSiteOption.TorrentUploadSpeedLimit)),
-			brushClientOption.MaxDownloadingTorrents,
-			brushClientOption.MaxTorrents,
-			brushClientOption.MinRatio,
-		)
-		result := strategy.Decide(status, clientTorrents, siteTorrents, brushSiteOption, brushClientOption)
-		log.Printf(
-			"Current client %s torrents: %d; Download speed / limit: %s/s / %s/s; "+
-				"Upload speed / limit: %s/s / %s/s;Free disk space: %s;",
-			clientInstance.GetName(),
-			len(clientTorrents),
-			util.BytesSize(float64(status.DownloadSpeed)),
-			util.BytesSize(float64(status.DownloadSpeedLimit)),
-			util.BytesSize(float64(status.UploadSpeed)),
-			util.BytesSize(float64(status.UploadSpeedLimit)),
-			util.BytesSize(float64(status.FreeSpaceOnDisk)),
-		)
-		log.Printf(
-			"Fetched site %s torrents: %d; Client add / modify / stall / delete torrents: %d / %d / %d / %d. Msg: %s",
-			siteInstance.GetName(),
-			len(siteTorrents),
-			len(result.AddTorrents),
-			len(result.ModifyTorrents),
-			len(result.StallTorrents),
-			len(result.DeleteTorrents),
-			result.Msg,
-		)
-
-		// delete
-		var deleteTorrentStats []*stats.TorrentStat
-		var deleteTorrentInfoHashes []string
-		log.Printf("Delete torrents:")
-		for _, torrent := range result.DeleteTorrents {
-			clientTorrent := *util.FindInSlice(clientTorrents, func(t *client.Torrent) bool {
-				return t.InfoHash == torrent.InfoHash
-			})
-			// double check
-			if clientTorrent == nil || clientTorrent.Category != config.BRUSH_CAT {
-				log.Warnf("Invalid torrent deletion target: %s", torrent.InfoHash)
+				log.Errorf("Failed to brush client %s site %s: %v", clientInstance.GetName(), sitename, err)
 				continue
 			}
-			duration := brushSiteOption.Now - clientTorrent.Atime
-			log.Printf("Torrent %s (%v): %v", torrent.Name, torrent.InfoHash, torrent.Msg)
-			log.Printf("Total Dl / Up: %s / %s; Lifespan: %s; Average lifespan Dl / Up speed: %s/s / %s/s",
-				util.BytesSize(float64(clientTorrent.Downloaded)),
-				util.BytesSize(float64(clientTorrent.Uploaded)),
-				util.GetDurationString(duration),
-				util.BytesSize(float64(clientTorrent.Downloaded)/float64(duration)),
-				util.BytesSize(float64(clientTorrent.Uploaded)/float64(duration)),
-			)
-			deleteTorrentStats = append(deleteTorrentStats, &stats.TorrentStat{
-				Client:     clientInstance.GetName(),
-				Site:       clientTorrent.GetSiteFromTag(),
-				InfoHash:   clientTorrent.InfoHash,
-				Category:   clientTorrent.Category,
-				Name:       clientTorrent.Name,
-				Atime:      clientTorrent.Atime,
-				Size:       clientTorrent.Size,
-				Uploaded:   clientTorrent.Uploaded,
-				Downloaded: clientTorrent.Downloaded,
-				Msg:        torrent.Msg,
-			})
-			deleteTorrentInfoHashes = append(deleteTorrentInfoHashes, clientTorrent.InfoHash)
-		}
-		if !dryRun {
-			err := client.DeleteTorrentsAuto(clientInstance, deleteTorrentInfoHashes)
-			log.Printf("Delete torrents result: error=%v", err)
-			if err == nil {
-				cntDeleteTorrents += int64(len(deleteTorrentInfoHashes))
-				if statDb != nil {
-					statDb.AddTorrentStats(brushSiteOption.Now, 1, deleteTorrentStats)
-				}
-			}
-		}
-
-		// stall
-		for _, torrent := range result.StallTorrents {
-			log.Printf("Stall client %s torrent: %v / %v / %v",
-				clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg)
-			if dryRun {
-				continue
+			if bc.bandwidthFull {
+				log.Printf(
+					"Client %s upload bandwidth is already full (Up speed/limit: %s/s/%s/s). Do not fetch site new torrents\n",
+					clientInstance.GetName(),
+					util.BytesSize(float64(bc.status.UploadSpeed)),
+					util.BytesSize(float64(bc.status.UploadSpeedLimit)),
+				)
+			} else if bc.noadd {
+				log.Printf("Client %s in NoAdd status. Do not fetch site new torrents", clientInstance.GetName())
+			} else {
+				canAdd = true
 			}
-			err := clientInstance.ModifyTorrent(torrent.InfoHash, &client.TorrentOption{
-				DownloadSpeedLimit: strategy.STALL_DOWNLOAD_SPEED,
-			}, torrent.Meta)
-			log.Printf("Stall torrent result: error=%v", err)
+			brushClients = append(brushClients, bc)
 		}
-
-		// resume
-		if len(result.ResumeTorrents) > 0 {
-			for _, torrent := range result.ResumeTorrents {
-				log.Printf("Resume client %s torrent: %v / %v / %v",
-					clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg)
-			}
-			if !dryRun {
-				err := clientInstance.ResumeTorrents(util.Map(result.ResumeTorrents,
-					func(t strategy.AlgorithmOperationTorrent) string {
-						return t.InfoHash
-					}))
-				log.Printf("Resume torrents result: error=%v", err)
-			}
+		if len(brushClients) == 0 {
+			continue
 		}
 
-		// modify
-		for _, torrent := range result.ModifyTorrents {
-			log.Printf("Modify client %s torrent: %v / %v / %v / %v ",
-				clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg, torrent.Meta)
-			if dryRun {
-				continue
+		var siteTorrents []*site.Torrent
+		if canAdd {
+			if !siteInstance.GetSiteConfig().BrushAllowHr && siteInstance.GetSiteConfig().GlobalHnR {
+				log.Printf("Site %s enforces global HnR. Do not fetch site new torrents", sitename)
+			} else if err := site.CheckAccountThreshold(siteInstance); err != nil {
+				log.Warnf("Site %s account check failed, brush it in delete-only mode: %v", sitename, err)
+				deleteOnlySites = append(deleteOnlySites, sitename)
+			} else if useRss && siteInstance.GetSiteConfig().RssUrl != "" {
+				siteTorrents, err = getRssTorrents(siteInstance)
+				if err != nil {
+					log.Printf("failed to fetch site %s feed torrents: %v", sitename, err)
+				}
+			} else {
+				siteTorrents, err = siteInstance.GetLatestTorrents(true)
+				if err != nil {
+					log.Printf("failed to fetch site %s torrents: %v", sitename, err)
+				}
 			}
-			err := clientInstance.ModifyTorrent(torrent.InfoHash, nil, torrent.Meta)
-			log.Printf("Modify torrent result: error=%v", err)
 		}
+		placeSiteTorrents(brushClients, siteTorrents)
 
-		// add
-		cndAddTorrents := 0
-		addedRootDirs := map[string]bool{}
-		for _, torrent := range result.AddTorrents {
-			log.Printf("Add site %s torrent to client %s: %s / %s / %v",
-				siteInstance.GetName(), clientInstance.GetName(), torrent.Name, torrent.Msg, torrent.Meta)
-			if dryRun {
-				continue
-			}
-			torrentdata, _, _, err := siteInstance.DownloadTorrent(torrent.DownloadUrl)
-			if err != nil {
-				log.Printf("Failed to download: %s. Skip \n", err)
-				continue
-			}
-			tinfo, err := torrentutil.ParseTorrent(torrentdata)
-			if err != nil {
-				continue
-			}
-			pClientTorrent, _ := clientInstance.GetTorrent(tinfo.InfoHash)
-			if pClientTorrent != nil {
-				log.Printf("Already existing in client. skip\n")
-				continue
-			}
-			if addedRootDirs[tinfo.RootDir] || clientInstance.TorrentRootPathExists(tinfo.RootDir) {
-				log.Printf("torrent rootpath %s existing in client. skip\n", tinfo.RootDir)
-				continue
-			}
-			log.Printf("torrent info: %s\n", tinfo.InfoHash)
-			cndAddTorrents++
-			tags := []string{client.GenerateTorrentTagFromSite(siteInstance.GetName())}
-			if tinfo.IsPrivate() {
-				tags = append(tags, config.PRIVATE_TAG)
-			} else {
-				tags = append(tags, config.PUBLIC_TAG)
+		siteAdded := false
+		siteChanged := false
+		for _, bc := range brushClients {
+			result := bc.strategy.Decide(bc.status, bc.torrents, bc.siteTorrents, bc.siteOption, bc.clientOption)
+			log.Printf(
+				"Current client %s torrents: %d; Download speed / limit: %s/s / %s/s; "+
+					"Upload speed / limit: %s/s / %s/s;Free disk space: %s;",
+				bc.instance.GetName(),
+				len(bc.torrents),
+				util.BytesSize(float64(bc.status.DownloadSpeed)),
+				util.BytesSize(float64(bc.status.DownloadSpeedLimit)),
+				util.BytesSize(float64(bc.status.UploadSpeed)),
+				util.BytesSize(float64(bc.status.UploadSpeedLimit)),
+				util.BytesSize(float64(bc.status.FreeSpaceOnDisk)),
+			)
+			log.Printf(
+				"Fetched site %s torrents: %d (placed to client %s: %d); "+
+					"Client add / modify / stall / delete torrents: %d / %d / %d / %d. Msg: %s",
+				siteInstance.GetName(),
+				len(siteTorrents),
+				bc.instance.GetName(),
+				len(bc.siteTorrents),
+				len(result.AddTorrents),
+				len(result.ModifyTorrents),
+				len(result.StallTorrents),
+				len(result.DeleteTorrents),
+				result.Msg,
+			)
+			added, deleted := executeBrushResult(bc, siteInstance, result, allClientInstances, addedInfoHashes, statDb)
+			cntAddTorrents += added
+			cntDeleteTorrents += deleted
+			if len(result.AddTorrents) > 0 {
+				siteAdded = true
 			}
-			torrentOption := &client.TorrentOption{
-				Name:             torrent.Name,
-				Pause:            addPaused,
-				Category:         config.BRUSH_CAT,
-				Tags:             tags,
-				UploadSpeedLimit: siteInstance.GetSiteConfig().TorrentUploadSpeedLimitValue,
+			if len(result.AddTorrents) > 0 || len(result.ModifyTorrents) > 0 ||
+				len(result.DeleteTorrents) > 0 || len(result.StallTorrents) > 0 {
+				siteChanged = true
+				bc.instance.PurgeCache()
 			}
-			if !dryRun {
-				err = clientInstance.AddTorrent(torrentdata, torrentOption, torrent.Meta)
-				log.Printf("Add torrent result: error=%v", err)
-				if err == nil {
-					// Ideally, we should update local client cache to reflect the latest state,
-					// including the new added torrent. It requires a major re-work of client codes.
-					addedRootDirs[tinfo.RootDir] = true
-					cntAddTorrents++
-				}
+			// stop brushing the client for following sites
+			if bc.noadd {
+				log.Printf("Client %s in NoAdd status. Skip it for follow sites.", bc.instance.GetName())
+				clientInstances = util.Filter(clientInstances, func(c client.Client) bool { return c != bc.instance })
+			} else if !result.CanAddMore {
+				log.Printf("Client %s capacity is full. Skip it for follow sites.", bc.instance.GetName())
+				clientInstances = util.Filter(clientInstances, func(c client.Client) bool { return c != bc.instance })
 			}
 		}
 
-		if len(result.AddTorrents) > 0 {
+		if siteAdded {
 			cntSuccessSite++
 		} else {
 			cntSkipSite++
 		}
-		if noadd {
-			log.Printf("Client in NoAdd status. Skip follow sites.")
+		if len(clientInstances) == 0 {
+			log.Printf("No client can add more torrents. Stop brushing.")
 			cntSkipSite += int64(len(sitenames) - 1 - i)
 			break
 		}
@@ -313,20 +234,17 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			cntSkipSite += int64(len(sitenames) - 1 - i)
 			break
 		}
-		if !result.CanAddMore {
-			log.Printf("Client capacity is full. Stop brushing.")
-			cntSkipSite += int64(len(sitenames) - 1 - i)
-			break
-		}
-		if i < len(sitenames)-1 && (len(result.AddTorrents) > 0 || len(result.ModifyTorrents) > 0 ||
-			len(result.DeleteTorrents) > 0 || len(result.StallTorrents) > 0) {
-			clientInstance.PurgeCache()
+		if i < len(sitenames)-1 && siteChanged {
 			util.Sleep(3)
 		}
 	}
 
 	fmt.Printf("Finish brushing %d sites: successSites=%d, skipSites=%d; Added / Deleted torrents: %d / %d\n",
 		len(sitenames), cntSuccessSite, cntSkipSite, cntAddTorrents, cntDeleteTorrents)
+	if len(deleteOnlySites) > 0 {
+		fmt.Printf("Sites brushed in delete-only mode (account ratio / buffer below threshold): %s\n",
+			strings.Join(deleteOnlySites, ", "))
+	}
 	cntFailedSite := int64(len(sitenames)) - cntSuccessSite - cntSkipSite
 	if cntFailedSite > 0 {
 		return fmt.Errorf("siteFailed=%d", cntFailedSite)
@@ -334,6 +252,313 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 	return nil
 }
 
+// Get the current status and brush torrents of client, and the brush options of site & client.
+func prepareBrushClient(clientInstance client.Client, siteInstance site.Site, now int64,
+	statDb *stats.StatDb) (*brushClient, error) {
+	status, err := clientInstance.GetStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client status: %w", err)
+	}
+	brushStrategy, err := strategy.GetBrushStrategy(siteInstance, clientInstance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get brush strategy: %w", err)
+	}
+	clientTorrents, err := clientInstance.GetTorrents("", config.BRUSH_CAT, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client torrents: %w", err)
+	}
+	brushSiteOption := strategy.GetBrushSiteOptions(siteInstance, now)
+	if statDb != nil && !dryRun {
+		err = statDb.AddTorrentSnapshots(brushSiteOption.Now, util.Map(clientTorrents,
+			func(t *client.Torrent) *stats.TorrentStat {
+				return newTorrentStat(clientInstance.GetName(), t, "")
+			}))
+		if err != nil {
+			log.Warnf("Failed to record torrents stats: %v", err)
+		}
+	}
+	brushMaxTorrents := clientInstance.GetClientConfig().BrushMaxTorrents
+	if siteInstance.GetSiteConfig().BrushAllowAddTorrentsPercent != 0 {
+		p := float64(siteInstance.GetSiteConfig().BrushAllowAddTorrentsPercent) / 100.0
+		brushMaxTorrents = int64(p * float64(clientInstance.GetClientConfig().BrushMaxTorrents))
+	}
+	currentTorrents := len(getTorrentsOfSite(clientTorrents, siteInstance.GetName()))
+	brushSiteOption.AllowAddTorrents = brushMaxTorrents - int64(currentTorrents)
+	log.Printf("Site %s already have %d torrents in client %s, max %d, allow %d", siteInstance.GetName(),
+		currentTorrents, clientInstance.GetName(), brushMaxTorrents, brushSiteOption.AllowAddTorrents)
+	brushClientOption := strategy.GetBrushClientOptions(clientInstance)
+	if savePaths := clientInstance.GetClientConfig().BrushSavePaths; len(savePaths) > 0 {
+		brushClientOption.SavePaths = common.GetSavePathsSpace(clientInstance, savePaths)
+		for _, savePath := range brushClientOption.SavePaths {
+			log.Printf("Brush save path %s: free space %s, min free space %s", savePath.Path,
+				util.BytesSize(float64(savePath.FreeSpace)), util.BytesSize(float64(savePath.MinFreeSpace)))
+		}
+	}
+	log.Printf(
+		"Brush Options: strategy=%s, minDiskSpace=%v, slowUploadSpeedTier=%v, torrentUploadSpeedLimit=%v/s,"+
+			" maxDownloadingTorrents=%d, maxTorrents=%d, minRatio=%f",
+		brushStrategy.GetName(),
+		util.BytesSize(float64(brushClientOption.MinDiskSpace)),
+		util.BytesSize(float64(brushClientOption.SlowUploadSpeedTier)),
+		util.BytesSize(float64(brushSiteOption.TorrentUploadSpeedLimit)),
+		brushClientOption.MaxDownloadingTorrents,
+		brushClientOption.MaxTorrents,
+		brushClientOption.MinRatio,
+	)
+	return &brushClient{
+		instance:      clientInstance,
+		status:        status,
+		strategy:      brushStrategy,
+		torrents:      clientTorrents,
+		siteOption:    brushSiteOption,
+		clientOption:  brushClientOption,
+		noadd:         !force && status.NoAdd,
+		bandwidthFull: brushStrategy.IsBandwidthFull(status),
+	}, nil
+}
+
+// Distribute fetched site torrents to brush clients that can add torrents.
+// If there is only one such client, all site torrents go to it and it's strategy decides which ones to add.
+// Otherwise each site torrent is placed to at most one client, by remaining capacity of clients.
+// Site torrents that already exist in any client are excluded.
+func placeSiteTorrents(brushClients []*brushClient, siteTorrents []*site.Torrent) {
+	var addableClients []*brushClient
+	for _, bc := range brushClients {
+		if !bc.noadd && !bc.bandwidthFull {
+			addableClients = append(addableClients, bc)
+		}
+	}
+	if len(addableClients) == 0 || len(siteTorrents) == 0 {
+		return
+	}
+	if len(addableClients) == 1 {
+		addableClients[0].siteTorrents = siteTorrents
+		return
+	}
+	existingInfoHashes := map[string]bool{}
+	for _, bc := range brushClients {
+		for _, torrent := range bc.torrents {
+			existingInfoHashes[torrent.InfoHash] = true
+		}
+	}
+	siteTorrents = util.Filter(siteTorrents, func(t *site.Torrent) bool {
+		return t.InfoHash == "" || !existingInfoHashes[t.InfoHash]
+	})
+	placementClients := util.Map(addableClients, func(bc *brushClient) *strategy.BrushPlacementClientStruct {
+		return strategy.NewBrushPlacementClient(bc.instance.GetName(), bc.status, bc.torrents,
+			bc.siteOption, bc.clientOption)
+	})
+	// all clients share the same site torrents rating here, use the strategy of the first one.
+	placed := strategy.PlaceSiteTorrents(addableClients[0].strategy, siteTorrents, addableClients[0].siteOption,
+		placementClients)
+	for i, bc := range addableClients {
+		bc.siteTorrents = placed[i]
+		log.Printf("Place %d site torrents to client %s", len(placed[i]), bc.instance.GetName())
+	}
+}
+
+// Apply the brush decision result to client. Return the number of added and deleted torrents.
+func executeBrushResult(bc *brushClient, siteInstance site.Site, result *strategy.AlgorithmResult,
+	clientInstances []client.Client, addedInfoHashes map[string]bool, statDb *stats.StatDb) (
+	cntAddTorrents int64, cntDeleteTorrents int64) {
+	clientInstance := bc.instance
+	clientTorrents := bc.torrents
+	brushSiteOption := bc.siteOption
+	brushStrategy := bc.strategy
+	// delete
+	var deleteTorrentStats []*stats.TorrentStat
+	var deleteTorrentInfoHashes []string
+	log.Printf("Delete torrents:")
+	for _, torrent := range result.DeleteTorrents {
+		clientTorrent := *util.FindInSlice(clientTorrents, func(t *client.Torrent) bool {
+			return t.InfoHash == torrent.InfoHash
+		})
+		// double check
+		if clientTorrent == nil || clientTorrent.Category != config.BRUSH_CAT {
+			log.Warnf("Invalid torrent deletion target: %s", torrent.InfoHash)
+			continue
+		}
+		if satisfied, remaining := clientTorrent.GetHnRStatus(brushSiteOption.Now); !satisfied {
+			log.Warnf("Torrent %s (%s) HnR requirements not satisfied (remaining seeding time: %s), skip deletion",
+				torrent.Name, torrent.InfoHash, util.GetDurationString(remaining))
+			continue
+		}
+		duration := brushSiteOption.Now - clientTorrent.Atime
+		log.Printf("Torrent %s (%v): %v", torrent.Name, torrent.InfoHash, torrent.Msg)
+		log.Printf("Total Dl / Up: %s / %s; Lifespan: %s; Average lifespan Dl / Up speed: %s/s / %s/s",
+			util.BytesSize(float64(clientTorrent.Downloaded)),
+			util.BytesSize(float64(clientTorrent.Uploaded)),
+			util.GetDurationString(duration),
+			util.BytesSize(float64(clientTorrent.Downloaded)/float64(duration)),
+			util.BytesSize(float64(clientTorrent.Uploaded)/float64(duration)),
+		)
+		deleteTorrentStats = append(deleteTorrentStats,
+			newTorrentStat(clientInstance.GetName(), clientTorrent, torrent.Msg))
+		deleteTorrentInfoHashes = append(deleteTorrentInfoHashes, clientTorrent.InfoHash)
+	}
+	if !dryRun {
+		skipped, err := client.DeleteTorrentsAuto(clientInstance, deleteTorrentInfoHashes, false)
+		log.Printf("Delete torrents result: error=%v, skipped (HnR unsatisfied)=%v", err, skipped)
+		if err == nil {
+			deleteTorrentStats = util.Filter(deleteTorrentStats, func(torrentStat *stats.TorrentStat) bool {
+				return !slices.Contains(skipped, torrentStat.InfoHash)
+			})
+			cntDeleteTorrents += int64(len(deleteTorrentStats))
+			for _, torrentStat := range deleteTorrentStats {
+				notify.Publish(&notify.Event{
+					Type:   notify.EVENT_BRUSH_DELETE,
+					Site:   torrentStat.Site,
+					Client: torrentStat.Client,
+					Title:  fmt.Sprintf("Brush deleted torrent %s from client %s", torrentStat.Name, torrentStat.Client),
+					Message: fmt.Sprintf("Size: %s; Dl / Up: %s / %s; Reason: %s",
+						util.BytesSize(float64(torrentStat.Size)), util.BytesSize(float64(torrentStat.Downloaded)),
+						util.BytesSize(float64(torrentStat.Uploaded)), torrentStat.Msg),
+					Data: map[string]any{"infoHash": torrentStat.InfoHash, "name": torrentStat.Name,
+						"size": torrentStat.Size, "downloaded": torrentStat.Downloaded,
+						"uploaded": torrentStat.Uploaded, "atime": torrentStat.Atime, "msg": torrentStat.Msg},
+				})
+			}
+			if statDb != nil {
+				if err := statDb.AddTorrentStats(brushSiteOption.Now, stats.EVENT_TORRENT_DELETED,
+					deleteTorrentStats); err != nil {
+					log.Warnf("Failed to record deleted torrents stats: %v", err)
+				}
+			}
+		}
+	}
+
+	// stall
+	for _, torrent := range result.StallTorrents {
+		log.Printf("Stall client %s torrent: %v / %v / %v",
+			clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg)
+		if dryRun {
+			continue
+		}
+		err := clientInstance.ModifyTorrent(torrent.InfoHash, &client.TorrentOption{
+			DownloadSpeedLimit: brushStrategy.GetStallDownloadSpeed(),
+		}, torrent.Meta)
+		log.Printf("Stall torrent result: error=%v", err)
+	}
+
+	// resume
+	if len(result.ResumeTorrents) > 0 {
+		for _, torrent := range result.ResumeTorrents {
+			log.Printf("Resume client %s torrent: %v / %v / %v",
+				clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg)
+		}
+		if !dryRun {
+			err := clientInstance.ResumeTorrents(util.Map(result.ResumeTorrents,
+				func(t strategy.AlgorithmOperationTorrent) string {
+					return t.InfoHash
+				}))
+			log.Printf("Resume torrents result: error=%v", err)
+		}
+	}
+
+	// modify
+	for _, torrent := range result.ModifyTorrents {
+		log.Printf("Modify client %s torrent: %v / %v / %v / %v ",
+			clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg, torrent.Meta)
+		if dryRun {
+			continue
+		}
+		err := clientInstance.ModifyTorrent(torrent.InfoHash, nil, torrent.Meta)
+		log.Printf("Modify torrent result: error=%v", err)
+	}
+
+	// add
+	cndAddTorrents := 0
+	addedRootDirs := map[string]bool{}
+	for _, torrent := range result.AddTorrents {
+		log.Printf("Add site %s torrent to client %s: %s / %s / %v / %s",
+			siteInstance.GetName(), clientInstance.GetName(), torrent.Name, torrent.Msg, torrent.Meta, torrent.SavePath)
+		if dryRun {
+			continue
+		}
+		torrentdata, _, _, err := siteInstance.DownloadTorrent(torrent.DownloadUrl)
+		if err != nil {
+			log.Printf("Failed to download: %s. Skip \n", err)
+			continue
+		}
+		tinfo, err := torrentutil.ParseTorrent(torrentdata)
+		if err != nil {
+			continue
+		}
+		if addedInfoHashes[tinfo.InfoHash] {
+			log.Printf("Already added to other client in this run. skip\n")
+			continue
+		}
+		if slices.ContainsFunc(clientInstances, func(c client.Client) bool {
+			pClientTorrent, _ := c.GetTorrent(tinfo.InfoHash)
+			return pClientTorrent != nil
+		}) {
+			log.Printf("Already existing in client. skip\n")
+			continue
+		}
+		if addedRootDirs[tinfo.RootDir] || clientInstance.TorrentRootPathExists(tinfo.RootDir) {
+			log.Printf("torrent rootpath %s existing in client. skip\n", tinfo.RootDir)
+			continue
+		}
+		log.Printf("torrent info: %s\n", tinfo.InfoHash)
+		cndAddTorrents++
+		tags := []string{client.GenerateTorrentTagFromSite(siteInstance.GetName())}
+		if tinfo.IsPrivate() {
+			tags = append(tags, config.PRIVATE_TAG)
+		} else {
+			tags = append(tags, config.PUBLIC_TAG)
+		}
+		// HnR torrents are added only if site brushAllowHr config is set.
+		if torrent.HasHnR || siteInstance.GetSiteConfig().GlobalHnR {
+			tags = append(tags, client.GenerateTorrentTagsFromHnR(siteInstance.GetSiteConfig())...)
+		}
+		torrentOption := &client.TorrentOption{
+			Name:             torrent.Name,
+			Pause:            addPaused,
+			SavePath:         torrent.SavePath,
+			Category:         config.BRUSH_CAT,
+			Tags:             tags,
+			UploadSpeedLimit: siteInstance.GetSiteConfig().TorrentUploadSpeedLimitValue,
+		}
+		if !dryRun {
+			err = clientInstance.AddTorrent(torrentdata, torrentOption, torrent.Meta)
+			log.Printf("Add torrent result: error=%v", err)
+			if err == nil {
+				// Ideally, we should update local client cache to reflect the latest state,
+				// including the new added torrent. It requires a major re-work of client codes.
+				addedRootDirs[tinfo.RootDir] = true
+				addedInfoHashes[tinfo.InfoHash] = true
+				cntAddTorrents++
+				notify.Publish(&notify.Event{
+					Type:   notify.EVENT_BRUSH_ADD,
+					Site:   siteInstance.GetName(),
+					Client: clientInstance.GetName(),
+					Title: fmt.Sprintf("Brush added site %s torrent %s to client %s",
+						siteInstance.GetName(), torrent.Name, clientInstance.GetName()),
+					Message: fmt.Sprintf("Size: %s; Reason: %s", util.BytesSize(float64(tinfo.Size)), torrent.Msg),
+					Data: map[string]any{"infoHash": tinfo.InfoHash, "name": torrent.Name, "size": tinfo.Size,
+						"msg": torrent.Msg},
+				})
+				if statDb != nil {
+					if err := statDb.AddTorrentStats(brushSiteOption.Now, stats.EVENT_TORRENT_ADDED,
+						[]*stats.TorrentStat{{
+							Client:   clientInstance.GetName(),
+							Site:     siteInstance.GetName(),
+							Category: config.BRUSH_CAT,
+							InfoHash: tinfo.InfoHash,
+							Name:     torrent.Name,
+							Size:     tinfo.Size,
+							Atime:    brushSiteOption.Now,
+							Msg:      torrent.Msg,
+						}}); err != nil {
+						log.Warnf("Failed to record added torrent stats: %v", err)
+					}
+				}
+			}
+		}
+	}
+	return cntAddTorrents, cntDeleteTorrents
+}
+
 func getTorrentsOfSite(torrents []*client.Torrent, siteName string) []*client.Torrent {
 	var ret []*client.Torrent
 	for _, torrent := range torrents {
@@ -343,3 +568,18 @@ func getTorrentsOfSite(torrents []*client.Torrent, siteName string) []*client.To
 	}
 	return ret
 }
+
+func newTorrentStat(clientName string, torrent *client.Torrent, msg string) *stats.TorrentStat {
+	return &stats.TorrentStat{
+		Client:     clientName,
+		Site:       torrent.GetSiteFromTag(),
+		InfoHash:   torrent.InfoHash,
+		Category:   torrent.Category,
+		Name:       torrent.Name,
+		Atime:      torrent.Atime,
+		Size:       torrent.Size,
+		Uploaded:   torrent.Uploaded,
+		Downloaded: torrent.Downloaded,
+		Msg:        msg,
+	}
+}
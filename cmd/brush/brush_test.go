@@ -0,0 +1,104 @@
+package brush
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+)
+
+// A minimal single file private torrent.
+const testTorrent = "d4:infod6:lengthi1024e4:name3:foo12:piece lengthi16384e" +
+	"6:pieces20:012345678901234567897:privatei1eee"
+
+// A fake site that only implements the methods used by adding brush torrents.
+type fakeSite struct {
+	site.Site
+	siteConfig *config.SiteConfigStruct
+}
+
+func (s *fakeSite) GetName() string {
+	return s.siteConfig.Name
+}
+
+func (s *fakeSite) GetSiteConfig() *config.SiteConfigStruct {
+	return s.siteConfig
+}
+
+func (s *fakeSite) DownloadTorrent(url string) ([]byte, string, string, error) {
+	return []byte(testTorrent), "foo.torrent", "1", nil
+}
+
+// A fake client that records the options of added torrents.
+type fakeClient struct {
+	client.Client
+	added []*client.TorrentOption
+}
+
+func (c *fakeClient) GetName() string {
+	return "fake"
+}
+
+func (c *fakeClient) GetTorrent(infoHash string) (*client.Torrent, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) TorrentRootPathExists(rootFolder string) bool {
+	return false
+}
+
+func (c *fakeClient) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
+	c.added = append(c.added, option)
+	return nil
+}
+
+func TestExecuteBrushResultHnRTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		hasHnR    bool
+		globalHnR bool
+		wantHnR   bool
+	}{
+		{"normal", false, false, false},
+		{"hnr torrent", true, false, true},
+		{"global hnr site", false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siteInstance := &fakeSite{siteConfig: &config.SiteConfigStruct{
+				Name:                "foo",
+				GlobalHnR:           tt.globalHnR,
+				BrushAllowHr:        true,
+				HnRSeedingTimeValue: 3600,
+				HnRRatio:            1.5,
+			}}
+			clientInstance := &fakeClient{}
+			bc := &brushClient{
+				instance:   clientInstance,
+				siteOption: &strategy.BrushSiteOptionStruct{},
+			}
+			result := &strategy.AlgorithmResult{
+				AddTorrents: []strategy.AlgorithmAddTorrent{{DownloadUrl: "1", Name: "foo", HasHnR: tt.hasHnR}},
+			}
+			cntAdd, _ := executeBrushResult(bc, siteInstance, result, []client.Client{clientInstance},
+				map[string]bool{}, nil)
+			if cntAdd != 1 || len(clientInstance.added) != 1 {
+				t.Fatalf("expect 1 torrent added, got %d", cntAdd)
+			}
+			tags := clientInstance.added[0].Tags
+			for _, tag := range []string{"site:foo", config.PRIVATE_TAG} {
+				if !slices.Contains(tags, tag) {
+					t.Errorf("expect tag %s, got %v", tag, tags)
+				}
+			}
+			for _, tag := range []string{config.HR_TAG, "meta.hnrst:3600", "meta.hnrr:150"} {
+				if slices.Contains(tags, tag) != tt.wantHnR {
+					t.Errorf("expect tag %s existence %t, got %v", tag, tt.wantHnR, tags)
+				}
+			}
+		})
+	}
+}
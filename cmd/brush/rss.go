@@ -0,0 +1,48 @@
+package brush
+
+import (
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/site"
+)
+
+// Max number of feed torrents that will be enriched from site in each brush run
+const RSS_ENRICH_LIMIT = 10
+
+// Get site new torrents from it's RSS feed.
+// Feed torrents usually lack discount / HnR / peers info, so the newest ones that pass the basic checks
+// (size limits and excludes) are enriched from site, the others are discarded.
+func getRssTorrents(siteInstance site.Site) ([]*site.Torrent, error) {
+	torrents, err := site.GetRssTorrents(siteInstance)
+	if err != nil {
+		return nil, err
+	}
+	siteConfig := siteInstance.GetSiteConfig()
+	sort.SliceStable(torrents, func(i, j int) bool {
+		return torrents[i].Time > torrents[j].Time
+	})
+	var siteTorrents []*site.Torrent
+	cntEnriched := 0
+	for _, torrent := range torrents {
+		if torrent.Size < siteConfig.BrushTorrentMinSizeLimitValue ||
+			torrent.Size > siteConfig.BrushTorrentMaxSizeLimitValue ||
+			torrent.MatchFiltersOr(siteConfig.BrushExcludes) || torrent.HasAnyTag(siteConfig.BrushExcludeTags) {
+			continue
+		}
+		if torrent.InfoIncomplete {
+			if cntEnriched >= RSS_ENRICH_LIMIT {
+				continue
+			}
+			cntEnriched++
+			if err := site.EnrichTorrent(siteInstance, torrent); err != nil {
+				log.Debugf("Failed to enrich feed torrent %s: %v", torrent.Name, err)
+				continue
+			}
+		}
+		siteTorrents = append(siteTorrents, torrent)
+	}
+	log.Printf("Fetched site %s feed torrents: %d, enriched: %d", siteInstance.GetName(), len(torrents), cntEnriched)
+	return siteTorrents, nil
+}
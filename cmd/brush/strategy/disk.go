@@ -0,0 +1,111 @@
+package strategy
+
+import (
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/common"
+)
+
+// Free space accounting of a disk in Decide: the disk of client default save path,
+// or the disk of a brush save path.
+type diskStruct struct {
+	savePath     string // empty for disk of client default save path
+	freespace    int64  // -1 means unknown
+	minFreeSpace int64
+	change       int64 // estimated free space change after deleting torrents
+}
+
+func (disk *diskStruct) needDelete() bool {
+	target := min(disk.minFreeSpace*2, disk.minFreeSpace+DELETE_TORRENTS_FREE_DISK_SPACE_TIER)
+	return disk.freespace >= 0 && disk.freespace <= disk.minFreeSpace && disk.freespace+disk.change <= target
+}
+
+func (disk *diskStruct) insufficient() bool {
+	return disk.freespace >= 0 && disk.freespace+disk.change < disk.minFreeSpace
+}
+
+func (disk *diskStruct) canResume() bool {
+	return disk.freespace+disk.change >= max(disk.minFreeSpace, RESUME_TORRENTS_FREE_DISK_SPACE_TIER)
+}
+
+func (disk *diskStruct) canAdd() bool {
+	return disk.freespace == -1 || disk.freespace+disk.change > disk.minFreeSpace
+}
+
+type disksStruct struct {
+	defaultDisk *diskStruct
+	savePaths   []*common.SavePathSpace
+	saveDisks   []*diskStruct // disks of brush save paths, in the same order of savePaths
+}
+
+func newDisks(clientStatus *client.Status, clientOption *BrushClientOptionStruct) *disksStruct {
+	disks := &disksStruct{
+		defaultDisk: &diskStruct{
+			freespace:    clientStatus.FreeSpaceOnDisk,
+			minFreeSpace: clientOption.MinDiskSpace,
+		},
+		savePaths: clientOption.SavePaths,
+	}
+	for _, savePath := range clientOption.SavePaths {
+		disks.saveDisks = append(disks.saveDisks, &diskStruct{
+			savePath:     savePath.Path,
+			freespace:    savePath.FreeSpace,
+			minFreeSpace: savePath.MinFreeSpace,
+		})
+	}
+	return disks
+}
+
+// Return the disk that torrent is in. Torrents outside of brush save paths belong to default disk.
+func (disks *disksStruct) of(torrent *client.Torrent) *diskStruct {
+	if index := common.MatchSavePath(disks.savePaths, torrent.SavePath); index != -1 {
+		return disks.saveDisks[index]
+	}
+	return disks.defaultDisk
+}
+
+// Return the set of all disks that match the test.
+func (disks *disksStruct) filter(test func(*diskStruct) bool) map[*diskStruct]bool {
+	set := map[*diskStruct]bool{}
+	for _, disk := range append([]*diskStruct{disks.defaultDisk}, disks.saveDisks...) {
+		if test(disk) {
+			set[disk] = true
+		}
+	}
+	return set
+}
+
+// Whether new torrents can be added to any disk.
+func (disks *disksStruct) canAdd() bool {
+	if len(disks.saveDisks) == 0 {
+		return disks.defaultDisk.canAdd()
+	}
+	for _, disk := range disks.saveDisks {
+		if disk.canAdd() {
+			return true
+		}
+	}
+	return false
+}
+
+// Pick the disk to add a new torrent of size to. If brush save paths are set, return the one that
+// has the most free space (above min free space) and can hold the torrent, and count the torrent size in;
+// return nil if none fits. Otherwise return the default disk.
+func (disks *disksStruct) pick(size int64) *diskStruct {
+	if len(disks.saveDisks) == 0 {
+		return disks.defaultDisk
+	}
+	var picked *diskStruct
+	for _, disk := range disks.saveDisks {
+		if !disk.canAdd() || disk.freespace >= 0 && disk.freespace+disk.change-disk.minFreeSpace < size {
+			continue
+		}
+		if picked == nil || picked.freespace >= 0 && (disk.freespace == -1 ||
+			disk.freespace+disk.change-disk.minFreeSpace > picked.freespace+picked.change-picked.minFreeSpace) {
+			picked = disk
+		}
+	}
+	if picked != nil {
+		picked.change -= size
+	}
+	return picked
+}
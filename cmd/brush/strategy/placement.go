@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"sort"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/site"
+)
+
+// The remaining capacity of a client for placing new site torrents, when brushing multiple clients.
+type BrushPlacementClientStruct struct {
+	Name string
+	// Free disk space above MinDiskSpace. -1 == unknown (do not check)
+	FreeSpace int64
+	// Free upload bandwidth. -1 == unlimited
+	UploadHeadroom int64
+	// Count of torrents that still can be added, limited by site AllowAddTorrents and client MaxTorrents
+	Slots int64
+}
+
+// Get the placement capacity of a client. clientTorrents are current brush torrents of client.
+func NewBrushPlacementClient(name string, clientStatus *client.Status, clientTorrents []*client.Torrent,
+	siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) *BrushPlacementClientStruct {
+	placementClient := &BrushPlacementClientStruct{
+		Name:           name,
+		FreeSpace:      -1,
+		UploadHeadroom: -1,
+		Slots:          min(siteOption.AllowAddTorrents, clientOption.MaxTorrents-int64(len(clientTorrents))),
+	}
+	if len(clientOption.SavePaths) > 0 {
+		placementClient.FreeSpace = 0
+		for _, savePath := range clientOption.SavePaths {
+			if savePath.Headroom() == -1 {
+				placementClient.FreeSpace = -1
+				break
+			}
+			placementClient.FreeSpace += savePath.Headroom()
+		}
+	} else if clientStatus.FreeSpaceOnDisk >= 0 {
+		placementClient.FreeSpace = max(clientStatus.FreeSpaceOnDisk-clientOption.MinDiskSpace, 0)
+	}
+	targetUploadSpeed := clientStatus.UploadSpeedLimit
+	if targetUploadSpeed <= 0 {
+		targetUploadSpeed = clientOption.DefaultUploadSpeedLimit
+	}
+	if targetUploadSpeed > 0 {
+		// same as Decide, which allows adding torrents until estimated upload speed reaches 2x of target.
+		placementClient.UploadHeadroom = max(targetUploadSpeed*2-clientStatus.UploadSpeed, 0)
+	}
+	return placementClient
+}
+
+func (c *BrushPlacementClientStruct) canPlace(torrent *site.Torrent) bool {
+	return c.Slots > 0 && c.UploadHeadroom != 0 && (c.FreeSpace == -1 || c.FreeSpace >= torrent.Size)
+}
+
+// Place site torrents to clients. Site torrents are rated by strategy and the ones with positive score are
+// placed in descending order of score. Each torrent is placed to the client with most remaining capacity
+// (sum of free disk space, upload headroom and free slots, each normalized against the max value of all clients),
+// then the capacity of that client is decreased. Each torrent is placed to at most one client;
+// torrents that can not be placed to any client are dropped.
+// Return the placed site torrents of each client, in the same order of clients.
+func PlaceSiteTorrents(strategy Strategy, siteTorrents []*site.Torrent, siteOption *BrushSiteOptionStruct,
+	clients []*BrushPlacementClientStruct) [][]*site.Torrent {
+	placed := make([][]*site.Torrent, len(clients))
+	type candidate struct {
+		torrent               *site.Torrent
+		score                 float64
+		predictionUploadSpeed int64
+	}
+	var candidates []candidate
+	for _, siteTorrent := range siteTorrents {
+		score, predictionUploadSpeed, _ := strategy.RateSiteTorrent(siteTorrent, siteOption)
+		if score > 0 {
+			candidates = append(candidates, candidate{siteTorrent, score, predictionUploadSpeed})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	var maxFreeSpace, maxUploadHeadroom, maxSlots int64
+	for _, c := range clients {
+		maxFreeSpace = max(maxFreeSpace, c.FreeSpace)
+		maxUploadHeadroom = max(maxUploadHeadroom, c.UploadHeadroom)
+		maxSlots = max(maxSlots, c.Slots)
+	}
+	normalize := func(value int64, maxValue int64) float64 {
+		if value == -1 || maxValue <= 0 {
+			return 1
+		}
+		return float64(value) / float64(maxValue)
+	}
+	for _, candidate := range candidates {
+		best := -1
+		bestScore := 0.0
+		for i, c := range clients {
+			if !c.canPlace(candidate.torrent) {
+				continue
+			}
+			score := normalize(c.FreeSpace, maxFreeSpace) + normalize(c.UploadHeadroom, maxUploadHeadroom) +
+				normalize(c.Slots, maxSlots)
+			if best == -1 || score > bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+		if best == -1 {
+			continue
+		}
+		c := clients[best]
+		placed[best] = append(placed[best], candidate.torrent)
+		c.Slots--
+		if c.FreeSpace != -1 {
+			c.FreeSpace -= candidate.torrent.Size
+		}
+		if c.UploadHeadroom != -1 {
+			c.UploadHeadroom = max(c.UploadHeadroom-candidate.predictionUploadSpeed, 0)
+		}
+	}
+	return placed
+}
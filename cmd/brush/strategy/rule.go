@@ -0,0 +1,128 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	RULE_ACTION_ADD    = "add"
+	RULE_ACTION_SKIP   = "skip"
+	RULE_ACTION_DELETE = "delete"
+	RULE_ACTION_STALL  = "stall"
+)
+
+// A parsed brush rule. All set conditions must match. Zero value of a (parsed) field means not set.
+type rule struct {
+	config         *config.BrushRuleConfigStruct
+	index          int
+	minAge         int64
+	maxAge         int64
+	minSize        int64
+	maxSize        int64
+	minUploadSpeed int64
+	maxUploadSpeed int64
+	scoreFactor    float64
+}
+
+func newRule(index int, ruleConfig *config.BrushRuleConfigStruct) (*rule, error) {
+	r := &rule{config: ruleConfig, index: index, scoreFactor: ruleConfig.ScoreFactor}
+	switch ruleConfig.Action {
+	case RULE_ACTION_ADD, RULE_ACTION_SKIP:
+		if ruleConfig.MinRatio != nil || ruleConfig.MaxRatio != nil || ruleConfig.MinUploadSpeed != "" ||
+			ruleConfig.MaxUploadSpeed != "" || ruleConfig.Incomplete {
+			return nil, fmt.Errorf("ratio / upload speed / incomplete conditions can not be used in %s rule",
+				ruleConfig.Action)
+		}
+	case RULE_ACTION_DELETE, RULE_ACTION_STALL:
+		if ruleConfig.ScoreFactor != 0 {
+			return nil, fmt.Errorf("scoreFactor can not be used in %s rule", ruleConfig.Action)
+		}
+	default:
+		return nil, fmt.Errorf("invalid action %q", ruleConfig.Action)
+	}
+	if r.scoreFactor == 0 {
+		r.scoreFactor = 1
+	}
+	var err error
+	for _, duration := range []struct {
+		value  string
+		target *int64
+	}{
+		{ruleConfig.MinAge, &r.minAge},
+		{ruleConfig.MaxAge, &r.maxAge},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		if *duration.target, err = util.ParseTimeDuration(duration.value); err != nil {
+			return nil, fmt.Errorf("invalid duration %q: %w", duration.value, err)
+		}
+	}
+	for _, size := range []struct {
+		value  string
+		target *int64
+	}{
+		{ruleConfig.MinSize, &r.minSize},
+		{ruleConfig.MaxSize, &r.maxSize},
+		{ruleConfig.MinUploadSpeed, &r.minUploadSpeed},
+		{ruleConfig.MaxUploadSpeed, &r.maxUploadSpeed},
+	} {
+		if size.value == "" {
+			continue
+		}
+		if *size.target, err = util.RAMInBytes(size.value); err != nil {
+			return nil, fmt.Errorf("invalid size %q: %w", size.value, err)
+		}
+	}
+	return r, nil
+}
+
+func (r *rule) String() string {
+	if r.config.Comment != "" {
+		return fmt.Sprintf("%s rule #%d (%s)", r.config.Action, r.index, r.config.Comment)
+	}
+	return fmt.Sprintf("%s rule #%d", r.config.Action, r.index)
+}
+
+// Check the conditions that applies to both site and client torrents.
+func (r *rule) matchCommon(age int64, size int64, seeders int64, leechers int64) bool {
+	return (r.minAge == 0 || age >= r.minAge) &&
+		(r.maxAge == 0 || age <= r.maxAge) &&
+		(r.minSize == 0 || size >= r.minSize) &&
+		(r.maxSize == 0 || size <= r.maxSize) &&
+		(r.config.MinSeeders == nil || seeders >= *r.config.MinSeeders) &&
+		(r.config.MaxSeeders == nil || seeders <= *r.config.MaxSeeders) &&
+		(r.config.MinLeechers == nil || leechers >= *r.config.MinLeechers) &&
+		(r.config.MaxLeechers == nil || leechers <= *r.config.MaxLeechers)
+}
+
+func (r *rule) matchSiteTorrent(torrent *site.Torrent, now int64) bool {
+	return r.matchCommon(now-torrent.Time, torrent.Size, torrent.Seeders, torrent.Leechers) &&
+		(len(r.config.Filters) == 0 || torrent.MatchFiltersOr(r.config.Filters))
+}
+
+func (r *rule) matchClientTorrent(torrent *client.Torrent, now int64) bool {
+	if !r.matchCommon(now-torrent.Atime, torrent.Size, torrent.Seeders, torrent.Leechers) ||
+		len(r.config.Filters) > 0 && !torrent.MatchFiltersOr(r.config.Filters) ||
+		r.config.Incomplete && torrent.IsComplete() ||
+		r.minUploadSpeed > 0 && torrent.UploadSpeed < r.minUploadSpeed ||
+		r.maxUploadSpeed > 0 && torrent.UploadSpeed > r.maxUploadSpeed {
+		return false
+	}
+	if r.config.MinRatio != nil || r.config.MaxRatio != nil {
+		ratio := float64(0)
+		if torrent.Size > 0 {
+			ratio = float64(torrent.Uploaded) / float64(torrent.Size)
+		}
+		if r.config.MinRatio != nil && ratio < *r.config.MinRatio ||
+			r.config.MaxRatio != nil && ratio > *r.config.MaxRatio {
+			return false
+		}
+	}
+	return true
+}
@@ -3,15 +3,19 @@ package strategy
 import (
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
 
+// Default values of strategy parameters. They can be overrided by [[brushStrategies]] config.
 const (
 	// new torrents timespan during which will NOT be examined at all
 	NEW_TORRENTS_TIMESPAN = int64(15 * 60)
@@ -28,8 +32,229 @@ const (
 	DELETE_TORRENT_IMMEDIATELY_SCORE     = float64(99999)
 	RESUME_TORRENTS_FREE_DISK_SPACE_TIER = int64(5 * 1024 * 1024 * 1024)  // 5GB
 	DELETE_TORRENTS_FREE_DISK_SPACE_TIER = int64(10 * 1024 * 1024 * 1024) // 10GB
+	// site torrents which discount will end in this time will NOT be added; client ones will be stalled
+	DISCOUNT_END_TIMESPAN = int64(3600)
+	SCORE_SEEDERS_FEW     = float64(50) // seeders <= 1
+	SCORE_SEEDERS_SOME    = float64(30) // seeders <= 3
+	SCORE_SEEDERS_MANY    = float64(10)
+	SCORE_LEECHER_WEIGHT  = float64(1)
+	SCORE_NONE_FREE_RATIO = float64(0.5)
+	DEFAULT_STRATEGY      = "default"
 )
 
+// Brush strategy decides which site torrents to add to client,
+// and which client torrents to delete / stall / resume / modify.
+type Strategy interface {
+	GetName() string
+	// Return true if client upload bandwidth is full, so that site new torrents should not be fetched.
+	IsBandwidthFull(clientStatus *client.Status) bool
+	// The download speed limit applied to stalled torrents
+	GetStallDownloadSpeed() int64
+	Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteTorrents []*site.Torrent,
+		siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) *AlgorithmResult
+	RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (
+		score float64, predictionUploadSpeed int64, note string)
+}
+
+type RegInfo struct {
+	Name string
+	// strategyConfig is nil if the default strategy of this type is required
+	Creator func(name string, strategyConfig *config.BrushStrategyConfigStruct) (Strategy, error)
+}
+
+var Registry = []*RegInfo{}
+
+func Register(regInfo *RegInfo) {
+	Registry = append(Registry, regInfo)
+}
+
+func init() {
+	Register(&RegInfo{
+		Name:    DEFAULT_STRATEGY,
+		Creator: NewDefaultStrategy,
+	})
+}
+
+// Get brush strategy of name, which is defined in [[brushStrategies]] config.
+// Empty name (or "default", if not defined in config) is the built-in default strategy.
+func Get(name string) (Strategy, error) {
+	if name == "" {
+		name = DEFAULT_STRATEGY
+	}
+	strategyConfig := config.GetBrushStrategyConfig(name)
+	strategyType := name
+	if strategyConfig != nil {
+		strategyType = strategyConfig.Type
+		if strategyType == "" {
+			strategyType = DEFAULT_STRATEGY
+		}
+	} else if name != DEFAULT_STRATEGY {
+		return nil, fmt.Errorf("brush strategy %s not found", name)
+	}
+	for _, regInfo := range Registry {
+		if regInfo.Name == strategyType {
+			strategy, err := regInfo.Creator(name, strategyConfig)
+			if err != nil {
+				return nil, fmt.Errorf("invalid brush strategy %s: %w", name, err)
+			}
+			return strategy, nil
+		}
+	}
+	return nil, fmt.Errorf("brush strategy %s: unsupported type %q", name, strategyType)
+}
+
+// Get the brush strategy for brushing site using client.
+// Site brushStrategy config takes precedence over client one.
+func GetBrushStrategy(siteInstance site.Site, clientInstance client.Client) (Strategy, error) {
+	name := ""
+	if siteInstance != nil {
+		name = siteInstance.GetSiteConfig().BrushStrategy
+	}
+	if name == "" && clientInstance != nil {
+		name = clientInstance.GetClientConfig().BrushStrategy
+	}
+	return Get(name)
+}
+
+// The built-in strategy. All parameters and scoring weights can be adjusted by config,
+// and additional add / skip / delete / stall rules can be applied.
+type defaultStrategy struct {
+	name                              string
+	newTorrentsTimespan               int64
+	newTorrentsStallExemptionTimespan int64
+	noProcessTorrentDeletionTimespan  int64
+	stallDownloadSpeed                int64
+	slowUploadSpeed                   int64
+	ratioCheckMinDownloadSpeed        int64
+	slowTorrentsCheckTimespan         int64
+	stallTorrentDeletionTimespan      int64
+	discountEndTimespan               int64
+	bandwidthFullPercent              float64
+	scoreSeedersFew                   float64
+	scoreSeedersSome                  float64
+	scoreSeedersMany                  float64
+	scoreLeecherWeight                float64
+	scoreNoneFreeRatio                float64
+	addRules                          []*rule
+	skipRules                         []*rule
+	deleteRules                       []*rule
+	stallRules                        []*rule
+}
+
+func NewDefaultStrategy(name string, strategyConfig *config.BrushStrategyConfigStruct) (Strategy, error) {
+	strategy := &defaultStrategy{
+		name:                              name,
+		newTorrentsTimespan:               NEW_TORRENTS_TIMESPAN,
+		newTorrentsStallExemptionTimespan: NEW_TORRENTS_STALL_EXEMPTION_TIMESPAN,
+		noProcessTorrentDeletionTimespan:  NO_PROCESS_TORRENT_DELETEION_TIMESPAN,
+		stallDownloadSpeed:                STALL_DOWNLOAD_SPEED,
+		slowUploadSpeed:                   SLOW_UPLOAD_SPEED,
+		ratioCheckMinDownloadSpeed:        RATIO_CHECK_MIN_DOWNLOAD_SPEED,
+		slowTorrentsCheckTimespan:         SLOW_TORRENTS_CHECK_TIMESPAN,
+		stallTorrentDeletionTimespan:      STALL_TORRENT_DELETEION_TIMESPAN,
+		discountEndTimespan:               DISCOUNT_END_TIMESPAN,
+		bandwidthFullPercent:              BANDWIDTH_FULL_PERCENT,
+		scoreSeedersFew:                   SCORE_SEEDERS_FEW,
+		scoreSeedersSome:                  SCORE_SEEDERS_SOME,
+		scoreSeedersMany:                  SCORE_SEEDERS_MANY,
+		scoreLeecherWeight:                SCORE_LEECHER_WEIGHT,
+		scoreNoneFreeRatio:                SCORE_NONE_FREE_RATIO,
+	}
+	if strategyConfig == nil {
+		return strategy, nil
+	}
+	for _, duration := range []struct {
+		value  string
+		target *int64
+	}{
+		{strategyConfig.NewTorrentsTimespan, &strategy.newTorrentsTimespan},
+		{strategyConfig.NewTorrentsStallExemptionTimespan, &strategy.newTorrentsStallExemptionTimespan},
+		{strategyConfig.NoProcessTorrentDeletionTimespan, &strategy.noProcessTorrentDeletionTimespan},
+		{strategyConfig.SlowTorrentsCheckTimespan, &strategy.slowTorrentsCheckTimespan},
+		{strategyConfig.StallTorrentDeletionTimespan, &strategy.stallTorrentDeletionTimespan},
+		{strategyConfig.DiscountEndTimespan, &strategy.discountEndTimespan},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		v, err := util.ParseTimeDuration(duration.value)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid duration %q: %w", duration.value, err)
+		}
+		*duration.target = v
+	}
+	for _, speed := range []struct {
+		value  string
+		target *int64
+	}{
+		{strategyConfig.StallDownloadSpeed, &strategy.stallDownloadSpeed},
+		{strategyConfig.SlowUploadSpeed, &strategy.slowUploadSpeed},
+		{strategyConfig.RatioCheckMinDownloadSpeed, &strategy.ratioCheckMinDownloadSpeed},
+	} {
+		if speed.value == "" {
+			continue
+		}
+		v, err := util.RAMInBytes(speed.value)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid speed %q: %w", speed.value, err)
+		}
+		*speed.target = v
+	}
+	// Unset (nil) weights use default values, while 0 is honoured.
+	for _, weight := range []struct {
+		value  *float64
+		target *float64
+	}{
+		{strategyConfig.BandwidthFullPercent, &strategy.bandwidthFullPercent},
+		{strategyConfig.ScoreSeedersFew, &strategy.scoreSeedersFew},
+		{strategyConfig.ScoreSeedersSome, &strategy.scoreSeedersSome},
+		{strategyConfig.ScoreSeedersMany, &strategy.scoreSeedersMany},
+		{strategyConfig.ScoreLeecherWeight, &strategy.scoreLeecherWeight},
+		{strategyConfig.ScoreNoneFreeFactor, &strategy.scoreNoneFreeRatio},
+	} {
+		if weight.value == nil {
+			continue
+		}
+		if *weight.value < 0 {
+			return nil, fmt.Errorf("invalid negative parameter value %v", *weight.value)
+		}
+		*weight.target = *weight.value
+	}
+	if strategy.bandwidthFullPercent <= 0 {
+		return nil, fmt.Errorf("invalid bandwidthFullPercent value %v: must be > 0", strategy.bandwidthFullPercent)
+	}
+	for i, ruleConfig := range strategyConfig.Rules {
+		r, err := newRule(i, ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("rule #%d: %w", i, err)
+		}
+		switch ruleConfig.Action {
+		case RULE_ACTION_ADD:
+			strategy.addRules = append(strategy.addRules, r)
+		case RULE_ACTION_SKIP:
+			strategy.skipRules = append(strategy.skipRules, r)
+		case RULE_ACTION_DELETE:
+			strategy.deleteRules = append(strategy.deleteRules, r)
+		case RULE_ACTION_STALL:
+			strategy.stallRules = append(strategy.stallRules, r)
+		}
+	}
+	return strategy, nil
+}
+
+func (strategy *defaultStrategy) GetName() string {
+	return strategy.name
+}
+
+func (strategy *defaultStrategy) IsBandwidthFull(clientStatus *client.Status) bool {
+	return clientStatus.UploadSpeedLimit > 0 && (clientStatus.UploadSpeedLimit < strategy.slowUploadSpeed ||
+		(float64(clientStatus.UploadSpeed)/float64(clientStatus.UploadSpeedLimit)) >= strategy.bandwidthFullPercent)
+}
+
+func (strategy *defaultStrategy) GetStallDownloadSpeed() int64 {
+	return strategy.stallDownloadSpeed
+}
+
 type BrushSiteOptionStruct struct {
 	AllowNoneFree           bool
 	AllowPaid               bool
@@ -52,12 +277,15 @@ type BrushClientOptionStruct struct {
 	MaxTorrents             int64
 	MinRatio                float64
 	DefaultUploadSpeedLimit int64
+	SavePaths               []*common.SavePathSpace // brush save paths (disks). Empty means client default save path
 }
 
 type AlgorithmAddTorrent struct {
 	DownloadUrl string
 	Name        string
 	Meta        map[string]int64
+	SavePath    string // empty means client default save path
+	HasHnR      bool
 	Msg         string
 }
 
@@ -91,6 +319,7 @@ type candidateTorrentStruct struct {
 	Size                  int64
 	PredictionUploadSpeed int64
 	Score                 float64
+	HasHnR                bool
 	Meta                  map[string]int64
 }
 
@@ -110,9 +339,9 @@ type clientTorrentInfoStruct struct {
 	DeleteFlag          bool
 }
 
-func countAsDownloading(torrent *client.Torrent, now int64) bool {
+func (strategy *defaultStrategy) countAsDownloading(torrent *client.Torrent, now int64) bool {
 	return !torrent.IsComplete() && torrent.Meta["stt"] == 0 &&
-		(torrent.DownloadSpeed >= STALL_DOWNLOAD_SPEED || now-torrent.Atime <= NEW_TORRENTS_TIMESPAN)
+		(torrent.DownloadSpeed >= strategy.stallDownloadSpeed || now-torrent.Atime <= strategy.newTorrentsTimespan)
 }
 
 func canStallTorrent(torrent *client.Torrent) bool {
@@ -142,15 +371,14 @@ func isTorrentStalled(torrent *client.Torrent) bool {
  * Also：
  *   * Use the current seeders / leechers info of torrent when make decisions
  */
-func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteTorrents []*site.Torrent,
-	siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) (result *AlgorithmResult) {
+func (strategy *defaultStrategy) Decide(clientStatus *client.Status, clientTorrents []*client.Torrent,
+	siteTorrents []*site.Torrent, siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) (result *AlgorithmResult) {
 	result = &AlgorithmResult{}
 
 	cntTorrents := int64(len(clientTorrents))
 	cntDownloadingTorrents := int64(0)
-	freespace := clientStatus.FreeSpaceOnDisk
 	freespaceChange := int64(0)
-	freespaceTarget := min(clientOption.MinDiskSpace*2, clientOption.MinDiskSpace+DELETE_TORRENTS_FREE_DISK_SPACE_TIER)
+	disks := newDisks(clientStatus, clientOption)
 	estimateUploadSpeed := clientStatus.UploadSpeed
 
 	var candidateTorrents []candidateTorrentStruct
@@ -176,7 +404,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	}
 
 	for _, siteTorrent := range siteTorrents {
-		score, predictionUploadSpeed, _ := RateSiteTorrent(siteTorrent, siteOption)
+		score, predictionUploadSpeed, _ := strategy.RateSiteTorrent(siteTorrent, siteOption)
 		if score > 0 {
 			candidateTorrent := candidateTorrentStruct{
 				Name:                  siteTorrent.Name,
@@ -184,6 +412,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 				DownloadUrl:           siteTorrent.DownloadUrl,
 				PredictionUploadSpeed: predictionUploadSpeed,
 				Score:                 score,
+				HasHnR:                siteTorrent.HasHnR,
 				Meta:                  map[string]int64{},
 			}
 			if siteTorrent.DiscountEndTime > 0 {
@@ -198,12 +427,13 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 
 	// mark torrents
 	for _, torrent := range clientTorrents {
-		if countAsDownloading(torrent, siteOption.Now) {
+		if strategy.countAsDownloading(torrent, siteOption.Now) {
 			cntDownloadingTorrents++
 		}
 
 		// mark torrents that discount time ends as stall
-		if torrent.Meta["dcet"] > 0 && torrent.Meta["dcet"]-siteOption.Now <= 3600 && torrent.Ctime <= 0 {
+		if torrent.Meta["dcet"] > 0 && torrent.Meta["dcet"]-siteOption.Now <= strategy.discountEndTimespan &&
+			torrent.Ctime <= 0 {
 			if canStallTorrent(torrent) {
 				meta := util.CopyMap(torrent.Meta, true)
 				meta["stt"] = siteOption.Now
@@ -217,13 +447,43 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			}
 		}
 
+		// rules have higher priority than built-in criterion
+		if !clientTorrentsMap[torrent.InfoHash].StallFlag && canStallTorrent(torrent) {
+			for _, r := range strategy.stallRules {
+				if r.matchClientTorrent(torrent, siteOption.Now) {
+					meta := util.CopyMap(torrent.Meta, true)
+					meta["stt"] = siteOption.Now
+					stallTorrents = append(stallTorrents, AlgorithmModifyTorrent{
+						InfoHash: torrent.InfoHash,
+						Name:     torrent.Name,
+						Msg:      "match " + r.String(),
+						Meta:     meta,
+					})
+					clientTorrentsMap[torrent.InfoHash].StallFlag = true
+					break
+				}
+			}
+		}
+		if index := slices.IndexFunc(strategy.deleteRules, func(r *rule) bool {
+			return r.matchClientTorrent(torrent, siteOption.Now)
+		}); index != -1 {
+			deleteCandidateTorrents = append(deleteCandidateTorrents, candidateClientTorrentStruct{
+				InfoHash:    torrent.InfoHash,
+				Score:       DELETE_TORRENT_IMMEDIATELY_SCORE,
+				FutureValue: 0,
+				Msg:         "match " + strategy.deleteRules[index].String(),
+			})
+			clientTorrentsMap[torrent.InfoHash].DeleteCandidateFlag = true
+			continue
+		}
+
 		// skip new added torrents
-		if siteOption.Now-torrent.Atime <= NEW_TORRENTS_TIMESPAN {
+		if siteOption.Now-torrent.Atime <= strategy.newTorrentsTimespan {
 			continue
 		}
 
 		if torrent.State == "error" && (torrent.UploadSpeed < clientOption.SlowUploadSpeedTier ||
-			torrent.UploadSpeed < clientOption.SlowUploadSpeedTier*2 && freespace == 0) &&
+			torrent.UploadSpeed < clientOption.SlowUploadSpeedTier*2 && disks.of(torrent).freespace == 0) &&
 			len(candidateTorrents) > 0 {
 			deleteCandidateTorrents = append(deleteCandidateTorrents, candidateClientTorrentStruct{
 				InfoHash:    torrent.InfoHash,
@@ -233,7 +493,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			})
 			clientTorrentsMap[torrent.InfoHash].DeleteCandidateFlag = true
 		} else if torrent.DownloadSpeed == 0 && torrent.SizeCompleted == 0 {
-			if siteOption.Now-torrent.Atime > NO_PROCESS_TORRENT_DELETEION_TIMESPAN {
+			if siteOption.Now-torrent.Atime > strategy.noProcessTorrentDeletionTimespan {
 				deleteCandidateTorrents = append(deleteCandidateTorrents, candidateClientTorrentStruct{
 					InfoHash:    torrent.InfoHash,
 					Score:       DELETE_TORRENT_IMMEDIATELY_SCORE,
@@ -245,14 +505,14 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 		} else if torrent.UploadSpeed < clientOption.SlowUploadSpeedTier {
 			// check slow torrents, add it to watch list first time and mark as deleteCandidate second time
 			if torrent.Meta["sct"] > 0 { // second encounter on slow torrent
-				if siteOption.Now-torrent.Meta["sct"] >= SLOW_TORRENTS_CHECK_TIMESPAN {
+				if siteOption.Now-torrent.Meta["sct"] >= strategy.slowTorrentsCheckTimespan {
 					averageUploadSpeedSinceSct := (torrent.Uploaded - torrent.Meta["sctu"]) /
 						(siteOption.Now - torrent.Meta["sct"])
 					if averageUploadSpeedSinceSct < clientOption.SlowUploadSpeedTier {
 						if canStallTorrent(torrent) &&
-							torrent.DownloadSpeed >= RATIO_CHECK_MIN_DOWNLOAD_SPEED &&
+							torrent.DownloadSpeed >= strategy.ratioCheckMinDownloadSpeed &&
 							float64(torrent.UploadSpeed)/float64(torrent.DownloadSpeed) < clientOption.MinRatio &&
-							siteOption.Now-torrent.Atime >= NEW_TORRENTS_STALL_EXEMPTION_TIMESPAN {
+							siteOption.Now-torrent.Atime >= strategy.newTorrentsStallExemptionTimespan {
 							meta := util.CopyMap(torrent.Meta, true)
 							meta["stt"] = siteOption.Now
 							stallTorrents = append(stallTorrents, AlgorithmModifyTorrent{
@@ -325,12 +585,11 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	for _, deleteTorrent := range deleteCandidateTorrents {
 		torrent := clientTorrentsMap[deleteTorrent.InfoHash].Torrent
 		shouldDelete := false
-		if deleteTorrent.Score >= DELETE_TORRENT_IMMEDIATELY_SCORE ||
-			(freespace >= 0 && freespace <= clientOption.MinDiskSpace && freespace+freespaceChange <= freespaceTarget) {
+		if deleteTorrent.Score >= DELETE_TORRENT_IMMEDIATELY_SCORE || disks.of(torrent).needDelete() {
 			shouldDelete = true
 		} else if torrent.Ctime <= 0 &&
 			torrent.Meta["stt"] > 0 &&
-			siteOption.Now-torrent.Meta["stt"] >= STALL_TORRENT_DELETEION_TIMESPAN {
+			siteOption.Now-torrent.Meta["stt"] >= strategy.stallTorrentDeletionTimespan {
 			shouldDelete = true
 		}
 		if !shouldDelete {
@@ -342,18 +601,20 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			Msg:      deleteTorrent.Msg,
 		})
 		freespaceChange += torrent.SizeCompleted
+		disks.of(torrent).change += torrent.SizeCompleted
 		estimateUploadSpeed -= torrent.UploadSpeed
 		clientTorrentsMap[torrent.InfoHash].DeleteFlag = true
-		if countAsDownloading(torrent, siteOption.Now) {
+		if strategy.countAsDownloading(torrent, siteOption.Now) {
 			cntDownloadingTorrents--
 		}
 		cntTorrents--
 	}
 
 	// if still not enough free space, delete ALL stalled incomplete torrents
-	if freespace >= 0 && freespace <= clientOption.MinDiskSpace && freespace+freespaceChange <= freespaceTarget {
+	if needDeleteDisks := disks.filter((*diskStruct).needDelete); len(needDeleteDisks) > 0 {
 		for _, torrent := range clientTorrents {
-			if clientTorrentsMap[torrent.InfoHash].DeleteFlag || !isTorrentStalled(torrent) {
+			if clientTorrentsMap[torrent.InfoHash].DeleteFlag || !isTorrentStalled(torrent) ||
+				!needDeleteDisks[disks.of(torrent)] {
 				continue
 			}
 			result.DeleteTorrents = append(result.DeleteTorrents, AlgorithmOperationTorrent{
@@ -362,9 +623,10 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 				Msg:      "delete stalled incomplete torrents due to insufficient disk space",
 			})
 			freespaceChange += torrent.SizeCompleted
+			disks.of(torrent).change += torrent.SizeCompleted
 			estimateUploadSpeed -= torrent.UploadSpeed
 			clientTorrentsMap[torrent.InfoHash].DeleteFlag = true
-			if countAsDownloading(torrent, siteOption.Now) {
+			if strategy.countAsDownloading(torrent, siteOption.Now) {
 				cntDownloadingTorrents--
 			}
 			cntTorrents--
@@ -385,9 +647,10 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 				Msg:      deleteTorrent.Msg + " (delete due to max torrents limit)",
 			})
 			freespaceChange += torrent.SizeCompleted
+			disks.of(torrent).change += torrent.SizeCompleted
 			estimateUploadSpeed -= torrent.UploadSpeed
 			clientTorrentsMap[torrent.InfoHash].DeleteFlag = true
-			if countAsDownloading(torrent, siteOption.Now) {
+			if strategy.countAsDownloading(torrent, siteOption.Now) {
 				cntDownloadingTorrents--
 			}
 			cntTorrents--
@@ -399,9 +662,10 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	}
 
 	// if still not enough free space, mark ALL torrents as stall
-	if freespace >= 0 && freespace+freespaceChange < clientOption.MinDiskSpace {
+	if insufficientDisks := disks.filter((*diskStruct).insufficient); len(insufficientDisks) > 0 {
 		for _, torrent := range clientTorrents {
-			if clientTorrentsMap[torrent.InfoHash].DeleteFlag || clientTorrentsMap[torrent.InfoHash].StallFlag {
+			if clientTorrentsMap[torrent.InfoHash].DeleteFlag || clientTorrentsMap[torrent.InfoHash].StallFlag ||
+				!insufficientDisks[disks.of(torrent)] {
 				continue
 			}
 			if canStallTorrent(torrent) {
@@ -419,10 +683,11 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	}
 
 	// mark torrents as resume
-	if freespace+freespaceChange >= max(clientOption.MinDiskSpace, RESUME_TORRENTS_FREE_DISK_SPACE_TIER) {
+	if resumableDisks := disks.filter((*diskStruct).canResume); len(resumableDisks) > 0 {
 		for _, torrent := range clientTorrents {
 			if torrent.State != "error" || torrent.UploadSpeed < clientOption.SlowUploadSpeedTier*4 ||
-				isTorrentStalled(torrent) || clientTorrentsMap[torrent.InfoHash].ResumeFlag {
+				isTorrentStalled(torrent) || clientTorrentsMap[torrent.InfoHash].ResumeFlag ||
+				!resumableDisks[disks.of(torrent)] {
 				continue
 			}
 			resumeTorrents = append(resumeTorrents, AlgorithmOperationTorrent{
@@ -440,7 +705,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			continue
 		}
 		result.StallTorrents = append(result.StallTorrents, stallTorrent)
-		if countAsDownloading(clientTorrentsMap[stallTorrent.InfoHash].Torrent, siteOption.Now) {
+		if strategy.countAsDownloading(clientTorrentsMap[stallTorrent.InfoHash].Torrent, siteOption.Now) {
 			cntDownloadingTorrents--
 		}
 	}
@@ -452,7 +717,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			continue
 		}
 		result.ResumeTorrents = append(result.ResumeTorrents, resumeTorrent)
-		if !countAsDownloading(clientTorrentsMap[resumeTorrent.InfoHash].Torrent, siteOption.Now) {
+		if !strategy.countAsDownloading(clientTorrentsMap[resumeTorrent.InfoHash].Torrent, siteOption.Now) {
 			cntDownloadingTorrents++
 		}
 	}
@@ -466,18 +731,23 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	}
 
 	// add new torrents
-	if (freespace == -1 || freespace+freespaceChange > clientOption.MinDiskSpace) &&
-		cntTorrents <= clientOption.MaxTorrents {
+	if disks.canAdd() && cntTorrents <= clientOption.MaxTorrents {
 		var added int64
 		for cntDownloadingTorrents < clientOption.MaxDownloadingTorrents &&
 			estimateUploadSpeed <= targetUploadSpeed*2 && len(candidateTorrents) > 0 &&
 			added < siteOption.AllowAddTorrents {
 			candidateTorrent := candidateTorrents[0]
 			candidateTorrents = candidateTorrents[1:]
+			disk := disks.pick(candidateTorrent.Size)
+			if disk == nil {
+				continue
+			}
 			result.AddTorrents = append(result.AddTorrents, AlgorithmAddTorrent{
 				DownloadUrl: candidateTorrent.DownloadUrl,
 				Name:        candidateTorrent.Name,
 				Meta:        candidateTorrent.Meta,
+				SavePath:    disk.savePath,
+				HasHnR:      candidateTorrent.HasHnR,
 				Msg:         fmt.Sprintf("new torrrent of score %.0f", candidateTorrent.Score),
 			})
 			added++
@@ -491,15 +761,14 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 
 	if cntTorrents <= clientOption.MaxTorrents &&
 		cntDownloadingTorrents < clientOption.MaxDownloadingTorrents &&
-		estimateUploadSpeed <= targetUploadSpeed*2 &&
-		(freespace == -1 || freespace+freespaceChange > clientOption.MinDiskSpace) {
+		estimateUploadSpeed <= targetUploadSpeed*2 && disks.canAdd() {
 		result.CanAddMore = true
 	}
 
 	return
 }
 
-func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (
+func (strategy *defaultStrategy) RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (
 	score float64, predictionUploadSpeed int64, note string) {
 	if log.GetLevel() >= log.TraceLevel {
 		defer func() {
@@ -520,7 +789,7 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 		(!siteOption.AllowPaid && siteTorrent.Paid && !siteTorrent.Bought) ||
 		siteTorrent.Size < siteOption.TorrentMinSizeLimit ||
 		siteTorrent.Size > siteOption.TorrentMaxSizeLimit ||
-		(siteTorrent.DiscountEndTime > 0 && siteTorrent.DiscountEndTime-siteOption.Now < 3600) ||
+		(siteTorrent.DiscountEndTime > 0 && siteTorrent.DiscountEndTime-siteOption.Now < strategy.discountEndTimespan) ||
 		(!siteOption.AllowZeroSeeders && siteTorrent.Seeders == 0) ||
 		((!siteOption.AcceptAnyFree || siteTorrent.DownloadMultiplier != 0) && siteTorrent.Leechers <= siteTorrent.Seeders) {
 		score = 0
@@ -536,6 +805,26 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 		note = "brush exclude tags match"
 		return
 	}
+	for _, r := range strategy.skipRules {
+		if r.matchSiteTorrent(siteTorrent, siteOption.Now) {
+			score = 0
+			note = "match " + r.String()
+			return
+		}
+	}
+	scoreFactor := float64(1)
+	if len(strategy.addRules) > 0 {
+		index := slices.IndexFunc(strategy.addRules, func(r *rule) bool {
+			return r.matchSiteTorrent(siteTorrent, siteOption.Now)
+		})
+		if index == -1 {
+			score = 0
+			note = "no add rule match"
+			return
+		}
+		scoreFactor = strategy.addRules[index].scoreFactor
+		note = "match " + strategy.addRules[index].String()
+	}
 	// 部分站点定期将旧种重新置顶免费。这类种子仍然可以获得很好的上传速度。
 	if !siteOption.AcceptAnyFree && siteOption.Now-siteTorrent.Time <= 86400*30 {
 		if siteOption.Now-siteTorrent.Time >= 86400 {
@@ -555,17 +844,17 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 	}
 
 	if siteTorrent.Seeders <= 1 {
-		score = 50
+		score = strategy.scoreSeedersFew
 	} else if siteTorrent.Seeders <= 3 {
-		score = 30
+		score = strategy.scoreSeedersSome
 	} else {
-		score = 10
+		score = strategy.scoreSeedersMany
 	}
-	score += float64(siteTorrent.Leechers)
+	score += float64(siteTorrent.Leechers) * strategy.scoreLeecherWeight
 
-	score *= siteTorrent.UploadMultiplier
+	score *= siteTorrent.UploadMultiplier * scoreFactor
 	if siteTorrent.DownloadMultiplier != 0 {
-		score *= 0.5
+		score *= strategy.scoreNoneFreeRatio
 	}
 
 	if siteTorrent.Size <= 1024*1024*1024 {
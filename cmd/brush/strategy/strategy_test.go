@@ -0,0 +1,148 @@
+package strategy_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+)
+
+const now = int64(1700000000)
+
+func TestStrategyRules(t *testing.T) {
+	maxRatio := 0.5
+	leecherWeight := float64(2)
+	configured, err := strategy.NewDefaultStrategy("custom", &config.BrushStrategyConfigStruct{
+		ScoreLeecherWeight: &leecherWeight,
+		Rules: []*config.BrushRuleConfigStruct{
+			{Action: "delete", MinAge: "6h", MaxRatio: &maxRatio, Comment: "low ratio"},
+			{Action: "skip", Filters: []string{"foo"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create strategy: %v", err)
+	}
+	defaultStrategy, _ := strategy.NewDefaultStrategy(strategy.DEFAULT_STRATEGY, nil)
+	if _, err = strategy.NewDefaultStrategy("invalid", &config.BrushStrategyConfigStruct{
+		Rules: []*config.BrushRuleConfigStruct{{Action: "skip", MaxRatio: &maxRatio}},
+	}); err == nil {
+		t.Errorf("expect error for client torrent only condition in skip rule")
+	}
+
+	clientStatus := &client.Status{FreeSpaceOnDisk: 1 << 40, UploadSpeedLimit: -1}
+	clientTorrents := []*client.Torrent{
+		{InfoHash: "a", Name: "a", State: "seeding", Atime: now - 7*3600, Ctime: now - 6*3600,
+			Size: 100 << 20, SizeCompleted: 100 << 20, Uploaded: 10 << 20, UploadSpeed: 1 << 20, Meta: map[string]int64{}},
+		{InfoHash: "b", Name: "b", State: "seeding", Atime: now - 7*3600, Ctime: now - 6*3600,
+			Size: 100 << 20, SizeCompleted: 100 << 20, Uploaded: 60 << 20, UploadSpeed: 1 << 20, Meta: map[string]int64{}},
+	}
+	siteOption := &strategy.BrushSiteOptionStruct{Now: now, TorrentMaxSizeLimit: 1 << 40, AllowAddTorrents: 10}
+	clientOption := &strategy.BrushClientOptionStruct{MinDiskSpace: 1 << 30, SlowUploadSpeedTier: 100 << 10,
+		MaxDownloadingTorrents: 6, MaxTorrents: 100, MinRatio: 0.2, DefaultUploadSpeedLimit: 10 << 20}
+
+	result := defaultStrategy.Decide(clientStatus, clientTorrents, nil, siteOption, clientOption)
+	if len(result.DeleteTorrents) != 0 {
+		t.Errorf("default strategy: expect no torrent deleted, got %v", result.DeleteTorrents)
+	}
+	result = configured.Decide(clientStatus, clientTorrents, nil, siteOption, clientOption)
+	if len(result.DeleteTorrents) != 1 || result.DeleteTorrents[0].InfoHash != "a" {
+		t.Errorf("configured strategy: expect torrent a deleted, got %v", result.DeleteTorrents)
+	}
+
+	siteTorrent := &site.Torrent{Name: "bar", Size: 1 << 30, Time: now - 600, Seeders: 2, Leechers: 10,
+		UploadMultiplier: 1}
+	defaultScore, _, _ := defaultStrategy.RateSiteTorrent(siteTorrent, siteOption)
+	score, _, _ := configured.RateSiteTorrent(siteTorrent, siteOption)
+	if defaultScore != (30+10)*10 || score != (30+20)*10 {
+		t.Errorf("unexpected scores: default=%v, configured=%v", defaultScore, score)
+	}
+	siteTorrent.Name = "foo"
+	if score, _, note := configured.RateSiteTorrent(siteTorrent, siteOption); score != 0 {
+		t.Errorf("expect site torrent skipped by rule, got score %v (%s)", score, note)
+	}
+
+	// explicit 0 weight is honoured
+	zero := float64(0)
+	noLeecher, err := strategy.NewDefaultStrategy("noleecher", &config.BrushStrategyConfigStruct{
+		ScoreLeecherWeight: &zero,
+	})
+	if err != nil {
+		t.Fatalf("failed to create strategy: %v", err)
+	}
+	siteTorrent.Name = "bar"
+	if score, _, _ := noLeecher.RateSiteTorrent(siteTorrent, siteOption); score != 30*10 {
+		t.Errorf("unexpected score of 0 leecher weight: %v", score)
+	}
+	if _, err = strategy.NewDefaultStrategy("invalid", &config.BrushStrategyConfigStruct{
+		BandwidthFullPercent: &zero,
+	}); err == nil {
+		t.Errorf("expect error for 0 bandwidthFullPercent")
+	}
+}
+
+func TestPlaceSiteTorrents(t *testing.T) {
+	defaultStrategy, _ := strategy.NewDefaultStrategy(strategy.DEFAULT_STRATEGY, nil)
+	siteOption := &strategy.BrushSiteOptionStruct{Now: now, TorrentMaxSizeLimit: 1 << 40, AllowAddTorrents: 10}
+	clientOption := &strategy.BrushClientOptionStruct{MinDiskSpace: 1 << 30, MaxTorrents: 100,
+		DefaultUploadSpeedLimit: 10 << 20}
+	var siteTorrents []*site.Torrent
+	for _, name := range []string{"a", "b", "c", "d"} {
+		siteTorrents = append(siteTorrents, &site.Torrent{Name: name, Size: 2 << 30, Time: now - 600,
+			Seeders: 2, Leechers: 10, UploadMultiplier: 1})
+	}
+	siteTorrents = append(siteTorrents, &site.Torrent{Name: "nonfree", Size: 1 << 30, Time: now - 600,
+		Seeders: 2, Leechers: 10, UploadMultiplier: 1, DownloadMultiplier: 1})
+	newClients := func(freeSpaces ...int64) (clients []*strategy.BrushPlacementClientStruct) {
+		for _, freeSpace := range freeSpaces {
+			clients = append(clients, strategy.NewBrushPlacementClient("",
+				&client.Status{FreeSpaceOnDisk: freeSpace, UploadSpeedLimit: -1}, nil, siteOption, clientOption))
+		}
+		return clients
+	}
+	names := func(torrents []*site.Torrent) (names string) {
+		for _, torrent := range torrents {
+			names += torrent.Name
+		}
+		return names
+	}
+
+	// box2 has only 2GiB free space above MinDiskSpace, all torrents go to box1.
+	placed := strategy.PlaceSiteTorrents(defaultStrategy, siteTorrents, siteOption, newClients(101<<30, 3<<30))
+	if names(placed[0]) != "abcd" || len(placed[1]) != 0 {
+		t.Errorf("expect all torrents placed to box1, got %q / %q", names(placed[0]), names(placed[1]))
+	}
+	// each client can only hold one torrent. The remaining ones are dropped, never placed twice.
+	placed = strategy.PlaceSiteTorrents(defaultStrategy, siteTorrents, siteOption, newClients(3<<30, 3<<30))
+	if names(placed[0]) != "a" || names(placed[1]) != "b" {
+		t.Errorf("expect torrents a / b placed to box1 / box2, got %q / %q", names(placed[0]), names(placed[1]))
+	}
+}
+
+func TestDecideSavePaths(t *testing.T) {
+	defaultStrategy, _ := strategy.NewDefaultStrategy(strategy.DEFAULT_STRATEGY, nil)
+	siteOption := &strategy.BrushSiteOptionStruct{Now: now, TorrentMaxSizeLimit: 1 << 40, AllowAddTorrents: 10}
+	clientOption := &strategy.BrushClientOptionStruct{MinDiskSpace: 1 << 30, SlowUploadSpeedTier: 100 << 10,
+		MaxDownloadingTorrents: 6, MaxTorrents: 100, MinRatio: 0.2, DefaultUploadSpeedLimit: 10 << 20,
+		SavePaths: []*common.SavePathSpace{
+			{Path: "/disk1", FreeSpace: 12 << 30, MinFreeSpace: 10 << 30},
+			{Path: "/disk2", FreeSpace: 15 << 30, MinFreeSpace: 10 << 30},
+		}}
+	clientStatus := &client.Status{FreeSpaceOnDisk: 1 << 40, UploadSpeedLimit: -1}
+	var siteTorrents []*site.Torrent
+	for _, name := range []string{"a", "b", "c"} {
+		siteTorrents = append(siteTorrents, &site.Torrent{Name: name, Size: 2 << 30, Time: now - 600,
+			Seeders: 2, Leechers: 10, UploadMultiplier: 1})
+	}
+	result := defaultStrategy.Decide(clientStatus, nil, siteTorrents, siteOption, clientOption)
+	var savePaths []string
+	for _, torrent := range result.AddTorrents {
+		savePaths = append(savePaths, torrent.SavePath)
+	}
+	// disk2 has 5GiB headroom and can hold 2 torrents, then disk1 (2GiB headroom) holds the last one.
+	if len(savePaths) != 3 || savePaths[0] != "/disk2" || savePaths[1] != "/disk2" || savePaths[2] != "/disk1" {
+		t.Errorf("unexpected save paths of added torrents: %v", savePaths)
+	}
+}
@@ -53,6 +53,12 @@ var (
 		{"tr_*", 0, false, false, "The transmission specific preferences. " +
 			"For full list see https://github.com/transmission/transmission/blob/3.00/extras/rpc-spec.txt#L482 . " +
 			"Convert argument name to snake_case. E.g. tr_config_dir"},
+		{"de_*", 0, false, false, "The Deluge specific core config. " +
+			"For full list see https://github.com/deluge-torrent/deluge/blob/develop/deluge/core/preferencesmanager.py . " +
+			"E.g. de_max_active_seeding"},
+		{"rt_*", 0, false, false, "The rTorrent specific config commands. " +
+			"For full list see https://kannibalox.github.io/rtorrent-docs/cmd-ref.html . " +
+			"E.g. rt_network.max_open_files"},
 	}
 	showRaw        = false
 	showValuesOnly = false
@@ -110,7 +116,9 @@ func clientctl(cmd *cobra.Command, args []string) error {
 		value := ""
 		var err error
 		if (clientInstance.GetClientConfig().Type == "qbittorrent" && strings.HasPrefix(variable, "qb_") ||
-			clientInstance.GetClientConfig().Type == "transmission" && strings.HasPrefix(variable, "tr_")) &&
+			clientInstance.GetClientConfig().Type == "transmission" && strings.HasPrefix(variable, "tr_") ||
+			clientInstance.GetClientConfig().Type == "deluge" && strings.HasPrefix(variable, "de_") ||
+			clientInstance.GetClientConfig().Type == "rtorrent" && strings.HasPrefix(variable, "rt_")) &&
 			len(variable) > 3 {
 			if len(s) == 1 {
 				value, err = clientInstance.GetConfig(name)
@@ -18,7 +18,9 @@ import (
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/notify"
 	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/site/tpl"
 	"github.com/sagan/ptool/util/osutil"
 )
 
@@ -77,6 +79,7 @@ func Execute() {
 		log.Debugf("ptool start: %v", os.Args)
 		log.Debugf("tty=%t, width=%d, height=%d", isTty, width, height)
 		log.Infof("config file: %s/%s", config.ConfigDir, config.ConfigFile)
+		tpl.LoadPacks(filepath.Join(config.ConfigDir, config.SITES_PACK_DIR))
 		if config.GlobalLock {
 			if config.LockFile != "" {
 				log.Fatalf("--lock and --global-lock flags are NOT compatible")
@@ -196,11 +199,15 @@ func init() {
 func Exit(code int) {
 	log.Tracef("Exit. Closing resources")
 	var resourcesWaitGroup sync.WaitGroup
-	resourcesWaitGroup.Add(2)
+	resourcesWaitGroup.Add(3)
 	go func() {
 		defer resourcesWaitGroup.Done()
 		client.Exit()
 	}()
+	go func() {
+		defer resourcesWaitGroup.Done()
+		notify.Exit()
+	}()
 	go func() {
 		defer resourcesWaitGroup.Done()
 		site.Exit()
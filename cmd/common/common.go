@@ -11,6 +11,7 @@ import (
 
 	"github.com/natefinch/atomic"
 	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/torrentutil"
 )
@@ -104,7 +105,7 @@ func (spm *PathMapper) Before2After(beforePath string) (afterPath string, match
 			if strings.HasPrefix(beforePath, before) {
 				return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
 			}
-		} else if strings.HasPrefix(beforePath, before+"/") {
+		} else if beforePath == before || strings.HasPrefix(beforePath, before+"/") {
 			return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
 		}
 	}
@@ -119,7 +120,7 @@ func (spm *PathMapper) After2Before(afterPath string) (beforePath string, match
 			if strings.HasPrefix(afterPath, after) {
 				return before + strings.TrimPrefix(afterPath, after), true
 			}
-		} else if strings.HasPrefix(afterPath, after+"/") {
+		} else if afterPath == after || strings.HasPrefix(afterPath, after+"/") {
 			return before + strings.TrimPrefix(afterPath, after), true
 		}
 	}
@@ -192,3 +193,30 @@ func ExportClientTorrent(clientInstance client.Client, torrent *client.Torrent,
 	}
 	return contents, tinfo, nil
 }
+
+// Add torrent (contents) to client as the xseed torrent of target, the existing client torrent of same contents.
+// The xseed torrent uses the save path of target; if option.Category is empty, it uses the category of target.
+// The xseed, site (if sitename is not empty), site HnR and private / public tags are added to option.Tags.
+func AddXseedTorrent(clientInstance client.Client, contents []byte, tinfo *torrentutil.TorrentMeta,
+	target *client.Torrent, sitename string, option *client.TorrentOption) error {
+	tags := []string{config.XSEED_TAG}
+	if sitename != "" {
+		tags = append(tags, client.GenerateTorrentTagFromSite(sitename))
+		if siteConfig := config.GetSiteConfig(sitename); siteConfig != nil && siteConfig.GlobalHnR {
+			tags = append(tags, client.GenerateTorrentTagsFromHnR(siteConfig)...)
+		}
+	}
+	tags = append(tags, option.Tags...)
+	if tinfo.IsPrivate() {
+		tags = append(tags, config.PRIVATE_TAG)
+	} else {
+		tags = append(tags, config.PUBLIC_TAG)
+		option.RatioLimit = config.Get().PublicTorrentRatioLimit
+	}
+	option.Tags = tags
+	option.SavePath = target.SavePath
+	if option.Category == "" {
+		option.Category = target.Category
+	}
+	return clientInstance.AddTorrent(contents, option, nil)
+}
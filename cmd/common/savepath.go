@@ -0,0 +1,102 @@
+package common
+
+import (
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/osutil"
+)
+
+// The disk space status of a client save path.
+type SavePathSpace struct {
+	Path         string
+	FreeSpace    int64 // -1 means unknown
+	MinFreeSpace int64
+}
+
+// Return the available space for new torrents (free space minus min free space).
+// Return -1 if free space is unknown.
+func (s *SavePathSpace) Headroom() int64 {
+	if s.FreeSpace < 0 {
+		return -1
+	}
+	return max(s.FreeSpace-s.MinFreeSpace, 0)
+}
+
+// Check whether savePath (of a client torrent) is this path or inside this path.
+func (s *SavePathSpace) Contains(savePath string) bool {
+	dir := path.Clean(util.ToSlash(s.Path))
+	savePath = path.Clean(util.ToSlash(savePath))
+	return savePath == dir || strings.HasPrefix(savePath, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// Return the index of the (longest) save path that contains savePath, or -1 if none matches.
+func MatchSavePath(savePaths []*SavePathSpace, savePath string) int {
+	index := -1
+	for i, s := range savePaths {
+		if s.Contains(savePath) && (index == -1 || len(s.Path) > len(savePaths[index].Path)) {
+			index = i
+		}
+	}
+	return index
+}
+
+// Get disk space status of client save paths. Free space of a path is reported by client;
+// if client can't report it, try to get it from local file system, by mapping the path using
+// client "mapSavePaths" config. If both failed, the free space is -1 (unknown).
+func GetSavePathsSpace(clientInstance client.Client, savePaths []*config.SavePathConfigStruct) []*SavePathSpace {
+	var savePathMapper *PathMapper
+	if rules := clientInstance.GetClientConfig().MapSavePaths; len(rules) > 0 {
+		var err error
+		if savePathMapper, err = NewPathMapper(rules); err != nil {
+			log.Warnf("Invalid mapSavePaths config of client %s: %v", clientInstance.GetName(), err)
+		}
+	}
+	var spaces []*SavePathSpace
+	for _, savePath := range savePaths {
+		space := &SavePathSpace{
+			Path:         savePath.Path,
+			FreeSpace:    -1,
+			MinFreeSpace: savePath.MinFreeSpaceValue,
+		}
+		freeSpace, err := clientInstance.GetFreeSpaceOnPath(savePath.Path)
+		if err != nil && savePathMapper != nil {
+			if localPath, match := savePathMapper.After2Before(savePath.Path); match {
+				freeSpace, err = osutil.GetFreeDiskSpace(localPath)
+			}
+		}
+		if err == nil {
+			space.FreeSpace = freeSpace
+		} else {
+			log.Warnf("Failed to get free space of client %s save path %s: %v",
+				clientInstance.GetName(), savePath.Path, err)
+		}
+		spaces = append(spaces, space)
+	}
+	return spaces
+}
+
+// Pick the save path that has the most headroom and can hold a new torrent of size,
+// then count the torrent size in it's free space. Paths of unknown free space are always preferred.
+// Return the index of picked save path, or -1 if none fits.
+func PickSavePath(savePaths []*SavePathSpace, size int64) int {
+	index := -1
+	for i, s := range savePaths {
+		if s.FreeSpace >= 0 && s.Headroom() < size {
+			continue
+		}
+		if index == -1 || savePaths[index].FreeSpace >= 0 &&
+			(s.FreeSpace < 0 || s.Headroom() > savePaths[index].Headroom()) {
+			index = i
+		}
+	}
+	if index != -1 && savePaths[index].FreeSpace >= 0 {
+		savePaths[index].FreeSpace -= size
+	}
+	return index
+}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+type xseedIndexTorrent struct {
+	torrent  *client.Torrent
+	contents []*client.TorrentContentFile
+}
+
+// Fingerprint index of content files (size & name) of client torrents.
+// It finds the client torrents that have the same contents with a local .torrent file,
+// without using any third-party xseed service.
+type XseedIndex struct {
+	Cnt      int64                           // number of indexed client torrents
+	torrents map[string][]*xseedIndexTorrent // file fingerprint => client torrents that have the file
+}
+
+// Result of matching a torrent against XseedIndex.
+type XseedMatch struct {
+	Target *client.Torrent
+	// Result of TorrentMeta.XseedCheckWithClientTorrent (0 or 1) if all files of torrent exist in Target;
+	// -1 if it's a partial match: only some files of torrent exist in Target.
+	Result int64
+	// Partial match only. Total size of torrent files that exist in Target,
+	// and the indexes of torrent files that do NOT exist in Target.
+	MatchedSize  int64
+	MissingFiles []int64
+}
+
+func xseedFingerprint(size int64, filepath string) string {
+	return fmt.Sprintf("%d/%s", size, strings.ToLower(path.Base(filepath)))
+}
+
+// Build the xseed index of client torrents using GetTorrentContents.
+// Torrents whose contents can not be fetched are skipped.
+func NewXseedIndex(clientInstance client.Client, torrents []*client.Torrent) *XseedIndex {
+	index := &XseedIndex{torrents: map[string][]*xseedIndexTorrent{}}
+	for _, torrent := range torrents {
+		contents, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+		if err != nil {
+			log.Debugf("Failed to get client torrent %s contents: %v", torrent.InfoHash, err)
+			continue
+		}
+		index.add(torrent, contents)
+	}
+	return index
+}
+
+func (index *XseedIndex) add(torrent *client.Torrent, contents []*client.TorrentContentFile) {
+	indexTorrent := &xseedIndexTorrent{torrent, contents}
+	for _, file := range contents {
+		fingerprint := xseedFingerprint(file.Size, file.Path)
+		if !slices.Contains(index.torrents[fingerprint], indexTorrent) {
+			index.torrents[fingerprint] = append(index.torrents[fingerprint], indexTorrent)
+		}
+	}
+	index.Cnt++
+}
+
+// Find the client torrent that matches with tinfo. A full match (all files of tinfo exist in client torrent)
+// is preferred; otherwise, the partial match that has the largest matched size. Return nil if none is found.
+// Client torrents of the same info hash of tinfo are ignored.
+func (index *XseedIndex) Match(tinfo *torrentutil.TorrentMeta) *XseedMatch {
+	var candidates []*xseedIndexTorrent
+	for _, file := range tinfo.Files {
+		for _, indexTorrent := range index.torrents[xseedFingerprint(file.Size, file.Path)] {
+			if indexTorrent.torrent.InfoHash != tinfo.InfoHash && !slices.Contains(candidates, indexTorrent) {
+				candidates = append(candidates, indexTorrent)
+			}
+		}
+	}
+	var partialMatch *XseedMatch
+	for _, candidate := range candidates {
+		if result := tinfo.XseedCheckWithClientTorrent(candidate.contents); result >= 0 {
+			return &XseedMatch{Target: candidate.torrent, Result: result, MatchedSize: tinfo.Size}
+		}
+		if match := partialXseedMatch(tinfo, candidate); match != nil &&
+			(partialMatch == nil || match.MatchedSize > partialMatch.MatchedSize) {
+			partialMatch = match
+		}
+	}
+	return partialMatch
+}
+
+// Match tinfo with a client torrent by file path (relative to root folder) and size.
+// The root folders of both must be the same. Return nil if none file matches.
+func partialXseedMatch(tinfo *torrentutil.TorrentMeta, indexTorrent *xseedIndexTorrent) *XseedMatch {
+	clientFilesSizeMap := map[string]int64{}
+	for _, file := range indexTorrent.contents {
+		filepath := file.Path
+		if tinfo.RootDir != "" {
+			rootDir, relativePath, found := strings.Cut(filepath, "/")
+			if !found || rootDir != tinfo.RootDir {
+				return nil
+			}
+			filepath = relativePath
+		}
+		clientFilesSizeMap[filepath] = file.Size
+	}
+	match := &XseedMatch{Target: indexTorrent.torrent, Result: -1}
+	for i, file := range tinfo.Files {
+		if size, ok := clientFilesSizeMap[file.Path]; ok && size == file.Size {
+			match.MatchedSize += file.Size
+		} else {
+			match.MissingFiles = append(match.MissingFiles, int64(i))
+		}
+	}
+	if match.MatchedSize == 0 {
+		return nil
+	}
+	return match
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// files: path, size pairs.
+func newTorrentMeta(infoHash string, rootDir string, files ...any) *torrentutil.TorrentMeta {
+	tinfo := &torrentutil.TorrentMeta{InfoHash: infoHash, RootDir: rootDir}
+	for i := 0; i < len(files); i += 2 {
+		file := &torrentutil.TorrentMetaFile{Path: files[i].(string), Size: int64(files[i+1].(int))}
+		tinfo.Files = append(tinfo.Files, file)
+		tinfo.Size += file.Size
+	}
+	return tinfo
+}
+
+func TestXseedIndexMatch(t *testing.T) {
+	index := &XseedIndex{torrents: map[string][]*xseedIndexTorrent{}}
+	index.add(&client.Torrent{InfoHash: "movie"}, []*client.TorrentContentFile{
+		{Path: "Movie/movie.mkv", Size: 1000},
+		{Path: "Movie/movie.nfo", Size: 10},
+	})
+	index.add(&client.Torrent{InfoHash: "season"}, []*client.TorrentContentFile{
+		{Path: "Show/E01.mkv", Size: 500},
+		{Path: "Show/E02.mkv", Size: 600},
+	})
+	cases := []struct {
+		name         string
+		tinfo        *torrentutil.TorrentMeta
+		target       string // "" == no match
+		result       int64
+		missingFiles []int64
+	}{
+		{"full", newTorrentMeta("x1", "Movie", "movie.mkv", 1000, "movie.nfo", 10), "movie", 0, nil},
+		{"subset", newTorrentMeta("x2", "Movie", "movie.mkv", 1000), "movie", 1, nil},
+		{"partial", newTorrentMeta("x3", "Show", "E01.mkv", 500, "E02.mkv", 600, "E03.mkv", 700),
+			"season", -1, []int64{2}},
+		{"sizeDiff", newTorrentMeta("x4", "Movie", "movie.mkv", 999), "", 0, nil},
+		{"self", newTorrentMeta("movie", "Movie", "movie.mkv", 1000, "movie.nfo", 10), "", 0, nil},
+	}
+	for _, c := range cases {
+		match := index.Match(c.tinfo)
+		if c.target == "" {
+			if match != nil {
+				t.Errorf("%s: Match() = %s, want nil", c.name, match.Target.InfoHash)
+			}
+			continue
+		}
+		if match == nil {
+			t.Errorf("%s: Match() = nil, want %s", c.name, c.target)
+			continue
+		}
+		if match.Target.InfoHash != c.target || match.Result != c.result ||
+			!slices.Equal(match.MissingFiles, c.missingFiles) {
+			t.Errorf("%s: Match() = %s / %d / %v, want %s / %d / %v", c.name, match.Target.InfoHash,
+				match.Result, match.MissingFiles, c.target, c.result, c.missingFiles)
+		}
+	}
+}
@@ -170,7 +170,14 @@ func configcmd(cmd *cobra.Command, args []string) error {
 			continue
 		}
 		emptyFlag = false
-		fmt.Printf("%-15s  %-s\n", groupConfig.Name, strings.Join(groupConfig.Sites, ", "))
+		members := strings.Join(groupConfig.Sites, ", ")
+		if len(groupConfig.Clients) > 0 {
+			if members != "" {
+				members += "; "
+			}
+			members += "clients: " + strings.Join(groupConfig.Clients, ", ")
+		}
+		fmt.Printf("%-15s  %-s\n", groupConfig.Name, members)
 	}
 	if emptyFlag {
 		fmt.Print(emptyListPlaceholder)
@@ -0,0 +1,273 @@
+package daemon
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/notify"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run as a long-running daemon that executes jobs on schedules.",
+	Long: `Run as a long-running daemon that executes jobs on schedules.
+
+Jobs are defined in [[jobs]] section of config file, e.g.:
+
+[[jobs]]
+name = "brush"
+schedule = "*/10 * * * *"
+cmd = "brush local mteam"
+
+[[jobs]]
+name = "sync"
+schedule = "@every 6h"
+cmd = "cookiecloud sync"
+
+The "schedule" is a standard 5 fields cron expression (minute hour day-of-month month day-of-week),
+or a descriptor like "@hourly", "@daily", "@every 30m". Prefix it with "CRON_TZ=<timezone> " to use a timezone
+other than the local one. The "cmd" is any ptool cmdline (without the leading "ptool"), or an alias.
+
+All jobs run sequentially in the daemon process: if a job is still running when another one is due,
+the later one waits; if a job is still running when it's due again, that run is skipped.
+Client and site instances (including their login sessions) are created once and shared by all job runs,
+but the data caches of them (e.g. torrents list) are purged before each run.
+Commands that use a client lock (e.g. "brush", "dynamicseeding") still acquire the <config_dir>/client-<name>.lock
+lock file during each run, and the run fails if the lock is held by another ptool process.
+
+All "embedded" type clients are started when daemon starts and keep seeding / downloading until it exits.
+
+Each job run is logged to stderr with it's result and duration.
+Send SIGINT / SIGTERM to stop the daemon, it exits after the current running job (if any) finishes.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: daemon,
+}
+
+var (
+	runOnStart = false
+	jobNames   []string
+)
+
+func init() {
+	command.Flags().BoolVarP(&runOnStart, "run-on-start", "", false, "Run all jobs once immediately when daemon starts")
+	command.Flags().StringArrayVarP(&jobNames, "job", "", nil,
+		"Only run the job of name. Can be used multiple times. Default: all (not disabled) jobs")
+	cmd.RootCmd.AddCommand(command)
+}
+
+type scheduledJob struct {
+	config   *config.JobConfigStruct
+	args     []string
+	schedule cron.Schedule
+	next     time.Time
+}
+
+func daemon(command *cobra.Command, args []string) error {
+	if config.InShell {
+		return fmt.Errorf("daemon can not be run in shell")
+	}
+	jobs := []*scheduledJob{}
+	for _, name := range jobNames {
+		if config.GetJobConfig(name) == nil {
+			return fmt.Errorf("job %s not found", name)
+		}
+	}
+	for _, jobConfig := range config.Get().Jobs {
+		if len(jobNames) > 0 && !slices.Contains(jobNames, jobConfig.Name) ||
+			len(jobNames) == 0 && jobConfig.Disabled {
+			continue
+		}
+		schedule, err := cron.ParseStandard(jobConfig.Schedule)
+		if err != nil {
+			return fmt.Errorf("job %s: invalid schedule %q: %w", jobConfig.Name, jobConfig.Schedule, err)
+		}
+		jobArgs, err := shlex.Split(jobConfig.Cmd)
+		if err != nil {
+			return fmt.Errorf("job %s: failed to parse cmd %q: %w", jobConfig.Name, jobConfig.Cmd, err)
+		}
+		if len(jobArgs) == 0 {
+			return fmt.Errorf("job %s: cmd is empty", jobConfig.Name)
+		}
+		if jobArgs[0] == command.Name() {
+			return fmt.Errorf("job %s: cmd can not be daemon itself", jobConfig.Name)
+		}
+		jobs = append(jobs, &scheduledJob{config: jobConfig, args: jobArgs, schedule: schedule})
+	}
+	startEmbeddedClients()
+	if len(jobs) == 0 {
+		log.Warnf("No jobs to run")
+	}
+	now := time.Now()
+	for _, job := range jobs {
+		if runOnStart {
+			job.next = now
+		} else {
+			job.next = job.schedule.Next(now)
+		}
+		fmt.Fprintf(os.Stderr, "[%s] Job %s scheduled, next run: %s\n",
+			util.FormatTime(now.Unix()), job.config.Name, util.FormatTime(job.next.Unix()))
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	flagsSnapshot := snapshotFlags(cmd.RootCmd)
+	osArgs := os.Args
+	defer func() {
+		os.Args = osArgs
+	}()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		next := time.Time{}
+		for _, job := range jobs {
+			if next.IsZero() || job.next.Before(next) {
+				next = job.next
+			}
+		}
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+		select {
+		case sig := <-sigs:
+			fmt.Fprintf(os.Stderr, "[%s] Received signal %v, exit\n", util.FormatTime(time.Now().Unix()), sig)
+			return nil
+		case <-timer.C:
+		}
+		for _, job := range jobs {
+			if time.Now().Before(job.next) {
+				continue
+			}
+			runJob(job, osArgs[0])
+			restoreFlags(flagsSnapshot)
+			// Any runs due during the execution are skipped.
+			job.next = job.schedule.Next(time.Now())
+			fmt.Fprintf(os.Stderr, "[%s] Job %s next run: %s\n",
+				util.FormatTime(time.Now().Unix()), job.config.Name, util.FormatTime(job.next.Unix()))
+			select {
+			case sig := <-sigs:
+				fmt.Fprintf(os.Stderr, "[%s] Received signal %v, exit\n", util.FormatTime(time.Now().Unix()), sig)
+				return nil
+			default:
+			}
+		}
+	}
+}
+
+// Start all enabled embedded clients, so they keep running in daemon process.
+func startEmbeddedClients() {
+	for _, clientConfig := range config.Get().ClientsEnabled {
+		if clientConfig.Type != "embedded" {
+			continue
+		}
+		clientInstance, err := client.CreateClient(clientConfig.Name)
+		if err == nil {
+			_, err = clientInstance.GetStatus()
+		}
+		if err != nil {
+			log.Errorf("Failed to start embedded client %s: %v", clientConfig.Name, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "[%s] Embedded client %s started\n", util.FormatTime(time.Now().Unix()),
+				clientConfig.Name)
+		}
+	}
+}
+
+func runJob(job *scheduledJob, program string) {
+	start := time.Now()
+	fmt.Fprintf(os.Stderr, "[%s] Job %s start: %v\n", util.FormatTime(start.Unix()), job.config.Name, job.args)
+	client.Purge("")
+	site.Purge("")
+	err := execute(program, job.args)
+	duration := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] Job %s failed (%v): %v\n",
+			util.FormatTime(time.Now().Unix()), job.config.Name, duration, err)
+		notify.Publish(&notify.Event{
+			Type:    notify.EVENT_JOB_FAILED,
+			Title:   fmt.Sprintf("Daemon job %s failed", job.config.Name),
+			Message: err.Error(),
+			Data:    map[string]any{"job": job.config.Name, "cmd": job.config.Cmd, "duration": duration.Seconds()},
+		})
+	} else {
+		fmt.Fprintf(os.Stderr, "[%s] Job %s succeeded (%v)\n",
+			util.FormatTime(time.Now().Unix()), job.config.Name, duration)
+	}
+}
+
+// Execute a ptool cmdline in current process, the same way as "ptool run".
+func execute(program string, args []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	os.Args = append([]string{program}, args...)
+	err = cmd.RootCmd.Execute()
+	if err != nil && strings.HasPrefix(err.Error(), "unknown command ") {
+		os.Args = append([]string{program, "alias"}, args...)
+		err = cmd.RootCmd.Execute()
+	}
+	return err
+}
+
+type flagState struct {
+	value   string
+	changed bool
+}
+
+// Record the current values of all flags of all commands.
+// Flag values are set by cmdline parsing and persist in the process, they must be restored after each run.
+func snapshotFlags(root *cobra.Command) map[*pflag.Flag]flagState {
+	snapshot := map[*pflag.Flag]flagState{}
+	var visit func(command *cobra.Command)
+	visit = func(command *cobra.Command) {
+		record := func(flag *pflag.Flag) {
+			snapshot[flag] = flagState{flag.Value.String(), flag.Changed}
+		}
+		command.PersistentFlags().VisitAll(record)
+		command.LocalFlags().VisitAll(record)
+		for _, subCommand := range command.Commands() {
+			visit(subCommand)
+		}
+	}
+	visit(root)
+	return snapshot
+}
+
+func restoreFlags(snapshot map[*pflag.Flag]flagState) {
+	for flag, state := range snapshot {
+		if flag.Value.String() == state.value && flag.Changed == state.changed {
+			continue
+		}
+		if sv, ok := flag.Value.(pflag.SliceValue); ok {
+			// value is "[" + writeAsCSV(values) + "]"
+			values, err := csv.NewReader(strings.NewReader(strings.TrimSuffix(
+				strings.TrimPrefix(state.value, "["), "]"))).Read()
+			if err != nil {
+				values = nil
+			}
+			sv.Replace(values)
+		} else {
+			flag.Value.Set(state.value)
+		}
+		flag.Changed = state.changed
+	}
+}
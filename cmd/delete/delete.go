@@ -22,7 +22,13 @@ var command = &cobra.Command{
 	Long: fmt.Sprintf(`Delete torrents from client.
 %s.
 
-It will ask for confirmation of deletion, unless --force flag is set.`, constants.HELP_INFOHASH_ARGS),
+It will ask for confirmation of deletion, unless --force flag is set.
+
+HnR torrents (added by ptool with "_hr" tag) that their HnR requirements (seeding time or ratio) are NOT satisfied
+yet are skipped, unless --force-hnr flag is set. Incomplete HnR torrents that have downloaded some data
+are also treated as NOT satisfied.
+Skipped torrents are reported but not considered an error.
+Use "ptool hnr <client>" to view these torrents.`, constants.HELP_INFOHASH_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: delete,
 }
@@ -32,6 +38,7 @@ var (
 	preserve          = false
 	preserveXseed     = false
 	force             = false
+	forceHnr          = false
 	filter            = ""
 	category          = ""
 	tag               = ""
@@ -47,6 +54,8 @@ func init() {
 	command.Flags().BoolVarP(&preserveXseed, "preserve-if-xseed-exist", "P", false,
 		"Preserve (don't delete) torrent content files on the disk if other xseed torrents exist")
 	command.Flags().BoolVarP(&force, "force", "", false, "Force deletion. Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&forceHnr, "force-hnr", "", false,
+		"Also delete HnR torrents that their HnR requirements are NOT satisfied yet (including incomplete ones)")
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
@@ -90,7 +99,7 @@ func delete(cmd *cobra.Command, args []string) error {
 		if len(infoHashes) == 0 {
 			return fmt.Errorf("no torrent to delete")
 		}
-		if force {
+		if force && forceHnr {
 			if err = clientInstance.DeleteTorrents(infoHashes, !preserve); err != nil {
 				return fmt.Errorf("failed to delete torrents: %w", err)
 			}
@@ -111,6 +120,20 @@ func delete(cmd *cobra.Command, args []string) error {
 			return true
 		})
 	}
+	var torrentsHnR []*client.Torrent
+	if !forceHnr {
+		now := util.Now()
+		torrents, torrentsHnR = client.FilterTorrentsHnR(torrents, now)
+		if len(torrentsHnR) > 0 {
+			fmt.Fprintf(os.Stderr, "%d torrents are skipped as their HnR requirements are NOT satisfied yet "+
+				"(use --force-hnr flag to delete them anyway):\n", len(torrentsHnR))
+			for _, torrent := range torrentsHnR {
+				_, remaining := torrent.GetHnRStatus(now)
+				fmt.Fprintf(os.Stderr, "- %s (%s): remaining seeding time %s\n",
+					torrent.Name, torrent.InfoHash, util.GetDurationString(remaining))
+			}
+		}
+	}
 	// if preserve-xseed flag is set, the torrents which contains other-not-delete xseed torrents
 	var torrentsWithXseed []*client.Torrent
 	if preserveXseed {
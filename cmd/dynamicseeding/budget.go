@@ -0,0 +1,167 @@
+package dynamicseeding
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// A torrent that competes for the shared dynamic seeding budget:
+// a current dynamic seeding torrent in client, or a candidate site torrent.
+type BudgetTorrent struct {
+	Size int64
+	// Seeders count after (or while) client seeds it. The fewer seeders, the more it deserves space.
+	Seeders int64
+	// Torrent that can not be deleted (e.g. downloading or too few seeders). It always gets space first.
+	Protected bool
+}
+
+// A site that shares the client dynamic seeding budget.
+type SiteBudget struct {
+	Name     string
+	MinShare int64 // -1 == no min share
+	MaxShare int64 // -1 == no max share
+	// Bonus points yield of site per hour per GiB of seeding size. <= 0 means unknown (as the average of sites).
+	Yield    float64
+	Torrents []*BudgetTorrent
+}
+
+// Allocate budget to sites. Each site first gets it's MinShare reserved.
+// Then all torrents of all sites are sorted by value (scarcity of seeders * site yield relative to average)
+// and the remaining budget goes to the sites of the most valuable torrents, until a site reaches it's MaxShare.
+// Return the allocated size of each site, in the same order of sites.
+func AllocateBudget(budget int64, sites []*SiteBudget) []int64 {
+	averageYield := 0.0
+	knownYields := 0
+	for _, s := range sites {
+		if s.Yield > 0 {
+			averageYield += s.Yield
+			knownYields++
+		}
+	}
+	if knownYields > 0 {
+		averageYield /= float64(knownYields)
+	}
+	type item struct {
+		site  int
+		size  int64
+		value float64
+	}
+	var items []item
+	reserved := make([]int64, len(sites))
+	maxShares := make([]int64, len(sites))
+	pool := budget
+	for i, s := range sites {
+		maxShares[i] = s.MaxShare
+		if maxShares[i] < 0 || maxShares[i] > budget {
+			maxShares[i] = budget
+		}
+		reserved[i] = min(max(s.MinShare, 0), maxShares[i])
+		pool -= reserved[i]
+		yieldFactor := 1.0
+		if s.Yield > 0 {
+			yieldFactor = s.Yield / averageYield
+		}
+		for _, torrent := range s.Torrents {
+			value := math.Inf(1)
+			if !torrent.Protected {
+				value = yieldFactor / float64(max(torrent.Seeders, 1))
+			}
+			items = append(items, item{i, torrent.Size, value})
+		}
+	}
+	// Sum of min shares exceeds budget. Min shares are reduced proportionally.
+	if pool < 0 {
+		ratio := float64(budget) / float64(budget-pool)
+		pool = budget
+		for i := range reserved {
+			reserved[i] = int64(float64(reserved[i]) * ratio)
+			pool -= reserved[i]
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].value > items[j].value
+	})
+	used := make([]int64, len(sites))
+	for _, item := range items {
+		newUsed := used[item.site] + item.size
+		if newUsed > maxShares[item.site] {
+			continue
+		}
+		// only the part that exceeds the reserved min share consumes the shared pool
+		need := newUsed - max(used[item.site], reserved[item.site])
+		if need > pool {
+			continue
+		}
+		if need > 0 {
+			pool -= need
+		}
+		used[item.site] = newUsed
+	}
+	allocated := make([]int64, len(sites))
+	for i := range sites {
+		allocated[i] = max(used[i], reserved[i])
+	}
+	return allocated
+}
+
+// Get the budget info of site: the current dynamic seeding torrents of site in client and the candidate
+// site torrents, which are also returned. Shares of site are parsed against budget.
+func getSiteBudget(clientInstance client.Client, siteInstance site.Site, ignores []string, budget int64,
+	timestamp int64, downloadingSpeedLimit int64) (siteBudget *SiteBudget, candidates []*site.Torrent, err error) {
+	siteConfig := siteInstance.GetSiteConfig()
+	siteBudget = &SiteBudget{Name: siteInstance.GetName()}
+	if siteBudget.MinShare, err = config.ParseDynamicSeedingShare(siteConfig.DynamicSeedingMinShare, budget); err != nil {
+		return nil, nil, fmt.Errorf("invalid dynamicSeedingMinShare: %w", err)
+	}
+	if siteBudget.MaxShare, err = config.ParseDynamicSeedingShare(siteConfig.DynamicSeedingMaxShare, budget); err != nil {
+		return nil, nil, fmt.Errorf("invalid dynamicSeedingMaxShare: %w", err)
+	}
+	minSeeders := util.FirstNonZeroIntegerArg(siteConfig.DynamicSeedingMinSeeders, MIN_SEEDERS)
+	dynamicSeedingTag := client.GenerateTorrentTagFromSite(siteInstance.GetName())
+	clientTorrents, err := clientInstance.GetTorrents("", config.DYNAMIC_SEEDING_CAT_PREFIX+siteInstance.GetName(), true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client current dynamic seeding torrents: %w", err)
+	}
+	contentPaths := map[string]bool{}
+	for _, torrent := range clientTorrents {
+		if !torrent.HasTag(dynamicSeedingTag) || torrent.ContentPath != "" && contentPaths[torrent.ContentPath] {
+			continue
+		}
+		contentPaths[torrent.ContentPath] = true
+		satisfied, _ := torrent.GetHnRStatus(timestamp)
+		siteBudget.Torrents = append(siteBudget.Torrents, &BudgetTorrent{
+			Size:    torrent.Size,
+			Seeders: torrent.Seeders,
+			Protected: torrent.HasTag(config.TORRENT_NODEL_TAG) || !satisfied || !torrent.IsComplete() ||
+				timestamp-torrent.Ctime < MIN_SEEDING_TIME || torrent.Seeders <= minSeeders,
+		})
+	}
+	candidates, err = scanSiteTorrents(siteInstance, ignores, timestamp, downloadingSpeedLimit,
+		budget, MAX_PARALLEL_DOWNLOAD)
+	if err != nil {
+		log.Warnf("Failed to get site %s torrents: %v", siteInstance.GetName(), err)
+	}
+	if candidates == nil {
+		candidates = []*site.Torrent{}
+	}
+	for _, torrent := range candidates {
+		siteBudget.Torrents = append(siteBudget.Torrents, &BudgetTorrent{
+			Size:    torrent.Size,
+			Seeders: torrent.Seeders + 1,
+		})
+	}
+	if status, err := siteInstance.GetStatus(); err != nil {
+		log.Warnf("Failed to get site %s status: %v", siteInstance.GetName(), err)
+	} else if status.UserSeedingSize > 0 {
+		siteBudget.Yield = status.UserBonusPerHour / (float64(status.UserSeedingSize) / (1 << 30))
+	}
+	return siteBudget, candidates, nil
+}
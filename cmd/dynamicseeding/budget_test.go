@@ -0,0 +1,57 @@
+package dynamicseeding_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/cmd/dynamicseeding"
+)
+
+func TestAllocateBudget(t *testing.T) {
+	newSites := func() []*dynamicseeding.SiteBudget {
+		return []*dynamicseeding.SiteBudget{
+			{
+				Name:     "a",
+				MinShare: -1,
+				MaxShare: -1,
+				Yield:    2,
+				Torrents: []*dynamicseeding.BudgetTorrent{
+					{Size: 30, Seeders: 2},
+					{Size: 50, Seeders: 5},
+				},
+			},
+			{
+				Name:     "b",
+				MinShare: -1,
+				MaxShare: -1,
+				Yield:    1,
+				Torrents: []*dynamicseeding.BudgetTorrent{
+					{Size: 20, Seeders: 10, Protected: true},
+					{Size: 40, Seeders: 2},
+				},
+			},
+		}
+	}
+	cases := []struct {
+		name   string
+		modify func(sites []*dynamicseeding.SiteBudget)
+		want   []int64
+	}{
+		{"value", func(sites []*dynamicseeding.SiteBudget) {}, []int64{30, 60}},
+		{"minShare", func(sites []*dynamicseeding.SiteBudget) { sites[0].MinShare = 60 }, []int64{80, 20}},
+		{"maxShare", func(sites []*dynamicseeding.SiteBudget) { sites[1].MaxShare = 30 }, []int64{80, 20}},
+		{"minShareOverflow", func(sites []*dynamicseeding.SiteBudget) {
+			sites[0].MinShare = 90
+			sites[1].MinShare = 60
+			sites[0].Torrents = nil
+			sites[1].Torrents = nil
+		}, []int64{60, 40}},
+	}
+	for _, c := range cases {
+		sites := newSites()
+		c.modify(sites)
+		if got := dynamicseeding.AllocateBudget(100, sites); !slices.Equal(got, c.want) {
+			t.Errorf("%s: AllocateBudget() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
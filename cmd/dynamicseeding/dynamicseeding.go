@@ -4,9 +4,10 @@
 // 当前保种的种子如果没有断种风险(做种人数充足)，会在需要时自动删除以腾出空间下载新的（亟需保种）种子。
 // 使用方法：在 ptoo.toml 站点配置里增加 "dynamicSeedingSize = 100GiB" 设置总保种体积上限，
 // 然后定时运行 ptool dynamicseeding <client> <site> 即可。
+// 也可以在客户端配置里设置 "dynamicSeedingSize" 总空间，由多个站点共享: ptool dynamicseeding <client> <site>...
 // 动态保种添加的种子会放到 dynamic-seeding-<site> 分类里并且打上 site:<site> 标签。
 // 用户也可以将手工下载的该站点种子放到该分类里（也需要打上 site:<site> 标签）以让动态保种管理。
-// @todo : 动态保种种子的辅种，有辅种的种子删除时保留文件。
+// 可以使用 --xseed 参数对新完成的动态保种种子辅种。有辅种的种子删除时保留文件，并且同一内容只计算一次保种体积。
 package dynamicseeding
 
 import (
@@ -14,6 +15,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -32,27 +34,60 @@ import (
 const IGNORE_FILE_SIZE = 100
 
 var command = &cobra.Command{
-	Use:         "dynamicseeding {client} {site}",
+	Use:         "dynamicseeding {client} {site | group}...",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "dynamicseeding"},
 	Short:       "Dynamic seeding torrents of sites.",
-	Long:        `Dynamic seeding torrents of sites.`,
-	Args:        cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
-	RunE:        dynamicseeding,
+	Long: `Dynamic seeding torrents of sites.
+
+By default, each site uses it's own "dynamicSeedingSize" space of client.
+If the client has "dynamicSeedingSize" config, all sites share that space. The space is allocated
+to sites according to seeders scarcity of their torrents, bonus points yield per GiB of sites,
+and "dynamicSeedingMinShare" / "dynamicSeedingMaxShare" of sites.
+
+If --xseed flag is set, it will add xseed torrents of newly completed dynamic seeding torrents to client,
+using iyuu or the local .torrent files of --xseed-dir. When deleting a dynamic seeding torrent that has
+xseed torrents in client, it's content files are kept.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: dynamicseeding,
 }
 
 var (
-	dryRun = false
+	dryRun    = false
+	xseedMode = ""
+	xseedDir  = ""
 )
 
 func init() {
 	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false,
 		"Dry run. Do NOT actually add or delete torrent to / from client")
+	cmd.AddEnumFlagP(command, &xseedMode, "xseed", "", &cmd.EnumFlag{
+		Description: "Xseed newly completed dynamic seeding torrents",
+		Options: [][2]string{
+			{"none", "do not xseed"},
+			{"iyuu", "use iyuu API"},
+			{"local", "use .torrent files of --xseed-dir"},
+		},
+	})
+	command.Flags().StringVarP(&xseedDir, "xseed-dir", "", "", `Dir of .torrent files used by "--xseed local"`)
 	cmd.RootCmd.AddCommand(command)
 }
 
+// A site of dynamic seeding, with it's ignore file (ids of site torrents recently deleted from client)
+// and xseed file (info hashes of client torrents already xseeded).
+type dynamicSeedingSite struct {
+	siteInstance site.Site
+	ignoreFile   *os.File
+	ignores      []string
+	xseedFile    *os.File
+	xseeded      []string
+}
+
 func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
+	if xseedMode == "local" && xseedDir == "" {
+		return fmt.Errorf(`"--xseed local" requires --xseed-dir flag`)
+	}
 	clientName := args[0]
-	sitename := args[1]
+	sitenames := config.ParseGroupAndOtherNames(args[1:]...)
 	clientInstance, err := client.CreateClient(clientName)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
@@ -62,37 +97,129 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 		return err
 	}
 	defer lock.Unlock()
-	siteInstance, err := site.CreateSite(sitename)
-	if err != nil {
-		return fmt.Errorf("failed to create site: %w", err)
-	}
-	ignoreFile, err := os.OpenFile(filepath.Join(config.ConfigDir,
-		fmt.Sprintf("dynamic-seeding-%s.ignore.txt", sitename)),
-		os.O_CREATE|os.O_RDWR, constants.PERM)
-	if err != nil {
-		return fmt.Errorf("failed to open ignore file: %w", err)
-	}
-	defer ignoreFile.Close()
-	var ignores []string
-	if contents, err := io.ReadAll(ignoreFile); err != nil {
-		return fmt.Errorf("failed to read ignore file: %w", err)
-	} else {
-		ignores = strings.Split(string(contents), "\n")
+	var sites []*dynamicSeedingSite
+	for _, sitename := range sitenames {
+		siteInstance, err := site.CreateSite(sitename)
+		if err != nil {
+			return fmt.Errorf("failed to create site %s: %w", sitename, err)
+		}
+		ignoreFile, err := os.OpenFile(filepath.Join(config.ConfigDir,
+			fmt.Sprintf("dynamic-seeding-%s.ignore.txt", sitename)),
+			os.O_CREATE|os.O_RDWR, constants.PERM)
+		if err != nil {
+			return fmt.Errorf("failed to open ignore file: %w", err)
+		}
+		defer ignoreFile.Close()
+		contents, err := io.ReadAll(ignoreFile)
+		if err != nil {
+			return fmt.Errorf("failed to read ignore file: %w", err)
+		}
+		seedingSite := &dynamicSeedingSite{
+			siteInstance: siteInstance,
+			ignoreFile:   ignoreFile,
+			ignores:      strings.Split(string(contents), "\n"),
+		}
+		if xseedMode != "none" {
+			xseedFile, err := os.OpenFile(filepath.Join(config.ConfigDir,
+				fmt.Sprintf("dynamic-seeding-%s.xseed.txt", sitename)),
+				os.O_CREATE|os.O_RDWR, constants.PERM)
+			if err != nil {
+				return fmt.Errorf("failed to open xseed file: %w", err)
+			}
+			defer xseedFile.Close()
+			contents, err := io.ReadAll(xseedFile)
+			if err != nil {
+				return fmt.Errorf("failed to read xseed file: %w", err)
+			}
+			seedingSite.xseedFile = xseedFile
+			seedingSite.xseeded = strings.Split(string(contents), "\n")
+		}
+		sites = append(sites, seedingSite)
 	}
 	// A workaround for transmission performance boost. tr can get all infos in batch
 	if trClient, ok := clientInstance.(*transmission.Client); ok {
 		trClient.Sync(true)
 	}
-	result, err := doDynamicSeeding(clientInstance, siteInstance, ignores)
-	if err != nil {
-		return err
+	sizes := make([]int64, len(sites))
+	candidates := make([][]*site.Torrent, len(sites))
+	if budget := clientInstance.GetClientConfig().DynamicSeedingSizeValue; budget > 0 {
+		if sizes, candidates, err = allocateSharedSize(clientInstance, sites, budget); err != nil {
+			return err
+		}
+	} else {
+		for i, s := range sites {
+			sizes[i] = s.siteInstance.GetSiteConfig().DynamicSeedingSizeValue
+			if sizes[i] <= MIN_SIZE {
+				return fmt.Errorf("site %s dynamicSeedingSize insufficient. Current value: %s. At least %s is required",
+					s.siteInstance.GetName(), util.BytesSizeAround(float64(sizes[i])),
+					util.BytesSizeAround(float64(MIN_SIZE)))
+			}
+		}
+	}
+	errorCnt := int64(0)
+	for i, s := range sites {
+		result, err := doDynamicSeeding(clientInstance, s.siteInstance, s.ignores, sizes[i], candidates[i])
+		if err != nil {
+			if len(sites) == 1 {
+				return err
+			}
+			log.Errorf("Failed to do dynamic seeding of site %s: %v", s.siteInstance.GetName(), err)
+			errorCnt++
+			continue
+		}
+		result.Print(os.Stdout)
+		if dryRun {
+			continue
+		}
+		errorCnt += s.apply(clientInstance, result)
+		if xseedMode != "none" {
+			errorCnt += s.xseed(clientInstance)
+		}
 	}
-	result.Print(os.Stdout)
 	if dryRun {
 		log.Warnf("Dry-run. Exit")
 		return nil
 	}
-	errorCnt := int64(0)
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+// Allocate the shared dynamic seeding size of client (budget) to sites.
+// Return the allocated size and the candidate site torrents of each site.
+func allocateSharedSize(clientInstance client.Client, sites []*dynamicSeedingSite, budget int64) (
+	sizes []int64, candidates [][]*site.Torrent, err error) {
+	timestamp := util.Now()
+	clientStatus, err := clientInstance.GetStatus()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client status: %w", err)
+	}
+	downloadingSpeedLimit := clientStatus.DownloadSpeedLimit
+	if downloadingSpeedLimit <= 0 {
+		downloadingSpeedLimit = constants.CLIENT_DEFAULT_DOWNLOADING_SPEED_LIMIT
+	}
+	var siteBudgets []*SiteBudget
+	for _, s := range sites {
+		siteBudget, siteCandidates, err := getSiteBudget(clientInstance, s.siteInstance, s.ignores, budget,
+			timestamp, downloadingSpeedLimit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get site %s dynamic seeding info: %w", s.siteInstance.GetName(), err)
+		}
+		siteBudgets = append(siteBudgets, siteBudget)
+		candidates = append(candidates, siteCandidates)
+	}
+	sizes = AllocateBudget(budget, siteBudgets)
+	fmt.Fprintf(os.Stderr, "Allocate shared dynamic seeding size %s of client:\n", util.BytesSize(float64(budget)))
+	for i, siteBudget := range siteBudgets {
+		fmt.Fprintf(os.Stderr, "%-15s  %s (yield %.2f/h/GiB, %d torrents)\n", siteBudget.Name,
+			util.BytesSize(float64(sizes[i])), siteBudget.Yield, len(siteBudget.Torrents))
+	}
+	return sizes, candidates, nil
+}
+
+// Apply dynamic seeding result of site to client. Return the count of errors.
+func (s *dynamicSeedingSite) apply(clientInstance client.Client, result *Result) (errorCnt int64) {
 	// add
 	addedSize := int64(0)
 	tags := result.AddTorrentsOption.Tags
@@ -101,7 +228,7 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 		if torrent == "" {
 			torrent = result.AddTorrents[0].DownloadUrl
 		}
-		if contents, _, _, err := siteInstance.DownloadTorrent(torrent); err != nil {
+		if contents, _, _, err := s.siteInstance.DownloadTorrent(torrent); err != nil {
 			log.Errorf("Failed to download site torrent %s", torrent)
 			errorCnt++
 		} else if tinfo, err := torrentutil.ParseTorrent(contents); err != nil {
@@ -117,6 +244,7 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 			}
 			result.AddTorrentsOption.Name = result.AddTorrents[0].Name
 			result.AddTorrentsOption.Tags = _tags
+			result.AddTorrentsOption.SavePath = result.AddTorrentsSavePaths[0]
 			meta := map[string]int64{}
 			if result.AddTorrents[0].Id != "" {
 				if id := util.ParseInt(result.AddTorrents[0].ID()); id != 0 {
@@ -131,41 +259,48 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 			}
 		}
 		result.AddTorrents = result.AddTorrents[1:]
+		result.AddTorrentsSavePaths = result.AddTorrentsSavePaths[1:]
 	}
 	// delete
 	deleteSize := int64(0)
 	var deleteInfoHashes []string
-	var deleteIds []string
+	deleteIds := map[string]string{} // info hash => site torrent id
 	log.Infof("Delete torrents:")
 	for len(result.DeleteTorrents) > 0 {
-		if deleteSize >= addedSize+result.OverflowSpace {
+		if deleteSize >= addedSize+result.OverflowSpace+result.PressureSpace {
 			break
 		}
 		deleteSize += result.DeleteTorrents[0].Size
 		if result.DeleteTorrents[0].Meta["id"] > 0 {
-			deleteIds = append(deleteIds, fmt.Sprint(result.DeleteTorrents[0].Meta["id"]))
+			deleteIds[result.DeleteTorrents[0].InfoHash] = fmt.Sprint(result.DeleteTorrents[0].Meta["id"])
 		}
 		deleteInfoHashes = append(deleteInfoHashes, result.DeleteTorrents[0].InfoHash)
 		log.Infof("Torrent %s (%s)", result.DeleteTorrents[0].Name, result.DeleteTorrents[0].InfoHash)
 		result.DeleteTorrents = result.DeleteTorrents[1:]
 	}
 	if len(deleteInfoHashes) > 0 {
-		err := client.DeleteTorrentsAuto(clientInstance, deleteInfoHashes)
-		log.Infof("Delete torrents result: %v", err)
+		skipped, err := client.DeleteTorrentsAuto(clientInstance, deleteInfoHashes, false)
+		log.Infof("Delete torrents result: error=%v, skipped (HnR unsatisfied)=%v", err, skipped)
 		if err != nil {
 			errorCnt++
-		} else if len(deleteIds) > 0 {
-			ignores = append(ignores, deleteIds...)
-			if len(ignores) > IGNORE_FILE_SIZE {
-				ignores = ignores[len(ignores)-IGNORE_FILE_SIZE:]
+		} else {
+			// Skipped (not deleted) torrents are not added to ignore list.
+			var ids []string
+			for _, infoHash := range deleteInfoHashes {
+				if deleteIds[infoHash] != "" && !slices.Contains(skipped, infoHash) {
+					ids = append(ids, deleteIds[infoHash])
+				}
+			}
+			if len(ids) > 0 {
+				ignores := append(s.ignores, ids...)
+				if len(ignores) > IGNORE_FILE_SIZE {
+					ignores = ignores[len(ignores)-IGNORE_FILE_SIZE:]
+				}
+				s.ignoreFile.Truncate(0)
+				s.ignoreFile.Seek(0, 0)
+				s.ignoreFile.WriteString(strings.Join(ignores, "\n"))
 			}
-			ignoreFile.Truncate(0)
-			ignoreFile.Seek(0, 0)
-			ignoreFile.WriteString(strings.Join(ignores, "\n"))
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return errorCnt
 }
@@ -49,15 +49,19 @@ const MIN_FREE_REMAINING_TIME = 3600 * 3
 const MAX_SCANNED_TORRENTS = 1000
 
 type Result struct {
-	OverflowSpace     int64 // If torrents current total size is over limit, the overflow size.
+	OverflowSpace int64 // If torrents current total size is over limit, the overflow size.
+	// Size of torrents deleted to free space of save paths that are below min free space.
+	PressureSpace     int64
 	Timestamp         int64
 	Sitename          string
 	Size              int64
 	DeleteTorrents    []*client.Torrent
 	AddTorrents       []*site.Torrent
 	AddTorrentsOption *client.TorrentOption
-	Msg               string
-	Log               string
+	// Save path of each AddTorrents, if dynamicSeedingSavePaths of client is set.
+	AddTorrentsSavePaths []string
+	Msg                  string
+	Log                  string
 }
 
 func (result *Result) Print(output io.Writer) {
@@ -75,14 +79,11 @@ func (result *Result) Print(output io.Writer) {
 	fmt.Fprintf(output, "\nLog:\n%s\n", result.Log)
 }
 
-func doDynamicSeeding(clientInstance client.Client, siteInstance site.Site, ignores []string) (
-	result *Result, err error) {
+// Decide dynamic seeding torrents of site in client, using at most size of space.
+// If candidates is not nil, use them as the candidate site torrents instead of scanning site.
+func doDynamicSeeding(clientInstance client.Client, siteInstance site.Site, ignores []string,
+	size int64, candidates []*site.Torrent) (result *Result, err error) {
 	timestamp := util.Now()
-	if siteInstance.GetSiteConfig().DynamicSeedingSizeValue <= MIN_SIZE {
-		return nil, fmt.Errorf("dynamicSeedingSize insufficient. Current value: %s. At least %s is required",
-			util.BytesSizeAround(float64(siteInstance.GetSiteConfig().DynamicSeedingSizeValue)),
-			util.BytesSizeAround(float64(MIN_SIZE)))
-	}
 	if siteInstance.GetSiteConfig().GlobalHnR {
 		return nil, fmt.Errorf("site that enforces global H&R policy is not supported at this time")
 	}
@@ -95,7 +96,7 @@ func doDynamicSeeding(clientInstance client.Client, siteInstance site.Site, igno
 	result = &Result{
 		Timestamp: timestamp,
 		Sitename:  siteInstance.GetName(),
-		Size:      siteInstance.GetSiteConfig().DynamicSeedingSizeValue,
+		Size:      size,
 		AddTorrentsOption: &client.TorrentOption{
 			Category: dynamicSeedingCat,
 			Tags:     []string{dynamicSeedingTag},
@@ -127,7 +128,6 @@ func doDynamicSeeding(clientInstance client.Client, siteInstance site.Site, igno
 	fmt.Fprintf(os.Stderr, "client category %q torrents:\n", dynamicSeedingCat)
 	client.PrintTorrents(os.Stderr, clientTorrents, "", 1, false)
 
-	var maxScan = util.FirstNonZeroIntegerArg(siteInstance.GetSiteConfig().DynamicSeedingMaxScan, MAX_SCANNED_TORRENTS)
 	var minSeeders = util.FirstNonZeroIntegerArg(siteInstance.GetSiteConfig().DynamicSeedingMinSeeders, MIN_SEEDERS)
 	var maxSeeders = util.FirstNonZeroIntegerArg(siteInstance.GetSiteConfig().DynamicSeedingMaxSeeders, MAX_SEEDERS)
 	var replaceSeeders = util.FirstNonZeroIntegerArg(siteInstance.GetSiteConfig().DynamicSeedingReplaceSeeders,
@@ -149,9 +149,33 @@ func doDynamicSeeding(clientInstance client.Client, siteInstance site.Site, igno
 	// Success: downloadingTorrents + protectedTorrents + unknownTorrents; will never be deleted.
 	// Fail: invalidTorrents + stalledTorrents + safeTorrents + normalTorrents; could be deleted.
 	var statistics = common.NewTorrentsStatistics()
+	// Dynamic seeding torrents of same content path (xseed) count only once; the duplicates are treated as others.
+	// A torrent that has other xseed torrents in client keeps files when deleted, so it frees no disk space.
+	duplicateTorrents := map[string]bool{}
+	contentPaths := map[string]bool{}
+	var dynamicSeedingTorrents []*client.Torrent
 	for _, torrent := range clientTorrents {
-		clientTorrentsMap[torrent.InfoHash] = torrent
 		if !torrent.HasTag(dynamicSeedingTag) {
+			continue
+		}
+		if torrent.ContentPath != "" && contentPaths[torrent.ContentPath] {
+			duplicateTorrents[torrent.InfoHash] = true
+			continue
+		}
+		contentPaths[torrent.ContentPath] = true
+		dynamicSeedingTorrents = append(dynamicSeedingTorrents, torrent)
+	}
+	_, torrentsXseed, err := client.FilterTorrentsXseed(clientInstance, dynamicSeedingTorrents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get xseed torrents of client: %w", err)
+	}
+	xseedTorrents := map[string]bool{}
+	for _, torrent := range torrentsXseed {
+		xseedTorrents[torrent.InfoHash] = true
+	}
+	for _, torrent := range clientTorrents {
+		clientTorrentsMap[torrent.InfoHash] = torrent
+		if !torrent.HasTag(dynamicSeedingTag) || duplicateTorrents[torrent.InfoHash] {
 			otherTorrents = append(otherTorrents, torrent.InfoHash)
 			continue
 		}
@@ -165,7 +189,7 @@ func doDynamicSeeding(clientInstance client.Client, siteInstance site.Site, igno
 				trackerStatus = TRACKER_OK
 			}
 		}
-		if torrent.HasTag(config.TORRENT_NODEL_TAG) {
+		if satisfied, _ := torrent.GetHnRStatus(timestamp); torrent.HasTag(config.TORRENT_NODEL_TAG) || !satisfied {
 			protectedTorrents = append(protectedTorrents, torrent.InfoHash)
 			statistics.UpdateClientTorrent(common.TORRENT_SUCCESS, torrent)
 		} else if !torrent.IsComplete() {
@@ -202,37 +226,167 @@ func doDynamicSeeding(clientInstance client.Client, siteInstance site.Site, igno
 		}
 	}
 	availableSlots := MAX_PARALLEL_DOWNLOAD - len(downloadingTorrents)
-	availableSpace := siteInstance.GetSiteConfig().DynamicSeedingSizeValue - statistics.SuccessSize
+	availableSpace := size - statistics.SuccessSize
 	if !clientStatus.NoDel {
 		availableSpace += statistics.FailureSize
 	}
-	if statistics.SuccessSize+statistics.FailureSize > siteInstance.GetSiteConfig().DynamicSeedingSizeValue {
-		result.OverflowSpace = statistics.SuccessSize + statistics.FailureSize -
-			siteInstance.GetSiteConfig().DynamicSeedingSizeValue
+	if statistics.SuccessSize+statistics.FailureSize > size {
+		result.OverflowSpace = statistics.SuccessSize + statistics.FailureSize - size
 	}
 	result.Log += fmt.Sprintf("Client torrents: others %d / invalid %d / stalled %d / downloading %d / safe %d "+
 		"/ normal %d / protected %d / unknown %d\n", len(otherTorrents), len(invalidTorrents), len(stalledTorrents),
 		len(downloadingTorrents), len(safeTorrents), len(normalTorrents), len(protectedTorrents), len(unknownTorrents))
+	if len(xseedTorrents) > 0 {
+		result.Log += fmt.Sprintf("Client torrents that have xseed torrents (files will be kept when deleted): %d\n",
+			len(xseedTorrents))
+	}
 	result.Log += fmt.Sprintf("CapSpace/ProtectedSize/NormalSize/AvailableSpace: %s / %s / %s /%s",
-		util.BytesSizeAround(float64(siteInstance.GetSiteConfig().DynamicSeedingSizeValue)),
+		util.BytesSizeAround(float64(size)),
 		util.BytesSizeAround(float64(statistics.SuccessSize)),
 		util.BytesSizeAround(float64(statistics.FailureSize)),
 		util.BytesSizeAround(float64(availableSpace)))
 
+	var savePaths []*common.SavePathSpace
+	if savePathConfigs := clientInstance.GetClientConfig().DynamicSeedingSavePaths; len(savePathConfigs) > 0 {
+		savePaths = common.GetSavePathsSpace(clientInstance, savePathConfigs)
+	}
+	// free space of save paths that are below min free space, by deleting torrents that could be deleted.
+	pressureFreedSpace := int64(0)
+	for i, savePath := range savePaths {
+		if clientStatus.NoDel || savePath.FreeSpace < 0 || savePath.FreeSpace >= savePath.MinFreeSpace {
+			continue
+		}
+		freedSpace := int64(0)
+		for _, list := range []*[]string{&invalidTorrents, &stalledTorrents, &safeTorrents} {
+			var remaining []string
+			for _, infoHash := range *list {
+				torrent := clientTorrentsMap[infoHash]
+				if savePath.FreeSpace+freedSpace >= savePath.MinFreeSpace || xseedTorrents[infoHash] ||
+					common.MatchSavePath(savePaths, torrent.SavePath) != i {
+					remaining = append(remaining, infoHash)
+					continue
+				}
+				freedSpace += torrent.Size
+				result.DeleteTorrents = append(result.DeleteTorrents, torrent)
+				result.Log += fmt.Sprintf("Delete client torrent %s due to insufficient free space of save path %s\n",
+					torrent.Name, savePath.Path)
+			}
+			*list = remaining
+		}
+		savePath.FreeSpace += freedSpace
+		pressureFreedSpace += freedSpace
+	}
+	result.PressureSpace = pressureFreedSpace
+
 	if len(downloadingTorrents) >= MAX_PARALLEL_DOWNLOAD {
 		result.Msg = "Already currently downloading enough torrents. Exit"
 		return
 	}
-	if availableSpace < min(siteInstance.GetSiteConfig().DynamicSeedingSizeValue/10, MIN_SIZE) {
+	if availableSpace < min(size/10, MIN_SIZE) {
 		result.Msg = "Insufficient dynamic seeding storage space in client. Exit"
 		return
 	}
+	var siteTorrents []*site.Torrent
+	if candidates != nil {
+		var siteTorrentsSize int64
+		for _, torrent := range candidates {
+			if torrent.Size <= availableSpace-siteTorrentsSize {
+				siteTorrents = append(siteTorrents, torrent)
+				siteTorrentsSize += torrent.Size
+			}
+		}
+	} else {
+		siteTorrents, err = scanSiteTorrents(siteInstance, ignores, timestamp, downloadingSpeedLimit,
+			availableSpace, availableSlots)
+		if err != nil {
+			result.Log += fmt.Sprintf("failed to get site torrents: %v\n", err)
+		}
+	}
+	if len(siteTorrents) == 0 {
+		result.Msg = "No candidate site dynamic seeding torrents found"
+		return
+	}
+	fmt.Fprintf(os.Stderr, "site candidate torrents:\n")
+	site.PrintTorrents(os.Stderr, siteTorrents, "", timestamp, false, false, nil)
+
+	availableSpace = size - statistics.SuccessSize - statistics.FailureSize + pressureFreedSpace
+	for _, torrent := range siteTorrents {
+		var deleteTorrents []string
+		var log string
+		if !clientStatus.NoDel {
+			for availableSpace < torrent.Size {
+				if len(invalidTorrents) > 0 {
+					availableSpace += clientTorrentsMap[invalidTorrents[0]].Size
+					log += fmt.Sprintf("Delete client invalid torrent %s\n", clientTorrentsMap[invalidTorrents[0]].Name)
+					deleteTorrents = append(deleteTorrents, invalidTorrents[0])
+					invalidTorrents = invalidTorrents[1:]
+				} else if len(stalledTorrents) > 0 {
+					availableSpace += clientTorrentsMap[stalledTorrents[0]].Size
+					log += fmt.Sprintf("Delete client stalled torrent %s\n", clientTorrentsMap[stalledTorrents[0]].Name)
+					deleteTorrents = append(deleteTorrents, stalledTorrents[0])
+					stalledTorrents = stalledTorrents[1:]
+				} else if len(safeTorrents) > 0 {
+					availableSpace += clientTorrentsMap[safeTorrents[0]].Size
+					log += fmt.Sprintf("Delete safe safe torrent %s\n", clientTorrentsMap[safeTorrents[0]].Name)
+					deleteTorrents = append(deleteTorrents, safeTorrents[0])
+					safeTorrents = safeTorrents[1:]
+				} else if len(normalTorrents) > 0 &&
+					clientTorrentsMap[normalTorrents[0]].Seeders-torrent.Seeders >= replaceSeeders {
+					availableSpace += clientTorrentsMap[normalTorrents[0]].Size
+					log += fmt.Sprintf("Delete client normal torrent %s\n", clientTorrentsMap[normalTorrents[0]].Name)
+					deleteTorrents = append(deleteTorrents, normalTorrents[0])
+					normalTorrents = normalTorrents[1:]
+				} else {
+					break
+				}
+			}
+		}
+		if availableSpace < torrent.Size {
+			break
+		}
+		savePath := ""
+		if len(savePaths) > 0 {
+			for _, infoHash := range deleteTorrents {
+				if xseedTorrents[infoHash] {
+					continue
+				}
+				if i := common.MatchSavePath(savePaths, clientTorrentsMap[infoHash].SavePath); i != -1 &&
+					savePaths[i].FreeSpace >= 0 {
+					savePaths[i].FreeSpace += clientTorrentsMap[infoHash].Size
+				}
+			}
+			i := common.PickSavePath(savePaths, torrent.Size)
+			if i == -1 {
+				result.Log += fmt.Sprintf("No save path has enough free space for site torrent %s\n", torrent.Name)
+				break
+			}
+			savePath = savePaths[i].Path
+		}
+		availableSpace -= torrent.Size
+		result.AddTorrents = append(result.AddTorrents, torrent)
+		result.AddTorrentsSavePaths = append(result.AddTorrentsSavePaths, savePath)
+		result.Log += log
+		result.Log += fmt.Sprintf("Add site torrent %s\n", torrent.Name)
+		for _, torrent := range deleteTorrents {
+			result.DeleteTorrents = append(result.DeleteTorrents, clientTorrentsMap[torrent])
+		}
+	}
+
+	return
+}
+
+// Scan site for candidate dynamic seeding torrents, in ascending order of seeders.
+// A torrent is a candidate only if it fits in availableSpace along with previous candidates.
+// Stop scanning once at least availableSlots candidates are found.
+func scanSiteTorrents(siteInstance site.Site, ignores []string, timestamp int64, downloadingSpeedLimit int64,
+	availableSpace int64, availableSlots int) (siteTorrents []*site.Torrent, err error) {
+	var maxScan = util.FirstNonZeroIntegerArg(siteInstance.GetSiteConfig().DynamicSeedingMaxScan, MAX_SCANNED_TORRENTS)
+	var maxSeeders = util.FirstNonZeroIntegerArg(siteInstance.GetSiteConfig().DynamicSeedingMaxSeeders, MAX_SEEDERS)
 	dynamicSeedingUrl := siteInstance.GetSiteConfig().DynamicSeedingTorrentsUrl
 	if siteInstance.GetSiteConfig().Type == "nexusphp" {
 		// See https://github.com/xiaomlove/nexusphp/blob/php8/public/torrents.php .
 		dynamicSeedingUrl = util.AppendUrlQueryString(dynamicSeedingUrl, "seeders_begin=1")
 	}
-	var siteTorrents []*site.Torrent
 	var siteTorrentsSize int64
 	var scannedTorrents int64
 	marker := ""
@@ -240,8 +394,7 @@ site_outer:
 	for {
 		torrents, nextPageMarker, err := siteInstance.GetAllTorrents("seeders", false, marker, dynamicSeedingUrl)
 		if err != nil {
-			result.Log += fmt.Sprintf("failed to get site torrents: %v\n", err)
-			break
+			return siteTorrents, err
 		}
 		rand.Shuffle(len(torrents), func(i, j int) { torrents[i], torrents[j] = torrents[j], torrents[i] })
 		slices.SortStableFunc(torrents, func(a, b *site.Torrent) int { return int(a.Seeders - b.Seeders) })
@@ -290,57 +443,5 @@ site_outer:
 		}
 		marker = nextPageMarker
 	}
-	if len(siteTorrents) == 0 {
-		result.Msg = "No candidate site dynamic seeding torrents found"
-		return
-	}
-	fmt.Fprintf(os.Stderr, "site candidate torrents:\n")
-	site.PrintTorrents(os.Stderr, siteTorrents, "", timestamp, false, false, nil)
-
-	availableSpace = siteInstance.GetSiteConfig().DynamicSeedingSizeValue -
-		statistics.SuccessSize - statistics.FailureSize
-	for _, torrent := range siteTorrents {
-		var deleteTorrents []string
-		var log string
-		if !clientStatus.NoDel {
-			for availableSpace < torrent.Size {
-				if len(invalidTorrents) > 0 {
-					availableSpace += clientTorrentsMap[invalidTorrents[0]].Size
-					log += fmt.Sprintf("Delete client invalid torrent %s\n", clientTorrentsMap[invalidTorrents[0]].Name)
-					deleteTorrents = append(deleteTorrents, invalidTorrents[0])
-					invalidTorrents = invalidTorrents[1:]
-				} else if len(stalledTorrents) > 0 {
-					availableSpace += clientTorrentsMap[stalledTorrents[0]].Size
-					log += fmt.Sprintf("Delete client stalled torrent %s\n", clientTorrentsMap[stalledTorrents[0]].Name)
-					deleteTorrents = append(deleteTorrents, stalledTorrents[0])
-					stalledTorrents = stalledTorrents[1:]
-				} else if len(safeTorrents) > 0 {
-					availableSpace += clientTorrentsMap[safeTorrents[0]].Size
-					log += fmt.Sprintf("Delete safe safe torrent %s\n", clientTorrentsMap[safeTorrents[0]].Name)
-					deleteTorrents = append(deleteTorrents, safeTorrents[0])
-					safeTorrents = safeTorrents[1:]
-				} else if len(normalTorrents) > 0 &&
-					clientTorrentsMap[normalTorrents[0]].Seeders-torrent.Seeders >= replaceSeeders {
-					availableSpace += clientTorrentsMap[normalTorrents[0]].Size
-					log += fmt.Sprintf("Delete client normal torrent %s\n", clientTorrentsMap[normalTorrents[0]].Name)
-					deleteTorrents = append(deleteTorrents, normalTorrents[0])
-					normalTorrents = normalTorrents[1:]
-				} else {
-					break
-				}
-			}
-		}
-		if availableSpace < torrent.Size {
-			break
-		}
-		availableSpace -= torrent.Size
-		result.AddTorrents = append(result.AddTorrents, torrent)
-		result.Log += log
-		result.Log += fmt.Sprintf("Add site torrent %s\n", torrent.Name)
-		for _, torrent := range deleteTorrents {
-			result.DeleteTorrents = append(result.DeleteTorrents, clientTorrentsMap[torrent])
-		}
-	}
-
-	return
+	return siteTorrents, nil
 }
@@ -19,10 +19,8 @@ func init() {
 		switch info.LastArgIndex {
 		case 1:
 			return suggest.ClientArg(info.MatchingPrefix)
-		case 2:
-			return suggest.SiteArg(info.MatchingPrefix)
 		default:
-			return nil
+			return suggest.SiteOrGroupArg(info.MatchingPrefix)
 		}
 	})
 }
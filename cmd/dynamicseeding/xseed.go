@@ -0,0 +1,198 @@
+package dynamicseeding
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/iyuu"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/notify"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/site/tpl"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// Parsed .torrent files of xseedDir, lazy loaded.
+var localXseedTorrents []*localXseedTorrent
+
+type localXseedTorrent struct {
+	filename string
+	contents []byte
+	tinfo    *torrentutil.TorrentMeta
+}
+
+// Xseed the newly completed dynamic seeding torrents of site in client.
+// A torrent is xseeded only once, the xseeded torrents are recorded in the xseed file of site.
+// Return the count of errors.
+func (s *dynamicSeedingSite) xseed(clientInstance client.Client) (errorCnt int64) {
+	sitename := s.siteInstance.GetName()
+	clientTorrents, err := clientInstance.GetTorrents("", config.DYNAMIC_SEEDING_CAT_PREFIX+sitename, true)
+	if err != nil {
+		log.Errorf("Failed to get client dynamic seeding torrents of site %s: %v", sitename, err)
+		return 1
+	}
+	dynamicSeedingTag := client.GenerateTorrentTagFromSite(sitename)
+	var xseeded []string
+	var targetTorrents []*client.Torrent
+	for _, torrent := range clientTorrents {
+		if !torrent.HasTag(dynamicSeedingTag) {
+			continue
+		}
+		if slices.Contains(s.xseeded, torrent.InfoHash) {
+			xseeded = append(xseeded, torrent.InfoHash)
+		} else if torrent.State == "seeding" && torrent.IsFullComplete() && !torrent.HasTag(config.NOXSEED_TAG) {
+			targetTorrents = append(targetTorrents, torrent)
+		}
+	}
+	if len(targetTorrents) > 0 {
+		var cnt int64
+		if xseedMode == "iyuu" {
+			cnt, err = iyuuXseed(clientInstance, targetTorrents)
+		} else {
+			cnt, err = localXseed(clientInstance, targetTorrents)
+		}
+		if err != nil {
+			log.Errorf("Failed to xseed dynamic seeding torrents of site %s: %v", sitename, err)
+			errorCnt++
+		} else {
+			xseeded = append(xseeded, util.Map(targetTorrents, func(t *client.Torrent) string { return t.InfoHash })...)
+		}
+		fmt.Printf("Xseed %d newly completed dynamic seeding torrents of site %s: added %d xseed torrents\n",
+			len(targetTorrents), sitename, cnt)
+	}
+	s.xseedFile.Truncate(0)
+	s.xseedFile.Seek(0, 0)
+	s.xseedFile.WriteString(strings.Join(xseeded, "\n"))
+	return errorCnt
+}
+
+// Find xseed torrents of targetTorrents using iyuu and add them to client. Return the count of added torrents.
+func iyuuXseed(clientInstance client.Client, targetTorrents []*client.Torrent) (cnt int64, err error) {
+	if config.Get().IyuuToken == "" {
+		return 0, fmt.Errorf("you must config iyuuToken in ptool.toml to use iyuu functions")
+	}
+	infoHashes := util.Map(targetTorrents, func(t *client.Torrent) string { return t.InfoHash })
+	if err = iyuu.UpdateDatabase(config.Get().IyuuToken, infoHashes); err != nil {
+		return 0, err
+	}
+	var iyuuSites []iyuu.Site
+	var iyuuTorrents []*iyuu.Torrent
+	iyuu.Db().Find(&iyuuSites)
+	iyuu.Db().Where("target_info_hash in ?", infoHashes).Find(&iyuuTorrents)
+	site2LocalMap := iyuu.GenerateIyuu2LocalSiteMap(iyuuSites, config.Get().SitesEnabled)
+	siteInstancesMap := map[string]site.Site{}
+	for _, iyuuTorrent := range iyuuTorrents {
+		sitename := site2LocalMap[iyuuTorrent.Sid]
+		if sitename == "" {
+			continue
+		}
+		if t, _ := clientInstance.GetTorrent(iyuuTorrent.InfoHash); t != nil {
+			continue
+		}
+		if siteInstancesMap[sitename] == nil {
+			siteInstance, err := site.CreateSite(sitename)
+			if err != nil {
+				log.Errorf("Failed to create site %s: %v", sitename, err)
+				continue
+			}
+			siteInstancesMap[sitename] = siteInstance
+		}
+		contents, _, err := siteInstancesMap[sitename].DownloadTorrentById(fmt.Sprint(iyuuTorrent.Tid))
+		if err != nil {
+			log.Errorf("Failed to download site %s torrent %d: %v", sitename, iyuuTorrent.Tid, err)
+			continue
+		}
+		tinfo, err := torrentutil.ParseTorrent(contents)
+		if err != nil {
+			log.Errorf("Failed to parse site %s torrent %d: %v", sitename, iyuuTorrent.Tid, err)
+			continue
+		}
+		targetTorrent := targetTorrents[slices.Index(infoHashes, iyuuTorrent.TargetInfoHash)]
+		if addXseedTorrent(clientInstance, targetTorrent, contents, tinfo, sitename) {
+			cnt++
+		}
+	}
+	return cnt, nil
+}
+
+// Find xseed torrents of targetTorrents from .torrent files of xseedDir and add them to client.
+// Return the count of added torrents.
+func localXseed(clientInstance client.Client, targetTorrents []*client.Torrent) (cnt int64, err error) {
+	if localXseedTorrents == nil {
+		filenames, err := filepath.Glob(filepath.Join(xseedDir, "*.torrent"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read xseed dir: %w", err)
+		}
+		localXseedTorrents = []*localXseedTorrent{}
+		for _, filename := range filenames {
+			contents, err := os.ReadFile(filename)
+			if err != nil {
+				log.Debugf("Failed to read %s: %v", filename, err)
+				continue
+			}
+			tinfo, err := torrentutil.ParseTorrent(contents)
+			if err != nil {
+				log.Debugf("Failed to parse %s: %v", filename, err)
+				continue
+			}
+			localXseedTorrents = append(localXseedTorrents, &localXseedTorrent{filename, contents, tinfo})
+		}
+	}
+	index := common.NewXseedIndex(clientInstance, targetTorrents)
+	for _, xseedTorrent := range localXseedTorrents {
+		if t, _ := clientInstance.GetTorrent(xseedTorrent.tinfo.InfoHash); t != nil {
+			continue
+		}
+		match := index.Match(xseedTorrent.tinfo)
+		if match == nil || match.Result < 0 {
+			continue
+		}
+		sitename, err := tpl.GuessSiteByTrackers(xseedTorrent.tinfo.Trackers, "")
+		if err != nil {
+			log.Debugf("Failed to find match site for %s by trackers: %v", xseedTorrent.filename, err)
+		}
+		if addXseedTorrent(clientInstance, match.Target, xseedTorrent.contents, xseedTorrent.tinfo, sitename) {
+			cnt++
+		}
+	}
+	return cnt, nil
+}
+
+// Add torrent (contents) to client as xseed torrent of targetTorrent if they have the same contents.
+// Return true if it's added.
+func addXseedTorrent(clientInstance client.Client, targetTorrent *client.Torrent, contents []byte,
+	tinfo *torrentutil.TorrentMeta, sitename string) bool {
+	targetTorrentContents, err := clientInstance.GetTorrentContents(targetTorrent.InfoHash)
+	if err != nil {
+		log.Debugf("Failed to get client torrent %s contents: %v", targetTorrent.InfoHash, err)
+		return false
+	}
+	if tinfo.XseedCheckWithClientTorrent(targetTorrentContents) < 0 {
+		log.Tracef("Xseed candidate %s is NOT identital with client torrent %s", tinfo.InfoHash, targetTorrent.InfoHash)
+		return false
+	}
+	err = common.AddXseedTorrent(clientInstance, contents, tinfo, targetTorrent, sitename,
+		&client.TorrentOption{SkipChecking: true})
+	log.Infof("Add xseed torrent %s (target %s) result: error=%v", tinfo.InfoHash, targetTorrent.Name, err)
+	if err != nil {
+		return false
+	}
+	notify.Publish(&notify.Event{
+		Type:   notify.EVENT_XSEED_ADD,
+		Site:   sitename,
+		Client: clientInstance.GetName(),
+		Title: fmt.Sprintf("Added site %s xseed torrent of %s to client %s",
+			sitename, targetTorrent.Name, clientInstance.GetName()),
+		Data: map[string]any{"infoHash": tinfo.InfoHash, "targetInfoHash": targetTorrent.InfoHash,
+			"name": targetTorrent.Name},
+	})
+	return true
+}
@@ -0,0 +1,142 @@
+package hnr
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "hnr {client} [--all] [--json]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "hnr"},
+	Short:       "Show HnR (Hit and Run) torrents of client that are not satisfied yet.",
+	Long: `Show HnR (Hit and Run) torrents of client that are not satisfied yet.
+
+HnR torrents are the ones added by ptool (add, batchdl, xseed, etc) with "_hr" tag, which are the torrents
+that have HnR flag in site, or of sites with "globalHnR" config. The HnR requirements of each torrent
+(seeding time and / or ratio) are recorded as "meta.hnrst" & "meta.hnrr" tags when added,
+using the "hnrSeedingTime" (default 72h) & "hnrRatio" configs of site.
+
+A torrent satisfies the requirements if it has been seeded (since completed) for the required time,
+or it's uploaded / size ratio reaches the required ratio (if set).
+Incomplete torrents that have downloaded some data are unsatisfied, the remaining seeding time is the full
+required seeding time, as the seeding obligation starts when completed.
+Unsatisfied HnR torrents are protected from "ptool delete" and the deletion of brush / dynamicseeding.
+
+It displays the seeded time, ratio, requirements and remaining seeding time of each torrent,
+sorted by remaining seeding time in asc order. If "--all" flag is set, satisfied HnR torrents
+are also displayed.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: hnr,
+}
+
+var (
+	showAll  = false
+	showJson = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&showAll, "all", "a", false,
+		"Show all HnR torrents, including satisfied ones")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	cmd.RootCmd.AddCommand(command)
+}
+
+type HnRTorrent struct {
+	InfoHash    string  `json:"infoHash"`
+	Name        string  `json:"name"`
+	Site        string  `json:"site"`
+	State       string  `json:"state"`
+	Seeded      int64   `json:"seeded"` // seeding time (seconds) since completed
+	Ratio       float64 `json:"ratio"`  // uploaded / size
+	SeedingTime int64   `json:"seedingTime"`
+	MinRatio    float64 `json:"minRatio"`
+	Satisfied   bool    `json:"satisfied"`
+	Incomplete  bool    `json:"incomplete"` // not completed yet, seeding time is not started
+	Remaining   int64   `json:"remaining"`  // remaining seeding time (seconds)
+}
+
+func hnr(cmd *cobra.Command, args []string) error {
+	clientInstance, err := client.CreateClient(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return fmt.Errorf("failed to get client torrents: %w", err)
+	}
+	now := util.Now()
+	hnrTorrents := []*HnRTorrent{}
+	for _, torrent := range torrents {
+		hnr, seedingTime, ratio := torrent.GetHnRRequirements()
+		if !hnr {
+			continue
+		}
+		satisfied, remaining := torrent.GetHnRStatus(now)
+		if satisfied && !showAll {
+			continue
+		}
+		hnrTorrent := &HnRTorrent{
+			InfoHash:    torrent.InfoHash,
+			Name:        torrent.Name,
+			Site:        torrent.GetSiteFromTag(),
+			State:       torrent.State,
+			SeedingTime: seedingTime,
+			MinRatio:    ratio,
+			Satisfied:   satisfied,
+			Incomplete:  torrent.Ctime <= 0,
+			Remaining:   remaining,
+		}
+		if torrent.Ctime > 0 {
+			hnrTorrent.Seeded = now - torrent.Ctime
+		}
+		if torrent.Size > 0 {
+			hnrTorrent.Ratio = float64(torrent.Uploaded) / float64(torrent.Size)
+		}
+		hnrTorrents = append(hnrTorrents, hnrTorrent)
+	}
+	slices.SortStableFunc(hnrTorrents, func(a, b *HnRTorrent) int {
+		return int(a.Remaining - b.Remaining)
+	})
+	if showJson {
+		return util.PrintJson(os.Stdout, hnrTorrents)
+	}
+	fmt.Printf("%-40s  %-40s  %-10s  %-11s  %-10s  %-5s  %-16s  %s\n",
+		"Name", "InfoHash", "Site", "State", "Seeded", "Ratio", "Requirements", "Remaining")
+	for _, torrent := range hnrTorrents {
+		requirements := durationString(torrent.SeedingTime)
+		if torrent.MinRatio > 0 {
+			requirements += fmt.Sprintf(" | %.2f", torrent.MinRatio)
+		}
+		remaining := "✓"
+		if !torrent.Satisfied {
+			remaining = durationString(torrent.Remaining)
+			if torrent.Incomplete {
+				remaining += " (incomplete)"
+			}
+		}
+		util.PrintStringInWidth(os.Stdout, torrent.Name, 40, true)
+		fmt.Printf("  %-40s  %-10s  %-11s  %-10s  %-5.2f  %-16s  %s\n", torrent.InfoHash, torrent.Site,
+			torrent.State, durationString(torrent.Seeded), torrent.Ratio,
+			requirements, remaining)
+	}
+	fmt.Printf("\n// Total %d torrents\n", len(hnrTorrents))
+	return nil
+}
+
+// Return duration string in minutes precision, or "-" if duration is 0.
+func durationString(seconds int64) string {
+	if seconds < 60 {
+		if seconds > 0 {
+			return "<1m"
+		}
+		return "-"
+	}
+	return util.GetDurationString(seconds / 60 * 60)
+}
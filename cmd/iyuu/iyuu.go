@@ -9,6 +9,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/config"
@@ -84,3 +85,78 @@ func (iyuuSite *Site) MatchFilter(filter string) bool {
 		util.ContainsI(iyuuSite.Url, filter) ||
 		fmt.Sprint(iyuuSite.Sid) == filter
 }
+
+// Query iyuu server for xseed info of torrents of infoHashes and update local iyuu db.
+func UpdateDatabase(token string, allInfoHashes []string) error {
+	log.Debugf("Querying iyuu server for xseed info of %d torrents.", len(allInfoHashes))
+
+	// update sites
+	iyuuSites, err := IyuuApiSites(token)
+	if err != nil {
+		log.Errorf("failed to get iyuu sites: %v", err)
+	} else {
+		Db().Transaction(func(tx *gorm.DB) error {
+			tx.Where("1 = 1").Delete(&Site{})
+			iyuuSiteRecords := util.Map(iyuuSites, func(iyuuSite *IyuuApiSite) Site {
+				return Site{
+					Sid:          iyuuSite.Id,
+					Name:         iyuuSite.Site,
+					Nickname:     iyuuSite.Nickname,
+					Url:          iyuuSite.GetUrl(),
+					DownloadPage: iyuuSite.Download_page,
+				}
+			})
+			tx.Create(&iyuuSiteRecords)
+			return nil
+		})
+	}
+
+	// report existing sites
+	sid_sha1, err := IyuuApiReportExisting(token, iyuuSites)
+	if err != nil {
+		log.Errorf("failed to report existing sites: %v", err)
+	}
+
+	for len(allInfoHashes) > 0 {
+		number := min(len(allInfoHashes), MAX_INTOHASH_NUMBER)
+		infoHashes := allInfoHashes[:number]
+		allInfoHashes = allInfoHashes[number:]
+
+		// update xseed torrents data
+		data, err := IyuuApiHash(token, infoHashes, sid_sha1)
+		if err != nil {
+			log.Errorf("iyuu apiHash error: %v", err)
+		} else {
+			log.Debugf("iyuu data len(data)=%d\n", len(data))
+			Db().Transaction(func(tx *gorm.DB) error {
+				for targetInfoHash, iyuuRecords := range data {
+					tx.Where("target_info_hash = ?", targetInfoHash).Delete(&Torrent{})
+					infoHashes := util.Map(iyuuRecords, func(record IyuuTorrentInfoHash) string {
+						return record.Info_hash
+					})
+					tx.Where("info_hash in ?", infoHashes).Delete(&Torrent{})
+					iyuuTorrents := util.Map(iyuuRecords, func(iyuuRecord IyuuTorrentInfoHash) Torrent {
+						return Torrent{
+							InfoHash:       iyuuRecord.Info_hash,
+							Sid:            iyuuRecord.Sid,
+							Tid:            iyuuRecord.Torrent_id,
+							TargetInfoHash: targetInfoHash,
+						}
+					})
+					tx.Create(&iyuuTorrents)
+				}
+
+				tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "key"}},
+					DoUpdates: clause.AssignmentColumns([]string{"value"}),
+				}).Create(&Meta{
+					Key:   "lastUpdateTime",
+					Value: fmt.Sprint(util.Now()),
+				})
+				return nil
+			})
+		}
+	}
+
+	return nil
+}
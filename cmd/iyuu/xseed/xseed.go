@@ -8,8 +8,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
@@ -17,6 +15,7 @@ import (
 	"github.com/sagan/ptool/cmd/iyuu"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/notify"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/torrentutil"
@@ -224,7 +223,7 @@ func xseed(cmd *cobra.Command, args []string) error {
 		doRequestServer = true
 	}
 	if doRequestServer {
-		updateIyuuDatabase(config.Get().IyuuToken, reqInfoHashes)
+		iyuu.UpdateDatabase(config.Get().IyuuToken, reqInfoHashes)
 	}
 
 	var sites []iyuu.Site
@@ -374,6 +373,9 @@ mainloop:
 				}
 				tags := []string{config.XSEED_TAG, client.GenerateTorrentTagFromSite(sitename)}
 				tags = append(tags, fixedTags...)
+				if siteInstance.GetSiteConfig().GlobalHnR {
+					tags = append(tags, client.GenerateTorrentTagsFromHnR(siteInstance.GetSiteConfig())...)
+				}
 				ratioLimit := float64(0)
 				if xseedTorrentInfo.IsPrivate() {
 					tags = append(tags, config.PRIVATE_TAG)
@@ -392,6 +394,15 @@ mainloop:
 				log.Infof("Add xseed torrent %s result: error=%v", xseedTorrent.InfoHash, err)
 				if err == nil {
 					cntSucccessXseedTorrents++
+					notify.Publish(&notify.Event{
+						Type:   notify.EVENT_XSEED_ADD,
+						Site:   sitename,
+						Client: clientInstance.GetName(),
+						Title: fmt.Sprintf("Added site %s xseed torrent of %s to client %s",
+							sitename, targetTorrent.Name, clientInstance.GetName()),
+						Data: map[string]any{"infoHash": xseedTorrent.InfoHash, "id": fmt.Sprint(xseedTorrent.Tid),
+							"targetInfoHash": targetTorrent.InfoHash, "name": targetTorrent.Name},
+					})
 				}
 				if maxXseedTorrents >= 0 && cntXseedTorrents >= maxXseedTorrents {
 					break mainloop
@@ -403,77 +414,3 @@ mainloop:
 		len(clientNames), cntTargetTorrents, cntXseedTorrents, cntSucccessXseedTorrents)
 	return nil
 }
-
-func updateIyuuDatabase(token string, allInfoHashes []string) error {
-	log.Debugf("Querying iyuu server for xseed info of %d torrents.", len(allInfoHashes))
-
-	// update sites
-	iyuuSites, err := iyuu.IyuuApiSites(token)
-	if err != nil {
-		log.Errorf("failed to get iyuu sites: %v", err)
-	} else {
-		iyuu.Db().Transaction(func(tx *gorm.DB) error {
-			tx.Where("1 = 1").Delete(&iyuu.Site{})
-			iyuuSiteRecords := util.Map(iyuuSites, func(iyuuSite *iyuu.IyuuApiSite) iyuu.Site {
-				return iyuu.Site{
-					Sid:          iyuuSite.Id,
-					Name:         iyuuSite.Site,
-					Nickname:     iyuuSite.Nickname,
-					Url:          iyuuSite.GetUrl(),
-					DownloadPage: iyuuSite.Download_page,
-				}
-			})
-			tx.Create(&iyuuSiteRecords)
-			return nil
-		})
-	}
-
-	// report existing sites
-	sid_sha1, err := iyuu.IyuuApiReportExisting(token, iyuuSites)
-	if err != nil {
-		log.Errorf("failed to report existing sites: %v", err)
-	}
-
-	for len(allInfoHashes) > 0 {
-		number := min(len(allInfoHashes), iyuu.MAX_INTOHASH_NUMBER)
-		infoHashes := allInfoHashes[:number]
-		allInfoHashes = allInfoHashes[number:]
-
-		// update xseed torrents data
-		data, err := iyuu.IyuuApiHash(token, infoHashes, sid_sha1)
-		if err != nil {
-			log.Errorf("iyuu apiHash error: %v", err)
-		} else {
-			log.Debugf("iyuu data len(data)=%d\n", len(data))
-			iyuu.Db().Transaction(func(tx *gorm.DB) error {
-				for targetInfoHash, iyuuRecords := range data {
-					tx.Where("target_info_hash = ?", targetInfoHash).Delete(&iyuu.Torrent{})
-					infoHashes := util.Map(iyuuRecords, func(record iyuu.IyuuTorrentInfoHash) string {
-						return record.Info_hash
-					})
-					tx.Where("info_hash in ?", infoHashes).Delete(&iyuu.Torrent{})
-					iyuuTorrents := util.Map(iyuuRecords, func(iyuuRecord iyuu.IyuuTorrentInfoHash) iyuu.Torrent {
-						return iyuu.Torrent{
-							InfoHash:       iyuuRecord.Info_hash,
-							Sid:            iyuuRecord.Sid,
-							Tid:            iyuuRecord.Torrent_id,
-							TargetInfoHash: targetInfoHash,
-						}
-					})
-					tx.Create(&iyuuTorrents)
-				}
-
-				tx.Clauses(clause.OnConflict{
-					Columns:   []clause.Column{{Name: "key"}},
-					DoUpdates: clause.AssignmentColumns([]string{"value"}),
-				}).Create(&iyuu.Meta{
-					Key:   "lastUpdateTime",
-					Value: fmt.Sprint(util.Now()),
-				})
-				return nil
-			})
-		}
-	}
-
-	return nil
-}
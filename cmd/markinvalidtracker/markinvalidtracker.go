@@ -12,6 +12,7 @@ import (
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/notify"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -90,6 +91,7 @@ func markinvalidtracker(cmd *cobra.Command, args []string) error {
 	}
 	errorCnt := int64(0)
 	infoHashes = nil
+	var newNotExistTorrents []*client.Torrent
 	for _, torrent := range torrents {
 		log.Debugf("Check %s (%s) trackers status...", torrent.InfoHash, torrent.Name)
 		trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
@@ -105,6 +107,11 @@ func markinvalidtracker(cmd *cobra.Command, args []string) error {
 		log.Warnf("torrent %s (%s)'s trackers seems invalid (%s): %v\n",
 			torrent.InfoHash, torrent.Name, client.TrackerValidityInfos[validity].Name, trackers)
 		invalidTorrents[validity] = append(invalidTorrents[validity], torrent.InfoHash)
+		// Only notify newly found ones, which are not marked by previous runs.
+		if validity == client.TRACKER_VALIDITY_NOT_EXIST &&
+			!torrent.HasTag(tagPrefix+client.TrackerValidityInfos[validity].Name) {
+			newNotExistTorrents = append(newNotExistTorrents, torrent)
+		}
 	}
 
 	tags := []string{}
@@ -129,6 +136,16 @@ func markinvalidtracker(cmd *cobra.Command, args []string) error {
 		}
 		fmt.Printf("Found %d torrents with invalid tracker, marked them with %q tag\n", len(infoHashes), tag)
 	}
+	for _, torrent := range newNotExistTorrents {
+		notify.Publish(&notify.Event{
+			Type:   notify.EVENT_TORRENT_NOT_EXIST,
+			Site:   torrent.GetSiteFromTag(),
+			Client: clientInstance.GetName(),
+			Title: fmt.Sprintf("Client %s torrent %s is not registered in tracker %s",
+				clientInstance.GetName(), torrent.Name, torrent.TrackerDomain),
+			Data: map[string]any{"infoHash": torrent.InfoHash, "name": torrent.Name, "tracker": torrent.Tracker},
+		})
+	}
 
 	if errorCnt > 0 {
 		return fmt.Errorf("%d errors", errorCnt)
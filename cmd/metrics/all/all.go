@@ -0,0 +1,6 @@
+package all
+
+import (
+	_ "github.com/sagan/ptool/cmd/metrics"
+	_ "github.com/sagan/ptool/cmd/metrics/serve"
+)
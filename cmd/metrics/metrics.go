@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+)
+
+var Command = &cobra.Command{
+	Use:   "metrics",
+	Short: "Export clients and sites metrics.",
+	Long:  `Export clients and sites metrics.`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Command)
+}
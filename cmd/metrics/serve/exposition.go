@@ -0,0 +1,88 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	GAUGE   = "gauge"
+	COUNTER = "counter"
+)
+
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	typ     string
+	samples []*sample
+}
+
+// A set of metric families, written in Prometheus text exposition format.
+// See https://prometheus.io/docs/instrumenting/exposition_formats/ .
+type metricSet struct {
+	families []*metricFamily
+	index    map[string]*metricFamily
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{index: map[string]*metricFamily{}}
+}
+
+// Add a sample. labels are label name & value pairs.
+func (ms *metricSet) add(name string, typ string, help string, value float64, labels ...string) {
+	family := ms.index[name]
+	if family == nil {
+		family = &metricFamily{name: name, help: help, typ: typ}
+		ms.index[name] = family
+		ms.families = append(ms.families, family)
+	}
+	s := &sample{value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		s.labels = append(s.labels, [2]string{labels[i], labels[i+1]})
+	}
+	family.samples = append(family.samples, s)
+}
+
+// Merge samples of other set into this one.
+func (ms *metricSet) merge(other *metricSet) {
+	for _, family := range other.families {
+		for _, s := range family.samples {
+			labels := []string{}
+			for _, label := range s.labels {
+				labels = append(labels, label[0], label[1])
+			}
+			ms.add(family.name, family.typ, family.help, s.value, labels...)
+		}
+	}
+}
+
+func (ms *metricSet) write(w io.Writer) error {
+	for _, family := range ms.families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n",
+			family.name, family.help, family.name, family.typ); err != nil {
+			return err
+		}
+		for _, s := range family.samples {
+			line := family.name
+			if len(s.labels) > 0 {
+				labels := []string{}
+				for _, label := range s.labels {
+					labels = append(labels, fmt.Sprintf(`%s="%s"`, label[0], labelValueReplacer.Replace(label[1])))
+				}
+				line += "{" + strings.Join(labels, ",") + "}"
+			}
+			if _, err := fmt.Fprintf(w, "%s %v\n", line, s.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
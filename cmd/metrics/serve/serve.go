@@ -0,0 +1,298 @@
+package serve
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/metrics"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve clients and sites metrics over http in Prometheus format.",
+	Long: `Serve clients and sites metrics over http in Prometheus format.
+Metrics are exposed at "/metrics" path of the listening address, e.g. http://localhost:9713/metrics .
+
+Exported metrics:
+- ptool_client_up{client} : 1 if the client status is fetched successfully, 0 otherwise.
+- ptool_client_download_speed_bytes{client} / ptool_client_upload_speed_bytes{client}
+- ptool_client_download_speed_limit_bytes{client} / ptool_client_upload_speed_limit_bytes{client} : <= 0 means no limit.
+- ptool_client_free_space_bytes{client} : Free disk space of default save path. Not exported if unknown.
+- ptool_client_unfinished_bytes{client} : Un-downloaded size of all unfinished torrents.
+- ptool_client_unfinished_downloading_bytes{client} : Same as above but excluding paused torrents.
+- ptool_client_torrents{client,state,category,tracker} : Torrents count. tracker is the base domain of tracker.
+- ptool_site_up{site}
+- ptool_site_uploaded_bytes{site} / ptool_site_downloaded_bytes{site} : User uploaded / downloaded.
+- ptool_site_seeding_torrents{site} / ptool_site_leeching_torrents{site}
+- ptool_site_bonus{site} / ptool_site_seeding_bytes{site} / ptool_site_hnr_torrents{site} : User bonus points,
+  total seeding size and unsatisfied HnR count. They are 0 if site does not provide them.
+- ptool_brush_torrents_added_total{client,site} / ptool_brush_torrents_deleted_total{client,site} :
+  Brush torrents add / delete counters. Requires the "brushEnableStats = true" config.
+
+Metrics are fetched when being scraped, and cached for a while (see --cache-ttl and --site-cache-ttl flags)
+to avoid stressing clients and sites.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: serve,
+}
+
+var (
+	listen          = ""
+	clientsFlag     = ""
+	sitesFlag       = ""
+	cacheTtlStr     = ""
+	siteCacheTtlStr = ""
+)
+
+func init() {
+	command.Flags().StringVarP(&listen, "listen", "", ":9713", "Http listening address")
+	command.Flags().StringVarP(&clientsFlag, "client", "", "",
+		"Comma-separated client names. If not set, all enabled clients are exported. Use \"-\" to export none")
+	command.Flags().StringVarP(&sitesFlag, "site", "", "", "Comma-separated site or group names. "+
+		"If not set, all enabled (not dead nor hidden) sites are exported. Use \"-\" to export none")
+	command.Flags().StringVarP(&cacheTtlStr, "cache-ttl", "", "1m", "Cache time of clients and brush metrics")
+	command.Flags().StringVarP(&siteCacheTtlStr, "site-cache-ttl", "", "30m", "Cache time of sites metrics")
+	metrics.Command.AddCommand(command)
+}
+
+type cachedMetrics struct {
+	time    int64
+	metrics *metricSet
+}
+
+type exporter struct {
+	clientnames  []string
+	sitenames    []string
+	statDb       *stats.StatDb
+	cacheTtl     int64
+	siteCacheTtl int64
+	mu           sync.Mutex
+	cache        map[string]*cachedMetrics
+}
+
+func serve(cmd *cobra.Command, args []string) error {
+	if config.InShell {
+		return fmt.Errorf("metrics serve can not be run in shell")
+	}
+	cacheTtl, err := util.ParseTimeDuration(cacheTtlStr)
+	if err != nil {
+		return fmt.Errorf("invalid cache-ttl: %w", err)
+	}
+	siteCacheTtl, err := util.ParseTimeDuration(siteCacheTtlStr)
+	if err != nil {
+		return fmt.Errorf("invalid site-cache-ttl: %w", err)
+	}
+	e := &exporter{cacheTtl: cacheTtl, siteCacheTtl: siteCacheTtl, cache: map[string]*cachedMetrics{}}
+	if clientsFlag == "" {
+		for _, clientConfig := range config.Get().ClientsEnabled {
+			e.clientnames = append(e.clientnames, clientConfig.Name)
+		}
+	} else if clientsFlag != "-" {
+		e.clientnames = util.UniqueSlice(util.SplitCsv(clientsFlag))
+		for _, clientname := range e.clientnames {
+			if !client.ClientExists(clientname) {
+				return fmt.Errorf("client %s not found", clientname)
+			}
+		}
+	}
+	if sitesFlag == "" {
+		for _, siteConfig := range config.Get().SitesEnabled {
+			if siteConfig.Dead || siteConfig.Hidden {
+				continue
+			}
+			e.sitenames = append(e.sitenames, siteConfig.GetName())
+		}
+	} else if sitesFlag != "-" {
+		e.sitenames = config.ParseGroupAndOtherNames(util.SplitCsv(sitesFlag)...)
+		for _, sitename := range e.sitenames {
+			if site.GetConfigSiteReginfo(sitename) == nil {
+				return fmt.Errorf("site %s not found", sitename)
+			}
+		}
+	}
+	if config.Get().BrushEnableStats {
+		e.statDb, err = stats.NewDb(filepath.Join(config.ConfigDir, config.STATS_FILENAME),
+			filepath.Join(config.ConfigDir, config.LEGACY_STATS_FILENAME))
+		if err != nil {
+			return fmt.Errorf("failed to open stats db: %w", err)
+		}
+	}
+	http.HandleFunc("/metrics", e.handle)
+	log.Printf("Serving metrics of %d clients and %d sites at %s/metrics", len(e.clientnames), len(e.sitenames), listen)
+	return http.ListenAndServe(listen, nil)
+}
+
+func (e *exporter) handle(w http.ResponseWriter, r *http.Request) {
+	buf := &bytes.Buffer{}
+	if err := e.collect().write(buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// Collect all metrics, refreshing the expired ones.
+// Concurrent scrapes are serialized so that each target is fetched at most once per ttl.
+func (e *exporter) collect() *metricSet {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := util.Now()
+	keys := []string{}
+	fetchers := map[string]func() *metricSet{}
+	// Client and site instances are created here, before fetching concurrently,
+	// as client.CreateClient and site.CreateSite are NOT safe for concurrent use.
+	for _, clientname := range e.clientnames {
+		key := "client." + clientname
+		keys = append(keys, key)
+		if cached := e.cache[key]; cached == nil || now-cached.time >= e.cacheTtl {
+			clientInstance, err := client.CreateClient(clientname)
+			fetchers[key] = func() *metricSet { return fetchClientMetrics(clientname, clientInstance, err) }
+		}
+	}
+	for _, sitename := range e.sitenames {
+		key := "site." + sitename
+		keys = append(keys, key)
+		if cached := e.cache[key]; cached == nil || now-cached.time >= e.siteCacheTtl {
+			siteInstance, err := site.CreateSite(sitename)
+			fetchers[key] = func() *metricSet { return fetchSiteMetrics(sitename, siteInstance, err) }
+		}
+	}
+	if e.statDb != nil {
+		key := "brush"
+		keys = append(keys, key)
+		if cached := e.cache[key]; cached == nil || now-cached.time >= e.cacheTtl {
+			fetchers[key] = func() *metricSet { return fetchBrushMetrics(e.statDb) }
+		}
+	}
+	results := make(chan [2]any, len(fetchers))
+	for key, fetcher := range fetchers {
+		go func() {
+			results <- [2]any{key, fetcher()}
+		}()
+	}
+	for range fetchers {
+		result := <-results
+		e.cache[result[0].(string)] = &cachedMetrics{time: now, metrics: result[1].(*metricSet)}
+	}
+	ms := newMetricSet()
+	for _, key := range keys {
+		ms.merge(e.cache[key].metrics)
+	}
+	return ms
+}
+
+// Fetch metrics of client. err is the error of creating clientInstance, if any.
+func fetchClientMetrics(clientname string, clientInstance client.Client, err error) *metricSet {
+	ms := newMetricSet()
+	var status *client.Status
+	var torrents []*client.Torrent
+	if err == nil {
+		clientInstance.PurgeCache()
+		if status, err = clientInstance.GetStatus(); err == nil {
+			torrents, err = clientInstance.GetTorrents("", "", true)
+		}
+	}
+	if err != nil {
+		log.Errorf("Failed to fetch client %s metrics: %v", clientname, err)
+		ms.add("ptool_client_up", GAUGE, "Whether the client status is fetched successfully.", 0, "client", clientname)
+		return ms
+	}
+	ms.add("ptool_client_up", GAUGE, "Whether the client status is fetched successfully.", 1, "client", clientname)
+	ms.add("ptool_client_download_speed_bytes", GAUGE, "Current download speed in bytes per second.",
+		float64(status.DownloadSpeed), "client", clientname)
+	ms.add("ptool_client_upload_speed_bytes", GAUGE, "Current upload speed in bytes per second.",
+		float64(status.UploadSpeed), "client", clientname)
+	ms.add("ptool_client_download_speed_limit_bytes", GAUGE, "Download speed limit in bytes per second.",
+		float64(status.DownloadSpeedLimit), "client", clientname)
+	ms.add("ptool_client_upload_speed_limit_bytes", GAUGE, "Upload speed limit in bytes per second.",
+		float64(status.UploadSpeedLimit), "client", clientname)
+	if status.FreeSpaceOnDisk >= 0 {
+		ms.add("ptool_client_free_space_bytes", GAUGE, "Free disk space of default save path in bytes.",
+			float64(status.FreeSpaceOnDisk), "client", clientname)
+	}
+	ms.add("ptool_client_unfinished_bytes", GAUGE, "Un-downloaded size of unfinished torrents in bytes.",
+		float64(status.UnfinishedSize), "client", clientname)
+	ms.add("ptool_client_unfinished_downloading_bytes", GAUGE,
+		"Un-downloaded size of unfinished (not paused) torrents in bytes.",
+		float64(status.UnfinishedDownloadingSize), "client", clientname)
+	type torrentsKey struct {
+		state    string
+		category string
+		tracker  string
+	}
+	counts := map[torrentsKey]int64{}
+	keys := []torrentsKey{}
+	for _, torrent := range torrents {
+		key := torrentsKey{torrent.State, torrent.Category, torrent.TrackerBaseDomain}
+		if counts[key] == 0 {
+			keys = append(keys, key)
+		}
+		counts[key]++
+	}
+	for _, key := range keys {
+		ms.add("ptool_client_torrents", GAUGE, "Count of torrents in client.", float64(counts[key]),
+			"client", clientname, "state", key.state, "category", key.category, "tracker", key.tracker)
+	}
+	return ms
+}
+
+// Fetch metrics of site. err is the error of creating siteInstance, if any.
+func fetchSiteMetrics(sitename string, siteInstance site.Site, err error) *metricSet {
+	ms := newMetricSet()
+	var status *site.Status
+	if err == nil {
+		siteInstance.PurgeCache()
+		status, err = siteInstance.GetStatus()
+	}
+	if err != nil {
+		log.Errorf("Failed to fetch site %s metrics: %v", sitename, err)
+		ms.add("ptool_site_up", GAUGE, "Whether the site status is fetched successfully.", 0, "site", sitename)
+		return ms
+	}
+	ms.add("ptool_site_up", GAUGE, "Whether the site status is fetched successfully.", 1, "site", sitename)
+	ms.add("ptool_site_uploaded_bytes", GAUGE, "User uploaded in bytes.", float64(status.UserUploaded),
+		"site", sitename)
+	ms.add("ptool_site_downloaded_bytes", GAUGE, "User downloaded in bytes.", float64(status.UserDownloaded),
+		"site", sitename)
+	ms.add("ptool_site_seeding_torrents", GAUGE, "Count of torrents user is seeding.",
+		float64(status.TorrentsSeedingCnt), "site", sitename)
+	ms.add("ptool_site_leeching_torrents", GAUGE, "Count of torrents user is leeching.",
+		float64(status.TorrentsLeechingCnt), "site", sitename)
+	ms.add("ptool_site_bonus", GAUGE, "User bonus points.", status.UserBonus, "site", sitename)
+	ms.add("ptool_site_seeding_bytes", GAUGE, "Total size of torrents user is seeding.",
+		float64(status.UserSeedingSize), "site", sitename)
+	ms.add("ptool_site_hnr_torrents", GAUGE, "Count of user's unsatisfied HnR torrents.",
+		float64(status.UserHnRCnt), "site", sitename)
+	return ms
+}
+
+func fetchBrushMetrics(statDb *stats.StatDb) *metricSet {
+	ms := newMetricSet()
+	counts, err := statDb.GetBrushEventCounts()
+	if err != nil {
+		log.Errorf("Failed to fetch brush metrics: %v", err)
+		return ms
+	}
+	for _, count := range counts {
+		switch count.Event {
+		case stats.EVENT_TORRENT_ADDED:
+			ms.add("ptool_brush_torrents_added_total", COUNTER, "Count of torrents added by brush.",
+				float64(count.Cnt), "client", count.Client, "site", count.Site)
+		case stats.EVENT_TORRENT_DELETED:
+			ms.add("ptool_brush_torrents_deleted_total", COUNTER, "Count of torrents deleted by brush.",
+				float64(count.Cnt), "client", count.Client, "site", count.Site)
+		}
+	}
+	return ms
+}
@@ -0,0 +1,79 @@
+package serve
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+)
+
+// A fake client that only implements the methods used by metrics fetching.
+type fakeClient struct {
+	client.Client
+	name string
+}
+
+func (c *fakeClient) GetName() string {
+	return c.name
+}
+
+func (c *fakeClient) PurgeCache() {
+}
+
+func (c *fakeClient) GetStatus() (*client.Status, error) {
+	return &client.Status{FreeSpaceOnDisk: -1, UploadSpeed: 1024}, nil
+}
+
+func (c *fakeClient) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	return []*client.Torrent{{InfoHash: "foo", State: "seeding", Category: "cat"}}, nil
+}
+
+func init() {
+	client.Register(&client.RegInfo{
+		Name: "metricsfake",
+		Creator: func(name string, clientConfig *config.ClientConfigStruct,
+			config *config.ConfigStruct) (client.Client, error) {
+			return &fakeClient{name: name}, nil
+		},
+	})
+}
+
+// Run with -race flag to verify that clients are created and fetched without data race.
+func TestCollectClients(t *testing.T) {
+	config.ConfigDir = t.TempDir()
+	config.ConfigFile = "ptool.toml"
+	config.ConfigName = "ptool"
+	config.ConfigType = "toml"
+	err := os.WriteFile(filepath.Join(config.ConfigDir, config.ConfigFile), []byte(`
+[[clients]]
+name = "a"
+type = "metricsfake"
+
+[[clients]]
+name = "b"
+type = "metricsfake"
+`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	e := &exporter{clientnames: []string{"a", "b"}, cache: map[string]*cachedMetrics{}}
+	buf := &bytes.Buffer{}
+	if err = e.collect().write(buf); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	output := buf.String()
+	for _, line := range []string{
+		`ptool_client_up{client="a"} 1`,
+		`ptool_client_up{client="b"} 1`,
+		`ptool_client_upload_speed_bytes{client="b"} 1024`,
+		`ptool_client_torrents{client="a",state="seeding",category="cat",tracker=""} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("metrics output does not contain %q:\n%s", line, output)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
@@ -82,6 +83,10 @@ type Torrent struct {
 The render result is trim spaced.
 E.g. '--format "{{.Id}} {{.Name}} {{.Size}}"'
 
+If "--rss" flag is set, it searches the torrents in the RSS feed of site (the "rssUrl" site config) instead,
+a torrent matches if it's title or subtitle contains every keyword. The feed usually only contains latest torrents,
+and lacks the discount / peers info of torrents, so the "--min-seeders" flag is ignored unless explicitly set.
+
 If "--json" flag is set, it prints the whole search result (found torrents
 along with search meta data) in json object format instead.`,
 	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
@@ -94,6 +99,7 @@ var (
 	newestFlag         = false
 	showJson           = false
 	showIdOnly         = false
+	useRss             = false
 	minSeeders         = int64(0)
 	maxResults         = int64(0)
 	perSiteMaxResults  = int64(0)
@@ -114,6 +120,8 @@ func init() {
 	command.Flags().BoolVarP(&newestFlag, "newest", "n", false, "Sort search result by torrent time in desc order")
 	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
 	command.Flags().BoolVarP(&showIdOnly, "show-id-only", "", false, "Output found torrent ids only")
+	command.Flags().BoolVarP(&useRss, "rss", "", false,
+		`Search torrents in the RSS feed of site ("rssUrl" site config) instead`)
 	command.Flags().Int64VarP(&maxResults, "max-results", "", 100,
 		"Number limit of search result of all sites combined. -1 == no limit")
 	command.Flags().Int64VarP(&perSiteMaxResults, "per-site-max-results", "", -1,
@@ -184,9 +192,17 @@ func search(cmd *cobra.Command, args []string) error {
 		}
 		siteInstancesMap[sitename] = siteInstance
 	}
+	if useRss && !cmd.Flags().Changed("min-seeders") {
+		minSeeders = -1
+	}
 	ch := make(chan SearchResult, len(sitenames))
 	for _, sitename := range sitenames {
 		go func(sitename string) {
+			if useRss {
+				torrents, err := searchRssTorrents(siteInstancesMap[sitename], args[1:])
+				ch <- SearchResult{sitename, torrents, err}
+				return
+			}
 			torrents, err := siteInstancesMap[sitename].SearchTorrents(keyword, baseUrl)
 			ch <- SearchResult{sitename, torrents, err}
 		}(sitename)
@@ -288,3 +304,16 @@ func search(cmd *cobra.Command, args []string) error {
 	site.PrintTorrents(os.Stdout, torrents, "", now.Unix(), false, dense, nil)
 	return nil
 }
+
+// Search torrents in site RSS feed, return those which title or subtitle contains every keyword.
+func searchRssTorrents(siteInstance site.Site, keywords []string) ([]*site.Torrent, error) {
+	torrents, err := site.GetRssTorrents(siteInstance)
+	if err != nil {
+		return nil, err
+	}
+	return util.Filter(torrents, func(torrent *site.Torrent) bool {
+		return !slices.ContainsFunc(keywords, func(keyword string) bool {
+			return !torrent.MatchFilter(keyword)
+		})
+	}), nil
+}
@@ -38,7 +38,11 @@ func show(cmd *cobra.Command, args []string) error {
 			fmt.Printf("# %s : failed to get detailed configuration: %v\n", sitename, err)
 			continue
 		}
-		fmt.Printf("# %s\n[[sites]]\n%s\n", sitename, str)
+		if source := tpl.SOURCES[sitename]; source != "" {
+			fmt.Printf("# %s (site pack: %s)\n[[sites]]\n%s\n", sitename, source, str)
+		} else {
+			fmt.Printf("# %s\n[[sites]]\n%s\n", sitename, str)
+		}
 	}
 	return nil
 }
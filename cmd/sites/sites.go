@@ -3,6 +3,7 @@ package sites
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -17,7 +18,12 @@ var Command = &cobra.Command{
 	Short: "Show internal supported PT sites list which can be used with this software.",
 	Long: `Show internal supported PT sites list which can be used with this software.
 By default it does NOT display obsolete / legacy site that is currently / already dead,
-unless --all flag is set.`,
+unless --all flag is set.
+
+Besides the sites built in this program, additional site definitions can be loaded from
+the external site packs (*.toml / *.yaml files) in "sites.d" dir of config dir,
+which override or extend the internal ones. The "Source" column displays where each site definition comes from:
+"internal" or the pack file name. Use "ptool sites validate" to check the site packs.`,
 	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
 	RunE: sites,
 }
@@ -45,6 +51,7 @@ func sites(cmd *cobra.Command, args []string) error {
 			}
 			siteData := util.StructToMap(*tpl.SITES[name], true, false)
 			siteData["name"] = name
+			siteData["source"] = getSource(name)
 			siteDatas = append(siteDatas, siteData)
 		}
 		util.PrintJson(os.Stdout, siteDatas)
@@ -56,7 +63,8 @@ func sites(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Printf("<applying filter '%s'>\n", filter)
 	}
-	fmt.Printf("%-15s  %-15s  %-30s  %13s  %-5s  %s\n", "Type", "Aliases", "Url", "Schema", "Flags", "Comment")
+	fmt.Printf("%-15s  %-15s  %-30s  %13s  %-5s  %-15s  %s\n",
+		"Type", "Aliases", "Url", "Schema", "Flags", "Source", "Comment")
 	for _, name := range tpl.SITENAMES {
 		siteInfo := tpl.SITES[name]
 		if siteInfo.Dead && !showAll {
@@ -72,8 +80,16 @@ func sites(cmd *cobra.Command, args []string) error {
 		if siteInfo.GlobalHnR {
 			flags = append(flags, "!")
 		}
-		fmt.Printf("%-15s  %-15s  %-30s  %13s  %-5s  %s\n", name, strings.Join(siteInfo.Aliases, ","),
-			siteInfo.Url, siteInfo.Type, strings.Join(flags, ""), siteInfo.Comment)
+		fmt.Printf("%-15s  %-15s  %-30s  %13s  %-5s  %-15s  %s\n", name, strings.Join(siteInfo.Aliases, ","),
+			siteInfo.Url, siteInfo.Type, strings.Join(flags, ""), filepath.Base(getSource(name)), siteInfo.Comment)
 	}
 	return nil
 }
+
+// Return the file of site pack that site definition comes from, or "internal".
+func getSource(name string) string {
+	if source := tpl.SOURCES[name]; source != "" {
+		return source
+	}
+	return "internal"
+}
@@ -0,0 +1,88 @@
+package validate
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd/sites"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site/tpl"
+)
+
+var command = &cobra.Command{
+	Use:   "validate [file]...",
+	Short: "Validate external site definition packs.",
+	Long: `Validate external site definition packs.
+Args is the site pack files to validate. If not provided, all site packs (*.toml / *.yaml files)
+in "sites.d" dir of config dir are validated.
+
+A site pack has the same format as the [[sites]] of ptool.toml, e.g.:
+
+[[sites]]
+name = 'mysite'  # site id
+type = 'nexusphp' # site schema
+url = 'https://mysite.example/'
+aliases = ['ms']
+domains = ['mysite-tracker.example']
+selectorTorrentFree = '.pro_free'
+
+A site that has the same name as an internal site overrides (is merged into) the internal one.
+It checks unknown keys of each pack file, and the name, type, url and CSS selectors of each site.`,
+	RunE: validate,
+}
+
+func init() {
+	sites.Command.AddCommand(command)
+}
+
+func validate(cmd *cobra.Command, args []string) error {
+	filenames := args
+	if len(filenames) == 0 {
+		dir := filepath.Join(config.ConfigDir, config.SITES_PACK_DIR)
+		var err error
+		if filenames, err = tpl.GetPackFiles(dir); err != nil {
+			return fmt.Errorf("failed to read site packs dir: %w", err)
+		}
+		if len(filenames) == 0 {
+			fmt.Printf("No site pack found in %s\n", dir)
+			return nil
+		}
+	}
+	errorCnt := int64(0)
+	definedIn := map[string]string{}
+	for _, filename := range filenames {
+		fileErrorCnt := int64(0)
+		packSites, err := tpl.ReadPack(filename, true)
+		if err != nil {
+			fmt.Printf("✕ %s: %v\n", filename, err)
+			fileErrorCnt++
+			// still validate sites of pack
+			if packSites, err = tpl.ReadPack(filename, false); err != nil {
+				errorCnt += fileErrorCnt
+				continue
+			}
+		}
+		for i, siteConfig := range packSites {
+			errs := tpl.ValidateSite(siteConfig)
+			if definedIn[siteConfig.Name] != "" {
+				errs = append(errs, fmt.Errorf("duplicate name, already defined in %s", definedIn[siteConfig.Name]))
+			} else {
+				definedIn[siteConfig.Name] = filename
+			}
+			for _, err := range errs {
+				fmt.Printf("✕ %s: site #%d %q: %v\n", filename, i+1, siteConfig.Name, err)
+			}
+			fileErrorCnt += int64(len(errs))
+		}
+		if fileErrorCnt == 0 {
+			fmt.Printf("✓ %s: %d sites\n", filename, len(packSites))
+		}
+		errorCnt += fileErrorCnt
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+package all
+
+import (
+	_ "github.com/sagan/ptool/cmd/sitestats"
+	_ "github.com/sagan/ptool/cmd/sitestats/record"
+	_ "github.com/sagan/ptool/cmd/sitestats/show"
+)
@@ -0,0 +1,117 @@
+package record
+
+import (
+	"fmt"
+	"math"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd/sitestats"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "record {site | group}... [-a]",
+	Short: "Record a snapshot of sites user status.",
+	Long: `Record a snapshot of sites user status.
+Args is site or group names. If "-a" flag is set, all enabled (not dead nor hidden) sites are recorded.
+
+It's suitable to be run periodically, e.g. in a cron job or a [[jobs]] task of "ptool daemon":
+ptool sitestats record -a
+
+Use "ptool sitestats show" to show the recorded history.`,
+	RunE: record,
+}
+
+var (
+	all = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&all, "all", "a", false, "Record all sites")
+	sitestats.Command.AddCommand(command)
+}
+
+type recordResult struct {
+	sitename string
+	status   *site.Status
+	err      error
+}
+
+func record(cmd *cobra.Command, args []string) error {
+	sitenames := config.ParseGroupAndOtherNames(args...)
+	if all {
+		if len(args) > 0 {
+			return fmt.Errorf("--all flag cann't be used with site names")
+		}
+		for _, siteConfig := range config.Get().SitesEnabled {
+			if siteConfig.Dead || siteConfig.Hidden {
+				continue
+			}
+			sitenames = append(sitenames, siteConfig.GetName())
+		}
+	}
+	if len(sitenames) == 0 {
+		return fmt.Errorf("no sites provided")
+	}
+	statDb, err := sitestats.OpenDb()
+	if err != nil {
+		return err
+	}
+	ch := make(chan *recordResult, len(sitenames))
+	for _, sitename := range sitenames {
+		// site.CreateSite is NOT safe for concurrent use, so create site instance before starting goroutine.
+		siteInstance, err := site.CreateSite(sitename)
+		if err != nil {
+			ch <- &recordResult{sitename: sitename, err: err}
+			continue
+		}
+		go func() {
+			status, err := siteInstance.GetStatus()
+			if err == nil && !status.IsOk() {
+				err = fmt.Errorf("got no user status, site parser may be broken or cookie is invalid")
+			}
+			ch <- &recordResult{sitename: sitename, status: status, err: err}
+		}()
+	}
+	now := util.Now()
+	errorCnt := int64(0)
+	snapshots := []*stats.SiteSnapshot{}
+	for range sitenames {
+		result := <-ch
+		if result.err != nil {
+			log.Errorf("Failed to get site %s status: %v", result.sitename, result.err)
+			errorCnt++
+			continue
+		}
+		status := result.status
+		ratio := status.Ratio()
+		if math.IsInf(ratio, 0) || math.IsNaN(ratio) {
+			ratio = 0
+		}
+		snapshots = append(snapshots, &stats.SiteSnapshot{
+			Site:        result.sitename,
+			Ts:          now,
+			UserName:    status.UserName,
+			Uploaded:    status.UserUploaded,
+			Downloaded:  status.UserDownloaded,
+			Ratio:       ratio,
+			Bonus:       status.UserBonus,
+			SeedingCnt:  status.TorrentsSeedingCnt,
+			SeedingSize: status.UserSeedingSize,
+			HnRCnt:      status.UserHnRCnt,
+		})
+	}
+	if err := statDb.AddSiteSnapshots(snapshots); err != nil {
+		return fmt.Errorf("failed to save snapshots: %w", err)
+	}
+	fmt.Printf("Recorded %d sites status, %d errors\n", len(snapshots), errorCnt)
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
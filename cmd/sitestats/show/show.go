@@ -0,0 +1,141 @@
+package show
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd/sitestats"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "show [site | group]... [--period day|week|month] [--count n]",
+	Short: "Show history trends of sites user status.",
+	Long: `Show history trends of sites user status.
+Args is site or group names. If not provided, all sites that have recorded snapshots are shown.
+The data is recorded by "ptool sitestats record" command.
+
+For each site and period (day, week or month), it displays the values of latest snapshot and the deltas of
+the current period, and the sparklines of uploaded & bonus deltas of the last n periods (see --count flag):
+- Uploaded / Downloaded / Bonus / Ratio : Values of the latest snapshot.
+- +Uploaded / +Downloaded / +Bonus : Deltas of the current period.
+- UploadTrend / BonusTrend : Sparklines of uploaded / bonus deltas of each period.
+
+If "--json" or "--csv" flag is set, it outputs stats of each period of sites, including periods without data.`,
+	RunE: show,
+}
+
+var (
+	period        = ""
+	count         = int64(0)
+	showJson      = false
+	showCsv       = false
+	defaultCounts = map[string]int{stats.PERIOD_DAY: 14, stats.PERIOD_WEEK: 8, stats.PERIOD_MONTH: 12}
+)
+
+func init() {
+	command.Flags().StringVarP(&period, "period", "", "",
+		"Only show stats of this period: day|week|month. By default all periods are shown")
+	command.Flags().Int64VarP(&count, "count", "", 0,
+		"Number of periods to show. Default: 14 for day, 8 for week, 12 for month")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().BoolVarP(&showCsv, "csv", "", false, "Show output in csv format")
+	sitestats.Command.AddCommand(command)
+}
+
+func show(cmd *cobra.Command, args []string) error {
+	if showJson && showCsv {
+		return fmt.Errorf("--json and --csv flags are NOT compatible")
+	}
+	periods := stats.Periods
+	if period != "" {
+		if !slices.Contains(stats.Periods, period) {
+			return fmt.Errorf("invalid period %q", period)
+		}
+		periods = []string{period}
+	}
+	statDb, err := sitestats.OpenDb()
+	if err != nil {
+		return err
+	}
+	sitenames := config.ParseGroupAndOtherNames(args...)
+	if len(sitenames) == 0 {
+		if sitenames, err = statDb.GetSnapshotSites(); err != nil {
+			return fmt.Errorf("failed to get sites: %w", err)
+		}
+	}
+	now := util.Now()
+	allStats := [][]*stats.SitePeriodStat{}
+	for _, sitename := range sitenames {
+		snapshots, err := statDb.GetSiteSnapshots(sitename, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get site %s snapshots: %w", sitename, err)
+		}
+		for _, period := range periods {
+			periodCount := defaultCounts[period]
+			if count > 0 {
+				periodCount = int(count)
+			}
+			allStats = append(allStats, stats.GetSitePeriodStats(sitename, snapshots, period, periodCount, now))
+		}
+	}
+
+	if showJson {
+		return util.PrintJson(os.Stdout, slices.Concat(allStats...))
+	}
+	if showCsv {
+		return printCsv(allStats)
+	}
+	fmt.Printf("%-15s  %-6s  %-10s  %-11s  %-10s  %-11s  %-12s  %-12s  %-6s  %-14s  %-14s\n", "Site", "Period",
+		"Uploaded", "+Uploaded", "Downloaded", "+Downloaded", "Bonus", "+Bonus", "Ratio", "UploadTrend", "BonusTrend")
+	for _, periodStats := range allStats {
+		latest := periodStats[len(periodStats)-1]
+		uploadedDeltas := []float64{}
+		bonusDeltas := []float64{}
+		for _, periodStat := range periodStats {
+			uploadedDeltas = append(uploadedDeltas, float64(periodStat.UploadedDelta))
+			bonusDeltas = append(bonusDeltas, periodStat.BonusDelta)
+		}
+		if !latest.Recorded {
+			fmt.Printf("%-15s  %-6s  %-10s  %-11s  %-10s  %-11s  %-12s  %-12s  %-6s  %s  %s\n",
+				latest.Site, latest.Period, "-", "-", "-", "-", "-", "-", "-",
+				padSparkline(uploadedDeltas), padSparkline(bonusDeltas))
+			continue
+		}
+		fmt.Printf("%-15s  %-6s  %-10s  %-11s  %-10s  %-11s  %-12.1f  %-12s  %-6.2f  %s  %s\n",
+			latest.Site, latest.Period, util.BytesSizeAround(float64(latest.Uploaded)),
+			"+"+util.BytesSizeAround(float64(latest.UploadedDelta)), util.BytesSizeAround(float64(latest.Downloaded)),
+			"+"+util.BytesSizeAround(float64(latest.DownloadedDelta)), latest.Bonus,
+			fmt.Sprintf("%+.1f", latest.BonusDelta), latest.Ratio,
+			padSparkline(uploadedDeltas), padSparkline(bonusDeltas))
+	}
+	return nil
+}
+
+// Return the sparkline of values, padded with spaces to 14 columns.
+// Use it with "%s" verb, as each block char of sparkline is 1 column but 3 bytes in UTF-8.
+func padSparkline(values []float64) string {
+	return util.Sparkline(values) + strings.Repeat(" ", max(0, 14-len(values)))
+}
+
+func printCsv(allStats [][]*stats.SitePeriodStat) error {
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{"site", "period", "start", "recorded", "uploaded", "downloaded", "ratio", "bonus",
+		"seedingSize", "uploadedDelta", "downloadedDelta", "bonusDelta"})
+	for _, periodStats := range allStats {
+		for _, s := range periodStats {
+			writer.Write([]string{s.Site, s.Period, s.Start, fmt.Sprint(s.Recorded), fmt.Sprint(s.Uploaded),
+				fmt.Sprint(s.Downloaded), fmt.Sprint(s.Ratio), fmt.Sprint(s.Bonus), fmt.Sprint(s.SeedingSize),
+				fmt.Sprint(s.UploadedDelta), fmt.Sprint(s.DownloadedDelta), fmt.Sprint(s.BonusDelta)})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,45 @@
+package sitestats
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/stats"
+)
+
+var Command = &cobra.Command{
+	Use:   "sitestats",
+	Short: "Record and show history of sites user status.",
+	Long: `Record and show history of sites user status.
+Use "ptool sitestats record" (e.g. in cron or daemon) to take snapshots of sites user status
+(uploaded, downloaded, ratio, bonus...), then use "ptool sitestats show" to show the trends.
+
+Snapshots are stored in the "` + config.STATS_FILENAME + `" SQLite database file (in the same dir of ptool.toml file).
+It does NOT require the "brushEnableStats = true" config.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+}
+
+var statsFilename = ""
+
+func init() {
+	Command.PersistentFlags().StringVarP(&statsFilename, "stats-file", "", "",
+		"Manually specify stats database file ("+config.STATS_FILENAME+") path")
+	cmd.RootCmd.AddCommand(Command)
+}
+
+// Open the stats database.
+func OpenDb() (*stats.StatDb, error) {
+	filename := statsFilename
+	if filename == "" {
+		filename = filepath.Join(config.ConfigDir, config.STATS_FILENAME)
+	}
+	statDb, err := stats.NewDb(filename, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stats db: %w", err)
+	}
+	return statDb, nil
+}
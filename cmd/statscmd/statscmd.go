@@ -17,19 +17,27 @@ var command = &cobra.Command{
 	Short:       "Show client brushing traffic statistics.",
 	Long: `Show client brushing traffic statistics.
 Only torrents added by ptool (of this machine) will be counted.
-The traffic info of a torrent will ONLY be recorded when it's been DELETED from the client.
+The traffic of brushing torrents is recorded by snapshots taken in each brush run,
+and is counted to the days during which it's generated.
 To use this command, enable the statistics feature by adding the "brushEnableStats = true"
-line to ptool.toml config file.`,
+line to ptool.toml config file.
+
+Statistics data is stored in the "` + config.STATS_FILENAME + `" SQLite database file
+(in the same dir of ptool.toml file). Records of the legacy "` + config.LEGACY_STATS_FILENAME + `" stats file,
+if exists, are imported into it on first use.`,
 	RunE: statscmd,
 }
 
 var (
-	statsFilename = ""
+	statsFilename       = ""
+	legacyStatsFilename = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&statsFilename, "stats-file", "", "",
-		"Manually specify stats file ("+config.STATS_FILENAME+") path")
+		"Manually specify stats database file ("+config.STATS_FILENAME+") path")
+	command.Flags().StringVarP(&legacyStatsFilename, "legacy-stats-file", "", "",
+		"Manually specify legacy stats file ("+config.LEGACY_STATS_FILENAME+") path to import")
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -38,12 +46,15 @@ func statscmd(cmd *cobra.Command, args []string) error {
 	if !config.Get().BrushEnableStats {
 		return fmt.Errorf("statistics feature is NOT enabled currently. " +
 			"To enable it, add the \"brushEnableStats = true\" line to the top of ptool.toml config file. " +
-			"It will use the \"ptool_stats.txt\" (in the same dir of ptool.toml file) as the statistics data file")
+			"It will use the \"" + config.STATS_FILENAME + "\" (in the same dir of ptool.toml file) as the statistics database")
 	}
 	if statsFilename == "" {
 		statsFilename = filepath.Join(config.ConfigDir, config.STATS_FILENAME)
 	}
-	statDb, err := stats.NewDb(statsFilename)
+	if legacyStatsFilename == "" {
+		legacyStatsFilename = filepath.Join(config.ConfigDir, config.LEGACY_STATS_FILENAME)
+	}
+	statDb, err := stats.NewDb(statsFilename, legacyStatsFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create stats db: %w", err)
 	}
@@ -76,12 +76,16 @@ func fetchSiteStatus(siteInstance site.Site, showTorrents bool, full bool, showS
 			response.Error = fmt.Errorf("cann't get site %s torrents: %w", siteInstance.GetName(), err)
 		} else {
 			if showScore {
-				brushSiteOption := strategy.GetBrushSiteOptions(siteInstance, util.Now())
-				scores := map[string]float64{}
-				for _, torrent := range siteTorrents {
-					scores[torrent.Id], _, _ = strategy.RateSiteTorrent(torrent, brushSiteOption)
+				if brushStrategy, err := strategy.GetBrushStrategy(siteInstance, nil); err != nil {
+					response.Error = fmt.Errorf("cann't get site %s brush strategy: %w", siteInstance.GetName(), err)
+				} else {
+					brushSiteOption := strategy.GetBrushSiteOptions(siteInstance, util.Now())
+					scores := map[string]float64{}
+					for _, torrent := range siteTorrents {
+						scores[torrent.Id], _, _ = brushStrategy.RateSiteTorrent(torrent, brushSiteOption)
+					}
+					response.SiteTorrentScores = scores
 				}
-				response.SiteTorrentScores = scores
 			}
 			response.SiteTorrents = siteTorrents
 		}
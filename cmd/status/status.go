@@ -50,6 +50,8 @@ For client, display following status info:
 For site, display following status info:
 - ↑: : Current uploading statistics.
 - ↓: : Current downloading statstics.
+- Other user info that site provides: user name, ratio, bonus (and bonus per hour), seeding torrents count (and size),
+  user class, unsatisfied HnR count, invites and join date.
 
 If "-t" flag is set, it will also show the active / latest torrents list of client / site.
 For the list format of client torrents, see help of "ptool show" command.
@@ -231,7 +233,10 @@ func status(cmd *cobra.Command, args []string) error {
 				successSitesUploaded += response.SiteStatus.UserUploaded
 				successSitesDownloaded += response.SiteStatus.UserDownloaded
 				additionalInfo := fmt.Sprintf("UserName: %s; Ratio: %.2f", response.SiteStatus.UserName,
-					float64(response.SiteStatus.UserUploaded)/float64(response.SiteStatus.UserDownloaded))
+					response.SiteStatus.Ratio())
+				if info := response.SiteStatus.Info(); info != "" {
+					additionalInfo += "; " + info
+				}
 				if len(response.SiteTorrents) > 0 {
 					additionalInfo += fmt.Sprintf("; Torrents: %d", len(response.SiteTorrents))
 				}
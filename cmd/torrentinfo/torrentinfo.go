@@ -0,0 +1,132 @@
+package torrentinfo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "torrentinfo {site.id}... [--site site]",
+	Short: "Show details of site torrents.",
+	Long: `Show details of site torrents.
+Args is torrent list that each one is a site torrent id (e.g. "mteam.488424").
+If "--site" flag is set, the args can also be pure ids (e.g. "488424").
+
+It fetches the torrent details from site (e.g. the "details.php?id=" page for NexusPHP sites),
+and displays full description, file list with sizes, info hash, uploader, IMDb / Douban links,
+HnR terms and peers count of torrent. Some fields may be empty if site does not provide them.
+Currently only NexusPHP, M-Team, UNIT3D (requires apiToken) and Gazelle sites are supported.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: torrentinfo,
+}
+
+var (
+	showJson    = false
+	noFiles     = false
+	defaultSite = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().BoolVarP(&noFiles, "no-files", "", false, "Do not show file list")
+	command.Flags().StringVarP(&defaultSite, "site", "", "", "Set default site of torrents")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func torrentinfo(cmd *cobra.Command, args []string) error {
+	errorCnt := int64(0)
+	for i, torrent := range args {
+		sitename, id, found := strings.Cut(torrent, ".")
+		if !found {
+			sitename, id = defaultSite, torrent
+		}
+		if sitename == "" || id == "" {
+			fmt.Fprintf(os.Stderr, "✕ %s: invalid torrent id, must be in site.id format\n", torrent)
+			errorCnt++
+			continue
+		}
+		siteInstance, err := site.CreateSite(sitename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✕ %s: failed to create site: %v\n", torrent, err)
+			errorCnt++
+			continue
+		}
+		details, err := siteInstance.GetTorrentDetails(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✕ %s: failed to get torrent details: %v\n", torrent, err)
+			errorCnt++
+			continue
+		}
+		if showJson {
+			if err := util.PrintJson(os.Stdout, details); err != nil {
+				fmt.Fprintf(os.Stderr, "✕ %s: %v\n", torrent, err)
+				errorCnt++
+			}
+			continue
+		}
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		printDetails(os.Stdout, details)
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+func printDetails(output io.Writer, details *site.TorrentDetails) {
+	discount := fmt.Sprintf("↓%.1fx ↑%.1fx", details.DownloadMultiplier, details.UploadMultiplier)
+	if details.Neutral {
+		discount += " (neutral)"
+	}
+	if details.DiscountEndTime > 0 {
+		discount += fmt.Sprintf(" until %s", util.FormatTime(details.DiscountEndTime))
+	}
+	hnr := "-"
+	if details.HasHnR {
+		hnr = "yes"
+		if details.HnRTerms != "" {
+			hnr += ": " + details.HnRTerms
+		}
+	}
+	fmt.Fprintf(output, "Torrent: %s\n", details.Id)
+	fmt.Fprintf(output, "Name: %s\n", details.Name)
+	fmt.Fprintf(output, "Size: %s (%d)\n", util.BytesSize(float64(details.Size)), details.Size)
+	fmt.Fprintf(output, "InfoHash: %s\n", details.InfoHash)
+	if details.Time > 0 {
+		fmt.Fprintf(output, "Time: %s\n", util.FormatTime(details.Time))
+	}
+	fmt.Fprintf(output, "Uploader: %s\n", details.Uploader)
+	fmt.Fprintf(output, "Discount: %s\n", discount)
+	fmt.Fprintf(output, "HnR: %s\n", hnr)
+	fmt.Fprintf(output, "Peers: %d seeders, %d leechers, %d snatched\n",
+		details.Seeders, details.Leechers, details.Snatched)
+	if len(details.Tags) > 0 {
+		fmt.Fprintf(output, "Tags: %s\n", strings.Join(details.Tags, ", "))
+	}
+	if details.ImdbUrl != "" {
+		fmt.Fprintf(output, "IMDb: %s\n", details.ImdbUrl)
+	}
+	if details.DoubanUrl != "" {
+		fmt.Fprintf(output, "Douban: %s\n", details.DoubanUrl)
+	}
+	fmt.Fprintf(output, "DownloadUrl: %s\n", details.DownloadUrl)
+	if !noFiles && details.Files != nil {
+		fmt.Fprintf(output, "Files: %d\n", len(details.Files))
+		for _, file := range details.Files {
+			fmt.Fprintf(output, "  %-10s  %s\n", util.BytesSize(float64(file.Size)), file.Path)
+		}
+	}
+	if description := strings.TrimSpace(details.Description); description != "" {
+		fmt.Fprintf(output, "Description:\n%s\n", description)
+	}
+}
@@ -0,0 +1,222 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/xseed"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site/tpl"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+var command = &cobra.Command{
+	Use:         "scan {client} {torrent-dir}...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "xseed.scan"},
+	Short:       "Find and add xseed torrents from local .torrent files.",
+	Long: `Find and add xseed torrents from local .torrent files.
+First arg is client. The following args is the dirs of .torrent files (e.g. the archives of batchdl).
+Only the top-level .torrent files of dirs will be read, it doesn't scan the dir recursively.
+
+It builds a fingerprint index (size & name of content files) of the completed seeding torrents in client,
+then finds every .torrent file that matches an existing client torrent (the "target torrent"),
+and adds it to client as the xseed torrent of the target torrent, the same way as "xseedadd".
+It does NOT depend on any third-party service like iyuu or reseed.
+
+If only some files of a .torrent exist in the target torrent (a partial match), it will be added
+with the other files marked as no-download, and the client will do hash checking for it.
+Partial matches of which the size ratio of existing files is lower than --min-partial-ratio are skipped.
+
+Use --dry-run flag to only report the matches.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: scan,
+}
+
+var (
+	dryRun          = false
+	addPaused       = false
+	check           = false
+	noPartial       = false
+	renameAdded     = false
+	deleteAdded     = false
+	minPartialRatio = float64(0)
+	addCategory     = ""
+	addTags         = ""
+	category        = ""
+	tag             = ""
+	filter          = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually add xseed torrents to client")
+	command.Flags().BoolVarP(&addPaused, "add-paused", "", false, "Add xseed torrents to client in paused state")
+	command.Flags().BoolVarP(&check, "check", "", false, "Let client do hash checking when adding xseed torrents")
+	command.Flags().BoolVarP(&noPartial, "no-partial", "", false, "Do NOT add partial matched xseed torrents")
+	command.Flags().BoolVarP(&renameAdded, "rename-added", "", false,
+		"Rename successfully added .torrent file to *"+constants.FILENAME_SUFFIX_ADDED+
+			" unless it's name already has that suffix")
+	command.Flags().BoolVarP(&deleteAdded, "delete-added", "", false, "Delete successfully added torrent file")
+	command.Flags().Float64VarP(&minPartialRatio, "min-partial-ratio", "", 0.5,
+		"Skip partial matched xseed torrent if the size ratio of it's files that exist in target torrent < this value")
+	command.Flags().StringVarP(&addCategory, "add-category", "", "",
+		"Manually set category of added xseed torrent. By Default it uses the original torrent's")
+	command.Flags().StringVarP(&addTags, "add-tags", "", "", "Set tags of added xseed torrent (comma-separated)")
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY_XSEED)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG_XSEED)
+	command.Flags().StringVarP(&filter, "filter", "", "", "Only xseed torrents which name contains this")
+	xseed.Command.AddCommand(command)
+}
+
+func scan(cmd *cobra.Command, args []string) error {
+	if renameAdded && deleteAdded {
+		return fmt.Errorf("--rename-added and --delete-added flags are NOT compatible")
+	}
+	clientName := args[0]
+	var filenames []string
+	for _, dir := range args[1:] {
+		filenames = append(filenames, helper.GetWildcardFilenames(filepath.Join(dir, "*.torrent"))...)
+	}
+	if len(filenames) == 0 {
+		fmt.Printf("No .torrent file found in dirs\n")
+		return nil
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	clientTorrents, err := clientInstance.GetTorrents("seeding", category, true)
+	if err != nil {
+		return fmt.Errorf("failed to get client torrents: %w", err)
+	}
+	clientTorrents = util.Filter(clientTorrents, func(t *client.Torrent) bool {
+		return t.IsFullComplete() && !t.HasTag(config.NOXSEED_TAG) && (tag == "" || t.HasAnyTag(tag)) &&
+			(filter == "" || util.ContainsI(t.Name, filter))
+	})
+	index := common.NewXseedIndex(clientInstance, clientTorrents)
+	fmt.Printf("Indexed %d client torrents. Scan %d .torrent files\n", index.Cnt, len(filenames))
+	fixedTags := util.SplitCsv(addTags)
+	addedInfoHashes := map[string]bool{}
+	errorCnt := int64(0)
+	cntMatch := int64(0)
+	cntAdded := int64(0)
+	for _, filename := range filenames {
+		contents, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Printf("X%s: failed to read: %v\n", filename, err)
+			errorCnt++
+			continue
+		}
+		tinfo, err := torrentutil.ParseTorrent(contents)
+		if err != nil {
+			fmt.Printf("X%s: failed to parse: %v\n", filename, err)
+			errorCnt++
+			continue
+		}
+		if addedInfoHashes[tinfo.InfoHash] {
+			continue
+		}
+		if t, _ := clientInstance.GetTorrent(tinfo.InfoHash); t != nil {
+			log.Debugf("%s: already exists in client as %s (%s)", filename, t.InfoHash, t.Name)
+			continue
+		}
+		match := index.Match(tinfo)
+		if match == nil {
+			log.Debugf("%s: no matched target torrent found in client", filename)
+			continue
+		}
+		matchType := "full"
+		if match.Result < 0 {
+			matchType = fmt.Sprintf("partial %d/%d files, %s/%s", len(tinfo.Files)-len(match.MissingFiles),
+				len(tinfo.Files), util.BytesSize(float64(match.MatchedSize)), util.BytesSize(float64(tinfo.Size)))
+			if noPartial || float64(match.MatchedSize)/float64(tinfo.Size) < minPartialRatio {
+				log.Debugf("%s: skip %s match with client torrent %s (%s)",
+					filename, matchType, match.Target.InfoHash, match.Target.Name)
+				continue
+			}
+		}
+		cntMatch++
+		if dryRun {
+			fmt.Printf("✓%s: %s match with client torrent %s (%s) (dry-run)\n",
+				filename, matchType, match.Target.InfoHash, match.Target.Name)
+			continue
+		}
+		sitename, err := tpl.GuessSiteByTrackers(tinfo.Trackers, "")
+		if err != nil {
+			log.Debugf("Failed to find match site for %s by trackers: %v", filename, err)
+		}
+		option := &client.TorrentOption{
+			Category:     addCategory,
+			Tags:         fixedTags,
+			Pause:        addPaused,
+			SkipChecking: !check,
+		}
+		if match.Result < 0 {
+			option.Pause = true
+			option.SkipChecking = false
+		}
+		err = common.AddXseedTorrent(clientInstance, contents, tinfo, match.Target, sitename, option)
+		if err == nil && match.Result < 0 {
+			err = setPartialXseedTorrent(clientInstance, tinfo.InfoHash, match.MissingFiles)
+		}
+		if err != nil {
+			fmt.Printf("X%s: %s match with client torrent %s (%s), but failed to add to client: %v\n",
+				filename, matchType, match.Target.InfoHash, match.Target.Name, err)
+			errorCnt++
+			continue
+		}
+		cntAdded++
+		addedInfoHashes[tinfo.InfoHash] = true
+		fmt.Printf("✓%s: %s match with client torrent %s (%s), added to client, save path: %s\n",
+			filename, matchType, match.Target.InfoHash, match.Target.Name, match.Target.SavePath)
+		if renameAdded && !strings.HasSuffix(filename, constants.FILENAME_SUFFIX_ADDED) {
+			if err := os.Rename(filename, util.TrimAnySuffix(filename,
+				constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_ADDED); err != nil {
+				log.Debugf("Failed to rename %s to *%s: %v", filename, constants.FILENAME_SUFFIX_ADDED, err)
+			}
+		} else if deleteAdded {
+			if err := os.Remove(filename); err != nil {
+				log.Debugf("Failed to delete %s: %v", filename, err)
+			}
+		}
+	}
+	fmt.Printf("Done. Matched / Added xseed torrents: %d / %d\n", cntMatch, cntAdded)
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+// Mark the missing files of a newly added (paused) partial xseed torrent as no-download,
+// then resume it unless --add-paused flag is set.
+// Some clients add torrent asynchronously, so it retries for a while until the torrent appears in client.
+func setPartialXseedTorrent(clientInstance client.Client, infoHash string, missingFiles []int64) (err error) {
+	if len(missingFiles) > 0 {
+		for i := 0; i < 5; i++ {
+			if i > 0 {
+				util.Sleep(1)
+			}
+			if err = clientInstance.SetFilePriority(infoHash, missingFiles, 0); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to mark missing files as no-download: %w", err)
+		}
+	}
+	if !addPaused {
+		if err = clientInstance.ResumeTorrents([]string{infoHash}); err != nil {
+			return fmt.Errorf("failed to resume torrent: %w", err)
+		}
+	}
+	return nil
+}
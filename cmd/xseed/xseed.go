@@ -0,0 +1,18 @@
+package xseed
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+)
+
+var Command = &cobra.Command{
+	Use:   "xseed",
+	Short: "Local cross seed utilities which do not depend on third-party services.",
+	Long:  `Local cross seed utilities which do not depend on third-party services.`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Command)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
@@ -162,30 +163,13 @@ func xseedadd(cmd *cobra.Command, args []string) error {
 				torrent, matchClientTorrent.InfoHash, matchClientTorrent.Name)
 			continue
 		}
-		category := matchClientTorrent.Category
-		if addCategory != "" {
-			category = addCategory
-		}
-		tags := []string{config.XSEED_TAG}
-		if sitename != "" {
-			tags = append(tags, client.GenerateTorrentTagFromSite(sitename))
-		}
-		tags = append(tags, fixedTags...)
-		ratioLmit := float64(0)
-		if tinfo.IsPrivate() {
-			tags = append(tags, config.PRIVATE_TAG)
-		} else {
-			tags = append(tags, config.PUBLIC_TAG)
-			ratioLmit = config.Get().PublicTorrentRatioLimit
-		}
-		err = clientInstance.AddTorrent(content, &client.TorrentOption{
-			SavePath:     matchClientTorrent.SavePath,
-			Category:     category,
-			Tags:         tags,
-			Pause:        addPaused,
-			SkipChecking: !check,
-			RatioLimit:   ratioLmit,
-		}, nil)
+		err = common.AddXseedTorrent(clientInstance, content, tinfo, matchClientTorrent, sitename,
+			&client.TorrentOption{
+				Category:     addCategory,
+				Tags:         fixedTags,
+				Pause:        addPaused,
+				SkipChecking: !check,
+			})
 		if err != nil {
 			fmt.Printf("X%s: matched with client torrent %s (%s), but failed to add to client: %v\n",
 				torrent, matchClientTorrent.InfoHash, matchClientTorrent.Name, err)
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -39,8 +40,10 @@ const (
 	HR_TAG                     = "_hr"
 	PRIVATE_TAG                = "_private"
 	PUBLIC_TAG                 = "_public"
-	STATS_FILENAME             = "ptool_stats.txt"
+	STATS_FILENAME             = "ptool_stats.db"
+	LEGACY_STATS_FILENAME      = "ptool_stats.txt" // imported into STATS_FILENAME db on first use
 	HISTORY_FILENAME           = "ptool_history"
+	SITES_PACK_DIR             = "sites.d"
 	SITE_TORRENTS_WIDTH        = 120 // min width for printing site torrents
 	CLIENT_TORRENTS_WIDTH      = 120 // min width for printing client torrents
 	GLOBAL_INTERNAL_LOCK_FILE  = "ptool.lock"
@@ -69,6 +72,7 @@ const (
 	DEFAULT_SITE_TORRENT_UPLOAD_SPEED_LIMIT         = int64(10 * 1024 * 1024)
 	DEFAULT_SITE_FLOW_CONTROL_INTERVAL              = int64(3)
 	DEFAULT_SITE_MAX_REDIRECTS                      = int64(3)
+	DEFAULT_SITE_HNR_SEEDING_TIME                   = int64(72 * 3600)
 	DEFAULT_COOKIECLOUD_TIMEOUT                     = DEFAULT_TIMEOUT
 )
 
@@ -87,6 +91,7 @@ type CookiecloudConfigStruct struct {
 type GroupConfigStruct struct {
 	Name    string   `yaml:"name"`
 	Sites   []string `yaml:"sites"`
+	Clients []string `yaml:"clients"` // client group. Currently only used by brush
 	Comment string   `yaml:"comment"`
 }
 
@@ -99,6 +104,111 @@ type AliasConfigStruct struct {
 	Internal    bool
 }
 
+// 定时任务，由 "ptool daemon" 执行
+type JobConfigStruct struct {
+	Name     string `yaml:"name"`
+	Schedule string `yaml:"schedule"` // cron 表达式，例如 "*/10 * * * *", "@every 1h", "@daily"
+	Cmd      string `yaml:"cmd"`      // ptool 命令行，例如 "brush local mteam"
+	Comment  string `yaml:"comment"`
+	Disabled bool   `yaml:"disabled"`
+}
+
+// 事件通知。type: webhook | smtp | http 。
+// webhook: 将事件以 JSON 格式 POST 到 url。
+// smtp: 通过 SMTP 服务器 (url 为 "host:port") 发送邮件，465 端口使用 TLS，其它端口在服务器支持时使用 STARTTLS。
+// http: 使用 template (Go text template) 渲染请求体后 POST 到 url，可用于 Telegram 等聊天机器人接口。
+type NotifierConfigStruct struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Comment     string   `yaml:"comment"`
+	Disabled    bool     `yaml:"disabled"`
+	Events      []string `yaml:"events"`  // 只通知这些类型的事件。未设置则通知所有事件
+	Sites       []string `yaml:"sites"`   // 只通知这些站点 (或分组) 相关的事件。未设置则不限制
+	Clients     []string `yaml:"clients"` // 只通知这些客户端相关的事件。未设置则不限制
+	Url         string   `yaml:"url"`
+	Headers     []string `yaml:"headers"`     // webhook / http: 额外的请求头，格式 "Name: value"
+	Template    string   `yaml:"template"`    // http: 请求体模板
+	ContentType string   `yaml:"contentType"` // http: 请求体类型。默认 "application/json"
+	Username    string   `yaml:"username"`    // smtp
+	Password    string   `yaml:"password"`    // smtp
+	From        string   `yaml:"from"`        // smtp
+	To          []string `yaml:"to"`          // smtp
+	Timeout     int64    `yaml:"timeout"`     // 超时时间 (秒)。默认 5
+}
+
+// 刷流策略。站点或客户端可以通过 brushStrategy 配置项选择使用的策略。
+// 大小 / 速度格式: "10GiB", "100KiB"。时间长度格式: "30m", "6h", "1d"。
+// 所有参数未设置时均使用内置默认策略的值。
+type BrushStrategyConfigStruct struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"` // 策略实现类型。默认(也是目前唯一内置的)为 "default"
+	Comment string `yaml:"comment"`
+	// 新添加种子的免检时间。默认 15m
+	NewTorrentsTimespan string `yaml:"newTorrentsTimespan"`
+	// 新添加种子的免 stall 时间。默认 30m
+	NewTorrentsStallExemptionTimespan string `yaml:"newTorrentsStallExemptionTimespan"`
+	// 完全没有下载进度的种子的删除时间。默认 30m
+	NoProcessTorrentDeletionTimespan string `yaml:"noProcessTorrentDeletionTimespan"`
+	// 下载速度低于此值的种子不计入正在下载种子数量；stall 种子的下载速度限制值。默认 10KiB
+	StallDownloadSpeed string `yaml:"stallDownloadSpeed"`
+	// 客户端上传速度限制低于此值时视为带宽已满。默认 100KiB
+	SlowUploadSpeed string `yaml:"slowUploadSpeed"`
+	// 下载速度高于此值时才检查种子的上传/下载速度比。默认 100KiB
+	RatioCheckMinDownloadSpeed string `yaml:"ratioCheckMinDownloadSpeed"`
+	// 慢速种子的观察时间。默认 15m
+	SlowTorrentsCheckTimespan string `yaml:"slowTorrentsCheckTimespan"`
+	// stall 状态的未完成种子的删除时间。默认 30m
+	StallTorrentDeletionTimespan string `yaml:"stallTorrentDeletionTimespan"`
+	// 种子促销剩余时间低于此值时不再添加 / 停止下载。默认 1h
+	DiscountEndTimespan string `yaml:"discountEndTimespan"`
+	// 以下参数未设置(nil)时使用默认值；设置为 0 时该项权重 / 系数即为 0 (例如不考虑下载者数量)。
+	// 客户端上传速度达到上传速度限制的此比例时视为带宽已满。必须 > 0。默认 0.8
+	BandwidthFullPercent *float64 `yaml:"bandwidthFullPercent"`
+	// 站点种子评分权重。做种人数 <=1 / <=3 / >3 的基础分数，默认 50 / 30 / 10
+	ScoreSeedersFew  *float64 `yaml:"scoreSeedersFew"`
+	ScoreSeedersSome *float64 `yaml:"scoreSeedersSome"`
+	ScoreSeedersMany *float64 `yaml:"scoreSeedersMany"`
+	// 每个下载者增加的分数。默认 1
+	ScoreLeecherWeight *float64 `yaml:"scoreLeecherWeight"`
+	// 非免费种子的分数系数。默认 0.5
+	ScoreNoneFreeFactor *float64                 `yaml:"scoreNoneFreeFactor"`
+	Rules               []*BrushRuleConfigStruct `yaml:"rules"`
+}
+
+// 刷流规则。规则设置的所有条件均满足时匹配。
+// 时间长度: 站点种子为发布时间至今，客户端种子为添加时间至今。
+type BrushRuleConfigStruct struct {
+	// add: 仅添加匹配任一 add 规则的站点种子(如果有 add 规则); skip: 不添加匹配的站点种子;
+	// delete: 删除匹配的客户端种子; stall: 停止下载匹配的客户端未完成种子(仍然上传)
+	Action      string   `yaml:"action"`
+	Comment     string   `yaml:"comment"`
+	MinAge      string   `yaml:"minAge"`
+	MaxAge      string   `yaml:"maxAge"`
+	MinSize     string   `yaml:"minSize"`
+	MaxSize     string   `yaml:"maxSize"`
+	MinSeeders  *int64   `yaml:"minSeeders"`
+	MaxSeeders  *int64   `yaml:"maxSeeders"`
+	MinLeechers *int64   `yaml:"minLeechers"`
+	MaxLeechers *int64   `yaml:"maxLeechers"`
+	Filters     []string `yaml:"filters"` // 种子名称或标签匹配任一 filter
+	// 以下条件仅适用于 delete / stall 规则。ratio = uploaded / size
+	MinRatio       *float64 `yaml:"minRatio"`
+	MaxRatio       *float64 `yaml:"maxRatio"`
+	MinUploadSpeed string   `yaml:"minUploadSpeed"`
+	MaxUploadSpeed string   `yaml:"maxUploadSpeed"`
+	Incomplete     bool     `yaml:"incomplete"` // 仅匹配未完成的种子
+	// 仅适用于 add 规则。匹配的站点种子评分乘以此系数。默认 1
+	ScoreFactor float64 `yaml:"scoreFactor"`
+}
+
+// A save path (usually one per disk) of client that brush or dynamic seeding adds torrents to.
+type SavePathConfigStruct struct {
+	Path string `yaml:"path"` // 客户端里的保存路径
+	// 该路径所在磁盘需要保留的最小剩余空间。默认使用客户端的 brushMinDiskSpace 值
+	MinFreeSpace      string `yaml:"minFreeSpace"`
+	MinFreeSpaceValue int64
+}
+
 type ClientConfigStruct struct {
 	Type     string `yaml:"type"`
 	Name     string `yaml:"name"`
@@ -119,11 +229,30 @@ type ClientConfigStruct struct {
 	BrushMaxTorrents                  int64   `yaml:"brushMaxTorrents"`
 	BrushMinRatio                     float64 `yaml:"brushMinRatio"`
 	BrushDefaultUploadSpeedLimit      string  `yaml:"brushDefaultUploadSpeedLimit"`
+	BrushStrategy                     string  `yaml:"brushStrategy"` // 刷流策略名称。默认 "default"
 	BrushMinDiskSpaceValue            int64
 	BrushSlowUploadSpeedTierValue     int64
 	BrushDefaultUploadSpeedLimitValue int64 ``
 	QbittorrentNoLogin                bool  `yaml:"qbittorrentNoLogin"`  // if set, will NOT send login request
 	QbittorrentNoLogout               bool  `yaml:"qbittorrentNoLogout"` // if set, will NOT send logout request
+	// embedded client only. 0: default (42069); -1: random port.
+	EmbeddedListenPort int64 `yaml:"embeddedListenPort"`
+	// embedded client only. default save path. Default: <config_dir>/embedded-<name>/downloads.
+	EmbeddedSavePath   string `yaml:"embeddedSavePath"`
+	EmbeddedDisableDht bool   `yaml:"embeddedDisableDht"` // embedded client only. disable DHT
+	// 刷流添加种子使用的保存路径(多磁盘)。新种子会放到剩余空间(减去 minFreeSpace 后)最多的路径。
+	// 未设置时使用客户端默认保存路径。
+	BrushSavePaths []*SavePathConfigStruct `yaml:"brushSavePaths"`
+	// 动态保种添加种子使用的保存路径(多磁盘)。格式同 brushSavePaths
+	DynamicSeedingSavePaths []*SavePathConfigStruct `yaml:"dynamicSeedingSavePaths"`
+	// 动态保种总空间。设置后 dynamicseeding 命令的多个站点共享该空间，由程序根据各站点种子的
+	// 做种人数稀缺程度、站点的魔力值产出效率 (每 GiB 做种体积每小时魔力) 以及站点
+	// dynamicSeedingMinShare / dynamicSeedingMaxShare 配置分配各站点的空间
+	DynamicSeedingSize      string `yaml:"dynamicSeedingSize"`
+	DynamicSeedingSizeValue int64
+	// "local_path|client_path" 格式的路径映射规则 (同 "--map-save-path" 参数)。
+	// 客户端无法报告 brushSavePaths 等路径的剩余空间时，通过映射后的本地路径获取。
+	MapSavePaths []string `yaml:"mapSavePaths"`
 }
 
 type SiteConfigStruct struct {
@@ -147,8 +276,11 @@ type SiteConfigStruct struct {
 	DynamicSeedingMinSeeders       int64      `yaml:"dynamicSeedingMinSeeders"`
 	DynamicSeedingMaxSeeders       int64      `yaml:"dynamicSeedingMaxSeeders"`
 	DynamicSeedingReplaceSeeders   int64      `yaml:"dynamicSeedingReplaceSeeders"`
+	DynamicSeedingMinShare         string     `yaml:"dynamicSeedingMinShare"` // 共享动态保种空间时最少分配空间 ("200GiB" 或 "30%")
+	DynamicSeedingMaxShare         string     `yaml:"dynamicSeedingMaxShare"` // 共享动态保种空间时最多分配空间
 	SearchQueryVariable            string     `yaml:"searchQueryVariable"`
 	TorrentsExtraUrls              []string   `yaml:"torrentsExtraUrls"`
+	RssUrl                         string     `yaml:"rssUrl"` // 种子 RSS / Atom 订阅地址(包含 passkey)
 	Cookie                         string     `yaml:"cookie"`
 	UserAgent                      string     `yaml:"userAgent"`
 	Impersonate                    string     `yaml:"impersonate"`
@@ -161,16 +293,21 @@ type SiteConfigStruct struct {
 	Secure                         bool       `yaml:"secure"`   // 访问站点时强制TLS证书安全校验
 	TorrentUploadSpeedLimit        string     `yaml:"torrentUploadSpeedLimit"`
 	GlobalHnR                      bool       `yaml:"globalHnR"`
+	HnRSeedingTime                 string     `yaml:"hnrSeedingTime"` // HnR 考查要求的做种时长。默认 72h
+	HnRRatio                       float64    `yaml:"hnrRatio"`       // HnR 考查要求的分享率。达到此值也视为满足考查
 	Timezone                       string     `yaml:"timezone"`
 	BrushTorrentMinSizeLimit       string     `yaml:"brushTorrentMinSizeLimit"`
 	BrushTorrentMaxSizeLimit       string     `yaml:"brushTorrentMaxSizeLimit"`
 	BrushAllowNoneFree             bool       `yaml:"brushAllowNoneFree"`
 	BrushAllowPaid                 bool       `yaml:"brushAllowPaid"`
 	BrushAllowHr                   bool       `yaml:"brushAllowHr"`
+	BrushMinSiteRatio              float64    `yaml:"brushMinSiteRatio"`  // 账号分享率低于此值时不再添加新种子
+	BrushMinSiteBuffer             string     `yaml:"brushMinSiteBuffer"` // 账号上传量-下载量低于此值时不再添加新种子
 	BrushAllowZeroSeeders          bool       `yaml:"brushAllowZeroSeeders"`
 	BrushExcludes                  []string   `yaml:"brushExcludes"`
 	BrushExcludeTags               []string   `yaml:"brushExcludeTags"`
 	BrushAcceptAnyFree             bool       `yaml:"brushAcceptAnyFree"`
+	BrushStrategy                  string     `yaml:"brushStrategy"` // 刷流策略名称。优先于客户端的 brushStrategy
 	SelectorTorrentsListHeader     string     `yaml:"selectorTorrentsListHeader"`
 	SelectorTorrentsList           string     `yaml:"selectorTorrentsList"`
 	SelectorTorrentBlock           string     `yaml:"selectorTorrentBlock"` // dom block of a torrent in list
@@ -194,6 +331,11 @@ type SiteConfigStruct struct {
 	SelectorUserInfoUserName       string     `yaml:"selectorUserInfoUserName"`
 	SelectorUserInfoUploaded       string     `yaml:"selectorUserInfoUploaded"`
 	SelectorUserInfoDownloaded     string     `yaml:"selectorUserInfoDownloaded"`
+	AttendanceUrl                  string     `yaml:"attendanceUrl"`     // 签到地址。"none": 禁用
+	AttendanceMethod               string     `yaml:"attendanceMethod"`  // 签到请求方法: GET (默认) 或 POST
+	AttendancePayload              string     `yaml:"attendancePayload"` // POST 请求 body, query string 格式
+	SelectorAttendanceLink         string     `yaml:"selectorAttendanceLink"`
+	SelectorAttendanceMessage      string     `yaml:"selectorAttendanceMessage"`
 	ImageUploadUrl                 string     `yaml:"imageUploadUrl"`
 	// Additional post payload when uploading image, query string format.
 	// E.g. "foo=a&bar=b".
@@ -219,10 +361,18 @@ type SiteConfigStruct struct {
 	UploadTorrentAdditionalPayload map[string]string `yaml:"uploadTorrentAdditionalPayload"`
 	// csv, eg: "type". It will check existence of these keys in metadata prior publishing.
 	UploadTorrentPayloadRequiredKeys string `yaml:"uploadTorrentPayloadRequiredKeys"`
-	TorrentDownloadUrl               string `yaml:"torrentDownloadUrl"` // use {id} placeholders in url
-	TorrentDownloadUrlPrefix         string `yaml:"torrentDownloadUrlPrefix"`
-	Passkey                          string `yaml:"passkey"`
-	UseCuhash                        bool   `yaml:"useCuhash"` // hdcity 使用机制。种子下载地址里必须有cuhash参数
+	// Map metadata to site category / type / resolution id when publishing torrent. Used by unit3d & mtorrent.
+	// Key is the value of the same name metadata field (e.g. "category") or any metadata tag, case-insensitive;
+	// "*" key is the fallback. The ids are rendered as "_category_id", "_type_id" and "_resolution_id"
+	// variables in uploadTorrentPayload.
+	UploadTorrentCategoryMap   map[string]string `yaml:"uploadTorrentCategoryMap"`
+	UploadTorrentTypeMap       map[string]string `yaml:"uploadTorrentTypeMap"`
+	UploadTorrentResolutionMap map[string]string `yaml:"uploadTorrentResolutionMap"`
+	TorrentDownloadUrl         string            `yaml:"torrentDownloadUrl"` // use {id} placeholders in url
+	TorrentDownloadUrlPrefix   string            `yaml:"torrentDownloadUrlPrefix"`
+	Passkey                    string            `yaml:"passkey"`
+	ApiToken                   string            `yaml:"apiToken"`  // unit3d: 站点 API token (api_token)。gazelle: ajax.php API 的 Authorization header 值
+	UseCuhash                  bool              `yaml:"useCuhash"` // hdcity 使用机制。种子下载地址里必须有cuhash参数
 	// ttg 使用机制。种子下载地址末段必须有4位数字校验码或Passkey参数(即使有 Cookie)
 	UseDigitHash                      bool   `yaml:"useDigitHash"`
 	UsePasskey                        bool   `yaml:"usePasskey"` // 部分站点(例如 ptt)必须使用包含 passkey 的链接下载种子
@@ -233,8 +383,10 @@ type SiteConfigStruct struct {
 	NoCookie                          bool   `yaml:"noCookie"`            // true: 该站点不使用 cookie 鉴权方式
 	AcceptAnyHttpStatus               bool   `yaml:"acceptAnyHttpStatus"` // true: 非200的http状态不认为是错误
 	TorrentUploadSpeedLimitValue      int64
+	HnRSeedingTimeValue               int64 // seconds
 	BrushTorrentMinSizeLimitValue     int64
 	BrushTorrentMaxSizeLimitValue     int64
+	BrushMinSiteBufferValue           int64
 	DynamicSeedingSizeValue           int64
 	DynamicSeedingTorrentMinSizeValue int64
 	DynamicSeedingTorrentMaxSizeValue int64
@@ -243,28 +395,31 @@ type SiteConfigStruct struct {
 }
 
 type ConfigStruct struct {
-	Hushshell           bool                       `yaml:"hushshell"`
-	ShellMaxSuggestions int64                      `yaml:"shellMaxSuggestions"` // -1 禁用
-	ShellMaxHistory     int64                      `yaml:"shellMaxHistory"`     // -1 禁用
-	IyuuToken           string                     `yaml:"iyuuToken"`
-	ReseedUsername      string                     `yaml:"reseedUsername"`
-	ReseedPassword      string                     `yaml:"reseedPassword"`
-	IyuuDomain          string                     `yaml:"iyuuDomain"` // iyuu API 域名。默认使用 2025.iyuu.cn
-	SiteProxy           string                     `yaml:"siteProxy"`
-	SiteUserAgent       string                     `yaml:"siteUserAgent"`
-	SiteImpersonate     string                     `yaml:"siteImpersonate"`
-	SiteHttpHeaders     [][]string                 `yaml:"siteHttpHeaders"`
-	SiteJa3             string                     `yaml:"siteJa3"`
-	SiteTimeout         int64                      `yaml:"siteTimeout"`  // 访问网站超时时间(秒)
-	SiteInsecure        bool                       `yaml:"siteInsecure"` // 强制禁用所有站点 TLS 证书校验。
-	SiteH2Fingerprint   string                     `yaml:"siteH2Fingerprint"`
-	BrushEnableStats    bool                       `yaml:"brushEnableStats"`
-	Clients             []*ClientConfigStruct      `yaml:"clients"`
-	Sites               []*SiteConfigStruct        `yaml:"sites"`
-	Groups              []*GroupConfigStruct       `yaml:"groups"`
-	Aliases             []*AliasConfigStruct       `yaml:"aliases"`
-	Cookieclouds        []*CookiecloudConfigStruct `yaml:"cookieclouds"`
-	Comment             string                     `yaml:"comment"`
+	Hushshell           bool                         `yaml:"hushshell"`
+	ShellMaxSuggestions int64                        `yaml:"shellMaxSuggestions"` // -1 禁用
+	ShellMaxHistory     int64                        `yaml:"shellMaxHistory"`     // -1 禁用
+	IyuuToken           string                       `yaml:"iyuuToken"`
+	ReseedUsername      string                       `yaml:"reseedUsername"`
+	ReseedPassword      string                       `yaml:"reseedPassword"`
+	IyuuDomain          string                       `yaml:"iyuuDomain"` // iyuu API 域名。默认使用 2025.iyuu.cn
+	SiteProxy           string                       `yaml:"siteProxy"`
+	SiteUserAgent       string                       `yaml:"siteUserAgent"`
+	SiteImpersonate     string                       `yaml:"siteImpersonate"`
+	SiteHttpHeaders     [][]string                   `yaml:"siteHttpHeaders"`
+	SiteJa3             string                       `yaml:"siteJa3"`
+	SiteTimeout         int64                        `yaml:"siteTimeout"`  // 访问网站超时时间(秒)
+	SiteInsecure        bool                         `yaml:"siteInsecure"` // 强制禁用所有站点 TLS 证书校验。
+	SiteH2Fingerprint   string                       `yaml:"siteH2Fingerprint"`
+	BrushEnableStats    bool                         `yaml:"brushEnableStats"`
+	Clients             []*ClientConfigStruct        `yaml:"clients"`
+	Sites               []*SiteConfigStruct          `yaml:"sites"`
+	Groups              []*GroupConfigStruct         `yaml:"groups"`
+	Aliases             []*AliasConfigStruct         `yaml:"aliases"`
+	Cookieclouds        []*CookiecloudConfigStruct   `yaml:"cookieclouds"`
+	Jobs                []*JobConfigStruct           `yaml:"jobs"`
+	BrushStrategies     []*BrushStrategyConfigStruct `yaml:"brushStrategies"`
+	Notifiers           []*NotifierConfigStruct      `yaml:"notifiers"`
+	Comment             string                       `yaml:"comment"`
 	// 公网 BT 种子的分享率(Up/Dl)限制(到达后停止做种)。"add" 等命令添加公网种子到BT客户端时会自动应用此限制。
 	// 0 : unlimited。仅 qBittorrent 支持此选项。
 	PublicTorrentRatioLimit float64 `yaml:"publicTorrentRatioLimit"`
@@ -299,6 +454,9 @@ var (
 	aliasesConfigMap      = map[string]*AliasConfigStruct{}
 	groupsConfigMap       = map[string]*GroupConfigStruct{}
 	cookiecloudsConfigMap = map[string]*CookiecloudConfigStruct{}
+	jobsConfigMap         = map[string]*JobConfigStruct{}
+	notifiersConfigMap    = map[string]*NotifierConfigStruct{}
+	brushStrategiesMap    = map[string]*BrushStrategyConfigStruct{}
 	internalAliasesMap    = map[string]*AliasConfigStruct{}
 	once                  sync.Once
 )
@@ -444,6 +602,21 @@ func Get() *ConfigStruct {
 			}
 			client.BrushDefaultUploadSpeedLimitValue = v
 
+			if client.DynamicSeedingSize != "" {
+				if v, err = util.RAMInBytes(client.DynamicSeedingSize); err != nil || v < 0 {
+					log.Fatalf("Invalid dynamicSeedingSize value %q in client config: %v", client.DynamicSeedingSize, err)
+				}
+				client.DynamicSeedingSizeValue = v
+			}
+
+			for _, savePath := range append(client.BrushSavePaths, client.DynamicSeedingSavePaths...) {
+				v, err = util.RAMInBytes(savePath.MinFreeSpace)
+				if err != nil || v < 0 {
+					v = client.BrushMinDiskSpaceValue
+				}
+				savePath.MinFreeSpaceValue = v
+			}
+
 			if client.Url != "" {
 				urlObj, err := url.Parse(client.Url)
 				if err != nil {
@@ -503,6 +676,27 @@ func Get() *ConfigStruct {
 			}
 			cookiecloudsConfigMap[cookiecloud.Name] = cookiecloud
 		}
+		for _, job := range configData.Jobs {
+			assertConfigItemNameIsValid("job", job.Name, job)
+			if jobsConfigMap[job.Name] != nil {
+				log.Fatalf("Invalid config file: duplicate job name %s found", job.Name)
+			}
+			jobsConfigMap[job.Name] = job
+		}
+		for _, brushStrategy := range configData.BrushStrategies {
+			assertConfigItemNameIsValid("brush strategy", brushStrategy.Name, brushStrategy)
+			if brushStrategiesMap[brushStrategy.Name] != nil {
+				log.Fatalf("Invalid config file: duplicate brush strategy name %s found", brushStrategy.Name)
+			}
+			brushStrategiesMap[brushStrategy.Name] = brushStrategy
+		}
+		for _, notifier := range configData.Notifiers {
+			assertConfigItemNameIsValid("notifier", notifier.Name, notifier)
+			if notifiersConfigMap[notifier.Name] != nil {
+				log.Fatalf("Invalid config file: duplicate notifier name %s found", notifier.Name)
+			}
+			notifiersConfigMap[notifier.Name] = notifier
+		}
 		configData.ClientsEnabled = util.Filter(configData.Clients, func(c *ClientConfigStruct) bool {
 			return !c.Disabled
 		})
@@ -546,6 +740,30 @@ func GetAliasConfig(name string) *AliasConfigStruct {
 	return internalAliasesMap[name]
 }
 
+func GetJobConfig(name string) *JobConfigStruct {
+	Get()
+	if name == "" {
+		return nil
+	}
+	return jobsConfigMap[name]
+}
+
+func GetNotifierConfig(name string) *NotifierConfigStruct {
+	Get()
+	if name == "" {
+		return nil
+	}
+	return notifiersConfigMap[name]
+}
+
+func GetBrushStrategyConfig(name string) *BrushStrategyConfigStruct {
+	Get()
+	if name == "" {
+		return nil
+	}
+	return brushStrategiesMap[name]
+}
+
 func GetCookiecloudConfig(name string) *CookiecloudConfigStruct {
 	Get()
 	if name == "" {
@@ -573,6 +791,28 @@ func GetGroupSites(name string) []string {
 	return nil
 }
 
+// Return client names of a group, or nil if group does not exist or has no clients
+func GetGroupClients(name string) []string {
+	group := GetGroupConfig(name)
+	if group != nil && len(group.Clients) > 0 {
+		return group.Clients
+	}
+	return nil
+}
+
+// Parse an slice of client or group names, expand group name to client names, return the final slice of names
+func ParseGroupClients(names ...string) []string {
+	names2 := []string{}
+	for _, name := range names {
+		if groupClients := GetGroupClients(name); groupClients != nil {
+			names2 = append(names2, groupClients...)
+		} else {
+			names2 = append(names2, name)
+		}
+	}
+	return util.UniqueSlice(names2)
+}
+
 func ParseGroupAndOtherNamesWithoutDeduplicate(names ...string) []string {
 	names2 := []string{}
 	for _, name := range names {
@@ -607,6 +847,9 @@ func (groupConfig *GroupConfigStruct) MatchFilter(filter string) bool {
 	return util.ContainsI(groupConfig.Name, filter) ||
 		slices.ContainsFunc(groupConfig.Sites, func(s string) bool {
 			return strings.EqualFold(s, filter)
+		}) ||
+		slices.ContainsFunc(groupConfig.Clients, func(s string) bool {
+			return strings.EqualFold(s, filter)
 		})
 }
 
@@ -630,6 +873,13 @@ func (siteConfig *SiteConfigStruct) Register() {
 		siteConfig.Url = urlObj.String()
 	}
 
+	siteConfig.HnRSeedingTimeValue = DEFAULT_SITE_HNR_SEEDING_TIME
+	if siteConfig.HnRSeedingTime != "" {
+		if siteConfig.HnRSeedingTimeValue, err = util.ParseTimeDuration(siteConfig.HnRSeedingTime); err != nil {
+			log.Fatalf("Invalid hnrSeedingTime value %q in site config: %v", siteConfig.HnRSeedingTime, err)
+		}
+	}
+
 	v, err = util.RAMInBytes(siteConfig.BrushTorrentMinSizeLimit)
 	if err != nil || v <= 0 {
 		v = DEFAULT_SITE_BRUSH_TORRENT_MIN_SIZE_LIMIT
@@ -642,6 +892,12 @@ func (siteConfig *SiteConfigStruct) Register() {
 	}
 	siteConfig.BrushTorrentMaxSizeLimitValue = v
 
+	if siteConfig.BrushMinSiteBuffer != "" {
+		if siteConfig.BrushMinSiteBufferValue, err = util.RAMInBytes(siteConfig.BrushMinSiteBuffer); err != nil {
+			log.Fatalf("Invalid brushMinSiteBuffer value %q in site config: %v", siteConfig.BrushMinSiteBuffer, err)
+		}
+	}
+
 	if siteConfig.DynamicSeedingSize != "" {
 		if v, err = util.RAMInBytes(siteConfig.DynamicSeedingSize); err != nil || v < 0 {
 			log.Fatalf("Invalid dynamicSeedingSize value %q in site config: %v", siteConfig.DynamicSeedingSize, err)
@@ -649,6 +905,12 @@ func (siteConfig *SiteConfigStruct) Register() {
 		siteConfig.DynamicSeedingSizeValue = v
 	}
 
+	for _, share := range []string{siteConfig.DynamicSeedingMinShare, siteConfig.DynamicSeedingMaxShare} {
+		if _, err = ParseDynamicSeedingShare(share, 0); err != nil {
+			log.Fatalf("Invalid dynamic seeding share value %q in site config: %v", share, err)
+		}
+	}
+
 	if siteConfig.DynamicSeedingTorrentMaxSize != "" {
 		if v, err = util.RAMInBytes(siteConfig.DynamicSeedingTorrentMaxSize); err != nil {
 			log.Fatalf("Invalid dynamicSeedingTorrentMaxSize value %q in site config: %v",
@@ -801,3 +1063,23 @@ func LockConfigDirFile(name string) (*flock.Flock, error) {
 	}
 	return lock, nil
 }
+
+// Parse a dynamic seeding share value of site: a size (e.g. "200GiB") or
+// a percentage of total dynamic seeding size (e.g. "30%"). Return -1 if share is empty.
+func ParseDynamicSeedingShare(share string, total int64) (int64, error) {
+	if share == "" {
+		return -1, nil
+	}
+	if percent, found := strings.CutSuffix(share, "%"); found {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p < 0 || p > 100 {
+			return 0, fmt.Errorf("invalid percentage %q", share)
+		}
+		return int64(p / 100 * float64(total)), nil
+	}
+	v, err := util.RAMInBytes(share)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", share)
+	}
+	return v, nil
+}
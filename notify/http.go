@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+
+	"github.com/sagan/ptool/config"
+)
+
+const DEFAULT_HTTP_CONTENT_TYPE = "application/json"
+
+// Post the rendered template to url. The template data is the Event, e.g. (Telegram bot):
+// {"chat_id": "123456", "text": {{ .Text | toJson }}}
+type HttpNotifier struct {
+	name     string
+	config   *config.NotifierConfigStruct
+	template *template.Template
+}
+
+func (n *HttpNotifier) GetName() string {
+	return n.name
+}
+
+func (n *HttpNotifier) GetNotifierConfig() *config.NotifierConfigStruct {
+	return n.config
+}
+
+func (n *HttpNotifier) Send(event *Event) error {
+	buf := &bytes.Buffer{}
+	if err := n.template.Execute(buf, event); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	contentType := n.config.ContentType
+	if contentType == "" {
+		contentType = DEFAULT_HTTP_CONTENT_TYPE
+	}
+	return post(n.config, contentType, buf.Bytes())
+}
+
+func NewHttpNotifier(notifierConfig *config.NotifierConfigStruct) (Notifier, error) {
+	if notifierConfig.Url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if notifierConfig.Template == "" {
+		return nil, fmt.Errorf("template is required")
+	}
+	tpl, err := template.New("template").Funcs(sprig.FuncMap()).Parse(notifierConfig.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return &HttpNotifier{name: notifierConfig.Name, config: notifierConfig, template: tpl}, nil
+}
+
+func init() {
+	Register(&RegInfo{
+		Name:    "http",
+		Creator: NewHttpNotifier,
+	})
+}
@@ -0,0 +1,161 @@
+// Event bus & notifiers.
+// Commands publish structured events, which are delivered to the matched notifiers in [[notifiers]] config.
+package notify
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+// Event types.
+const (
+	EVENT_BRUSH_ADD           = "brush_add"           // brush added a torrent to client
+	EVENT_BRUSH_DELETE        = "brush_delete"        // brush deleted a torrent from client
+	EVENT_XSEED_ADD           = "xseed_add"           // iyuu added a xseed torrent to client
+	EVENT_TORRENT_NOT_EXIST   = "torrent_not_exist"   // client torrent's tracker reports it's not registered
+	EVENT_SITE_COOKIE_EXPIRED = "site_cookie_expired" // site cookie is invalid (redirected to login page)
+	EVENT_JOB_FAILED          = "job_failed"          // daemon job run failed
+)
+
+var EventTypes = []string{
+	EVENT_BRUSH_ADD,
+	EVENT_BRUSH_DELETE,
+	EVENT_XSEED_ADD,
+	EVENT_TORRENT_NOT_EXIST,
+	EVENT_SITE_COOKIE_EXPIRED,
+	EVENT_JOB_FAILED,
+}
+
+// Max time to wait for pending deliveries when program exits.
+const EXIT_TIMEOUT = 30 * time.Second
+
+type Event struct {
+	Type    string         `json:"type"`
+	Time    int64          `json:"time"`
+	Site    string         `json:"site,omitempty"`
+	Client  string         `json:"client,omitempty"`
+	Title   string         `json:"title"`             // one line summary
+	Message string         `json:"message,omitempty"` // optional details
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// Return the human readable text of event: title + message.
+func (event *Event) Text() string {
+	if event.Message == "" {
+		return event.Title
+	}
+	return event.Title + "\n" + event.Message
+}
+
+type Notifier interface {
+	GetName() string
+	GetNotifierConfig() *config.NotifierConfigStruct
+	Send(event *Event) error
+}
+
+type RegInfo struct {
+	Name    string
+	Creator func(*config.NotifierConfigStruct) (Notifier, error)
+}
+
+var (
+	registryMap = map[string]*RegInfo{}
+	notifiers   = map[string]Notifier{}
+	mu          sync.Mutex
+	pending     sync.WaitGroup
+)
+
+func Register(regInfo *RegInfo) {
+	registryMap[regInfo.Name] = regInfo
+}
+
+func CreateNotifier(name string) (Notifier, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if notifiers[name] != nil {
+		return notifiers[name], nil
+	}
+	notifierConfig := config.GetNotifierConfig(name)
+	if notifierConfig == nil {
+		return nil, fmt.Errorf("notifier %s not found", name)
+	}
+	for _, eventType := range notifierConfig.Events {
+		if !slices.Contains(EventTypes, eventType) {
+			return nil, fmt.Errorf("notifier %s: invalid event type %q", name, eventType)
+		}
+	}
+	regInfo := registryMap[notifierConfig.Type]
+	if regInfo == nil {
+		return nil, fmt.Errorf("unsupported notifier type %s", notifierConfig.Type)
+	}
+	notifierInstance, err := regInfo.Creator(notifierConfig)
+	if err != nil {
+		return nil, err
+	}
+	notifiers[name] = notifierInstance
+	return notifierInstance, nil
+}
+
+// Return true if the event passes the notifier's filters.
+// If sites (or clients) filter is set, events not related to any site (or client) do not match.
+func Match(notifierConfig *config.NotifierConfigStruct, event *Event) bool {
+	if len(notifierConfig.Events) > 0 && !slices.Contains(notifierConfig.Events, event.Type) {
+		return false
+	}
+	if len(notifierConfig.Sites) > 0 &&
+		(event.Site == "" || !slices.Contains(config.ParseGroupAndOtherNames(notifierConfig.Sites...), event.Site)) {
+		return false
+	}
+	if len(notifierConfig.Clients) > 0 && (event.Client == "" || !slices.Contains(notifierConfig.Clients, event.Client)) {
+		return false
+	}
+	return true
+}
+
+// Publish an event. It's delivered to all matched enabled notifiers asynchronously.
+// Delivery failures are logged but otherwise ignored.
+func Publish(event *Event) {
+	if event.Time == 0 {
+		event.Time = util.Now()
+	}
+	for _, notifierConfig := range config.Get().Notifiers {
+		if notifierConfig.Disabled || !Match(notifierConfig, event) {
+			continue
+		}
+		notifierInstance, err := CreateNotifier(notifierConfig.Name)
+		if err != nil {
+			log.Warnf("Failed to create notifier %s: %v", notifierConfig.Name, err)
+			continue
+		}
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+			if err := notifierInstance.Send(event); err != nil {
+				log.Warnf("Failed to send %s event to notifier %s: %v", event.Type, notifierInstance.GetName(), err)
+			} else {
+				log.Debugf("Sent %s event to notifier %s", event.Type, notifierInstance.GetName())
+			}
+		}()
+	}
+}
+
+// Wait for all pending deliveries to finish, or EXIT_TIMEOUT.
+func Exit() {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(EXIT_TIMEOUT):
+		log.Warnf("Timeout waiting for pending notifications")
+	}
+}
@@ -0,0 +1,149 @@
+package notify_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/notify"
+)
+
+var event = &notify.Event{
+	Type:    notify.EVENT_BRUSH_DELETE,
+	Time:    1700000000,
+	Site:    "mteam",
+	Client:  "local",
+	Title:   `Brush deleted "foo" torrent`,
+	Message: "Ratio: 1.5",
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		config *config.NotifierConfigStruct
+		want   bool
+	}{
+		{&config.NotifierConfigStruct{}, true},
+		{&config.NotifierConfigStruct{Events: []string{notify.EVENT_BRUSH_DELETE}, Clients: []string{"local"}}, true},
+		{&config.NotifierConfigStruct{Events: []string{notify.EVENT_XSEED_ADD}}, false},
+		{&config.NotifierConfigStruct{Clients: []string{"remote"}}, false},
+	}
+	for i, c := range cases {
+		if got := notify.Match(c.config, event); got != c.want {
+			t.Errorf("case %d: expect %t, got %t", i, c.want, got)
+		}
+	}
+}
+
+func TestHttpNotifiers(t *testing.T) {
+	var contentType, body, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		auth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	webhook, err := notify.NewWebhookNotifier(&config.NotifierConfigStruct{Name: "webhook", Url: server.URL,
+		Headers: []string{"Authorization: Bearer token"}})
+	if err != nil {
+		t.Fatalf("failed to create webhook notifier: %v", err)
+	}
+	if err = webhook.Send(event); err != nil {
+		t.Fatalf("failed to send webhook: %v", err)
+	}
+	received := &notify.Event{}
+	json.Unmarshal([]byte(body), received)
+	if contentType != "application/json" || auth != "Bearer token" ||
+		received.Title != event.Title || received.Site != event.Site {
+		t.Errorf("unexpected webhook request: content-type=%s, auth=%s, body=%s", contentType, auth, body)
+	}
+
+	chat, err := notify.NewHttpNotifier(&config.NotifierConfigStruct{Name: "chat", Url: server.URL,
+		Template: `{"chat_id": "123", "text": {{ .Text | toJson }}}`})
+	if err != nil {
+		t.Fatalf("failed to create http notifier: %v", err)
+	}
+	if err = chat.Send(event); err != nil {
+		t.Fatalf("failed to send http notification: %v", err)
+	}
+	want := `{"chat_id": "123", "text": "Brush deleted \"foo\" torrent\nRatio: 1.5"}`
+	if body != want {
+		t.Errorf("unexpected http notification body: %s", body)
+	}
+}
+
+func TestSmtpNotifier(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	mails := make(chan string, 1)
+	go serveSmtp(listener, mails)
+
+	smtpNotifier, err := notify.NewSmtpNotifier(&config.NotifierConfigStruct{Name: "mail",
+		Url: listener.Addr().String(), From: "ptool@example.com", To: []string{"admin@example.com"}})
+	if err != nil {
+		t.Fatalf("failed to create smtp notifier: %v", err)
+	}
+	if err = smtpNotifier.Send(event); err != nil {
+		t.Fatalf("failed to send mail: %v", err)
+	}
+	mail := <-mails
+	if !strings.Contains(mail, "To: admin@example.com") || !strings.Contains(mail, "Ratio: 1.5") ||
+		!strings.Contains(mail, "Subject: [ptool] Brush deleted") {
+		t.Errorf("unexpected mail: %s", mail)
+	}
+}
+
+// A minimal stand-in SMTP server that accepts one mail.
+func serveSmtp(listener net.Listener, mails chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case command == "DATA":
+			reply("354 go ahead")
+			data := ""
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data += line
+			}
+			mails <- data
+			reply("250 ok")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
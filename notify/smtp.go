@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/config"
+)
+
+// Send event as plain text email via SMTP server.
+type SmtpNotifier struct {
+	name   string
+	config *config.NotifierConfigStruct
+	host   string
+}
+
+func (n *SmtpNotifier) GetName() string {
+	return n.name
+}
+
+func (n *SmtpNotifier) GetNotifierConfig() *config.NotifierConfigStruct {
+	return n.config
+}
+
+func (n *SmtpNotifier) Send(event *Event) error {
+	timeout := getTimeout(n.config)
+	var conn net.Conn
+	var err error
+	implicitTls := strings.HasSuffix(n.config.Url, ":465")
+	if implicitTls {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", n.config.Url,
+			&tls.Config{ServerName: n.host})
+	} else {
+		conn, err = net.DialTimeout("tcp", n.config.Url, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if !implicitTls {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+				return err
+			}
+		}
+	}
+	if n.config.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.host)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(n.config.From); err != nil {
+		return err
+	}
+	for _, to := range n.config.To {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(n.message(event)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (n *SmtpNotifier) message(event *Event) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(n.config.To, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "[ptool] "+event.Title))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Unix(event.Time, 0).Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qw := quotedprintable.NewWriter(buf)
+	qw.Write([]byte(event.Text() + "\n"))
+	qw.Close()
+	return buf.Bytes()
+}
+
+func NewSmtpNotifier(notifierConfig *config.NotifierConfigStruct) (Notifier, error) {
+	host, _, err := net.SplitHostPort(notifierConfig.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q, must be host:port: %w", notifierConfig.Url, err)
+	}
+	if notifierConfig.From == "" || len(notifierConfig.To) == 0 {
+		return nil, fmt.Errorf("from and to are required")
+	}
+	return &SmtpNotifier{name: notifierConfig.Name, config: notifierConfig, host: host}, nil
+}
+
+func init() {
+	Register(&RegInfo{
+		Name:    "smtp",
+		Creator: NewSmtpNotifier,
+	})
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/config"
+)
+
+// Post event as JSON to url.
+type WebhookNotifier struct {
+	name   string
+	config *config.NotifierConfigStruct
+}
+
+func (n *WebhookNotifier) GetName() string {
+	return n.name
+}
+
+func (n *WebhookNotifier) GetNotifierConfig() *config.NotifierConfigStruct {
+	return n.config
+}
+
+func (n *WebhookNotifier) Send(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return post(n.config, "application/json", body)
+}
+
+func NewWebhookNotifier(notifierConfig *config.NotifierConfigStruct) (Notifier, error) {
+	if notifierConfig.Url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	return &WebhookNotifier{name: notifierConfig.Name, config: notifierConfig}, nil
+}
+
+// Post body to notifierConfig.Url, return error if the response status is not 2xx.
+func post(notifierConfig *config.NotifierConfigStruct, contentType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, notifierConfig.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for _, header := range notifierConfig.Headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found {
+			return fmt.Errorf("invalid header %q", header)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	httpClient := &http.Client{Timeout: getTimeout(notifierConfig)}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("status=%d, body=%s", res.StatusCode, msg)
+	}
+	io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func getTimeout(notifierConfig *config.NotifierConfigStruct) time.Duration {
+	if notifierConfig.Timeout > 0 {
+		return time.Duration(notifierConfig.Timeout) * time.Second
+	}
+	return time.Duration(config.DEFAULT_TIMEOUT) * time.Second
+}
+
+func init() {
+	Register(&RegInfo{
+		Name:    "webhook",
+		Creator: NewWebhookNotifier,
+	})
+}
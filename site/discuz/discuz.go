@@ -81,6 +81,14 @@ func (dzsite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torr
 	return nil, site.ErrUnimplemented
 }
 
+func (dzsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
+func (dzsite *Site) Attendance() (*site.AttendanceResult, error) {
+	return nil, site.ErrAttendanceUnsupported
+}
+
 func (dzsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
 	if !util.IsUrl(torrentUrl) {
 		id = strings.TrimPrefix(torrentUrl, dzsite.GetName()+".")
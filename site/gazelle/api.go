@@ -0,0 +1,443 @@
+package gazelle
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Noooste/azuretls-client"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// Gazelle JSON API (ajax.php), which is also available in most Gazelle forks (e.g. GazellePW).
+// Docs: https://github.com/OPSnet/Gazelle/wiki/JSON-API-Documentation .
+// Requests are authenticated by site cookie, or by "Authorization: <apiToken>" header if apiToken is configured.
+// Some sites require a prefix in header value, e.g. OPS uses "token <key>", so configure apiToken accordingly.
+const API_URL = "ajax.php"
+
+var apiSortFields = map[string]string{
+	"time":     "time",
+	"size":     "size",
+	"seeders":  "seeders",
+	"leechers": "leechers",
+	"snatched": "snatched",
+}
+
+// The API client shared by Gazelle family sites.
+type Api struct {
+	Site       site.Site
+	HttpClient *azuretls.Session
+	Location   *time.Location
+}
+
+type apiResponse struct {
+	Status   string          `json:"status"`
+	Error    string          `json:"error"`
+	Response json.RawMessage `json:"response"`
+}
+
+type apiIndex struct {
+	Username  string     `json:"username"`
+	Id        jsonNumber `json:"id"`
+	Userstats struct {
+		Uploaded           jsonNumber `json:"uploaded"`
+		Downloaded         jsonNumber `json:"downloaded"`
+		Ratio              jsonNumber `json:"ratio"`
+		Class              string     `json:"class"`
+		BonusPoints        jsonNumber `json:"bonusPoints"`        // Orpheus and some other sites only
+		BonusPointsPerHour jsonNumber `json:"bonusPointsPerHour"` // Orpheus and some other sites only
+	} `json:"userstats"`
+}
+
+type apiUser struct {
+	Stats struct {
+		JoinedDate string `json:"joinedDate"`
+	} `json:"stats"`
+	Community struct {
+		Seeding  jsonNumber `json:"seeding"`
+		Leeching jsonNumber `json:"leeching"`
+	} `json:"community"`
+}
+
+type apiBrowse struct {
+	CurrentPage jsonNumber  `json:"currentPage"`
+	Pages       jsonNumber  `json:"pages"`
+	Results     []*apiGroup `json:"results"`
+}
+
+// A torrent group (e.g. an album or a movie) of browse results.
+// Groups of non-music categories do not have torrents list, instead the only torrent's fields are in group itself.
+type apiGroup struct {
+	GroupId   jsonNumber    `json:"groupId"`
+	GroupName string        `json:"groupName"`
+	GroupYear jsonNumber    `json:"groupYear"`
+	GroupTime string        `json:"groupTime"`
+	Artist    string        `json:"artist"`
+	Tags      []string      `json:"tags"`
+	Torrents  []*apiTorrent `json:"torrents"`
+	apiTorrent
+}
+
+type apiTorrent struct {
+	TorrentId           jsonNumber `json:"torrentId"`
+	RemasterYear        jsonNumber `json:"remasterYear"`
+	RemasterTitle       string     `json:"remasterTitle"`
+	Format              string     `json:"format"`
+	Encoding            string     `json:"encoding"`
+	Media               string     `json:"media"`
+	Source              string     `json:"source"`     // GazellePW
+	Codec               string     `json:"codec"`      // GazellePW
+	Resolution          string     `json:"resolution"` // GazellePW
+	Container           string     `json:"container"`  // GazellePW
+	Time                string     `json:"time"`
+	Size                jsonNumber `json:"size"`
+	Seeders             jsonNumber `json:"seeders"`
+	Leechers            jsonNumber `json:"leechers"`
+	Snatches            jsonNumber `json:"snatches"`
+	IsFreeleech         jsonNumber `json:"isFreeleech"`
+	IsNeutralLeech      jsonNumber `json:"isNeutralLeech"`
+	IsPersonalFreeleech jsonNumber `json:"isPersonalFreeleech"` // a freeleech token has been used on it
+	CanUseToken         jsonNumber `json:"canUseToken"`
+}
+
+// Response of action=torrent.
+type apiTorrentDetails struct {
+	Group struct {
+		Id        jsonNumber `json:"id"`
+		Name      string     `json:"name"`
+		Year      jsonNumber `json:"year"`
+		Tags      []string   `json:"tags"`
+		WikiBody  string     `json:"wikiBody"`
+		MusicInfo *struct {
+			Artists []struct {
+				Name string `json:"name"`
+			} `json:"artists"`
+		} `json:"musicInfo"`
+	} `json:"group"`
+	Torrent struct {
+		Id          jsonNumber `json:"id"`
+		InfoHash    string     `json:"infoHash"`
+		Description string     `json:"description"`
+		FreeTorrent jsonNumber `json:"freeTorrent"` // 1: freeleech; 2: neutral leech
+		Snatched    jsonNumber `json:"snatched"`
+		FileList    string     `json:"fileList"`
+		Username    string     `json:"username"`
+		apiTorrent
+	} `json:"torrent"`
+}
+
+// A json value that may be a number, a numeric string or a bool. Other values are treated as 0.
+type jsonNumber float64
+
+func (n *jsonNumber) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+	if str == "true" {
+		*n = 1
+	} else {
+		value, _ := strconv.ParseFloat(str, 64)
+		*n = jsonNumber(value)
+	}
+	return nil
+}
+
+func (n jsonNumber) String() string {
+	return fmt.Sprint(int64(n))
+}
+
+func NewApi(siteInstance site.Site, httpClient *azuretls.Session, location *time.Location) *Api {
+	return &Api{
+		Site:       siteInstance,
+		HttpClient: httpClient,
+		Location:   location,
+	}
+}
+
+// Return http headers with the API authorization header appended if apiToken is configured.
+func GetApiHttpHeaders(apiToken string, httpHeaders [][]string) [][]string {
+	if apiToken == "" {
+		return httpHeaders
+	}
+	headers := append([][]string{}, httpHeaders...)
+	return append(headers, []string{"Authorization", apiToken})
+}
+
+// Request ajax.php?action=<action>, parse the "response" field of result into v.
+func (api *Api) Request(action string, params url.Values, v any) error {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("action", action)
+	apiUrl := api.Site.GetSiteConfig().Url + API_URL + "?" + query.Encode()
+	res, _, err := util.FetchUrlWithAzuretls(apiUrl, api.HttpClient, api.Site.GetSiteConfig().Cookie,
+		site.GetUa(api.Site), api.Site.GetDefaultHttpHeaders())
+	if res != nil && strings.Contains(res.Request.Url, "login.php") {
+		return site.NewNotLoginedError(api.Site.GetName())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch api: %w", err)
+	}
+	var apiRes apiResponse
+	if err = json.Unmarshal(res.Body, &apiRes); err != nil {
+		return fmt.Errorf("failed to parse api response: %w", err)
+	}
+	if apiRes.Status != "success" {
+		return fmt.Errorf("api error: %s", apiRes.Error)
+	}
+	return json.Unmarshal(apiRes.Response, v)
+}
+
+func (api *Api) GetStatus() (*site.Status, error) {
+	var index apiIndex
+	if err := api.Request("index", nil, &index); err != nil {
+		return nil, err
+	}
+	status := &site.Status{
+		UserName:         index.Username,
+		UserUploaded:     int64(index.Userstats.Uploaded),
+		UserDownloaded:   int64(index.Userstats.Downloaded),
+		UserRatio:        float64(index.Userstats.Ratio),
+		UserClass:        index.Userstats.Class,
+		UserBonus:        float64(index.Userstats.BonusPoints),
+		UserBonusPerHour: float64(index.Userstats.BonusPointsPerHour),
+	}
+	// seeding / leeching counts are only available in user profile, which may be hidden by paranoia settings.
+	var user apiUser
+	if err := api.Request("user", url.Values{"id": {index.Id.String()}}, &user); err == nil {
+		status.TorrentsSeedingCnt = int64(user.Community.Seeding)
+		status.TorrentsLeechingCnt = int64(user.Community.Leeching)
+		status.UserJoinTime, _ = util.ParseTime(user.Stats.JoinedDate, api.Location)
+	}
+	return status, nil
+}
+
+// pageMarker is the page number (starts from 1). baseUrl is the torrents.php (or ajax.php?action=browse) url,
+// whose query params are used as browse params.
+func (api *Api) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
+	torrents []*site.Torrent, nextPageMarker string, err error) {
+	if sort != "" && sort != constants.NONE && apiSortFields[sort] == "" {
+		return nil, "", fmt.Errorf("unsupported sort field: %s", sort)
+	}
+	params, err := api.getBrowseParams(baseUrl)
+	if err != nil {
+		return nil, "", err
+	}
+	page := int64(1)
+	if pageMarker != "" {
+		if page, err = strconv.ParseInt(pageMarker, 10, 64); err != nil || page < 1 {
+			return nil, "", fmt.Errorf("invalid page marker %q", pageMarker)
+		}
+	}
+	params.Set("page", fmt.Sprint(page))
+	if sort != "" && sort != constants.NONE {
+		params.Set("order_by", apiSortFields[sort])
+		if desc {
+			params.Set("order_way", "desc")
+		} else {
+			params.Set("order_way", "asc")
+		}
+	}
+	torrents, pages, err := api.browse(params)
+	if err != nil {
+		return nil, "", err
+	}
+	if page < pages {
+		nextPageMarker = fmt.Sprint(page + 1)
+	}
+	return torrents, nextPageMarker, nil
+}
+
+func (api *Api) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
+	torrents, _, err := api.browse(url.Values{"order_by": {"time"}, "order_way": {"desc"}})
+	return torrents, err
+}
+
+func (api *Api) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
+	if baseUrl != "" && strings.Contains(baseUrl, "%s") {
+		baseUrl = strings.Replace(baseUrl, "%s", url.QueryEscape(keyword), 1)
+		keyword = ""
+	}
+	params, err := api.getBrowseParams(baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	if keyword != "" {
+		params.Set("searchstr", keyword)
+	}
+	torrents, _, err := api.browse(params)
+	return torrents, err
+}
+
+// Get torrent details by id using action=torrent. Different from browse results, it has info hash & file list.
+func (api *Api) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	var details apiTorrentDetails
+	if err := api.Request("torrent", url.Values{"id": {id}}, &details); err != nil {
+		return nil, err
+	}
+	group := &details.Group
+	artist := ""
+	if group.MusicInfo != nil {
+		var artists []string
+		for _, a := range group.MusicInfo.Artists {
+			artists = append(artists, a.Name)
+		}
+		artist = strings.Join(artists, " & ")
+	}
+	torrentDetails := &details.Torrent
+	torrentDetails.TorrentId = torrentDetails.Id
+	torrentDetails.Snatches = torrentDetails.Snatched
+	switch torrentDetails.FreeTorrent {
+	case 1:
+		torrentDetails.IsFreeleech = 1
+	case 2:
+		torrentDetails.IsNeutralLeech = 1
+	}
+	torrent := api.convertTorrent(&apiGroup{
+		GroupName: group.Name,
+		GroupYear: group.Year,
+		Artist:    artist,
+		Tags:      group.Tags,
+	}, &torrentDetails.apiTorrent)
+	torrent.InfoHash = strings.ToLower(torrentDetails.InfoHash)
+	// the edition & format infos is kept as the first line.
+	if description := strings.TrimSpace(html.UnescapeString(torrentDetails.Description)); description != "" {
+		torrent.Description += "\n\n" + description
+	}
+	result := &site.TorrentDetails{
+		Torrent:  torrent,
+		Files:    parseFileList(torrentDetails.FileList),
+		Uploader: torrentDetails.Username,
+	}
+	result.ImdbUrl, result.DoubanUrl = site.FindImdbDoubanUrls(torrentDetails.Description + " " + group.WikiBody)
+	return result, nil
+}
+
+// Parse file list of action=torrent, which is in "name{{{size}}}|||name{{{size}}}" format.
+func parseFileList(fileList string) (files []*site.TorrentFile) {
+	for _, file := range strings.Split(html.UnescapeString(fileList), "|||") {
+		name, size, found := strings.Cut(strings.TrimSuffix(file, "}}}"), "{{{")
+		if !found {
+			continue
+		}
+		sizeValue, _ := strconv.ParseInt(size, 10, 64)
+		files = append(files, &site.TorrentFile{Path: name, Size: sizeValue})
+	}
+	return files
+}
+
+// Return the download url of torrent. If apiToken is configured while cookie is not,
+// use the API download action, which accepts Authorization header.
+func (api *Api) GetDownloadUrl(id string) string {
+	siteConfig := api.Site.GetSiteConfig()
+	if siteConfig.ApiToken != "" && siteConfig.Cookie == "" {
+		return siteConfig.Url + API_URL + "?action=download&id=" + id
+	}
+	return siteConfig.Url + "torrents.php?action=download&id=" + id
+}
+
+// Parse browse params from query of baseUrl. Return empty params if baseUrl is empty.
+func (api *Api) getBrowseParams(baseUrl string) (url.Values, error) {
+	if baseUrl == "" {
+		return url.Values{}, nil
+	}
+	urlObj, err := url.Parse(api.Site.GetSiteConfig().ParseSiteUrl(baseUrl, false))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	params := urlObj.Query()
+	params.Del("action")
+	params.Del("page")
+	return params, nil
+}
+
+// Browse torrents, flatten the torrent groups. Return torrents and total pages.
+func (api *Api) browse(params url.Values) (torrents []*site.Torrent, pages int64, err error) {
+	var res apiBrowse
+	if err = api.Request("browse", params, &res); err != nil {
+		return nil, 0, err
+	}
+	for _, group := range res.Results {
+		if len(group.Torrents) == 0 {
+			if group.TorrentId > 0 {
+				torrents = append(torrents, api.convertTorrent(group, &group.apiTorrent))
+			}
+			continue
+		}
+		for _, torrent := range group.Torrents {
+			torrents = append(torrents, api.convertTorrent(group, torrent))
+		}
+	}
+	return torrents, int64(res.Pages), nil
+}
+
+func (api *Api) convertTorrent(group *apiGroup, apiTorrent *apiTorrent) *site.Torrent {
+	id := apiTorrent.TorrentId.String()
+	name := html.UnescapeString(group.GroupName)
+	if group.Artist != "" {
+		name = html.UnescapeString(group.Artist) + " - " + name
+	}
+	if group.GroupYear > 0 {
+		name += fmt.Sprintf(" [%d]", int64(group.GroupYear))
+	}
+	// edition & format infos, e.g. "2011 Remaster / FLAC / Lossless / CD"
+	var infos []string
+	edition := html.UnescapeString(apiTorrent.RemasterTitle)
+	if apiTorrent.RemasterYear > 0 {
+		edition = strings.TrimSpace(fmt.Sprint(int64(apiTorrent.RemasterYear), " ", edition))
+	}
+	tags := []string{}
+	for _, tag := range group.Tags {
+		tags = append(tags, html.UnescapeString(tag))
+	}
+	for _, info := range []string{edition, apiTorrent.Format, apiTorrent.Encoding, apiTorrent.Media,
+		apiTorrent.Source, apiTorrent.Codec, apiTorrent.Resolution, apiTorrent.Container} {
+		if info != "" {
+			infos = append(infos, info)
+			if info != edition {
+				tags = append(tags, info)
+			}
+		}
+	}
+	downloadMultiplier := 1.0
+	uploadMultiplier := 1.0
+	neutral := false
+	if apiTorrent.IsNeutralLeech > 0 {
+		downloadMultiplier = 0
+		uploadMultiplier = 0
+		neutral = true
+	} else if apiTorrent.IsFreeleech > 0 || apiTorrent.IsPersonalFreeleech > 0 {
+		downloadMultiplier = 0
+	}
+	if apiTorrent.CanUseToken > 0 && downloadMultiplier > 0 {
+		tags = append(tags, "token")
+	}
+	torrentTime := group.GroupTime
+	if apiTorrent.Time != "" {
+		torrentTime = apiTorrent.Time
+	}
+	ts, _ := util.ParseTime(torrentTime, api.Location)
+	return &site.Torrent{
+		Name:               name,
+		Description:        strings.Join(infos, " / "),
+		Id:                 api.Site.GetName() + "." + id,
+		DownloadUrl:        api.GetDownloadUrl(id),
+		DownloadMultiplier: downloadMultiplier,
+		UploadMultiplier:   uploadMultiplier,
+		DiscountEndTime:    -1,
+		Time:               ts,
+		Size:               int64(apiTorrent.Size),
+		IsSizeAccurate:     true,
+		Seeders:            int64(apiTorrent.Seeders),
+		Leechers:           int64(apiTorrent.Leechers),
+		Snatched:           int64(apiTorrent.Snatches),
+		Neutral:            neutral,
+		Tags:               tags,
+	}
+}
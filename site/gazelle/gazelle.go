@@ -7,6 +7,7 @@ package gazelle
 // 注意下载时的 id 与 torrent.php 页面url里的 id 不同，后者是当前音乐专辑的 id
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -27,6 +28,7 @@ type Site struct {
 	Config      *config.ConfigStruct
 	HttpClient  *azuretls.Session
 	HttpHeaders [][]string
+	Api         *Api
 }
 
 // PublishTorrent implements site.Site.
@@ -55,7 +57,27 @@ func (gzsite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return gzsite.SiteConfig
 }
 
-func (gzsite *Site) GetStatus() (*site.Status, error) {
+// Use the API if possible. Parse the torrents page instead if custom userinfo selectors are configured,
+// or the API is not available.
+func (gzsite *Site) GetStatus() (status *site.Status, err error) {
+	siteConfig := gzsite.SiteConfig
+	if siteConfig.SelectorUserInfoUserName == "" && siteConfig.SelectorUserInfoUploaded == "" &&
+		siteConfig.SelectorUserInfoDownloaded == "" {
+		status, err = gzsite.Api.GetStatus()
+		if err != nil && !errors.Is(err, site.ErrNotLogined) && siteConfig.Cookie != "" {
+			log.Debugf("Failed to get site %s status from api: %v. Fallback to parse page", gzsite.Name, err)
+			status, err = gzsite.getStatusFromPage()
+		}
+	} else {
+		status, err = gzsite.getStatusFromPage()
+	}
+	if err == nil && status.IsOk() {
+		site.SetLogined(gzsite.GetName())
+	}
+	return status, err
+}
+
+func (gzsite *Site) getStatusFromPage() (*site.Status, error) {
 	doc, _, err := util.GetUrlDocWithAzuretls(gzsite.SiteConfig.Url+"torrents.php", gzsite.HttpClient,
 		gzsite.GetSiteConfig().Cookie, site.GetUa(gzsite), gzsite.GetDefaultHttpHeaders())
 	if err != nil {
@@ -87,15 +109,23 @@ func (gzsite *Site) GetStatus() (*site.Status, error) {
 
 func (gzsite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
 	torrents []*site.Torrent, nextPageMarker string, err error) {
-	return nil, "", site.ErrUnimplemented
+	return gzsite.Api.GetAllTorrents(sort, desc, pageMarker, baseUrl)
 }
 
 func (gzsite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	return gzsite.Api.GetLatestTorrents(full)
 }
 
 func (gzsite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	return gzsite.Api.SearchTorrents(keyword, baseUrl)
+}
+
+func (gzsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return gzsite.Api.GetTorrentDetails(id)
+}
+
+func (gzsite *Site) Attendance() (*site.AttendanceResult, error) {
+	return nil, site.ErrAttendanceUnsupported
 }
 
 func (gzsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
@@ -112,12 +142,12 @@ func (gzsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename
 }
 
 func (gzsite *Site) DownloadTorrentById(id string) ([]byte, string, error) {
-	torrentUrl := gzsite.SiteConfig.Url + "torrents.php?action=download&id=" + id
+	torrentUrl := gzsite.Api.GetDownloadUrl(id)
 	return site.DownloadTorrentByUrl(gzsite, gzsite.HttpClient, torrentUrl, id)
 }
 
 func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (site.Site, error) {
-	if siteConfig.Cookie == "" {
+	if siteConfig.Cookie == "" && siteConfig.ApiToken == "" {
 		log.Warnf("Site %s has no cookie provided", name)
 	}
 	location, err := time.LoadLocation(siteConfig.GetTimezone())
@@ -134,8 +164,9 @@ func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.Co
 		SiteConfig:  siteConfig,
 		Config:      config,
 		HttpClient:  httpClient,
-		HttpHeaders: httpHeaders,
+		HttpHeaders: GetApiHttpHeaders(siteConfig.ApiToken, httpHeaders),
 	}
+	site.Api = NewApi(site, httpClient, location)
 	return site, nil
 }
 
@@ -0,0 +1,111 @@
+package gazelle_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site/gazelle"
+)
+
+const browseResponse = `{"status": "success", "response": {"currentPage": 1, "pages": 3, "results": [
+  {"groupId": 410618, "groupName": "Foo &amp; Bar", "artist": "Artist", "tags": ["rock"], "groupYear": 2011,
+   "groupTime": "1339117820", "torrents": [
+    {"torrentId": 959473, "remasterYear": 2011, "remasterTitle": "Deluxe", "media": "CD", "format": "FLAC",
+     "encoding": "Lossless", "time": "2012-06-08 01:10:20", "size": 1000, "snatches": 2, "seeders": 3,
+     "leechers": 4, "isFreeleech": false, "isNeutralLeech": false, "isPersonalFreeleech": true, "canUseToken": true},
+    {"torrentId": 959474, "media": "WEB", "format": "MP3", "encoding": "320", "time": "2012-06-09 01:10:20",
+     "size": 500, "snatches": 0, "seeders": 1, "leechers": 0, "isFreeleech": false, "isNeutralLeech": true,
+     "isPersonalFreeleech": false, "canUseToken": false}
+  ]},
+  {"groupId": 410619, "groupName": "Some App", "torrentId": 959475, "tags": ["apps"], "groupTime": "1339117820",
+   "size": "2000", "snatches": 1, "seeders": 5, "leechers": 6, "isFreeleech": true}
+]}}`
+
+const indexResponse = `{"status": "success", "response": {"username": "alice", "id": 7,
+  "userstats": {"uploaded": 5000, "downloaded": 1000, "ratio": 5, "class": "Member", "bonusPoints": 100}}}`
+
+const userResponse = `{"status": "success", "response": {"stats": {"joinedDate": "2012-06-08 01:10:20"},
+  "community": {"seeding": 12, "leeching": 1}}}`
+
+const torrentResponse = `{"status": "success", "response": {
+  "group": {"id": 410618, "name": "Foo", "year": 2011, "tags": ["rock"],
+    "musicInfo": {"artists": [{"id": 1, "name": "A"}, {"id": 2, "name": "B"}]}},
+  "torrent": {"id": 959473, "infoHash": "ABCDEF0123456789ABCDEF0123456789ABCDEF01", "media": "CD",
+    "format": "FLAC", "encoding": "Lossless", "size": 1000, "seeders": 3, "leechers": 4, "snatched": 2,
+    "freeTorrent": "2", "time": "2012-06-08 01:10:20", "username": "bob",
+    "fileList": "01 - Intro.flac{{{100}}}|||02 - Foo &amp; Bar.flac{{{900}}}"}}}`
+
+func TestGazelleApi(t *testing.T) {
+	var auth, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("action") {
+		case "browse":
+			query = r.URL.RawQuery
+			fmt.Fprint(w, browseResponse)
+		case "index":
+			fmt.Fprint(w, indexResponse)
+		case "user":
+			fmt.Fprint(w, userResponse)
+		case "torrent":
+			fmt.Fprint(w, torrentResponse)
+		default:
+			fmt.Fprint(w, `{"status": "failure", "error": "bad parameters"}`)
+		}
+	}))
+	defer server.Close()
+
+	siteInstance, err := gazelle.NewSite("gz", &config.SiteConfigStruct{Url: server.URL + "/", ApiToken: "secret",
+		Timezone: "UTC"}, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	torrents, nextPageMarker, err := siteInstance.GetAllTorrents("size", true, "", "torrents.php?filter_cat[1]=1")
+	if err != nil || len(torrents) != 3 {
+		t.Fatalf("failed to get all torrents: %v (%d torrents)", err, len(torrents))
+	}
+	if auth != "secret" || nextPageMarker != "2" ||
+		query != "action=browse&filter_cat%5B1%5D=1&order_by=size&order_way=desc&page=1" {
+		t.Errorf("unexpected browse request: auth=%s, query=%s, next page: %s", auth, query, nextPageMarker)
+	}
+	torrent := torrents[0]
+	if torrent.Id != "gz.959473" || torrent.Name != "Artist - Foo & Bar [2011]" ||
+		torrent.Description != "2011 Deluxe / FLAC / Lossless / CD" || torrent.Size != 1000 ||
+		torrent.Seeders != 3 || torrent.Leechers != 4 || torrent.Snatched != 2 || torrent.DownloadMultiplier != 0 ||
+		torrent.Time != 1339117820 || !torrent.HasTag("rock") || !torrent.HasTag("FLAC") || torrent.HasTag("token") ||
+		torrent.DownloadUrl != server.URL+"/ajax.php?action=download&id=959473" {
+		t.Errorf("unexpected music torrent: %+v", torrent)
+	}
+	if torrent = torrents[1]; !torrent.Neutral || torrent.UploadMultiplier != 0 || torrent.Time != 1339204220 {
+		t.Errorf("unexpected neutral torrent: %+v", torrent)
+	}
+	if torrent = torrents[2]; torrent.Id != "gz.959475" || torrent.Name != "Some App" || torrent.Size != 2000 ||
+		torrent.DownloadMultiplier != 0 || torrent.Time != 1339117820 {
+		t.Errorf("unexpected non-music torrent: %+v", torrent)
+	}
+
+	if _, err = siteInstance.SearchTorrents("foo", ""); err != nil || query != "action=browse&searchstr=foo" {
+		t.Errorf("failed to search torrents: %v, query=%s", err, query)
+	}
+
+	status, err := siteInstance.GetStatus()
+	if err != nil || status.UserName != "alice" || status.UserUploaded != 5000 || status.UserDownloaded != 1000 ||
+		status.TorrentsSeedingCnt != 12 || status.TorrentsLeechingCnt != 1 || status.UserRatio != 5 ||
+		status.UserClass != "Member" || status.UserBonus != 100 || status.UserJoinTime != 1339117820 {
+		t.Errorf("unexpected status: %+v, err=%v", status, err)
+	}
+
+	details, err := siteInstance.GetTorrentDetails("959473")
+	if err != nil {
+		t.Fatalf("failed to get torrent details: %v", err)
+	}
+	if details.Name != "A & B - Foo [2011]" || !details.Neutral || details.Uploader != "bob" ||
+		details.InfoHash != "abcdef0123456789abcdef0123456789abcdef01" || details.Snatched != 2 ||
+		len(details.Files) != 2 || details.Files[1].Path != "02 - Foo & Bar.flac" || details.Files[1].Size != 900 {
+		t.Errorf("unexpected torrent details: %+v, torrent: %+v", details, details.Torrent)
+	}
+}
@@ -7,6 +7,7 @@ package gazellepw
 // 注意下载时的 id 与 torrent.php 页面url里的 id 不同，后者是当前电影整个分组的 id
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/site/gazelle"
 	"github.com/sagan/ptool/util"
 )
 
@@ -27,6 +29,7 @@ type Site struct {
 	Config      *config.ConfigStruct
 	HttpClient  *azuretls.Session
 	HttpHeaders [][]string
+	Api         *gazelle.Api
 }
 
 // PublishTorrent implements site.Site.
@@ -49,7 +52,17 @@ func (gpwsite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return gpwsite.SiteConfig
 }
 
+// Use the API if possible, fallback to parse the torrents page.
 func (gpwsite *Site) GetStatus() (*site.Status, error) {
+	status, err := gpwsite.Api.GetStatus()
+	if err == nil || errors.Is(err, site.ErrNotLogined) || gpwsite.SiteConfig.Cookie == "" {
+		return status, err
+	}
+	log.Debugf("Failed to get site %s status from api: %v. Fallback to parse page", gpwsite.Name, err)
+	return gpwsite.getStatusFromPage()
+}
+
+func (gpwsite *Site) getStatusFromPage() (*site.Status, error) {
 	doc, _, err := util.GetUrlDocWithAzuretls(gpwsite.SiteConfig.Url+"torrents.php", gpwsite.HttpClient,
 		gpwsite.GetSiteConfig().Cookie, site.GetUa(gpwsite), gpwsite.GetDefaultHttpHeaders())
 	if err != nil {
@@ -68,15 +81,23 @@ func (gpwsite *Site) GetStatus() (*site.Status, error) {
 
 func (gpwsite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
 	torrents []*site.Torrent, nextPageMarker string, err error) {
-	return nil, "", site.ErrUnimplemented
+	return gpwsite.Api.GetAllTorrents(sort, desc, pageMarker, baseUrl)
 }
 
 func (gpwsite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	return gpwsite.Api.GetLatestTorrents(full)
 }
 
 func (gpwsite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	return gpwsite.Api.SearchTorrents(keyword, baseUrl)
+}
+
+func (gpwsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return gpwsite.Api.GetTorrentDetails(id)
+}
+
+func (gpwsite *Site) Attendance() (*site.AttendanceResult, error) {
+	return nil, site.ErrAttendanceUnsupported
 }
 
 func (gpwsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
@@ -93,12 +114,12 @@ func (gpwsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filenam
 }
 
 func (gpwsite *Site) DownloadTorrentById(id string) ([]byte, string, error) {
-	torrentUrl := gpwsite.SiteConfig.Url + "torrents.php?action=download&id=" + id
+	torrentUrl := gpwsite.Api.GetDownloadUrl(id)
 	return site.DownloadTorrentByUrl(gpwsite, gpwsite.HttpClient, torrentUrl, id)
 }
 
 func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (site.Site, error) {
-	if siteConfig.Cookie == "" {
+	if siteConfig.Cookie == "" && siteConfig.ApiToken == "" {
 		log.Warnf("Site %s has no cookie provided", name)
 	}
 	location, err := time.LoadLocation(siteConfig.GetTimezone())
@@ -115,8 +136,9 @@ func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.Co
 		SiteConfig:  siteConfig,
 		Config:      config,
 		HttpClient:  httpClient,
-		HttpHeaders: httpHeaders,
+		HttpHeaders: gazelle.GetApiHttpHeaders(siteConfig.ApiToken, httpHeaders),
 	}
+	site.Api = gazelle.NewApi(site, httpClient, location)
 	return site, nil
 }
 
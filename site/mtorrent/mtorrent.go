@@ -1,10 +1,12 @@
 package mtorrent
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	neturl "net/url"
+	"regexp"
 	"strings"
 
 	"github.com/Noooste/azuretls-client"
@@ -19,9 +21,17 @@ const (
 	APIPath_GenerateDownloadToken = "/api/torrent/genDlToken"
 	APIPath_TorrentSearch         = "/api/torrent/search"
 	APIPath_Profile               = "/api/member/profile"
+	APIPath_PeerStatus            = "/api/tracker/myPeerStatus"
+	APIPath_TorrentCreate         = "/api/torrent/createOredit"
+	APIPath_TorrentDetail         = "/api/torrent/detail"
+	APIPath_TorrentFiles          = "/api/torrent/files"
 )
 
 var (
+	// API response message of invalid or expired api key / token / session
+	notLoginedMessageRegexp = regexp.MustCompile(
+		`(?i)(key|token|session|auth|認證|认证|登入|登录|登錄).*(invalid|expire|無效|无效|過期|过期|失效|失敗|失败)|未登|unauthorized`)
+
 	// sortFields: https://test2.m-team.cc/api/doc.html#/%E5%B8%B8%E8%A7%84%E6%8E%A5%E5%8F%A3/%E7%A8%AE%E5%AD%90/search
 	sortFields = map[string]string{
 		"time": "CREATED_DATE",
@@ -40,6 +50,20 @@ var (
 		"_2X_FREE":       2,
 		"_2X_PERCENT_50": 2,
 	}
+
+	// user role (class) id => name. Same as NexusPHP user classes.
+	userClasses = map[int64]string{
+		1:  "User",
+		2:  "Power User",
+		3:  "Elite User",
+		4:  "Crazy User",
+		5:  "Insane User",
+		6:  "Veteran User",
+		7:  "Extreme User",
+		8:  "Ultimate User",
+		9:  "Nexus Master",
+		10: "VIP",
+	}
 )
 
 var _ site.Site = (*Site)(nil)
@@ -51,9 +75,74 @@ type Site struct {
 	HttpHeaders [][]string
 }
 
-// PublishTorrent implements site.Site.
+// M-Team default upload torrent form data:
+//
+//	file: .torrent binary file
+//	name: 标题
+//	smallDescr: 副标题
+//	descr: 简介, bbcode
+//	category, medium, standard: 分类、媒介、分辨率 id
+//	anonymous: true=匿名发布
+//
+// The ids are mapped from metadata by uploadTorrentCategoryMap, uploadTorrentTypeMap
+// and uploadTorrentResolutionMap configs respectively.
+var defaultUploadTorrentPayload = map[string]string{
+	"name": `{% if number %}[{{number}}]{% endif %}{% if author %}[{{author}}]{% endif %}{{title}}`,
+	"smallDescr": `{% if narrator %}{{narrator | join(" ")}} {% endif %}` +
+		`{% if series_name %}{{series_name}} {% endif %}{% if tags %}{{tags | join(" ")}}{% endif %}`,
+	"descr": `
+{% if _cover %}
+[img]{{_cover}}[/img]
+{% endif %}
+{% if _images %}
+{% for image in _images %}
+[img]{{image}}[/img]
+{% endfor %}
+{% endif %}
+{{_text}}{% if comment %}
+
+---
+
+{{comment}}{% endif %}`,
+	"category":  `{{_category_id}}`,
+	"medium":    `{{_type_id}}`,
+	"standard":  `{{_resolution_id}}`,
+	"imdb":      `{{imdb}}`,
+	"douban":    `{{douban}}`,
+	"anonymous": `true`,
+}
+
+// PublishTorrent upload torrent to site using the JSON API.
 func (m *Site) PublishTorrent(contents []byte, metadata neturl.Values) (id string, err error) {
-	return "", site.ErrUnimplemented
+	payload, err := site.RenderUploadTorrentPayload(m, m.HttpClient, metadata, defaultUploadTorrentPayload,
+		[]string{"name", "category"})
+	if err != nil {
+		if err == constants.ErrDryRun {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to render payload: %w", err)
+	}
+	uploadUrl, err := neturl.JoinPath(m.SiteConfig.Url, APIPath_TorrentCreate)
+	if err != nil {
+		return "", err
+	}
+	reqHeaders := util.GetHttpReqHeaders(m.GetDefaultHttpHeaders(), m.GetSiteConfig().Cookie, site.GetUa(m))
+	res, err := util.PostUploadFile(m.HttpClient, uploadUrl, "a.torrent", bytes.NewReader(contents), "file",
+		payload, reqHeaders)
+	if err != nil {
+		return "", fmt.Errorf("%s error: %w", APIPath_TorrentCreate, err)
+	}
+	var resp CreateTorrentResponse
+	if err = json.Unmarshal(res.Body, &resp); err != nil {
+		return "", fmt.Errorf("unmarshal response as json error: %w", err)
+	}
+	if err = resp.GetError(); err != nil {
+		return "", err
+	}
+	if id = resp.Id(); id == "" {
+		return "", fmt.Errorf("got no id from response: %s", string(resp.Data))
+	}
+	return id, nil
 }
 
 func (m *Site) GetName() string {
@@ -160,19 +249,69 @@ func (m *Site) SearchTorrents(keyword string, baseUrl string) (torrents []*site.
 	return
 }
 
+// GetTorrentDetails get torrent detail and file list. The uploader is not available in API.
+func (m *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	q := make(neturl.Values)
+	q.Add("id", id)
+	var resp TorrentDetailResponse
+	if err := m.do(APIPath_TorrentDetail, q, nil, &resp); err != nil {
+		return nil, fmt.Errorf("%s error: %w", APIPath_TorrentDetail, err)
+	}
+	var filesResp TorrentFilesResponse
+	if err := m.do(APIPath_TorrentFiles, q, nil, &filesResp); err != nil {
+		return nil, fmt.Errorf("%s error: %w", APIPath_TorrentFiles, err)
+	}
+	torrent := m.convertTorrents(&TorrentList{Data: []Torrent{resp.Data.Torrent}})[0]
+	torrent.Description = resp.Data.Descr
+	torrent.Snatched = resp.Data.Status.TimesCompleted.Value()
+	details := &site.TorrentDetails{
+		Torrent:   torrent,
+		ImdbUrl:   resp.Data.Imdb,
+		DoubanUrl: resp.Data.Douban,
+	}
+	for _, file := range filesResp.Data {
+		details.Files = append(details.Files, &site.TorrentFile{Path: file.Name, Size: file.Size.Value()})
+	}
+	return details, nil
+}
+
+func (m *Site) Attendance() (*site.AttendanceResult, error) {
+	return nil, site.ErrAttendanceUnsupported
+}
+
 func (m *Site) GetStatus() (*site.Status, error) {
 	var resp ProfileResponse
 	if err := m.do(APIPath_Profile, nil, nil, &resp); err != nil {
 		return nil, err
+	}
+	status := &site.Status{
+		UserName:       resp.Data.UserName,
+		UserDownloaded: resp.Data.MemberCount.Downloaded.Value(),
+		UserUploaded:   resp.Data.MemberCount.Uploaded.Value(),
+		UserBonus:      resp.Data.MemberCount.Bonus.Value(),
+		UserRatio:      resp.Data.MemberCount.ShareRate.Value(),
+		UserInvites:    util.ParseInt(strings.Trim(string(resp.Data.Invites), `"`)),
+		UserJoinTime:   resp.Data.CreateDate.Unix(),
+	}
+	if role := util.ParseInt(strings.Trim(string(resp.Data.Role), `"`)); role > 0 {
+		status.UserClass = userClasses[role]
+		if status.UserClass == "" {
+			status.UserClass = fmt.Sprintf("Role %d", role)
+		}
+	}
+	if status.UserJoinTime < 0 {
+		status.UserJoinTime = 0
+	}
+	// seeding / leeching count is optional
+	var peerResp PeerStatusResponse
+	if err := m.do(APIPath_PeerStatus, nil, nil, &peerResp); err != nil {
+		log.Debugf("failed to get site %s peer status: %v", m.Name, err)
 	} else {
-		return &site.Status{
-			UserName:            resp.Data.UserName,
-			UserDownloaded:      resp.Data.MemberCount.Downloaded.Value(),
-			UserUploaded:        resp.Data.MemberCount.Uploaded.Value(),
-			TorrentsSeedingCnt:  0,
-			TorrentsLeechingCnt: 0,
-		}, nil
+		status.TorrentsSeedingCnt = peerResp.Data.Seeder.Value()
+		status.TorrentsLeechingCnt = peerResp.Data.Leecher.Value()
 	}
+	site.SetLogined(m.GetName())
+	return status, nil
 }
 
 func (m *Site) PurgeCache() {
@@ -199,6 +338,9 @@ func (m *Site) do(path string, query neturl.Values, body any, result any) error
 		return fmt.Errorf("failed to fetch url: %w", err)
 	}
 	log.Tracef("Azuretls.Do response status=%d", res.StatusCode)
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return site.NewNotLoginedError(m.GetName())
+	}
 	if res.StatusCode != 200 {
 		return fmt.Errorf("failed to fetch url: status=%d", res.StatusCode)
 	}
@@ -208,7 +350,12 @@ func (m *Site) do(path string, query neturl.Values, body any, result any) error
 	}
 
 	if c, ok := result.(errorGetter); ok {
-		return c.GetError()
+		if err := c.GetError(); err != nil {
+			if c.IsNotLogined() {
+				return site.NewNotLoginedError(m.GetName())
+			}
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,73 @@
+package mtorrent_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/site/mtorrent"
+)
+
+const profileResponse = `{"code":"0","message":"SUCCESS","data":{"id":"1","createdDate":"2023-11-14 22:13:20",
+"lastModifiedDate":"2023-11-14 22:13:20","username":"foo","role":"1","invites":"2",
+"memberCount":{"bonus":"100.5","uploaded":"2048","downloaded":"1024","shareRate":"2.00"}}}`
+
+func TestMtorrentStatus(t *testing.T) {
+	var status int
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/member/profile":
+			w.WriteHeader(status)
+			fmt.Fprint(w, response)
+		case "/api/tracker/myPeerStatus":
+			fmt.Fprint(w, `{"code":"0","message":"SUCCESS","data":{"seeder":"3","leecher":"1"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	siteInstance, err := mtorrent.NewSite("mt", &config.SiteConfigStruct{Url: server.URL + "/"},
+		&config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+
+	status, response = http.StatusOK, profileResponse
+	siteStatus, err := siteInstance.GetStatus()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if siteStatus.UserName != "foo" || siteStatus.UserUploaded != 2048 || siteStatus.TorrentsSeedingCnt != 3 {
+		t.Errorf("unexpected status: %+v", siteStatus)
+	}
+
+	tests := []struct {
+		name       string
+		status     int
+		response   string
+		notLogined bool
+	}{
+		{"invalid key", http.StatusOK, `{"code":"1","message":"key無效"}`, true},
+		{"expired token", http.StatusOK, `{"code":"1","message":"Token expired"}`, true},
+		{"unauthorized code", http.StatusOK, `{"code":"401","message":"Full authentication is required"}`, true},
+		{"unauthorized status", http.StatusUnauthorized, ``, true},
+		{"other error", http.StatusOK, `{"code":"1","message":"請求過於頻繁"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response = tt.status, tt.response
+			_, err := siteInstance.GetStatus()
+			if err == nil {
+				t.Fatalf("expect error")
+			}
+			if errors.Is(err, site.ErrNotLogined) != tt.notLogined {
+				t.Errorf("expect not logined error %t, got %v", tt.notLogined, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package mtorrent
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
 
 const (
 	TorrentSearchMode_Normal = "normal"
@@ -92,6 +96,7 @@ type TorrentStatus struct {
 	DiscountEndTime *Time  `json:"discountEndTime"`
 	Leechers        Int64  `json:"leechers"`
 	Seeders         Int64  `json:"seeders"`
+	TimesCompleted  Int64  `json:"timesCompleted"`
 	Status          string `json:"status"`
 }
 
@@ -106,6 +111,28 @@ type Torrent struct {
 	Status           TorrentStatus `json:"status"`
 }
 
+type TorrentDetail struct {
+	Torrent
+	Descr  string `json:"descr"`
+	Imdb   string `json:"imdb"`
+	Douban string `json:"douban"`
+}
+
+type TorrentDetailResponse struct {
+	ResponseCode
+	Data TorrentDetail `json:"data"`
+}
+
+type TorrentFile struct {
+	Name string `json:"name"`
+	Size Int64  `json:"size"`
+}
+
+type TorrentFilesResponse struct {
+	ResponseCode
+	Data []TorrentFile `json:"data"`
+}
+
 type TorrentList struct {
 	PageNumber Int64     `json:"pageNumber"`
 	PageSize   Int64     `json:"pageSize"`
@@ -120,12 +147,14 @@ type TorrentSearchResponse struct {
 }
 
 type Profile struct {
-	Id               string `json:"id"`
-	CreateDate       Time   `json:"createdDate"`
-	LastModifiedDate Time   `json:"lastModifiedDate"`
-	UserName         string `json:"username"`
+	Id               string          `json:"id"`
+	CreateDate       Time            `json:"createdDate"`
+	LastModifiedDate Time            `json:"lastModifiedDate"`
+	UserName         string          `json:"username"`
+	Role             json.RawMessage `json:"role"`    // user class id, e.g. "1"
+	Invites          json.RawMessage `json:"invites"` // invite count
 	MemberCount      struct {
-		Bonus      Int64String   `json:"bonus"`
+		Bonus      Float64String `json:"bonus"`
 		Uploaded   Int64String   `json:"uploaded"`
 		Downloaded Int64String   `json:"downloaded"`
 		ShareRate  Float64String `json:"shareRate"`
@@ -137,8 +166,40 @@ type ProfileResponse struct {
 	Data Profile `json:"data"`
 }
 
+type PeerStatusResponse struct {
+	ResponseCode
+	Data struct {
+		Seeder  Int64String `json:"seeder"`
+		Leecher Int64String `json:"leecher"`
+	} `json:"data"`
+}
+
+// Data is the id of created torrent, or the created torrent object.
+type CreateTorrentResponse struct {
+	ResponseCode
+	Data json.RawMessage `json:"data"`
+}
+
+func (r *CreateTorrentResponse) Id() string {
+	var torrent struct {
+		Id Int64 `json:"id"`
+	}
+	if err := json.Unmarshal(r.Data, &torrent); err != nil || torrent.Id == 0 {
+		if err := json.Unmarshal(r.Data, &torrent.Id); err != nil || torrent.Id == 0 {
+			return ""
+		}
+	}
+	return fmt.Sprint(torrent.Id.Value())
+}
+
 type errorGetter interface {
 	GetError() error
+	IsNotLogined() bool
+}
+
+// Return true if response error is caused by invalid or expired api key / token / session.
+func (r ResponseCode) IsNotLogined() bool {
+	return r.Code == http.StatusUnauthorized || notLoginedMessageRegexp.MatchString(r.Message)
 }
 
 func (r ResponseCode) GetError() error {
@@ -1,7 +1,9 @@
 package nexusphp
 
 import (
+	"bytes"
 	"fmt"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
@@ -29,6 +31,9 @@ type Site struct {
 	extraTorrents    []*site.Torrent
 	datatime         int64
 	datetimeExtra    int64
+	datetimeUser     int64
+	userId           string
+	attendanceUrl    string // attendance link in site page
 	cuhash           string
 	passkey          string
 	digitHashPasskey string
@@ -126,6 +131,7 @@ func (npclient *Site) PurgeCache() {
 	npclient.latestTorrents = nil
 	npclient.extraTorrents = nil
 	npclient.siteStatus = nil
+	npclient.datetimeUser = 0
 	npclient.cuhash = ""
 }
 
@@ -163,7 +169,7 @@ func (npclient *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.To
 		return nil, fmt.Errorf("failed to parse site page dom: %w", err)
 	}
 	if strings.Contains(res.Request.Url, "/login.php") {
-		return nil, fmt.Errorf("not logined (cookie may has expired)")
+		return nil, site.NewNotLoginedError(npclient.GetName())
 	}
 	return npclient.parseTorrentsFromDoc(doc, util.Now())
 }
@@ -253,6 +259,33 @@ func (npclient *Site) DownloadTorrentById(id string) ([]byte, string, error) {
 	return site.DownloadTorrentByUrl(npclient, npclient.HttpClient, torrentUrl, id)
 }
 
+// Parse details page and file list (viewfilelist.php) of torrent.
+func (npclient *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	detailsUrl := npclient.SiteConfig.ParseSiteUrl("details.php?id="+url.QueryEscape(id)+"&hit=1", false)
+	doc, res, err := util.GetUrlDocWithAzuretls(detailsUrl, npclient.HttpClient,
+		npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrent details page: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login.php") {
+		return nil, site.NewNotLoginedError(npclient.GetName())
+	}
+	details := parseTorrentDetails(doc, npclient.GetName()+"."+id, npclient.torrentsParserOption)
+	if details.Name == "" {
+		return nil, fmt.Errorf("torrent not found")
+	}
+	details.DownloadUrl = npclient.SiteConfig.ParseSiteUrl(generateTorrentDownloadUrl(id,
+		npclient.torrentsParserOption.torrentDownloadUrl, npclient.torrentsParserOption.npletdown), false)
+	fileListUrl := npclient.SiteConfig.ParseSiteUrl("viewfilelist.php?id="+url.QueryEscape(id), false)
+	if doc, _, err := util.GetUrlDocWithAzuretls(fileListUrl, npclient.HttpClient, npclient.SiteConfig.Cookie,
+		site.GetUa(npclient), npclient.GetDefaultHttpHeaders()); err == nil {
+		details.Files = parseTorrentFileList(doc)
+	} else {
+		log.Debugf("Failed to get torrent %s file list: %v", id, err)
+	}
+	return details, nil
+}
+
 func (npclient *Site) getDigithash(id string) (string, error) {
 	detailsUrl := npclient.SiteConfig.ParseSiteUrl(fmt.Sprintf("t/%s/", id), false)
 	doc, _, err := util.GetUrlDocWithAzuretls(detailsUrl, npclient.HttpClient,
@@ -289,6 +322,10 @@ func (npclient *Site) GetStatus() (*site.Status, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch site data: %w", err)
 	}
+	npclient.syncUser()
+	if npclient.siteStatus.IsOk() {
+		site.SetLogined(npclient.GetName())
+	}
 	return npclient.siteStatus, nil
 }
 
@@ -371,7 +408,7 @@ func (npclient *Site) GetAllTorrents(sort string, desc bool, pageMarker string,
 		return
 	}
 	if strings.Contains(res.Request.Url, "/login.php") {
-		return nil, "", fmt.Errorf("not logined (cookie may has expired)")
+		return nil, "", site.NewNotLoginedError(npclient.GetName())
 	}
 
 	lastPage := int64(0)
@@ -400,7 +437,7 @@ labelLastPage:
 			return
 		}
 		if strings.Contains(res.Request.Url, "/login.php") {
-			err = fmt.Errorf("not logined (cookie may has expired)")
+			err = site.NewNotLoginedError(npclient.GetName())
 			return
 		}
 	}
@@ -465,7 +502,7 @@ func (npclient *Site) sync() error {
 		return fmt.Errorf("failed to get site page dom: %w", err)
 	}
 	if strings.Contains(res.Request.Url, "/login.php") {
-		return fmt.Errorf("not logined (cookie may has expired)")
+		return site.NewNotLoginedError(npclient.GetName())
 	}
 	html := doc.Find("html")
 	npclient.datatime = util.Now()
@@ -521,6 +558,16 @@ func (npclient *Site) sync() error {
 		siteStatus.UserName = doc.Find(`*[href*="userdetails.php?"]`).First().Text()
 	}
 	siteStatus.UserName = strings.TrimSpace(siteStatus.UserName)
+	userLink := doc.Find(`*[href*="userdetails.php?"]`).First().AttrOr("href", "")
+	if m := userIdRegexp.FindStringSubmatch(userLink); m != nil {
+		npclient.userId = m[userIdRegexp.SubexpIndex("id")]
+	}
+	parseUserInfoBlock(infoTr, infoTxt, siteStatus)
+	selectorAttendanceLink := npclient.SiteConfig.SelectorAttendanceLink
+	if selectorAttendanceLink == "" {
+		selectorAttendanceLink = SELECTOR_ATTENDANCE_LINK
+	}
+	npclient.attendanceUrl = doc.Find(selectorAttendanceLink).First().AttrOr("href", "")
 
 	// possibly parsing error or some problem
 	if !siteStatus.IsOk() {
@@ -539,6 +586,79 @@ func (npclient *Site) sync() error {
 	return nil
 }
 
+func (npclient *Site) Attendance() (*site.AttendanceResult, error) {
+	attendanceUrl := npclient.SiteConfig.AttendanceUrl
+	if attendanceUrl == constants.NONE {
+		return nil, site.ErrAttendanceUnsupported
+	}
+	if attendanceUrl == "" {
+		if err := npclient.sync(); err != nil {
+			return nil, fmt.Errorf("failed to fetch site data: %w", err)
+		}
+		if npclient.attendanceUrl == "" {
+			return nil, site.ErrAttendanceUnsupported
+		}
+		attendanceUrl = npclient.attendanceUrl
+	}
+	attendanceUrl = npclient.SiteConfig.ParseSiteUrl(attendanceUrl, false)
+	var res *azuretls.Response
+	var err error
+	if strings.EqualFold(npclient.SiteConfig.AttendanceMethod, http.MethodPost) {
+		headers := append([][]string{}, npclient.GetDefaultHttpHeaders()...)
+		headers = append(headers, []string{"Content-Type", "application/x-www-form-urlencoded"})
+		req := &azuretls.Request{
+			Method:         http.MethodPost,
+			Url:            attendanceUrl,
+			Body:           npclient.SiteConfig.AttendancePayload,
+			NoCookie:       true,
+			OrderedHeaders: util.GetHttpReqHeaders(headers, npclient.SiteConfig.Cookie, site.GetUa(npclient)),
+		}
+		if res, err = npclient.HttpClient.Do(req); err == nil && res.StatusCode != 200 {
+			err = fmt.Errorf("status=%d", res.StatusCode)
+		}
+	} else {
+		res, _, err = util.FetchUrlWithAzuretls(attendanceUrl, npclient.HttpClient, npclient.SiteConfig.Cookie,
+			site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to request attendance url: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login.php") {
+		return nil, site.NewNotLoginedError(npclient.GetName())
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(res.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse attendance response: %w", err)
+	}
+	return parseAttendance(doc, npclient.SiteConfig.SelectorAttendanceMessage)
+}
+
+// Fetch user details page & bonus page to get additional user status (class, join date, bonus per hour...).
+// Errors are ignored as these fields are optional.
+func (npclient *Site) syncUser() {
+	if npclient.datetimeUser > 0 || npclient.siteStatus == nil {
+		return
+	}
+	npclient.datetimeUser = util.Now()
+	if npclient.userId != "" {
+		doc, _, err := util.GetUrlDocWithAzuretls(
+			npclient.SiteConfig.ParseSiteUrl("userdetails.php?id="+npclient.userId, false), npclient.HttpClient,
+			npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+		if err != nil {
+			log.Debugf("failed to get site %s user details page: %v", npclient.Name, err)
+		} else {
+			parseUserDetails(doc, npclient.siteStatus, npclient.Location)
+		}
+	}
+	doc, _, err := util.GetUrlDocWithAzuretls(npclient.SiteConfig.ParseSiteUrl("mybonus.php", false),
+		npclient.HttpClient, npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		log.Debugf("failed to get site %s bonus page: %v", npclient.Name, err)
+	} else {
+		parseBonusPage(doc, npclient.siteStatus)
+	}
+}
+
 func (npclient *Site) syncExtra() error {
 	if npclient.datetimeExtra > 0 {
 		return nil
@@ -552,7 +672,7 @@ func (npclient *Site) syncExtra() error {
 			continue
 		}
 		if strings.Contains(res.Request.Url, "/login.php") {
-			return fmt.Errorf("not logined (cookie may has expired)")
+			return site.NewNotLoginedError(npclient.GetName())
 		}
 		torrents, err := npclient.parseTorrentsFromDoc(doc, util.Now())
 		if err != nil {
@@ -0,0 +1,127 @@
+package nexusphp_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/site/nexusphp"
+)
+
+const torrentsPage = `<html><body><table id="info_block"><tr><td>
+欢迎回来, <a href="userdetails.php?id=42" class="User_Name"><b>alice</b></a> [<a href="logout.php">退出</a>]
+魔力值 [<a href="mybonus.php">使用</a>]: 12,345.6 邀请 [<a href="invite.php?id=42">发送</a>]: 2
+分享率: 2.500 上传量: 1.5 TB 下载量: 600 GB
+当前活动: <img class="arrowup" alt="Torrents seeding" title="当前做种" src="pic/trans.gif" />12
+<img class="arrowdown" alt="Torrents leeching" title="当前下载" src="pic/trans.gif" />1
+H&amp;R: [<a href="myhr.php">3/10</a>] <a href="attendance.php" class="faqlink">[签到得魔力]</a>
+</td></tr></table></body></html>`
+
+const userDetailsPage = `<html><body><table>
+<tr><td class="rowhead">加入日期</td><td class="rowfollow">2020-01-02 03:04:05 (3年前)</td></tr>
+<tr><td class="rowhead">等级</td><td class="rowfollow"><img alt="Elite User" title="Elite User" src="pic/elite.gif" /></td></tr>
+<tr><td class="rowhead">做种大小</td><td class="rowfollow">3.5 TB</td></tr>
+</table></body></html>`
+
+const attendancePage = `<html><body><table class="main"><tr><td class="embedded"><h2>签到成功</h2>
+<table width="100%"><tr><td class="text">这是您的第 <b>10</b> 次签到，已连续签到 <b>3</b> 天，本次签到获得 <b>1,020</b> 个魔力值。
+</td></tr></table></td></tr></table></body></html>`
+
+const bonusPage = `<html><body><p>你当前每小时能获取12.34个魔力值</p></body></html>`
+
+func TestNexusphpStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/torrents.php":
+			fmt.Fprint(w, torrentsPage)
+		case "/userdetails.php":
+			if r.URL.Query().Get("id") == "42" {
+				fmt.Fprint(w, userDetailsPage)
+			}
+		case "/mybonus.php":
+			fmt.Fprint(w, bonusPage)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	siteConfig := &config.SiteConfigStruct{Url: server.URL + "/", Cookie: "a=b", Timezone: "UTC"}
+	siteInstance, err := nexusphp.NewSite("np", siteConfig, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	status, err := siteInstance.GetStatus()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status.UserName != "alice" || status.UserUploaded != 3<<39 || status.UserBonus != 12345.6 ||
+		status.UserInvites != 2 || status.UserRatio != 2.5 || status.TorrentsSeedingCnt != 12 ||
+		status.TorrentsLeechingCnt != 1 || status.UserHnRCnt != 3 {
+		t.Errorf("unexpected info block status: %+v", status)
+	}
+	if status.UserClass != "Elite User" || status.UserJoinTime != 1577934245 || status.UserSeedingSize != 7<<39 ||
+		status.UserBonusPerHour != 12.34 {
+		t.Errorf("unexpected user details status: %+v", status)
+	}
+
+	siteConfig.BrushMinSiteRatio = 2
+	siteConfig.BrushMinSiteBufferValue = 800 << 30
+	if err = site.CheckAccountThreshold(siteInstance); err != nil {
+		t.Errorf("expect account above threshold, got %v", err)
+	}
+	siteConfig.BrushMinSiteBufferValue = 1 << 40
+	if err = site.CheckAccountThreshold(siteInstance); !errors.Is(err, site.ErrAccountBelowThreshold) {
+		t.Errorf("expect account below buffer threshold, got %v", err)
+	}
+	siteConfig.BrushMinSiteRatio, siteConfig.BrushMinSiteBufferValue = 3, 0
+	if err = site.CheckAccountThreshold(siteInstance); !errors.Is(err, site.ErrAccountBelowThreshold) {
+		t.Errorf("expect account below ratio threshold, got %v", err)
+	}
+}
+
+func TestNexusphpAttendance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/torrents.php":
+			fmt.Fprint(w, torrentsPage)
+		case "/attendance.php":
+			fmt.Fprint(w, attendancePage)
+		case "/showup.php":
+			if r.Method == http.MethodPost && r.FormValue("action") == "showup" {
+				fmt.Fprint(w, `<html><body><div id="msg">您今天已经签到过了</div><td class="text">foo</td></body></html>`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	siteInstance, err := nexusphp.NewSite("np", &config.SiteConfigStruct{Url: server.URL + "/", Cookie: "a=b"},
+		&config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	result, err := siteInstance.Attendance()
+	if err != nil || !result.Attended || result.AlreadyAttended || result.Total != 10 || result.Streak != 3 ||
+		result.Reward != 1020 {
+		t.Errorf("unexpected attendance result: %+v, err=%v", result, err)
+	}
+
+	siteInstance, _ = nexusphp.NewSite("np2", &config.SiteConfigStruct{Url: server.URL + "/", Cookie: "a=b",
+		AttendanceUrl: "showup.php", AttendanceMethod: "post", AttendancePayload: "action=showup",
+		SelectorAttendanceMessage: "#msg"}, &config.ConfigStruct{})
+	if result, err = siteInstance.Attendance(); err != nil || !result.AlreadyAttended || result.Attended {
+		t.Errorf("unexpected custom attendance result: %+v, err=%v", result, err)
+	}
+
+	siteInstance, _ = nexusphp.NewSite("np3", &config.SiteConfigStruct{Url: server.URL + "/", Cookie: "a=b",
+		SelectorAttendanceLink: "a.nonexistent"}, &config.ConfigStruct{})
+	if _, err = siteInstance.Attendance(); err != site.ErrAttendanceUnsupported {
+		t.Errorf("expect unsupported error, got %v", err)
+	}
+}
@@ -25,6 +25,10 @@ const (
 	// see https://github.com/xiaomlove/nexusphp/blob/php8/app/Repositories/TorrentRepository.php .
 	// function getPaidIcon.
 	SELECTOR_TORRENT_PAID = `span[title="收费种子"],span[title="收費種子"],span[title="Paid torrent"]`
+	// attendance link in info block, e.g. "[签到得魔力]" or "[签到已得20, 补签卡: 0]"
+	SELECTOR_ATTENDANCE_LINK = `a[href*="attendance.php"]`
+	// NexusPHP stdmsg() message text element
+	SELECTOR_ATTENDANCE_MESSAGE = `td.text`
 	// skip NP download notice. see https://github.com/xiaomlove/nexusphp/blob/php8/public/download.php
 	LETDOWN_QUERYSTRING = "letdown=1"
 )
@@ -575,3 +579,243 @@ func generateTorrentDownloadUrl(id string, torrentDownloadUrl string, letdown bo
 	}
 	return downloadUrl
 }
+
+// Discount font classes in torrent title of NexusPHP details page.
+// See https://github.com/xiaomlove/nexusphp/blob/php8/include/functions.php function get_torrent_promotion_append.
+var detailsDiscountClasses = map[string][2]float64{ // class => [downloadMultiplier, uploadMultiplier]
+	"free":          {0, 1},
+	"twoup":         {1, 2},
+	"twoupfree":     {0, 2},
+	"halfdown":      {0.5, 1},
+	"twouphalfdown": {0.5, 2},
+	"thirtypercent": {0.3, 1},
+}
+
+var (
+	detailsSizeRegexp     = regexp.MustCompile(`(?i)(大小|Size)[：:]\s*(?P<size>[\d.,]+\s*[KMGTP]i?B)`)
+	detailsInfoHashRegexp = regexp.MustCompile(`(?i)(Hash[码碼]?|Info Hash)\s*[：:]\s*(?P<hash>[a-f0-9]{40})\b`)
+	detailsSeedersRegexp  = regexp.MustCompile(`(?i)(?P<n>\d+)\s*(个|個)?\s*(做种者|做種者|seeders?)`)
+	detailsLeechersRegexp = regexp.MustCompile(`(?i)(?P<n>\d+)\s*(个|個)?\s*(下载者|下載者|leechers?)`)
+)
+
+// Parse torrent details page (details.php?id=).
+// Some fields (e.g. file list, time, snatched) are not available in this page and are left empty.
+func parseTorrentDetails(doc *goquery.Document, id string, option *TorrentsParserOption) *site.TorrentDetails {
+	torrent := &site.Torrent{
+		Id:                 id,
+		DownloadMultiplier: 1,
+		UploadMultiplier:   1,
+		DiscountEndTime:    -1,
+	}
+	details := &site.TorrentDetails{Torrent: torrent}
+	title := doc.Find("h1#top").First()
+	// the title contains torrent name text and discount / tags elements.
+	title.Contents().Each(func(i int, s *goquery.Selection) {
+		if s.Nodes[0].Type == html.TextNode {
+			torrent.Name += s.Text()
+		}
+	})
+	torrent.Name = strings.TrimSpace(strings.ReplaceAll(torrent.Name, "\u00a0", " "))
+	for class, multipliers := range detailsDiscountClasses {
+		if title.Find("."+class).Length() > 0 {
+			torrent.DownloadMultiplier, torrent.UploadMultiplier = multipliers[0], multipliers[1]
+			break
+		}
+	}
+	if option.globalHr || doc.Find(`*[title="H&R"],*[alt="H&R"],*[title="Hit and Run"]`).Length() > 0 ||
+		option.selectorTorrentHnR != "" && title.Find(option.selectorTorrentHnR).Length() > 0 {
+		torrent.HasHnR = true
+	}
+	doc.Find("td.rowhead").Each(func(i int, s *goquery.Selection) {
+		head := util.DomSanitizedText(s)
+		value := s.Next()
+		text := util.DomSanitizedText(value)
+		switch {
+		case strings.Contains(head, "基本信息") || strings.Contains(head, "基本資訊") || strings.EqualFold(head, "Basic Info"):
+			if m := detailsSizeRegexp.FindStringSubmatch(text); m != nil {
+				torrent.Size, _ = util.RAMInBytes(strings.ReplaceAll(m[detailsSizeRegexp.SubexpIndex("size")], ",", ""))
+			}
+			if uploader := value.Find(`a[href*="userdetails.php?id="]`).First(); uploader.Length() > 0 {
+				details.Uploader = util.DomSanitizedText(uploader)
+			}
+		case strings.Contains(head, "同伴") || strings.EqualFold(head, "Peers"):
+			if m := detailsSeedersRegexp.FindStringSubmatch(text); m != nil {
+				torrent.Seeders = util.ParseInt(m[detailsSeedersRegexp.SubexpIndex("n")])
+			}
+			if m := detailsLeechersRegexp.FindStringSubmatch(text); m != nil {
+				torrent.Leechers = util.ParseInt(m[detailsLeechersRegexp.SubexpIndex("n")])
+			}
+		case strings.Contains(strings.ToUpper(head), "H&R") || strings.EqualFold(head, "HnR"):
+			details.HnRTerms = text
+			torrent.HasHnR = true
+		}
+	})
+	if m := detailsInfoHashRegexp.FindStringSubmatch(util.DomSanitizedText(doc.Find("body"))); m != nil {
+		torrent.InfoHash = strings.ToLower(m[detailsInfoHashRegexp.SubexpIndex("hash")])
+	}
+	torrent.Description = strings.TrimSpace(doc.Find("#kdescr").Text())
+	if pageHtml, err := doc.Html(); err == nil {
+		details.ImdbUrl, details.DoubanUrl = site.FindImdbDoubanUrls(pageHtml)
+	}
+	return details
+}
+
+// Parse file list fragment (viewfilelist.php?id=) of NexusPHP details page.
+func parseTorrentFileList(doc *goquery.Document) (files []*site.TorrentFile) {
+	doc.Find("tr").Each(func(i int, s *goquery.Selection) {
+		tds := s.Children().Filter("td")
+		if tds.Length() != 2 || tds.First().HasClass("colhead") {
+			return
+		}
+		size, err := util.RAMInBytes(util.DomSanitizedText(tds.Last()))
+		if err != nil {
+			return
+		}
+		files = append(files, &site.TorrentFile{Path: util.DomSanitizedText(tds.First()), Size: size})
+	})
+	return files
+}
+
+var (
+	userIdRegexp        = regexp.MustCompile(`\bid=(?P<id>\d+)`)
+	leadingNumberRegexp = regexp.MustCompile(`^[\d,]+`)
+	userBonusRegexp     = regexp.MustCompile(`(?i)(魔力值|魔力|積分|积分|Bonus)\s*(\[[^\]]*\])?\s*[：:]\s*(?P<n>[\d,.]+)`)
+	userRatioRegexp     = regexp.MustCompile(`(?i)(分享率|Ratio)\s*[：:]\s*(?P<n>[\d,.]+)`)
+	userInvitesRegexp   = regexp.MustCompile(`(?i)(邀请|邀請|Invites?)\s*(\[[^\]]*\])?\s*[：:]\s*(?P<n>\d+)`)
+	userHnRRegexp       = regexp.MustCompile(`(?i)(H&R|HnR)\s*[：:]\s*\[?\s*(?P<n>\d+)`)
+	// e.g. "你当前每小时能获取12.3个魔力值", "You are currently getting 12.3 bonus points per hour".
+	userBonusPerHourRegexp = regexp.MustCompile(
+		`(?i)(每小时能获取|每小時能獲取|每小时获取|每小時獲取|getting)\s*(?P<n>[\d,.]+)\s*(个|個)?\s*(魔力|bonus)`)
+	userSeedingSizeRegexp = regexp.MustCompile(
+		`(?i)(做种体积|做種體積|做种大小|做種大小|Seeding size)\s*[：:]?\s*(?P<size>[\d.,]+\s*[KMGTPE]i?B)`)
+)
+
+// Parse user info from the info block (top user panel) of NexusPHP pages.
+// UserName, UserUploaded and UserDownloaded are parsed by caller.
+func parseUserInfoBlock(infoBlock *goquery.Selection, infoTxt string, status *site.Status) {
+	if m := userBonusRegexp.FindStringSubmatch(infoTxt); m != nil {
+		status.UserBonus = util.ParseFloat(m[userBonusRegexp.SubexpIndex("n")])
+	}
+	if m := userRatioRegexp.FindStringSubmatch(infoTxt); m != nil {
+		status.UserRatio = util.ParseFloat(m[userRatioRegexp.SubexpIndex("n")])
+	}
+	if m := userInvitesRegexp.FindStringSubmatch(infoTxt); m != nil {
+		status.UserInvites = util.ParseInt(m[userInvitesRegexp.SubexpIndex("n")])
+	}
+	if m := userHnRRegexp.FindStringSubmatch(infoTxt); m != nil {
+		status.UserHnRCnt = util.ParseInt(m[userHnRRegexp.SubexpIndex("n")])
+	}
+	// <img class="arrowup" alt="Torrents seeding" title="当前做种" src="pic/trans.gif" />12
+	status.TorrentsSeedingCnt = parseCountAfterNode(infoBlock.Find("img.arrowup"))
+	status.TorrentsLeechingCnt = parseCountAfterNode(infoBlock.Find("img.arrowdown"))
+}
+
+// Return the number in the text node that immediately follows the first node of s.
+func parseCountAfterNode(s *goquery.Selection) int64 {
+	if s.Length() == 0 {
+		return 0
+	}
+	next := s.Nodes[0].NextSibling
+	if next == nil || next.Type != html.TextNode {
+		return 0
+	}
+	return util.ParseInt(leadingNumberRegexp.FindString(strings.TrimSpace(next.Data)))
+}
+
+// Parse user details page (userdetails.php?id=), which has user class and join date.
+func parseUserDetails(doc *goquery.Document, status *site.Status, location *time.Location) {
+	doc.Find("td.rowhead").Each(func(i int, s *goquery.Selection) {
+		head := util.DomSanitizedText(s)
+		value := s.Next()
+		switch {
+		case strings.Contains(head, "加入日期") || strings.EqualFold(head, "Join date"):
+			// e.g. "2020-01-01 12:00:00 (3年前)"
+			text, _, _ := strings.Cut(util.DomSanitizedText(value), "(")
+			status.UserJoinTime, _ = util.ParseTime(strings.TrimSpace(text), location)
+		case strings.Contains(head, "等级") || strings.Contains(head, "等級") || strings.EqualFold(head, "Class"):
+			// class is usually displayed as an image
+			if img := value.Find("img[title],img[alt]").First(); img.Length() > 0 {
+				status.UserClass = img.AttrOr("title", "")
+				if status.UserClass == "" {
+					status.UserClass = img.AttrOr("alt", "")
+				}
+			}
+			if status.UserClass == "" {
+				status.UserClass = util.DomSanitizedText(value)
+			}
+		}
+	})
+	if status.UserSeedingSize == 0 {
+		parseSeedingSize(util.DomSanitizedText(doc.Find("body")), status)
+	}
+}
+
+// Parse bonus page (mybonus.php), which has bonus per hour and (in some sites) seeding size.
+func parseBonusPage(doc *goquery.Document, status *site.Status) {
+	text := util.DomSanitizedText(doc.Find("body"))
+	if m := userBonusPerHourRegexp.FindStringSubmatch(text); m != nil {
+		status.UserBonusPerHour = util.ParseFloat(m[userBonusPerHourRegexp.SubexpIndex("n")])
+	}
+	if status.UserSeedingSize == 0 {
+		parseSeedingSize(text, status)
+	}
+}
+
+func parseSeedingSize(text string, status *site.Status) {
+	if m := userSeedingSizeRegexp.FindStringSubmatch(text); m != nil {
+		status.UserSeedingSize, _ = util.RAMInBytes(
+			strings.ReplaceAll(m[userSeedingSizeRegexp.SubexpIndex("size")], ",", ""))
+	}
+}
+
+// See https://github.com/xiaomlove/nexusphp/blob/php8/lang/chs/lang_attendance.php .
+// e.g. "这是您的第 <b>10</b> 次签到，已连续签到 <b>3</b> 天，本次签到获得 <b>20</b> 个魔力值。"
+var (
+	attendanceTotalRegexp = regexp.MustCompile(`(?i)(第\s*(?P<n>\d+)\s*次签到|第\s*(?P<n2>\d+)\s*次簽到|` +
+		`your\s+(?P<n3>\d+)\s*(st|nd|rd|th)?\s+attendance)`)
+	attendanceStreakRegexp = regexp.MustCompile(`(?i)(连续签到|連續簽到|连续|連續)\s*(?P<n>\d+)\s*天|` +
+		`(?P<n2>\d+)\s*(consecutive|continuous) days?`)
+	attendanceRewardRegexp = regexp.MustCompile(`(?i)(获得|獲得|got|gained|earned)\s*(?P<n>[\d,.]+)\s*(个|個|點|点)?\s*` +
+		`(魔力|bonus|积分|積分)`)
+	attendanceAlreadyRegexp = regexp.MustCompile(`(?i)(已经签到|已經簽到|已签到|已簽到|签到过|簽到過|` +
+		`already (attended|signed|checked))`)
+)
+
+// Parse attendance response page. selector is the element of result message, use default if empty.
+func parseAttendance(doc *goquery.Document, selector string) (*site.AttendanceResult, error) {
+	if selector == "" {
+		selector = SELECTOR_ATTENDANCE_MESSAGE
+	}
+	message := util.DomSanitizedText(doc.Find(selector))
+	if message == "" {
+		message = util.DomSanitizedText(doc.Find("body"))
+	}
+	result := &site.AttendanceResult{Message: message}
+	if m := attendanceTotalRegexp.FindStringSubmatch(message); m != nil {
+		result.Total = util.ParseInt(firstSubmatch(attendanceTotalRegexp, m, "n", "n2", "n3"))
+	}
+	if m := attendanceStreakRegexp.FindStringSubmatch(message); m != nil {
+		result.Streak = util.ParseInt(firstSubmatch(attendanceStreakRegexp, m, "n", "n2"))
+	}
+	if m := attendanceRewardRegexp.FindStringSubmatch(message); m != nil {
+		result.Reward = util.ParseFloat(m[attendanceRewardRegexp.SubexpIndex("n")])
+	}
+	if attendanceAlreadyRegexp.MatchString(message) {
+		result.AlreadyAttended = true
+	} else if result.Total > 0 || result.Streak > 0 || result.Reward > 0 {
+		result.Attended = true
+	} else {
+		return result, fmt.Errorf("unrecognized attendance response: %s", util.StringPrefixInBytes(message, 200))
+	}
+	return result, nil
+}
+
+// Return the first non-empty value of named subexps in match.
+func firstSubmatch(re *regexp.Regexp, match []string, names ...string) string {
+	for _, name := range names {
+		if value := match[re.SubexpIndex(name)]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
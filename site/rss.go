@@ -0,0 +1,318 @@
+package site
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html/charset"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+// RSS 2.0 <item> or Atom <entry> of a feed. Some common extensions are also supported:
+// torznab (Jackett / Prowlarr) attrs: <torznab:attr name="seeders" value="5" />;
+// ezrss torrent namespace: <torrent><contentLength>1024</contentLength><infoHash>...</infoHash></torrent>.
+type feedItem struct {
+	Title       string   `xml:"title"`
+	Guid        string   `xml:"guid"`
+	Id          string   `xml:"id"`
+	Description string   `xml:"description"`
+	Summary     string   `xml:"summary"`
+	PubDate     string   `xml:"pubDate"`
+	Published   string   `xml:"published"`
+	Updated     string   `xml:"updated"`
+	Categories  []string `xml:"category"`
+	Size        string   `xml:"size"`
+	Enclosure   struct {
+		Url    string `xml:"url,attr"`
+		Length string `xml:"length,attr"`
+	} `xml:"enclosure"`
+	// RSS: <link>url</link>; Atom: <link href="url" rel="alternate|enclosure" />
+	Links []struct {
+		Value  string `xml:",chardata"`
+		Href   string `xml:"href,attr"`
+		Rel    string `xml:"rel,attr"`
+		Length string `xml:"length,attr"`
+	} `xml:"link"`
+	Attrs []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"attr"`
+	ContentLength string `xml:"torrent>contentLength"`
+	InfoHash      string `xml:"torrent>infoHash"`
+}
+
+type feed struct {
+	Items   []*feedItem `xml:"channel>item"` // RSS 2.0
+	Entries []*feedItem `xml:"entry"`        // Atom
+}
+
+var (
+	// Size at the end of title, e.g. "Foo [12.34 GB]". Some sites (e.g. NexusPHP) do this.
+	feedTitleSizeRegexp = regexp.MustCompile(`\s*[\[(]\s*(\d+(\.\d+)?\s*[KMGTP]i?B)\s*[\])]\s*$`)
+	feedUrlIdRegexp     = regexp.MustCompile(`/(?P<id>\d+)(/|\.torrent)?([?#]|$)`)
+	feedTimeLayouts     = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST"}
+)
+
+// Parse a RSS 2.0 or Atom feed of torrents. Relative urls in feed are resolved against siteUrl.
+// The torrent id is extracted from url using idRegexp (the "id" subgroup), if it's not nil,
+// then from "id" query string parameter or the last number path segment of url.
+// The discount / HnR / peers info of torrents are usually not available in feed,
+// in which case the InfoIncomplete field of returned torrents is set.
+func ParseRssFeed(contents []byte, sitename string, siteUrl string, idRegexp *regexp.Regexp) ([]*Torrent, error) {
+	data := &feed{}
+	decoder := xml.NewDecoder(bytes.NewReader(contents))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	if err := decoder.Decode(data); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+	baseUrl, _ := url.Parse(siteUrl)
+	resolve := func(link string) string {
+		link = strings.TrimSpace(link)
+		if link == "" || baseUrl == nil {
+			return link
+		}
+		if urlObj, err := baseUrl.Parse(link); err == nil {
+			return urlObj.String()
+		}
+		return link
+	}
+	var torrents []*Torrent
+	for _, item := range append(data.Items, data.Entries...) {
+		torrent := &Torrent{
+			Name:               strings.TrimSpace(item.Title),
+			Description:        strings.TrimSpace(item.Description),
+			InfoHash:           strings.ToLower(strings.TrimSpace(item.InfoHash)),
+			DownloadUrl:        resolve(item.Enclosure.Url),
+			DownloadMultiplier: 1,
+			UploadMultiplier:   1,
+			Seeders:            -1,
+			Leechers:           -1,
+			Snatched:           -1,
+			Tags: util.Filter(util.Map(item.Categories, strings.TrimSpace), func(s string) bool {
+				return s != ""
+			}),
+		}
+		if summary := strings.TrimSpace(item.Summary); summary != "" {
+			torrent.Description = summary
+		}
+		// Description of some feeds (e.g. NexusPHP) is the full html description, which is too long to use
+		if len(torrent.Description) > 200 || strings.Contains(torrent.Description, "<") {
+			torrent.Description = ""
+		}
+		detailsLink := ""
+		for _, link := range item.Links {
+			if link.Href == "" {
+				link.Href = link.Value
+			}
+			switch link.Rel {
+			case "enclosure":
+				if torrent.DownloadUrl == "" {
+					torrent.DownloadUrl = resolve(link.Href)
+				}
+				if item.Enclosure.Length == "" {
+					item.Enclosure.Length = link.Length
+				}
+			case "", "alternate":
+				if detailsLink == "" {
+					detailsLink = resolve(link.Href)
+				}
+			}
+		}
+		if torrent.DownloadUrl == "" {
+			torrent.DownloadUrl = detailsLink
+		}
+		for _, str := range []string{item.Enclosure.Length, item.ContentLength, item.Size} {
+			if size := util.ParseInt(str); size > 0 {
+				torrent.Size = size
+				torrent.IsSizeAccurate = true
+				break
+			}
+		}
+		if m := feedTitleSizeRegexp.FindStringSubmatch(torrent.Name); m != nil {
+			if torrent.Size == 0 {
+				torrent.Size, _ = util.RAMInBytes(m[1])
+			}
+			torrent.Name = torrent.Name[:len(torrent.Name)-len(m[0])]
+		}
+		// NexusPHP uses info-hash as guid
+		if guid := strings.TrimSpace(item.Guid); torrent.InfoHash == "" && len(guid) == 40 &&
+			util.IsHexString(guid, 40) {
+			torrent.InfoHash = strings.ToLower(guid)
+		}
+		for _, str := range []string{item.PubDate, item.Published, item.Updated} {
+			if torrent.Time = parseFeedTime(str); torrent.Time > 0 {
+				break
+			}
+		}
+		for _, link := range []string{detailsLink, torrent.DownloadUrl, resolve(item.Guid), resolve(item.Id)} {
+			if id := parseFeedTorrentId(link, idRegexp); id != "" {
+				torrent.Id = sitename + "." + id
+				break
+			}
+		}
+		// torznab feeds (e.g. from Jackett) provide all the info
+		infoFields := 0
+		for _, attr := range item.Attrs {
+			value := strings.TrimSpace(attr.Value)
+			switch attr.Name {
+			case "size":
+				if torrent.Size <= 0 {
+					torrent.Size = util.ParseInt(value)
+				}
+			case "seeders":
+				torrent.Seeders = util.ParseInt(value)
+				infoFields++
+			case "peers":
+				torrent.Leechers = util.ParseInt(value)
+			case "leechers":
+				torrent.Leechers = util.ParseInt(value)
+			case "grabs":
+				torrent.Snatched = util.ParseInt(value)
+			case "infohash":
+				torrent.InfoHash = strings.ToLower(value)
+			case "downloadvolumefactor":
+				torrent.DownloadMultiplier = parseFloat(value)
+				infoFields++
+			case "uploadvolumefactor":
+				torrent.UploadMultiplier = parseFloat(value)
+			case "minimumseedtime", "minimumratio":
+				if parseFloat(value) > 0 {
+					torrent.HasHnR = true
+				}
+			}
+		}
+		if torrent.Seeders >= 0 && torrent.Leechers > torrent.Seeders {
+			// torznab "peers" includes seeders
+			torrent.Leechers -= torrent.Seeders
+		}
+		torrent.InfoIncomplete = infoFields < 2
+		if torrent.Name == "" || torrent.DownloadUrl == "" {
+			log.Debugf("Skip invalid feed item %v", item)
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+func parseFloat(str string) float64 {
+	v, _ := strconv.ParseFloat(str, 64)
+	return v
+}
+
+func parseFeedTime(str string) int64 {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0
+	}
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t.Unix()
+		}
+	}
+	ts, _ := util.ParseTime(str, nil)
+	return ts
+}
+
+func parseFeedTorrentId(link string, idRegexp *regexp.Regexp) string {
+	if link == "" {
+		return ""
+	}
+	if idRegexp != nil {
+		if m := idRegexp.FindStringSubmatch(link); m != nil {
+			return m[idRegexp.SubexpIndex("id")]
+		}
+	}
+	urlObj, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if id := urlObj.Query().Get("id"); id != "" {
+		return id
+	}
+	if m := feedUrlIdRegexp.FindStringSubmatch(urlObj.Path); m != nil {
+		return m[feedUrlIdRegexp.SubexpIndex("id")]
+	}
+	return ""
+}
+
+// Fetch and parse the RSS / Atom feed (rssUrl config) of site.
+func GetRssTorrents(siteInstance Site) ([]*Torrent, error) {
+	siteConfig := siteInstance.GetSiteConfig()
+	if siteConfig.RssUrl == "" {
+		return nil, fmt.Errorf("site %s does not have rssUrl configured", siteInstance.GetName())
+	}
+	httpClient, _, err := CreateSiteHttpClient(siteConfig, config.Get())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create site http client: %w", err)
+	}
+	rssUrl := siteConfig.RssUrl
+	if siteConfig.Url != "" {
+		rssUrl = util.ParseRelativeUrl(rssUrl, siteConfig.Url)
+	}
+	cookie := ""
+	if !siteConfig.NoCookie {
+		cookie = siteConfig.Cookie
+	}
+	res, _, err := util.FetchUrlWithAzuretls(rssUrl, httpClient, cookie, GetUa(siteInstance),
+		siteInstance.GetDefaultHttpHeaders())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch site feed: %w", err)
+	}
+	var idRegexp *regexp.Regexp
+	if siteConfig.TorrentUrlIdRegexp != "" {
+		if idRegexp, err = regexp.Compile(siteConfig.TorrentUrlIdRegexp); err != nil {
+			return nil, fmt.Errorf("invalid torrentUrlIdRegexp: %w", err)
+		}
+	}
+	return ParseRssFeed(res.Body, siteInstance.GetName(), siteConfig.Url, idRegexp)
+}
+
+// Fill the discount / HnR / peers info of an InfoIncomplete torrent (e.g. from feed),
+// by fetching the details of the torrent with the same id from site.
+// It does nothing if the torrent info is already complete.
+func EnrichTorrent(siteInstance Site, torrent *Torrent) error {
+	if !torrent.InfoIncomplete {
+		return nil
+	}
+	if torrent.Id == "" {
+		return fmt.Errorf("torrent %s id is unknown", torrent.Name)
+	}
+	details, err := siteInstance.GetTorrentDetails(torrent.ID())
+	if err != nil {
+		return fmt.Errorf("failed to get site torrent details: %w", err)
+	}
+	if details == nil || details.Torrent == nil {
+		return fmt.Errorf("torrent %s (%s) not found in site", torrent.Name, torrent.Id)
+	}
+	found := details.Torrent
+	torrent.DownloadMultiplier = found.DownloadMultiplier
+	torrent.UploadMultiplier = found.UploadMultiplier
+	torrent.DiscountEndTime = found.DiscountEndTime
+	torrent.Seeders = found.Seeders
+	torrent.Leechers = found.Leechers
+	torrent.Snatched = found.Snatched
+	torrent.HasHnR = found.HasHnR
+	torrent.IsActive = found.IsActive
+	torrent.IsCurrentActive = found.IsCurrentActive
+	torrent.Paid = found.Paid
+	torrent.Bought = found.Bought
+	torrent.Neutral = found.Neutral
+	if torrent.Description == "" {
+		torrent.Description = found.Description
+	}
+	torrent.Tags = util.UniqueSlice(append(torrent.Tags, found.Tags...))
+	torrent.InfoIncomplete = false
+	return nil
+}
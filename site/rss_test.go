@@ -0,0 +1,138 @@
+package site_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/site"
+)
+
+const nexusphpFeed = `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0">
+<channel>
+<title>Example Torrents</title>
+<link>https://example.com</link>
+<item>
+<title><![CDATA[Foo.2024.1080p.WEB-DL [1.50 GB]]]></title>
+<link>https://example.com/details.php?id=12345&amp;hit=1</link>
+<description><![CDATA[<img src="cover.jpg" /> very long description]]></description>
+<author>anonymous@example.com</author>
+<category domain="https://example.com/torrents.php?cat=401">Movies</category>
+<comments><![CDATA[https://example.com/details.php?id=12345&cmtpage=0#startcomments]]></comments>
+<enclosure url="https://example.com/download.php?id=12345&amp;passkey=abc" length="1610612736" type="application/x-bittorrent" />
+<guid isPermaLink="false">0123456789abcdef0123456789abcdef01234567</guid>
+<pubDate>Tue, 14 Nov 2023 22:13:20 +0000</pubDate>
+</item>
+</channel>
+</rss>`
+
+const torznabFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+<channel>
+<item>
+<title>Bar</title>
+<guid>https://example.com/torrent/678</guid>
+<link>/dl/678.torrent</link>
+<pubDate>Tue, 14 Nov 2023 22:13:20 +0000</pubDate>
+<size>2048</size>
+<torznab:attr name="seeders" value="3" />
+<torznab:attr name="peers" value="10" />
+<torznab:attr name="downloadvolumefactor" value="0" />
+<torznab:attr name="uploadvolumefactor" value="2" />
+<torznab:attr name="minimumseedtime" value="172800" />
+</item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<entry>
+<title>Baz</title>
+<id>urn:uuid:1</id>
+<link rel="alternate" href="/torrents/999" />
+<link rel="enclosure" href="/torrents/download/999" length="4096" />
+<updated>2023-11-14T22:13:20Z</updated>
+<summary>Baz subtitle</summary>
+</entry>
+</feed>`
+
+func TestParseRssFeed(t *testing.T) {
+	torrents, err := site.ParseRssFeed([]byte(nexusphpFeed), "example", "https://example.com/", nil)
+	if err != nil || len(torrents) != 1 {
+		t.Fatalf("failed to parse nexusphp feed: %v (%d torrents)", err, len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.Name != "Foo.2024.1080p.WEB-DL" || torrent.Id != "example.12345" || torrent.Size != 1610612736 ||
+		torrent.Time != 1700000000 || torrent.InfoHash != "0123456789abcdef0123456789abcdef01234567" ||
+		torrent.DownloadUrl != "https://example.com/download.php?id=12345&passkey=abc" ||
+		torrent.Description != "" || !torrent.HasTag("Movies") || !torrent.InfoIncomplete {
+		t.Errorf("unexpected nexusphp feed torrent: %+v", torrent)
+	}
+
+	torrents, err = site.ParseRssFeed([]byte(torznabFeed), "example", "https://example.com/", nil)
+	if err != nil || len(torrents) != 1 {
+		t.Fatalf("failed to parse torznab feed: %v (%d torrents)", err, len(torrents))
+	}
+	torrent = torrents[0]
+	if torrent.Id != "example.678" || torrent.Size != 2048 ||
+		torrent.DownloadUrl != "https://example.com/dl/678.torrent" || torrent.Seeders != 3 || torrent.Leechers != 7 || torrent.DownloadMultiplier != 0 ||
+		torrent.UploadMultiplier != 2 || !torrent.HasHnR || torrent.InfoIncomplete {
+		t.Errorf("unexpected torznab feed torrent: %+v", torrent)
+	}
+
+	torrents, err = site.ParseRssFeed([]byte(atomFeed), "example", "https://example.com/", nil)
+	if err != nil || len(torrents) != 1 {
+		t.Fatalf("failed to parse atom feed: %v (%d torrents)", err, len(torrents))
+	}
+	torrent = torrents[0]
+	if torrent.Id != "example.999" || torrent.Size != 4096 || torrent.Time != 1700000000 ||
+		torrent.DownloadUrl != "https://example.com/torrents/download/999" || torrent.Description != "Baz subtitle" {
+		t.Errorf("unexpected atom feed torrent: %+v", torrent)
+	}
+}
+
+// A fake site that only implements GetTorrentDetails.
+type fakeSite struct {
+	site.Site
+	requested []string
+}
+
+func (s *fakeSite) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	s.requested = append(s.requested, id)
+	return &site.TorrentDetails{Torrent: &site.Torrent{
+		Id:                 "example." + id,
+		Name:               "Another name",
+		DownloadMultiplier: 0,
+		UploadMultiplier:   1,
+		Seeders:            5,
+		HasHnR:             true,
+		Tags:               []string{"free"},
+	}}, nil
+}
+
+func TestEnrichTorrent(t *testing.T) {
+	torrents, err := site.ParseRssFeed([]byte(nexusphpFeed), "example", "https://example.com/", nil)
+	if err != nil || len(torrents) != 1 {
+		t.Fatalf("failed to parse nexusphp feed: %v (%d torrents)", err, len(torrents))
+	}
+	torrent := torrents[0]
+	siteInstance := &fakeSite{}
+	if err = site.EnrichTorrent(siteInstance, torrent); err != nil {
+		t.Fatalf("failed to enrich torrent: %v", err)
+	}
+	if len(siteInstance.requested) != 1 || siteInstance.requested[0] != "12345" {
+		t.Errorf("expect details of torrent 12345 requested, got %v", siteInstance.requested)
+	}
+	if torrent.Name != "Foo.2024.1080p.WEB-DL" || torrent.Id != "example.12345" || torrent.DownloadMultiplier != 0 ||
+		torrent.Seeders != 5 || !torrent.HasHnR || !torrent.HasTag("free") || !torrent.HasTag("Movies") ||
+		torrent.InfoIncomplete {
+		t.Errorf("unexpected enriched torrent: %+v", torrent)
+	}
+	// already complete torrent is not enriched again.
+	if err = site.EnrichTorrent(siteInstance, torrent); err != nil || len(siteInstance.requested) != 1 {
+		t.Errorf("expect complete torrent not enriched, err=%v", err)
+	}
+
+	if err = site.EnrichTorrent(siteInstance, &site.Torrent{Name: "bar", InfoIncomplete: true}); err == nil {
+		t.Errorf("expect error of enriching torrent without id")
+	}
+}
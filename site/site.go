@@ -7,6 +7,7 @@ import (
 	"mime"
 	"net/url"
 	"os"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ import (
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/jinja"
+	"github.com/sagan/ptool/notify"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/crypto"
 	"github.com/sagan/ptool/util/impersonateutil"
@@ -48,14 +50,51 @@ type Torrent struct {
 	Bought             bool     // 适用于付费种子：已购买
 	Neutral            bool     // 中性种子：不计算上传、下载、做种魔力
 	Tags               []string // labels, e.g. category and other meta infos.
+	// true if discount / HnR / peers info is unknown (e.g. torrent from RSS feed). See EnrichTorrent
+	InfoIncomplete bool
 }
 
+type TorrentFile struct {
+	Path string
+	Size int64
+}
+
+// Full details of a site torrent, as shown in torrent details page.
+// The Description of embedded Torrent is the full description (body) of torrent.
+type TorrentDetails struct {
+	*Torrent
+	Files     []*TorrentFile // may be nil if site does not provide it
+	Uploader  string         // empty if torrent is anonymous or uploader is unknown
+	ImdbUrl   string
+	DoubanUrl string
+	HnRTerms  string // human readable HnR requirements text, if site provides it
+}
+
+// Result of daily attendance (check-in, 签到). Fields that site does not provide are left as zero values.
+type AttendanceResult struct {
+	Attended        bool    // attended successfully in this request
+	AlreadyAttended bool    // had already attended today before this request
+	Reward          float64 // bonus points got in this attendance
+	Streak          int64   // consecutive days of attendance
+	Total           int64   // total times of attendance
+	Message         string  // message text of site response
+}
+
+// Site user status. Fields that site does not provide are left as zero values.
 type Status struct {
 	UserName            string
 	UserDownloaded      int64
 	UserUploaded        int64
 	TorrentsSeedingCnt  int64
 	TorrentsLeechingCnt int64
+	UserBonus           float64 // bonus points (魔力值)
+	UserBonusPerHour    float64
+	UserRatio           float64 // ratio provided by site. Use Ratio() to get the effective ratio
+	UserSeedingSize     int64   // total size of seeding torrents
+	UserClass           string  // user class (level), e.g. "Power User"
+	UserHnRCnt          int64   // count of unsatisfied HnR (Hit and Run) torrents
+	UserInvites         int64
+	UserJoinTime        int64 // unix timestamp (seconds)
 }
 
 type Site interface {
@@ -74,11 +113,16 @@ type Site interface {
 		torrents []*Torrent, nextPageMarker string, err error)
 	// can use "%s" as keyword placeholder in baseUrl
 	SearchTorrents(keyword string, baseUrl string) ([]*Torrent, error)
+	// get full details of torrent by torrent id (e.g. "12345")
+	GetTorrentDetails(id string) (*TorrentDetails, error)
 	// Publish (upload) new torrent to site, return uploaded torrent id
 	// Some keys in metadata should be handled specially:
 	// If metadata contains "_dryrun", use dry run mode;
 	PublishTorrent(contents []byte, metadata url.Values) (id string, err error)
 	GetStatus() (*Status, error)
+	// Do daily attendance (check-in, 签到).
+	// Return ErrAttendanceUnsupported if site does not support it.
+	Attendance() (*AttendanceResult, error)
 	PurgeCache()
 }
 
@@ -93,15 +137,41 @@ type SiteCreator func(*RegInfo) (Site, error)
 var (
 	// Error that indicates the feature is not implemented in current site.
 	ErrUnimplemented = fmt.Errorf("not implemented yet")
+	// Error that indicates site cookie is invalid or has expired. Use NewNotLoginedError to create it.
+	ErrNotLogined = fmt.Errorf("not logined (cookie may has expired)")
+	// Error that indicates site does not support daily attendance (check-in).
+	ErrAttendanceUnsupported = fmt.Errorf("attendance is not supported")
+	// Error that indicates site user account ratio or buffer is below the threshold of site config.
+	ErrAccountBelowThreshold = fmt.Errorf("account ratio or buffer is below threshold")
 )
 
 var (
-	registryMap  = map[string]*RegInfo{}
-	sites        = map[string]Site{}
-	siteSessions = map[string]*azuretls.Session{}
-	mu           sync.Mutex
+	registryMap           = map[string]*RegInfo{}
+	sites                 = map[string]Site{}
+	siteSessions          = map[string]*azuretls.Session{}
+	mu                    sync.Mutex
+	cookieExpiredNotified sync.Map
 )
 
+// Return ErrNotLogined. Also publish a site cookie expired event,
+// which is done only once for a site until it's logined again (see SetLogined).
+func NewNotLoginedError(sitename string) error {
+	if _, notified := cookieExpiredNotified.LoadOrStore(sitename, true); !notified {
+		notify.Publish(&notify.Event{
+			Type:  notify.EVENT_SITE_COOKIE_EXPIRED,
+			Site:  sitename,
+			Title: fmt.Sprintf("Site %s cookie is invalid or has expired", sitename),
+		})
+	}
+	return ErrNotLogined
+}
+
+// Mark site as logined, so that the cookie expired event will be published again
+// when it's cookie expires next time. Site implementations call it when user status is fetched successfully.
+func SetLogined(sitename string) {
+	cookieExpiredNotified.Delete(sitename)
+}
+
 func (ss *Status) Print(f io.Writer, name string, additionalInfo string) {
 	fmt.Printf(constants.STATUS_FMT, "Site", name, fmt.Sprintf("↑: %s", util.BytesSizeAround(float64(ss.UserUploaded))),
 		fmt.Sprintf("↓: %s", util.BytesSizeAround(float64(ss.UserDownloaded))), additionalInfo)
@@ -126,6 +196,16 @@ func (torrent *Torrent) ID() string {
 	}
 }
 
+var (
+	imdbUrlRegexp   = regexp.MustCompile(`https?://(?:www\.|m\.)?imdb\.com/title/tt\d+`)
+	doubanUrlRegexp = regexp.MustCompile(`https?://(?:movie\.|m\.)?douban\.com/(?:movie/)?subject/\d+`)
+)
+
+// Find the first IMDb & Douban urls in str (e.g. torrent description or details page html).
+func FindImdbDoubanUrls(str string) (imdbUrl string, doubanUrl string) {
+	return imdbUrlRegexp.FindString(str), doubanUrlRegexp.FindString(str)
+}
+
 func (torrent *Torrent) HasTag(tag string) bool {
 	return slices.ContainsFunc(torrent.Tags, func(t string) bool {
 		return strings.EqualFold(tag, t)
@@ -146,6 +226,78 @@ func (torrent *Torrent) MatchFilter(filter string) bool {
 	return false
 }
 
+// Return the user ratio provided by site. If site does not provide it, calculate it from uploaded / downloaded.
+// If user has not downloaded anything, return +Inf.
+func (status *Status) Ratio() float64 {
+	if status.UserRatio > 0 {
+		return status.UserRatio
+	}
+	return float64(status.UserUploaded) / float64(status.UserDownloaded)
+}
+
+// Return additional info text of site user status, contains only the fields that site provides.
+// e.g. "Bonus: 12345.6 (+10.5/h); Class: Elite User; HnR: 1".
+func (status *Status) Info() string {
+	infos := []string{}
+	if status.UserBonus != 0 {
+		bonus := fmt.Sprintf("Bonus: %.1f", status.UserBonus)
+		if status.UserBonusPerHour != 0 {
+			bonus += fmt.Sprintf(" (+%.1f/h)", status.UserBonusPerHour)
+		}
+		infos = append(infos, bonus)
+	}
+	if status.TorrentsSeedingCnt > 0 || status.UserSeedingSize > 0 {
+		seeding := fmt.Sprintf("Seeding: %d", status.TorrentsSeedingCnt)
+		if status.UserSeedingSize > 0 {
+			seeding += fmt.Sprintf(" (%s)", util.BytesSizeAround(float64(status.UserSeedingSize)))
+		}
+		infos = append(infos, seeding)
+	}
+	if status.TorrentsLeechingCnt > 0 {
+		infos = append(infos, fmt.Sprintf("Leeching: %d", status.TorrentsLeechingCnt))
+	}
+	if status.UserClass != "" {
+		infos = append(infos, "Class: "+status.UserClass)
+	}
+	if status.UserHnRCnt > 0 {
+		infos = append(infos, fmt.Sprintf("HnR: %d", status.UserHnRCnt))
+	}
+	if status.UserInvites > 0 {
+		infos = append(infos, fmt.Sprintf("Invites: %d", status.UserInvites))
+	}
+	if status.UserJoinTime > 0 {
+		infos = append(infos, "Joined: "+util.FormatDate(status.UserJoinTime))
+	}
+	return strings.Join(infos, "; ")
+}
+
+// Check site user account against the brushMinSiteRatio & brushMinSiteBuffer (uploaded - downloaded) configs,
+// it's used to decide whether new torrents of site can be added to client.
+// Return an error that wraps ErrAccountBelowThreshold if account is below any threshold,
+// or other error if failed to get site status. If neither config is set, it returns nil without checking.
+func CheckAccountThreshold(siteInstance Site) error {
+	siteConfig := siteInstance.GetSiteConfig()
+	if siteConfig.BrushMinSiteRatio <= 0 && siteConfig.BrushMinSiteBufferValue == 0 {
+		return nil
+	}
+	status, err := siteInstance.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get site status: %w", err)
+	}
+	if !status.IsOk() {
+		return fmt.Errorf("failed to get site status: no user info")
+	}
+	if ratio := status.Ratio(); siteConfig.BrushMinSiteRatio > 0 && ratio < siteConfig.BrushMinSiteRatio {
+		return fmt.Errorf("%w: ratio %.3f < %.3f", ErrAccountBelowThreshold, ratio, siteConfig.BrushMinSiteRatio)
+	}
+	if buffer := status.UserUploaded - status.UserDownloaded; siteConfig.BrushMinSiteBufferValue != 0 &&
+		buffer < siteConfig.BrushMinSiteBufferValue {
+		return fmt.Errorf("%w: buffer %s < %s", ErrAccountBelowThreshold, util.BytesSize(float64(buffer)),
+			util.BytesSize(float64(siteConfig.BrushMinSiteBufferValue)))
+	}
+	return nil
+}
+
 // Check if (seems) as a valid site status
 func (status *Status) IsOk() bool {
 	return status.UserName != "" || status.UserDownloaded > 0 || status.UserUploaded > 0
@@ -178,6 +330,14 @@ func Register(regInfo *RegInfo) {
 	}
 }
 
+// Return the registered site type (schema or internal site) of name, which can also be an alias.
+func Find(name string) (*RegInfo, error) {
+	if regInfo := registryMap[name]; regInfo != nil {
+		return regInfo, nil
+	}
+	return nil, fmt.Errorf("didn't find site type %q", name)
+}
+
 func CreateSiteInternal(name string,
 	siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (Site, error) {
 	regInfo := registryMap[siteConfig.Type]
@@ -524,6 +684,26 @@ func DownloadTorrentByUrl(siteInstance Site, httpClient *azuretls.Session, torre
 //	_array_keys : variable of these keys are rendered as array.
 func UploadTorrent(siteInstance Site, httpClient *azuretls.Session, uploadUrl string, contents []byte,
 	metadata url.Values, fallbackPayloadTemplate map[string]string) (res *azuretls.Response, err error) {
+	payload, err := RenderUploadTorrentPayload(siteInstance, httpClient, metadata, fallbackPayloadTemplate, nil)
+	if err != nil {
+		return nil, err
+	}
+	headers := util.GetHttpReqHeaders(siteInstance.GetDefaultHttpHeaders(), siteInstance.GetSiteConfig().Cookie, "")
+	if siteInstance.GetSiteConfig().Type == "nexusphp" {
+		headers = append(headers, []string{"Referer", siteInstance.GetSiteConfig().ParseSiteUrl("upload.php", false)})
+	}
+	return util.PostUploadFile(httpClient, uploadUrl, "a.torrent", bytes.NewReader(contents), "file",
+		payload, headers)
+}
+
+// Render the payload of upload torrent request, uploading cover & images to site image server if necessary.
+// It's the first half of UploadTorrent, for sites that post the payload in their own way.
+// Besides metadata, the "_category_id", "_type_id" and "_resolution_id" variables are also available to
+// payload templates, which are mapped from metadata using site's uploadTorrent*Map configs.
+// If payload of any key of requiredKeys is empty, return an error. Site config's
+// uploadTorrentPayloadRequiredKeys overrides it. In dry run mode, return constants.ErrDryRun.
+func RenderUploadTorrentPayload(siteInstance Site, httpClient *azuretls.Session, metadata url.Values,
+	fallbackPayloadTemplate map[string]string, requiredKeys []string) (payload url.Values, err error) {
 	metadataRaw := map[string]any{}
 	for key := range metadata {
 		if slices.Contains(metadata[constants.METADATA_KEY_ARRAY_KEYS], key) {
@@ -532,8 +712,13 @@ func UploadTorrent(siteInstance Site, httpClient *azuretls.Session, uploadUrl st
 			metadataRaw[key] = metadata.Get(key)
 		}
 	}
+	siteConfig := siteInstance.GetSiteConfig()
+	metadataRaw["_category_id"] = GetUploadTorrentMappedId(siteConfig.UploadTorrentCategoryMap, metadata, "category")
+	metadataRaw["_type_id"] = GetUploadTorrentMappedId(siteConfig.UploadTorrentTypeMap, metadata, "type")
+	metadataRaw["_resolution_id"] = GetUploadTorrentMappedId(siteConfig.UploadTorrentResolutionMap,
+		metadata, "resolution")
 
-	payload := url.Values{}
+	payload = url.Values{}
 	payloadTemplate := siteInstance.GetSiteConfig().UploadTorrentPayload
 	if payloadTemplate == nil {
 		payloadTemplate = fallbackPayloadTemplate
@@ -603,11 +788,15 @@ func UploadTorrent(siteInstance Site, httpClient *azuretls.Session, uploadUrl st
 	}
 
 	log.Debugf("Publish torrent payload: %v", payload)
-	if keys := siteInstance.GetSiteConfig().UploadTorrentPayloadRequiredKeys; keys != "" && keys != constants.NONE {
-		for _, key := range util.SplitCsv(keys) {
-			if payload.Get(key) == "" {
-				return nil, fmt.Errorf("required payload %s is not found or is empty", key)
-			}
+	if keys := siteInstance.GetSiteConfig().UploadTorrentPayloadRequiredKeys; keys != "" {
+		requiredKeys = nil
+		if keys != constants.NONE {
+			requiredKeys = util.SplitCsv(keys)
+		}
+	}
+	for _, key := range requiredKeys {
+		if payload.Get(key) == "" {
+			return nil, fmt.Errorf("required payload %s is not found or is empty", key)
 		}
 	}
 
@@ -657,12 +846,36 @@ func UploadTorrent(siteInstance Site, httpClient *azuretls.Session, uploadUrl st
 	if metadata.Has(constants.METADATA_KEY_DRY_RUN) {
 		return nil, constants.ErrDryRun
 	}
-	headers := util.GetHttpReqHeaders(siteInstance.GetDefaultHttpHeaders(), siteInstance.GetSiteConfig().Cookie, "")
-	if siteInstance.GetSiteConfig().Type == "nexusphp" {
-		headers = append(headers, []string{"Referer", siteInstance.GetSiteConfig().ParseSiteUrl("upload.php", false)})
+	return payload, nil
+}
+
+// Map metadata to site id using mapping table (e.g. uploadTorrentCategoryMap).
+// It looks up the value of metadata field, then each of metadata tags, in mapping (case-insensitive).
+// If none found, use the value of "*" key. Return empty string if mapping is nil or nothing matches.
+func GetUploadTorrentMappedId(mapping map[string]string, metadata url.Values, field string) string {
+	if mapping == nil {
+		return ""
+	}
+	lookup := func(key string) (string, bool) {
+		for k, v := range mapping {
+			if strings.EqualFold(k, key) {
+				return v, true
+			}
+		}
+		return "", false
 	}
-	return util.PostUploadFile(httpClient, uploadUrl, "a.torrent", bytes.NewReader(contents), "file",
-		payload, headers)
+	keys := []string{}
+	if value := strings.TrimSpace(metadata.Get(field)); value != "" {
+		keys = append(keys, value)
+	}
+	keys = append(keys, metadata["tags"]...)
+	keys = append(keys, "*")
+	for _, key := range keys {
+		if id, ok := lookup(strings.TrimSpace(key)); ok {
+			return id
+		}
+	}
+	return ""
 }
 
 func init() {
@@ -102,6 +102,14 @@ func (tnsite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torr
 	return nil, site.ErrUnimplemented
 }
 
+func (tnsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
+func (tnsite *Site) Attendance() (*site.AttendanceResult, error) {
+	return nil, site.ErrAttendanceUnsupported
+}
+
 func (tnsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
 	if !util.IsUrl(torrentUrl) {
 		id = strings.TrimPrefix(torrentUrl, tnsite.GetName()+".")
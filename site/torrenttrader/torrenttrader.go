@@ -97,6 +97,14 @@ func (usite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torre
 	return nil, site.ErrUnimplemented
 }
 
+func (usite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
+func (usite *Site) Attendance() (*site.AttendanceResult, error) {
+	return nil, site.ErrAttendanceUnsupported
+}
+
 func (usite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
 	if !util.IsUrl(torrentUrl) {
 		id = strings.TrimPrefix(torrentUrl, usite.GetName()+".")
@@ -0,0 +1,193 @@
+package tpl
+
+// 外部站点定义包(pack)。
+// 配置文件目录下 sites.d 目录里的 *.toml / *.yaml 文件，格式与 ptool.toml 的 [[sites]] 相同。
+// 每个站点使用 name 作为站点 id，type 为站点架构类型(例如 nexusphp)。
+// 与内置站点同名的定义会覆盖(合并到)内置站点模板，否则作为新的站点模板。
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+type Pack struct {
+	Sites []*config.SiteConfigStruct
+}
+
+var (
+	// site (canonical) name => the pack file that site definition comes from.
+	// Internal sites that are not overrided by any pack do NOT exist in it.
+	SOURCES = map[string]string{}
+	// all internal site names, before loading packs.
+	internalSitenames []string
+	siteNameRegexp    = regexp.MustCompile(`^[a-z0-9][-a-z0-9]*$`)
+)
+
+// Return all site definition pack files (*.toml / *.yaml / *.yml) in dir, in lexical order.
+func GetPackFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var filenames []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".toml", ".yaml", ".yml":
+			filenames = append(filenames, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return filenames, nil
+}
+
+// Read sites of a site definition pack file.
+// If exact is true, return an error if the file contains any unknown key.
+func ReadPack(filename string, exact bool) ([]*config.SiteConfigStruct, error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	pack := &Pack{}
+	unmarshal := v.Unmarshal
+	if exact {
+		unmarshal = v.UnmarshalExact
+	}
+	if err := unmarshal(pack); err != nil {
+		return nil, err
+	}
+	return pack.Sites, nil
+}
+
+// Validate a site definition of pack. Check the name, type and all CSS selectors (Selector* fields) of site.
+// The name and aliases must not be the same as any registered site schema (e.g. nexusphp).
+func ValidateSite(siteConfig *config.SiteConfigStruct) (errs []error) {
+	name := siteConfig.Name
+	if !siteNameRegexp.MatchString(name) {
+		errs = append(errs, fmt.Errorf("invalid name %q", name))
+	}
+	for _, name := range append([]string{name}, siteConfig.Aliases...) {
+		if isSchemaName(name) {
+			errs = append(errs, fmt.Errorf("name or alias %q conflicts with site schema", name))
+		}
+	}
+	if siteConfig.Type == "" {
+		if !slices.Contains(internalSitenames, name) {
+			errs = append(errs, fmt.Errorf("type is required for non-internal site"))
+		}
+	} else if _, err := site.Find(siteConfig.Type); err != nil || SITES[siteConfig.Type] != nil {
+		errs = append(errs, fmt.Errorf("invalid type %q: must be a site schema (e.g. nexusphp)", siteConfig.Type))
+	}
+	if siteConfig.Url != "" && !util.IsUrl(siteConfig.Url) {
+		errs = append(errs, fmt.Errorf("invalid url %q", siteConfig.Url))
+	}
+	value := reflect.ValueOf(siteConfig).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !strings.HasPrefix(field.Name, "Selector") || field.Type.Kind() != reflect.String {
+			continue
+		}
+		selector := value.Field(i).String()
+		if selector == "" {
+			continue
+		}
+		selector = strings.TrimSuffix(strings.TrimSuffix(selector, "@text"), "@after")
+		if _, err := cascadia.Compile(selector); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s selector %q: %w", field.Name, selector, err))
+		}
+	}
+	return errs
+}
+
+// Return true if name is registered as a site schema (not a tpl site), e.g. "nexusphp".
+func isSchemaName(name string) bool {
+	_, err := site.Find(name)
+	return err == nil && SITES[name] == nil
+}
+
+// Add a site definition of pack to SITES and register it.
+// If it has the same name with an existing site, it's merged into the existing one.
+func addPackSite(siteConfig *config.SiteConfigStruct, source string) error {
+	if errs := ValidateSite(siteConfig); len(errs) > 0 {
+		return errors.New(strings.Join(util.Map(errs, func(err error) string { return err.Error() }), "; "))
+	}
+	name := siteConfig.Name
+	newConfig := &config.SiteConfigStruct{}
+	existing := SITES[name]
+	if existing != nil {
+		if !slices.Contains(SITENAMES, name) {
+			return fmt.Errorf("name %s is an alias of other site", name)
+		}
+		*newConfig = *existing // copy
+	}
+	util.Assign(newConfig, siteConfig, nil)
+	newConfig.Name = ""
+	for _, alias := range newConfig.Aliases {
+		if SITES[alias] != nil && SITES[alias] != existing {
+			return fmt.Errorf("alias %s conflicts with other site", alias)
+		}
+	}
+	if existing != nil {
+		for key, value := range SITES {
+			if value == existing {
+				SITES[key] = newConfig
+			}
+		}
+	} else {
+		SITENAMES = append(SITENAMES, name)
+		sort.Strings(SITENAMES)
+	}
+	SITES[name] = newConfig
+	for _, alias := range newConfig.Aliases {
+		SITES[alias] = newConfig
+	}
+	SOURCES[name] = source
+	site.Register(&site.RegInfo{
+		Name:    name,
+		Aliases: newConfig.Aliases,
+		Creator: create,
+	})
+	return nil
+}
+
+// Load all site definition packs in dir, which override or extend the internal sites.
+// Invalid pack files or site definitions are skipped, with errors logged.
+func LoadPacks(dir string) {
+	filenames, err := GetPackFiles(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Failed to read site packs dir %s: %v", dir, err)
+		}
+		return
+	}
+	for _, filename := range filenames {
+		sites, err := ReadPack(filename, false)
+		if err != nil {
+			log.Errorf("Failed to read site pack %s: %v", filename, err)
+			continue
+		}
+		for _, siteConfig := range sites {
+			if err := addPackSite(siteConfig, filename); err != nil {
+				log.Errorf("Invalid site %q in site pack %s: %v", siteConfig.Name, filename, err)
+			}
+		}
+		log.Debugf("Loaded site pack %s: %d sites", filename, len(sites))
+	}
+}
@@ -0,0 +1,81 @@
+package tpl_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/site/tpl"
+
+	_ "github.com/sagan/ptool/site/nexusphp"
+)
+
+const pack = `
+[[sites]]
+name = 'packsite'
+type = 'nexusphp'
+url = 'https://packsite.example/'
+aliases = ['ps']
+selectorTorrentFree = '.pro_free'
+
+[[sites]]
+name = 'zmpt'
+comment = 'overrided'
+selectorTorrentSeeders = 'a[href$="seeders"]@text'
+
+[[sites]]
+name = 'badsite'
+type = 'zmpt'
+selectorTorrentFree = 'a[href='
+
+[[sites]]
+name = 'nexusphp'
+type = 'nexusphp'
+url = 'https://schema.example/'
+
+[[sites]]
+name = 'aliassite'
+type = 'nexusphp'
+url = 'https://aliassite.example/'
+aliases = ['nexusphp']
+`
+
+func TestLoadPacks(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "pack.toml")
+	os.WriteFile(filename, []byte(pack), 0600)
+	os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not a pack"), 0600)
+	tpl.LoadPacks(dir)
+
+	packsite := tpl.SITES["packsite"]
+	if packsite == nil || tpl.SITES["ps"] != packsite || packsite.Type != "nexusphp" ||
+		packsite.SelectorTorrentFree != ".pro_free" || packsite.Name != "" || tpl.SOURCES["packsite"] != filename {
+		t.Errorf("unexpected pack site: %+v", packsite)
+	}
+	zmpt := tpl.SITES["zmpt"]
+	if zmpt.Comment != "overrided" || zmpt.Type != "nexusphp" || zmpt.Url != "https://zmpt.cc/" ||
+		tpl.SOURCES["zmpt"] != filename {
+		t.Errorf("unexpected overrided site: %+v", zmpt)
+	}
+	if tpl.SITES["badsite"] != nil {
+		t.Errorf("invalid site should not be loaded")
+	}
+	if tpl.SITES["nexusphp"] != nil || tpl.SITES["aliassite"] != nil {
+		t.Errorf("site that conflicts with site schema should not be loaded")
+	}
+
+	sites, err := tpl.ReadPack(filename, true)
+	if err != nil || len(sites) != 5 {
+		t.Fatalf("failed to read pack: %v", err)
+	}
+	if errs := tpl.ValidateSite(sites[2]); len(errs) != 2 {
+		t.Errorf("expect 2 errors of invalid site, got %v", errs)
+	}
+	if errs := tpl.ValidateSite(sites[3]); len(errs) != 1 {
+		t.Errorf("expect 1 error of site named as site schema, got %v", errs)
+	}
+	os.WriteFile(filename, []byte("[[sites]]\nname = 'foo'\nunknownKey = 1\n"), 0600)
+	if _, err := tpl.ReadPack(filename, true); err == nil {
+		t.Errorf("expect error of unknown key")
+	}
+}
@@ -767,6 +767,7 @@ func init() {
 	sort.Slice(SITENAMES, func(i, j int) bool {
 		return SITENAMES[i] < SITENAMES[j]
 	})
+	internalSitenames = util.CopySlice(SITENAMES)
 	for _, name := range SITENAMES {
 		config := SITES[name]
 		for _, alias := range config.Aliases {
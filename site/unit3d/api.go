@@ -0,0 +1,263 @@
+package unit3d
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// UNIT3D API. Docs: https://hdinnovations.github.io/UNIT3D-Community-Edition-Docs/torrent_api.html .
+// All API requests are authenticated by "api_token" query parameter.
+const (
+	API_TORRENTS_FILTER = "api/torrents/filter"
+	API_TORRENTS_UPLOAD = "api/torrents/upload"
+	API_USER            = "api/user"
+	API_MAX_PER_PAGE    = 100
+)
+
+var downloadIdRegexp = regexp.MustCompile(`/download/(?P<id>\d+)\b`)
+
+var apiSortFields = map[string]string{
+	"time": "created_at",
+	"size": "size",
+	"name": "name",
+}
+
+type apiTorrentsResponse struct {
+	Data  []*apiTorrent `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+type apiTorrent struct {
+	Id         json.Number `json:"id"`
+	Attributes struct {
+		Name           string          `json:"name"`
+		Category       string          `json:"category"`
+		Type           string          `json:"type"`
+		Resolution     string          `json:"resolution"`
+		InfoHash       string          `json:"info_hash"`
+		Size           int64           `json:"size"`
+		Freeleech      json.RawMessage `json:"freeleech"`     // "100%" or 100 in old versions
+		DoubleUpload   json.RawMessage `json:"double_upload"` // true or 1 in old versions
+		Featured       json.RawMessage `json:"featured"`
+		Seeders        int64           `json:"seeders"`
+		Leechers       int64           `json:"leechers"`
+		TimesCompleted int64           `json:"times_completed"`
+		CreatedAt      string          `json:"created_at"`
+		DownloadLink   string          `json:"download_link"`
+		// Below fields are only available in single torrent API.
+		Description string          `json:"description"`
+		Uploader    string          `json:"uploader"`
+		ImdbId      json.RawMessage `json:"imdb_id"` // number without "tt" prefix
+		Files       []struct {
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"files"`
+	} `json:"attributes"`
+}
+
+type apiTorrentResponse struct {
+	Data *apiTorrent `json:"data"`
+}
+
+// Response of user API. Uploaded / downloaded are formatted size strings, e.g. "1.5 TiB".
+type apiUserResponse struct {
+	Username   string          `json:"username"`
+	Group      string          `json:"group"`
+	Uploaded   string          `json:"uploaded"`
+	Downloaded string          `json:"downloaded"`
+	Ratio      json.RawMessage `json:"ratio"`
+	Seeding    int64           `json:"seeding"`
+	Leeching   int64           `json:"leeching"`
+	Seedbonus  json.RawMessage `json:"seedbonus"`
+	HitAndRuns int64           `json:"hit_and_runs"`
+}
+
+func (usite *Site) getApiStatus() (*site.Status, error) {
+	apiUrl := usite.SiteConfig.Url + API_USER + "?api_token=" + url.QueryEscape(usite.SiteConfig.ApiToken)
+	var res apiUserResponse
+	if err := util.FetchJsonWithAzuretls(apiUrl, &res, usite.HttpClient, "", site.GetUa(usite),
+		usite.GetDefaultHttpHeaders()); err != nil {
+		return nil, fmt.Errorf("failed to fetch api: %w", err)
+	}
+	if res.Username == "" {
+		return nil, fmt.Errorf("invalid api response")
+	}
+	uploaded, _ := util.ExtractSizeStr(res.Uploaded)
+	downloaded, _ := util.ExtractSizeStr(res.Downloaded)
+	return &site.Status{
+		UserName:            res.Username,
+		UserUploaded:        uploaded,
+		UserDownloaded:      downloaded,
+		TorrentsSeedingCnt:  res.Seeding,
+		TorrentsLeechingCnt: res.Leeching,
+		UserBonus:           parseJsonNumber(res.Seedbonus),
+		UserRatio:           parseJsonNumber(res.Ratio),
+		UserClass:           res.Group,
+		UserHnRCnt:          res.HitAndRuns,
+	}, nil
+}
+
+// Fetch a page of torrents from API. apiUrl is the full url (with query) of the page, without api_token.
+func (usite *Site) getApiTorrents(apiUrl string) (torrents []*site.Torrent, nextPageUrl string, err error) {
+	urlObj, err := url.Parse(apiUrl)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid api url: %w", err)
+	}
+	query := urlObj.Query()
+	query.Set("api_token", usite.SiteConfig.ApiToken)
+	urlObj.RawQuery = query.Encode()
+	var res apiTorrentsResponse
+	// The API does not use cookie.
+	if err = util.FetchJsonWithAzuretls(urlObj.String(), &res, usite.HttpClient, "", site.GetUa(usite),
+		usite.GetDefaultHttpHeaders()); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch api: %w", err)
+	}
+	for _, apiTorrent := range res.Data {
+		torrents = append(torrents, usite.convertApiTorrent(apiTorrent))
+	}
+	if res.Links.Next != "" {
+		// The next link contains the cursor (or page) parameter, but not api_token.
+		if nextUrlObj, err := url.Parse(res.Links.Next); err == nil {
+			nextQuery := nextUrlObj.Query()
+			nextQuery.Del("api_token")
+			nextUrlObj.RawQuery = nextQuery.Encode()
+			nextPageUrl = nextUrlObj.String()
+		}
+	}
+	return torrents, nextPageUrl, nil
+}
+
+// Get torrent details from API: /api/torrents/{id} .
+func (usite *Site) getApiTorrentDetails(id string) (*site.TorrentDetails, error) {
+	apiUrl := usite.SiteConfig.Url + "api/torrents/" + url.PathEscape(id) +
+		"?api_token=" + url.QueryEscape(usite.SiteConfig.ApiToken)
+	var res apiTorrentResponse
+	if err := util.FetchJsonWithAzuretls(apiUrl, &res, usite.HttpClient, "", site.GetUa(usite),
+		usite.GetDefaultHttpHeaders()); err != nil {
+		return nil, fmt.Errorf("failed to fetch api: %w", err)
+	}
+	if res.Data == nil {
+		return nil, fmt.Errorf("torrent not found")
+	}
+	attributes := &res.Data.Attributes
+	torrent := usite.convertApiTorrent(res.Data)
+	torrent.Description = attributes.Description
+	details := &site.TorrentDetails{
+		Torrent:  torrent,
+		Uploader: attributes.Uploader,
+	}
+	if imdbId := int64(parseJsonNumber(attributes.ImdbId)); imdbId > 0 {
+		details.ImdbUrl = fmt.Sprintf("https://www.imdb.com/title/tt%07d", imdbId)
+	}
+	imdbUrl, doubanUrl := site.FindImdbDoubanUrls(attributes.Description)
+	if details.ImdbUrl == "" {
+		details.ImdbUrl = imdbUrl
+	}
+	details.DoubanUrl = doubanUrl
+	for _, file := range attributes.Files {
+		details.Files = append(details.Files, &site.TorrentFile{Path: file.Name, Size: file.Size})
+	}
+	return details, nil
+}
+
+func (usite *Site) getApiUrl(params url.Values) string {
+	params.Set("perPage", fmt.Sprint(API_MAX_PER_PAGE))
+	return usite.SiteConfig.Url + API_TORRENTS_FILTER + "?" + params.Encode()
+}
+
+func (usite *Site) convertApiTorrent(apiTorrent *apiTorrent) *site.Torrent {
+	attributes := &apiTorrent.Attributes
+	id := apiTorrent.Id.String()
+	downloadMultiplier := 1.0
+	uploadMultiplier := 1.0
+	if freeleech := parseJsonNumber(attributes.Freeleech); freeleech > 0 {
+		downloadMultiplier = max(0, 1-freeleech/100)
+	}
+	if parseJsonNumber(attributes.DoubleUpload) > 0 {
+		uploadMultiplier = 2
+	}
+	// Featured torrents are 100% free and double upload.
+	if parseJsonNumber(attributes.Featured) > 0 {
+		downloadMultiplier = 0
+		uploadMultiplier = 2
+	}
+	downloadUrl := attributes.DownloadLink
+	if downloadUrl == "" {
+		downloadUrl = usite.SiteConfig.Url + "torrents/download/" + id
+	}
+	tags := []string{}
+	for _, tag := range []string{attributes.Category, attributes.Type, attributes.Resolution} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return &site.Torrent{
+		Name:               attributes.Name,
+		Id:                 usite.GetName() + "." + id,
+		InfoHash:           strings.ToLower(attributes.InfoHash),
+		DownloadUrl:        downloadUrl,
+		DownloadMultiplier: downloadMultiplier,
+		UploadMultiplier:   uploadMultiplier,
+		DiscountEndTime:    -1,
+		Time:               usite.parseTime(attributes.CreatedAt),
+		Size:               attributes.Size,
+		IsSizeAccurate:     true,
+		Seeders:            attributes.Seeders,
+		Leechers:           attributes.Leechers,
+		Snatched:           attributes.TimesCompleted,
+		Tags:               tags,
+	}
+}
+
+// Response of upload API. On success, data is the download url of the uploaded torrent;
+// on failure, data is the validation errors of fields.
+type apiUploadResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+}
+
+// Parse upload API response, return the uploaded torrent id.
+func parseApiUploadResponse(body []byte) (id string, err error) {
+	var res apiUploadResponse
+	if err = json.Unmarshal(body, &res); err != nil {
+		return "", fmt.Errorf("failed to parse upload response: %w", err)
+	}
+	if !res.Success {
+		return "", fmt.Errorf("failed to upload torrent: %s %s", res.Message, string(res.Data))
+	}
+	var downloadUrl string
+	json.Unmarshal(res.Data, &downloadUrl)
+	if m := downloadIdRegexp.FindStringSubmatch(downloadUrl); m != nil {
+		return m[downloadIdRegexp.SubexpIndex("id")], nil
+	}
+	return "", fmt.Errorf("got no id from upload response: %s", string(res.Data))
+}
+
+func (usite *Site) parseTime(str string) int64 {
+	if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return t.Unix()
+	}
+	ts, _ := util.ParseTime(str, usite.Location)
+	return ts
+}
+
+// Parse a json value that may be a number, a bool or a string like "50%". Return 0 if failed.
+func parseJsonNumber(value json.RawMessage) float64 {
+	str := strings.ReplaceAll(strings.Trim(strings.TrimSpace(string(value)), `"`), ",", "")
+	if str == "true" {
+		return 1
+	}
+	number, _ := strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+	return number
+}
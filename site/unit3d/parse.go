@@ -0,0 +1,92 @@
+package unit3d
+
+import (
+	"regexp"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// Selectors of UNIT3D (v6+) torrents list page (/torrents).
+const (
+	SELECTOR_TORRENT_ROW          = ".torrent-search--list__row, .torrent-search--list__results tbody tr"
+	SELECTOR_TORRENT_NAME         = ".torrent-search--list__name"
+	SELECTOR_TORRENT_DOWNLOAD     = `a[href*="/torrents/download/"]`
+	SELECTOR_TORRENT_TIME         = ".torrent-search--list__age"
+	SELECTOR_TORRENT_SIZE         = ".torrent-search--list__size"
+	SELECTOR_TORRENT_SEEDERS      = ".torrent-search--list__seeders"
+	SELECTOR_TORRENT_LEECHERS     = ".torrent-search--list__leechers"
+	SELECTOR_TORRENT_COMPLETED    = ".torrent-search--list__completed"
+	SELECTOR_TORRENT_FREELEECH    = ".torrent-icons__freeleech"
+	SELECTOR_TORRENT_DOUBLEUPLOAD = ".torrent-icons__double-upload"
+	SELECTOR_TORRENT_FEATURED     = ".torrent-icons__featured"
+)
+
+var (
+	torrentIdRegexp = regexp.MustCompile(`/torrents/(?P<id>\d+)\b`)
+	percentRegexp   = regexp.MustCompile(`(?P<percent>\d+)\s*%`)
+)
+
+func (usite *Site) parseTorrentsFromDoc(doc *goquery.Document) []*site.Torrent {
+	torrents := []*site.Torrent{}
+	doc.Find(SELECTOR_TORRENT_ROW).Each(func(i int, s *goquery.Selection) {
+		nameEl := s.Find(SELECTOR_TORRENT_NAME).First()
+		if nameEl.Length() == 0 {
+			nameEl = s.Find(`a[href*="/torrents/"]`).Not(SELECTOR_TORRENT_DOWNLOAD).First()
+		}
+		id := s.AttrOr("data-torrent-id", "")
+		if id == "" {
+			if m := torrentIdRegexp.FindStringSubmatch(nameEl.AttrOr("href", "")); m != nil {
+				id = m[torrentIdRegexp.SubexpIndex("id")]
+			}
+		}
+		if id == "" {
+			return
+		}
+		downloadMultiplier := 1.0
+		uploadMultiplier := 1.0
+		if freeleechEl := s.Find(SELECTOR_TORRENT_FREELEECH); freeleechEl.Length() > 0 {
+			downloadMultiplier = 0
+			if m := percentRegexp.FindStringSubmatch(freeleechEl.AttrOr("title", "")); m != nil {
+				downloadMultiplier = max(0, 1-float64(util.ParseInt(m[percentRegexp.SubexpIndex("percent")]))/100)
+			}
+		}
+		if s.Find(SELECTOR_TORRENT_DOUBLEUPLOAD).Length() > 0 {
+			uploadMultiplier = 2
+		}
+		if s.Find(SELECTOR_TORRENT_FEATURED).Length() > 0 {
+			downloadMultiplier = 0
+			uploadMultiplier = 2
+		}
+		downloadUrl := usite.SiteConfig.ParseSiteUrl(s.Find(SELECTOR_TORRENT_DOWNLOAD).AttrOr("href", ""), false)
+		if downloadUrl == "" {
+			downloadUrl = usite.SiteConfig.Url + "torrents/download/" + id
+		}
+		timeEl := s.Find(SELECTOR_TORRENT_TIME)
+		torrentTime := usite.parseTime(timeEl.Find("time").AttrOr("datetime", ""))
+		if torrentTime == 0 {
+			torrentTime = util.DomTime(timeEl, usite.Location)
+		}
+		size, _ := util.ExtractSizeStr(util.DomSanitizedText(s.Find(SELECTOR_TORRENT_SIZE)))
+		torrents = append(torrents, &site.Torrent{
+			Name:               util.DomSanitizedText(nameEl),
+			Id:                 usite.GetName() + "." + id,
+			DownloadUrl:        downloadUrl,
+			DownloadMultiplier: downloadMultiplier,
+			UploadMultiplier:   uploadMultiplier,
+			DiscountEndTime:    -1,
+			Time:               torrentTime,
+			Size:               size,
+			Seeders:            domInt(s.Find(SELECTOR_TORRENT_SEEDERS)),
+			Leechers:           domInt(s.Find(SELECTOR_TORRENT_LEECHERS)),
+			Snatched:           domInt(s.Find(SELECTOR_TORRENT_COMPLETED)),
+		})
+	})
+	return torrents
+}
+
+func domInt(s *goquery.Selection) int64 {
+	return util.ParseInt(util.DomSanitizedText(s))
+}
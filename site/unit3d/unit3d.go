@@ -5,6 +5,7 @@ package unit3d
 // 种子下载链接格式：https://jptv.club/torrents/download/39683
 
 import (
+	"bytes"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -15,6 +16,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
@@ -28,15 +30,80 @@ type Site struct {
 	HttpHeaders [][]string
 }
 
-// PublishTorrent implements site.Site.
+// UNIT3D default upload torrent API form data:
+//
+//	torrent: .torrent binary file
+//	name: 标题
+//	description: 简介, bbcode
+//	category_id, type_id, resolution_id: 分类、类型(WEB-DL / Remux 等)、分辨率 id
+//	tmdb, imdb, tvdb, mal, igdb: 外部数据库 id, 0 表示无
+//	anonymous: 1=匿名发布
+//
+// The ids are site specific, they are mapped from metadata by uploadTorrent*Map configs.
+var defaultUploadTorrentPayload = map[string]string{
+	"name": `{% if number %}[{{number}}]{% endif %}{% if author %}[{{author}}]{% endif %}{{title}}`,
+	"description": `
+{% if _cover %}
+[img]{{_cover}}[/img]
+{% endif %}
+{% if _images %}
+{% for image in _images %}
+[img]{{image}}[/img]
+{% endfor %}
+{% endif %}
+{{_text}}{% if comment %}
+
+---
+
+{{comment}}{% endif %}`,
+	"category_id":      `{{_category_id}}`,
+	"type_id":          `{{_type_id}}`,
+	"resolution_id":    `{{_resolution_id}}`,
+	"tmdb":             `{% if tmdb %}{{tmdb}}{% else %}0{% endif %}`,
+	"imdb":             `{% if imdb %}{{imdb}}{% else %}0{% endif %}`,
+	"tvdb":             `0`,
+	"mal":              `0`,
+	"igdb":             `0`,
+	"anonymous":        `1`,
+	"stream":           `0`,
+	"sd":               `0`,
+	"personal_release": `0`,
+}
+
+// Upload torrent to UNIT3D site using API, which requires apiToken.
+// See: https://github.com/HDInnovations/UNIT3D-Community-Edition/blob/master/app/Http/Controllers/API/TorrentController.php .
+// Upload: POST /api/torrents/upload?api_token= with multipart/form-data.
 func (usite *Site) PublishTorrent(contents []byte, metadata url.Values) (id string, err error) {
-	return "", site.ErrUnimplemented
+	if usite.SiteConfig.ApiToken == "" {
+		return "", fmt.Errorf("apiToken is not configured")
+	}
+	payload, err := site.RenderUploadTorrentPayload(usite, usite.HttpClient, metadata, defaultUploadTorrentPayload,
+		[]string{"name", "category_id", "type_id"})
+	if err != nil {
+		if err == constants.ErrDryRun {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to render payload: %w", err)
+	}
+	uploadUrl := usite.SiteConfig.Url + API_TORRENTS_UPLOAD + "?api_token=" + url.QueryEscape(usite.SiteConfig.ApiToken)
+	headers := util.GetHttpReqHeaders(usite.GetDefaultHttpHeaders(), "", site.GetUa(usite))
+	headers = append(headers, []string{"Accept", "application/json"})
+	res, err := util.PostUploadFile(usite.HttpClient, uploadUrl, "a.torrent", bytes.NewReader(contents), "torrent",
+		payload, headers)
+	if res == nil {
+		return "", fmt.Errorf("failed to upload torrent: %w", err)
+	}
+	return parseApiUploadResponse(res.Body)
 }
 
 const (
 	SELECTOR_USERNAME        = ".top-nav__username"
 	SELECTOR_USER_UPLOADED   = ".ratio-bar__uploaded"
 	SELECTOR_USER_DOWNLOADED = ".ratio-bar__downloaded"
+	SELECTOR_USER_SEEDING    = ".ratio-bar__seeding"
+	SELECTOR_USER_LEECHING   = ".ratio-bar__leeching"
+	SELECTOR_USER_POINTS     = ".ratio-bar__points"
+	SELECTOR_USER_RATIO      = ".ratio-bar__ratio"
 )
 
 func (usite *Site) GetDefaultHttpHeaders() [][]string {
@@ -54,14 +121,23 @@ func (usite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return usite.SiteConfig
 }
 
+// If apiToken is set, get status from API, and fallback to parsing html page if API fails.
 func (usite *Site) GetStatus() (*site.Status, error) {
+	if usite.SiteConfig.ApiToken != "" {
+		status, err := usite.getApiStatus()
+		if err == nil {
+			site.SetLogined(usite.GetName())
+			return status, nil
+		}
+		log.Debugf("failed to get site %s status from api: %v", usite.GetName(), err)
+	}
 	doc, res, err := util.GetUrlDocWithAzuretls(usite.SiteConfig.Url+"torrents", usite.HttpClient,
 		usite.GetSiteConfig().Cookie, site.GetUa(usite), usite.GetDefaultHttpHeaders())
 	if err != nil {
 		return nil, err
 	}
 	if strings.Contains(res.Request.Url, "/login") {
-		return nil, fmt.Errorf("not logined (cookie may has expired)")
+		return nil, site.NewNotLoginedError(usite.GetName())
 	}
 	userNameSelector := SELECTOR_USERNAME
 	userUploadedSelector := SELECTOR_USER_UPLOADED
@@ -80,24 +156,103 @@ func (usite *Site) GetStatus() (*site.Status, error) {
 	downloadedEl := doc.Find(userDownloadedSelector)
 	userUploaded, _ := util.ExtractSizeStr(util.DomSanitizedText(uploadedEl))
 	userDownloaded, _ := util.ExtractSizeStr(util.DomSanitizedText(downloadedEl))
-	return &site.Status{
-		UserName:       util.DomSanitizedText(usernameEl),
-		UserUploaded:   userUploaded,
-		UserDownloaded: userDownloaded,
-	}, nil
+	status := &site.Status{
+		UserName:            util.DomSanitizedText(usernameEl),
+		UserUploaded:        userUploaded,
+		UserDownloaded:      userDownloaded,
+		TorrentsSeedingCnt:  util.ParseInt(util.DomSanitizedText(doc.Find(SELECTOR_USER_SEEDING))),
+		TorrentsLeechingCnt: util.ParseInt(util.DomSanitizedText(doc.Find(SELECTOR_USER_LEECHING))),
+		UserBonus:           util.ParseFloat(util.DomSanitizedText(doc.Find(SELECTOR_USER_POINTS))),
+		UserRatio:           util.ParseFloat(util.DomSanitizedText(doc.Find(SELECTOR_USER_RATIO))),
+	}
+	if status.IsOk() {
+		site.SetLogined(usite.GetName())
+	}
+	return status, nil
 }
 
+// pageMarker is the url of next page. The API uses cursor pagination,
+// while html pages use page number, both are contained in the next page url.
 func (usite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
 	torrents []*site.Torrent, nextPageMarker string, err error) {
-	return nil, "", site.ErrUnimplemented
+	if sort != "" && sort != constants.NONE && apiSortFields[sort] == "" {
+		return nil, "", fmt.Errorf("unsupported sort field: %s", sort)
+	}
+	if pageMarker == "" {
+		params := url.Values{}
+		if sort != "" && sort != constants.NONE {
+			params.Set("sortField", apiSortFields[sort])
+			if desc {
+				params.Set("sortDirection", "desc")
+			} else {
+				params.Set("sortDirection", "asc")
+			}
+		}
+		pageMarker = usite.getListUrl(baseUrl, params)
+	}
+	return usite.getTorrents(pageMarker)
 }
 
 func (usite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	torrents, _, err := usite.getTorrents(usite.getListUrl("", url.Values{
+		"sortField":     {"created_at"},
+		"sortDirection": {"desc"},
+	}))
+	return torrents, err
 }
 
 func (usite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	if baseUrl != "" && strings.Contains(baseUrl, "%s") {
+		torrents, _, err := usite.getTorrents(usite.SiteConfig.ParseSiteUrl(
+			strings.Replace(baseUrl, "%s", url.QueryEscape(keyword), 1), false))
+		return torrents, err
+	}
+	torrents, _, err := usite.getTorrents(usite.getListUrl(baseUrl, url.Values{"name": {keyword}}))
+	return torrents, err
+}
+
+// Only available with apiToken.
+func (usite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	if usite.SiteConfig.ApiToken == "" {
+		return nil, fmt.Errorf("apiToken is not configured: %w", site.ErrUnimplemented)
+	}
+	return usite.getApiTorrentDetails(id)
+}
+
+func (usite *Site) Attendance() (*site.AttendanceResult, error) {
+	return nil, site.ErrAttendanceUnsupported
+}
+
+// Return the url of torrents list (API if api token is configured, or html page otherwise) with params.
+// If baseUrl is not empty, use it as list url instead, params are appended.
+func (usite *Site) getListUrl(baseUrl string, params url.Values) string {
+	if baseUrl == "" {
+		if usite.SiteConfig.ApiToken != "" {
+			return usite.getApiUrl(params)
+		}
+		params.Set("perPage", "100")
+		return usite.SiteConfig.Url + "torrents?" + params.Encode()
+	}
+	return usite.SiteConfig.ParseSiteUrl(baseUrl, true) + params.Encode()
+}
+
+func (usite *Site) getTorrents(listUrl string) (torrents []*site.Torrent, nextPageUrl string, err error) {
+	if usite.SiteConfig.ApiToken != "" && strings.Contains(listUrl, "/"+API_TORRENTS_FILTER) {
+		return usite.getApiTorrents(listUrl)
+	}
+	doc, res, err := util.GetUrlDocWithAzuretls(listUrl, usite.HttpClient,
+		usite.GetSiteConfig().Cookie, site.GetUa(usite), usite.GetDefaultHttpHeaders())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse site page dom: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login") {
+		return nil, "", site.NewNotLoginedError(usite.GetName())
+	}
+	torrents = usite.parseTorrentsFromDoc(doc)
+	if nextLink := doc.Find(`a[rel="next"]`).AttrOr("href", ""); nextLink != "" {
+		nextPageUrl = usite.SiteConfig.ParseSiteUrl(nextLink, false)
+	}
+	return torrents, nextPageUrl, nil
 }
 
 func (usite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
@@ -114,9 +269,8 @@ func (usite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename
 			return
 		}
 	}
-	idRegexp := regexp.MustCompile(`/download/(?P<id>\d+)\b`)
-	if m := idRegexp.FindStringSubmatch(torrentUrl); m != nil {
-		id = m[idRegexp.SubexpIndex("id")]
+	if m := downloadIdRegexp.FindStringSubmatch(torrentUrl); m != nil {
+		id = m[downloadIdRegexp.SubexpIndex("id")]
 	}
 	content, filename, err = site.DownloadTorrentByUrl(usite, usite.HttpClient, torrentUrl, id)
 	return
@@ -0,0 +1,173 @@
+package unit3d_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site/unit3d"
+)
+
+const apiResponse = `{
+  "data": [
+    {
+      "type": "torrent",
+      "id": "123",
+      "attributes": {
+        "name": "Foo 2023 1080p WEB-DL",
+        "category": "Movie",
+        "type": "WEB-DL",
+        "resolution": "1080p",
+        "info_hash": "ABCDEF0123456789ABCDEF0123456789ABCDEF01",
+        "size": 1073741824,
+        "freeleech": "50%",
+        "double_upload": true,
+        "featured": false,
+        "seeders": 3,
+        "leechers": 7,
+        "times_completed": 11,
+        "created_at": "2023-06-01T12:00:00.000000Z",
+        "download_link": "{server}/torrent/download/123.rsskey"
+      }
+    }
+  ],
+  "links": {"next": "{server}/api/torrents/filter?cursor=abc&api_token=secret"},
+  "meta": {"per_page": 100}
+}`
+
+const htmlResponse = `<html><body><table class="torrent-search--list__results"><tbody>
+<tr class="torrent-search--list__row" data-torrent-id="456">
+  <td class="torrent-search--list__overview">
+    <a class="torrent-search--list__name" href="/torrents/456">Bar S01 2160p</a>
+    <i class="torrent-icons__freeleech" title="100% Freeleech"></i>
+  </td>
+  <td><a href="/torrents/download/456">Download</a></td>
+  <td class="torrent-search--list__age"><time datetime="2023-06-01 12:00:00">1 hour ago</time></td>
+  <td class="torrent-search--list__size"><span>2.00 GiB</span></td>
+  <td class="torrent-search--list__seeders"><a><span>1,024</span></a></td>
+  <td class="torrent-search--list__leechers"><a><span>2</span></a></td>
+  <td class="torrent-search--list__completed"><a><span>5</span></a></td>
+</tr>
+</tbody></table><a rel="next" href="/torrents?page=2">Next</a></body></html>`
+
+func TestUnit3dTorrents(t *testing.T) {
+	var apiToken, name, cursor string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/torrents/filter":
+			apiToken = r.URL.Query().Get("api_token")
+			name = r.URL.Query().Get("name")
+			cursor = r.URL.Query().Get("cursor")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, strings.ReplaceAll(apiResponse, "{server}", server.URL))
+		case "/torrents":
+			fmt.Fprint(w, htmlResponse)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	apiSite, err := unit3d.NewSite("u3d", &config.SiteConfigStruct{Url: server.URL + "/", ApiToken: "secret"},
+		&config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	torrents, err := apiSite.SearchTorrents("foo", "")
+	if err != nil || len(torrents) != 1 {
+		t.Fatalf("failed to search torrents: %v (%d torrents)", err, len(torrents))
+	}
+	torrent := torrents[0]
+	if apiToken != "secret" || name != "foo" {
+		t.Errorf("unexpected api request: api_token=%s, name=%s", apiToken, name)
+	}
+	if torrent.Id != "u3d.123" || torrent.Size != 1073741824 || torrent.Seeders != 3 || torrent.Leechers != 7 ||
+		torrent.Snatched != 11 || torrent.DownloadMultiplier != 0.5 || torrent.UploadMultiplier != 2 ||
+		torrent.Time != 1685620800 || torrent.InfoHash != "abcdef0123456789abcdef0123456789abcdef01" ||
+		torrent.DownloadUrl != server.URL+"/torrent/download/123.rsskey" {
+		t.Errorf("unexpected api torrent: %+v", torrent)
+	}
+	_, nextPageMarker, err := apiSite.GetAllTorrents("time", true, "", "")
+	if err != nil {
+		t.Fatalf("failed to get all torrents: %v", err)
+	}
+	if _, _, err = apiSite.GetAllTorrents("time", true, nextPageMarker, ""); err != nil || cursor != "abc" ||
+		apiToken != "secret" {
+		t.Errorf("failed to get next page (marker %s): err=%v, cursor=%s", nextPageMarker, err, cursor)
+	}
+
+	htmlSite, err := unit3d.NewSite("u3d", &config.SiteConfigStruct{Url: server.URL + "/", Timezone: "UTC"},
+		&config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	torrents, nextPageMarker, err = htmlSite.GetAllTorrents("", false, "", "")
+	if err != nil || len(torrents) != 1 {
+		t.Fatalf("failed to get html torrents: %v (%d torrents)", err, len(torrents))
+	}
+	torrent = torrents[0]
+	if torrent.Id != "u3d.456" || torrent.Name != "Bar S01 2160p" || torrent.Size != 2<<30 ||
+		torrent.Seeders != 1024 || torrent.Leechers != 2 || torrent.Snatched != 5 ||
+		torrent.DownloadMultiplier != 0 || torrent.UploadMultiplier != 1 || torrent.Time != 1685620800 ||
+		torrent.DownloadUrl != server.URL+"/torrents/download/456" || nextPageMarker != server.URL+"/torrents?page=2" {
+		t.Errorf("unexpected html torrent: %+v, next page: %s", torrent, nextPageMarker)
+	}
+}
+
+func TestUnit3dPublish(t *testing.T) {
+	var form url.Values
+	var apiToken, torrentFilename string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/torrents/upload" {
+			http.NotFound(w, r)
+			return
+		}
+		apiToken = r.URL.Query().Get("api_token")
+		r.ParseMultipartForm(1 << 20)
+		form = r.MultipartForm.Value
+		if _, header, err := r.FormFile("torrent"); err == nil {
+			torrentFilename = header.Filename
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "data": "%s/torrent/download/789.rsskey", "message": "ok"}`, "http://"+r.Host)
+	}))
+	defer server.Close()
+
+	siteInstance, err := unit3d.NewSite("u3d", &config.SiteConfigStruct{
+		Url:                        server.URL + "/",
+		ApiToken:                   "secret",
+		UploadTorrentCategoryMap:   map[string]string{"movie": "1", "*": "9"},
+		UploadTorrentTypeMap:       map[string]string{"web-dl": "4"},
+		UploadTorrentResolutionMap: map[string]string{"1080p": "3"},
+	}, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	metadata := url.Values{
+		"title":       {"Foo"},
+		"category":    {"Movie"},
+		"type":        {"WEB-DL"},
+		"tags":        {"1080p", "HDR"},
+		"_array_keys": {"tags"},
+	}
+	id, err := siteInstance.PublishTorrent([]byte("d4:infod4:name3:fooee"), metadata)
+	if err != nil || id != "789" {
+		t.Fatalf("failed to publish torrent: id=%s, err=%v", id, err)
+	}
+	if apiToken != "secret" || torrentFilename != "a.torrent" || form.Get("name") != "Foo" ||
+		form.Get("category_id") != "1" || form.Get("type_id") != "4" || form.Get("resolution_id") != "3" ||
+		form.Get("tmdb") != "0" {
+		t.Errorf("unexpected upload request: api_token=%s, file=%s, form=%v", apiToken, torrentFilename, form)
+	}
+
+	metadata.Set("_dryrun", "1")
+	if _, err = siteInstance.PublishTorrent(nil, metadata); err != constants.ErrDryRun {
+		t.Errorf("expect dry run error, got %v", err)
+	}
+}
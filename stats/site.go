@@ -0,0 +1,141 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Site stats periods.
+const (
+	PERIOD_DAY   = "day"
+	PERIOD_WEEK  = "week"
+	PERIOD_MONTH = "month"
+)
+
+var Periods = []string{PERIOD_DAY, PERIOD_WEEK, PERIOD_MONTH}
+
+// A snapshot of site user status, recorded by "sitestats record" command.
+type SiteSnapshot struct {
+	Site        string `gorm:"primaryKey"`
+	Ts          int64  `gorm:"primaryKey"`
+	UserName    string
+	Uploaded    int64
+	Downloaded  int64
+	Ratio       float64
+	Bonus       float64
+	SeedingCnt  int64
+	SeedingSize int64
+	HnRCnt      int64
+}
+
+// Site stats of a period (day / week / month). Values are of the last snapshot in the period.
+// Deltas are the differences between the last snapshot of this period and that of the previous one
+// (or the first snapshot of this period if there is no earlier snapshot).
+type SitePeriodStat struct {
+	Site            string  `json:"site"`
+	Period          string  `json:"period"`
+	Start           string  `json:"start"` // start day of period, YYYY-MM-DD
+	Recorded        bool    `json:"recorded"`
+	Uploaded        int64   `json:"uploaded"`
+	Downloaded      int64   `json:"downloaded"`
+	Ratio           float64 `json:"ratio"`
+	Bonus           float64 `json:"bonus"`
+	SeedingSize     int64   `json:"seedingSize"`
+	UploadedDelta   int64   `json:"uploadedDelta"`
+	DownloadedDelta int64   `json:"downloadedDelta"`
+	BonusDelta      float64 `json:"bonusDelta"`
+}
+
+func (db *StatDb) AddSiteSnapshots(snapshots []*SiteSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.sqldb.Save(snapshots).Error
+}
+
+// Get snapshots of a site recorded at or after since, in time asc order.
+func (db *StatDb) GetSiteSnapshots(site string, since int64) ([]*SiteSnapshot, error) {
+	snapshots := []*SiteSnapshot{}
+	if err := db.sqldb.Where("site = ? AND ts >= ?", site, since).Order("ts").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Get all sites that have snapshots.
+func (db *StatDb) GetSnapshotSites() ([]string, error) {
+	sites := []string{}
+	if err := db.sqldb.Model(&SiteSnapshot{}).Distinct("site").Order("site").Pluck("site", &sites).Error; err != nil {
+		return nil, err
+	}
+	return sites, nil
+}
+
+// Return the start time of the (local) period that ts is in. Weeks start on Monday.
+func PeriodStart(period string, ts int64) int64 {
+	t := time.Unix(ts, 0)
+	switch period {
+	case PERIOD_WEEK:
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location()).Unix()
+	case PERIOD_MONTH:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Unix()
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Unix()
+	}
+}
+
+// Return the start time of the count-th period before (count < 0) or after the period that ts is in.
+func PeriodAdd(period string, ts int64, count int) int64 {
+	t := time.Unix(PeriodStart(period, ts), 0)
+	switch period {
+	case PERIOD_WEEK:
+		return t.AddDate(0, 0, 7*count).Unix()
+	case PERIOD_MONTH:
+		return t.AddDate(0, count, 0).Unix()
+	default:
+		return t.AddDate(0, 0, count).Unix()
+	}
+}
+
+// Calculate stats of the last count periods (including the current one that now is in) of a site.
+// snapshots must be in time asc order and should include the ones before the first period if exists,
+// which are used as the baseline of deltas.
+func GetSitePeriodStats(site string, snapshots []*SiteSnapshot, period string, count int,
+	now int64) []*SitePeriodStat {
+	periodStats := []*SitePeriodStat{}
+	index := 0
+	var previous *SiteSnapshot
+	for i := count - 1; i >= 0; i-- {
+		start := PeriodAdd(period, now, -i)
+		end := PeriodAdd(period, now, -i+1)
+		for index < len(snapshots) && snapshots[index].Ts < start {
+			previous = snapshots[index]
+			index++
+		}
+		periodStat := &SitePeriodStat{Site: site, Period: period, Start: util.FormatDate(start)}
+		baseline := previous
+		for index < len(snapshots) && snapshots[index].Ts < end {
+			if baseline == nil {
+				baseline = snapshots[index]
+			}
+			previous = snapshots[index]
+			index++
+			periodStat.Recorded = true
+		}
+		if periodStat.Recorded {
+			periodStat.Uploaded = previous.Uploaded
+			periodStat.Downloaded = previous.Downloaded
+			periodStat.Ratio = previous.Ratio
+			periodStat.Bonus = previous.Bonus
+			periodStat.SeedingSize = previous.SeedingSize
+			periodStat.UploadedDelta = previous.Uploaded - baseline.Uploaded
+			periodStat.DownloadedDelta = previous.Downloaded - baseline.Downloaded
+			periodStat.BonusDelta = previous.Bonus - baseline.Bonus
+		}
+		periodStats = append(periodStats, periodStat)
+	}
+	return periodStats
+}
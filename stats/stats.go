@@ -7,15 +7,31 @@ import (
 	"os"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
 
-	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
 )
 
+// Brush events.
+const (
+	EVENT_TORRENT_DELETED = int64(1)
+	EVENT_TORRENT_ADDED   = int64(2)
+)
+
+const META_LEGACY_IMPORTED = "legacy_imported"
+
+// Retention (seconds) of torrent snapshots, see pruneSnapshots.
+const (
+	SNAPSHOT_RETENTION         = 30 * 86400
+	DELETED_SNAPSHOT_RETENTION = 86400
+)
+
+// Per-day traffic of a client, aggregated by site.
 type TorrentTraffic struct {
 	Client     string `gorm:"primaryKey"`
 	Day        string `gorm:"primaryKey"`
@@ -24,6 +40,38 @@ type TorrentTraffic struct {
 	Uploaded   int64
 }
 
+// The latest snapshot of a client torrent. Traffic between two snapshots is added to TorrentTraffic.
+type TorrentSnapshot struct {
+	Client     string `gorm:"primaryKey"`
+	InfoHash   string `gorm:"primaryKey"`
+	Site       string
+	Atime      int64
+	Ts         int64
+	Downloaded int64
+	Uploaded   int64
+}
+
+type BrushEvent struct {
+	Id         int64  `gorm:"primaryKey;autoIncrement"`
+	Ts         int64  `gorm:"index"`
+	Event      int64  `gorm:"index"`
+	Client     string `gorm:"index"`
+	Site       string
+	Category   string
+	InfoHash   string
+	Name       string
+	Size       int64
+	Atime      int64
+	Uploaded   int64
+	Downloaded int64
+	Msg        string
+}
+
+type StatMeta struct {
+	Key   string `gorm:"primaryKey"`
+	Value string
+}
+
 type TorrentStat struct {
 	Client     string `json:"client"`
 	Site       string `json:"site"`
@@ -46,22 +94,199 @@ type Statistics struct {
 	Uploaded   int64
 }
 type StatDb struct {
-	statFilename string
-	file         *os.File
-	mu           sync.Mutex
-	sqldb        *gorm.DB
+	mu    sync.Mutex
+	sqldb *gorm.DB
+}
+
+// Record snapshots of client torrents taken at ts. The traffic since the previous snapshot of each torrent
+// is added to the per-day traffic. For a torrent without previous snapshot, the traffic since it's added
+// to client (Atime) is evenly spread over the days. Outdated snapshots are pruned.
+func (db *StatDb) AddTorrentSnapshots(ts int64, torrentStats []*TorrentStat) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.sqldb.Transaction(func(tx *gorm.DB) error {
+		for _, torrentStat := range torrentStats {
+			if err := addSnapshot(tx, ts, torrentStat); err != nil {
+				return err
+			}
+		}
+		return pruneSnapshots(tx, ts)
+	})
 }
 
-func (db *StatDb) AddTorrentStats(ts int64, event int64, torrentStats []*TorrentStat) {
+// Record brush events. For deleted torrents, a final snapshot is also taken to record the remaining traffic.
+func (db *StatDb) AddTorrentStats(ts int64, event int64, torrentStats []*TorrentStat) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	for _, torrentStat := range torrentStats {
-		json.NewEncoder(db.file).Encode(Stat{
-			Ts:    ts,
-			Event: event,
-			Data:  torrentStat,
-		})
+	return db.sqldb.Transaction(func(tx *gorm.DB) error {
+		for _, torrentStat := range torrentStats {
+			if event == EVENT_TORRENT_DELETED {
+				if err := addSnapshot(tx, ts, torrentStat); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(newBrushEvent(ts, event, torrentStat)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func newBrushEvent(ts int64, event int64, torrentStat *TorrentStat) *BrushEvent {
+	return &BrushEvent{
+		Ts:         ts,
+		Event:      event,
+		Client:     torrentStat.Client,
+		Site:       torrentStat.Site,
+		Category:   torrentStat.Category,
+		InfoHash:   torrentStat.InfoHash,
+		Name:       torrentStat.Name,
+		Size:       torrentStat.Size,
+		Atime:      torrentStat.Atime,
+		Uploaded:   torrentStat.Uploaded,
+		Downloaded: torrentStat.Downloaded,
+		Msg:        torrentStat.Msg,
+	}
+}
+
+func addSnapshot(tx *gorm.DB, ts int64, torrentStat *TorrentStat) error {
+	snapshot := &TorrentSnapshot{}
+	err := tx.Where("client = ? AND info_hash = ?", torrentStat.Client, torrentStat.InfoHash).
+		Limit(1).Find(snapshot).Error
+	if err != nil {
+		return err
+	}
+	start := torrentStat.Atime
+	downloaded := torrentStat.Downloaded
+	uploaded := torrentStat.Uploaded
+	// A torrent re-added to client (different atime) starts from scratch.
+	if snapshot.InfoHash != "" && snapshot.Atime == torrentStat.Atime {
+		if ts <= snapshot.Ts {
+			return nil
+		}
+		start = snapshot.Ts
+		// Counters that go backwards (e.g. client data reset) are treated as starting from zero.
+		if downloaded >= snapshot.Downloaded {
+			downloaded -= snapshot.Downloaded
+		}
+		if uploaded >= snapshot.Uploaded {
+			uploaded -= snapshot.Uploaded
+		}
+	}
+	if err = addTraffic(tx, torrentStat.Client, torrentStat.Site, start, ts, downloaded, uploaded); err != nil {
+		return err
+	}
+	return tx.Save(&TorrentSnapshot{
+		Client:     torrentStat.Client,
+		InfoHash:   torrentStat.InfoHash,
+		Site:       torrentStat.Site,
+		Atime:      torrentStat.Atime,
+		Ts:         ts,
+		Downloaded: torrentStat.Downloaded,
+		Uploaded:   torrentStat.Uploaded,
+	}).Error
+}
+
+// Delete snapshots of torrents that are no longer in client.
+// The final snapshot of a deleted brush torrent is kept for DELETED_SNAPSHOT_RETENTION, to dedupe repeated
+// deletion records; other snapshots that have not been updated for SNAPSHOT_RETENTION are also deleted,
+// e.g. the torrents deleted by user.
+func pruneSnapshots(tx *gorm.DB, ts int64) error {
+	// DELETE FROM torrent_snapshots WHERE ts < ? AND (ts < ? OR EXISTS (SELECT 1 FROM brush_events
+	//	WHERE event = ? AND client = torrent_snapshots.client AND info_hash = torrent_snapshots.info_hash
+	//	AND ts >= torrent_snapshots.ts));
+	return tx.Where("ts < ? AND (ts < ? OR EXISTS (?))", ts-DELETED_SNAPSHOT_RETENTION, ts-SNAPSHOT_RETENTION,
+		tx.Model(&BrushEvent{}).Select("1").Where("brush_events.event = ? AND "+
+			"brush_events.client = torrent_snapshots.client AND brush_events.info_hash = torrent_snapshots.info_hash "+
+			"AND brush_events.ts >= torrent_snapshots.ts", EVENT_TORRENT_DELETED)).
+		Delete(&TorrentSnapshot{}).Error
+}
+
+// Add the traffic generated during [start, end] to the per-day traffic, evenly spread over the (local) days.
+func addTraffic(tx *gorm.DB, client string, site string, start int64, end int64,
+	downloaded int64, uploaded int64) error {
+	if downloaded <= 0 && uploaded <= 0 {
+		return nil
 	}
+	if start <= 0 || start > end {
+		start = end
+	}
+	for {
+		dayEnd := nextDayStart(start)
+		segmentDownloaded, segmentUploaded := downloaded, uploaded
+		if dayEnd < end {
+			segmentDownloaded = downloaded * (dayEnd - start) / (end - start)
+			segmentUploaded = uploaded * (dayEnd - start) / (end - start)
+		}
+		// INSERT INTO torrent_traffics (client, day, site, downloaded, uploaded) VALUES (?,?,?,?,?)
+		//	ON CONFLICT(client, day, site) DO UPDATE SET downloaded = downloaded + ?, uploaded = uploaded + ?;
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "client"}, {Name: "day"}, {Name: "site"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"downloaded": gorm.Expr("downloaded + ?", segmentDownloaded),
+				"uploaded":   gorm.Expr("uploaded + ?", segmentUploaded),
+			}),
+		}).Create(&TorrentTraffic{
+			Client:     client,
+			Day:        util.FormatDate(start),
+			Site:       site,
+			Downloaded: segmentDownloaded,
+			Uploaded:   segmentUploaded,
+		}).Error
+		if err != nil || dayEnd >= end {
+			return err
+		}
+		downloaded -= segmentDownloaded
+		uploaded -= segmentUploaded
+		start = dayEnd
+	}
+}
+
+func nextDayStart(ts int64) int64 {
+	t := time.Unix(ts, 0)
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Unix()
+}
+
+// Get the sum of traffic of the days in [startDay, endDay] (YYYY-MM-DD). Empty client / site / day means any.
+func (db *StatDb) GetTrafficStats(client string, site string, startDay string, endDay string) (*Statistics, error) {
+	statistics := &Statistics{}
+	tx := db.sqldb.Table("torrent_traffics").
+		Select("ifnull(sum(downloaded),0) as downloaded", "ifnull(sum(uploaded),0) as uploaded")
+	if client != "" {
+		tx = tx.Where("client = ?", client)
+	}
+	if site != "" {
+		tx = tx.Where("site = ?", site)
+	}
+	if startDay != "" {
+		tx = tx.Where("day >= ?", startDay)
+	}
+	if endDay != "" {
+		tx = tx.Where("day <= ?", endDay)
+	}
+	if err := tx.Scan(statistics).Error; err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
+
+type BrushEventCount struct {
+	Client string
+	Site   string
+	Event  int64
+	Cnt    int64
+}
+
+// Get the count of all time brush events, grouped by client, site and event.
+func (db *StatDb) GetBrushEventCounts() ([]*BrushEventCount, error) {
+	counts := []*BrushEventCount{}
+	err := db.sqldb.Table("brush_events").Select("client", "site", "event", "count(*) as cnt").
+		Group("client").Group("site").Group("event").Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
 }
 
 func (db *StatDb) ShowTrafficStats(client string) {
@@ -189,94 +414,74 @@ func (db *StatDb) ShowTrafficStats(client string) {
 	}
 }
 
-func NewDb(statFilename string) (*StatDb, error) {
-	db := &StatDb{}
-
-	f, err := os.OpenFile(statFilename, os.O_RDWR|os.O_APPEND|os.O_CREATE, constants.PERM)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open stats file %s: %w", statFilename, err)
-	}
-	db.statFilename = statFilename
-	db.file = f
-
-	sqldb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+// Open (create if not exists) the on-disk stats database.
+// If legacyFilename (the old JSON lines stats file) is not empty and exists,
+// it's records are imported into the database, only once.
+func NewDb(dbFilename string, legacyFilename string) (*StatDb, error) {
+	sqldb, err := gorm.Open(sqlite.Open(dbFilename+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"),
+		&gorm.Config{Logger: logger.Discard})
 	if err != nil {
-		return nil, fmt.Errorf("error create stats sqldb: %w", err)
+		return nil, fmt.Errorf("failed to open stats db %s: %w", dbFilename, err)
 	}
-	err = sqldb.AutoMigrate(&TorrentTraffic{})
+	err = sqldb.AutoMigrate(&TorrentTraffic{}, &TorrentSnapshot{}, &BrushEvent{}, &StatMeta{}, &SiteSnapshot{})
 	if err != nil {
 		return nil, fmt.Errorf("sql schema init error: %w", err)
 	}
-	db.sqldb = sqldb
-	db.file.Seek(0, 0)
-	fileScanner := bufio.NewScanner(db.file)
-	fileScanner.Split(bufio.ScanLines)
-
-	flagMap := map[string]bool{}
-	for fileScanner.Scan() {
-		statRecord := Stat{}
-		err := json.Unmarshal([]byte(fileScanner.Text()), &statRecord)
-		if err != nil || statRecord.Event != 1 {
-			continue
-		}
-		timespan := statRecord.Ts - statRecord.Data.Atime
-		if timespan == 0 {
-			continue // just skip it
-		}
-		id := fmt.Sprint(statRecord.Data.Client, statRecord.Data.InfoHash, statRecord.Data.Atime)
-		if flagMap[id] {
-			continue // duplicate records
-		}
-		flagMap[id] = true
-		aDownloadSpeed := statRecord.Data.Downloaded / timespan
-		aUploadSpeed := statRecord.Data.Uploaded / timespan
-		dailyDownloaded := 86400 * aDownloadSpeed
-		dailyUploaded := 86400 * aUploadSpeed
-
-		time := statRecord.Data.Atime
-		day := util.FormatDate(time)
-		nexydayTime, _ := util.ParseLocalDateTime(day)
-		nexydayTime += 86400
-		for statRecord.Ts > time {
-			isFullDay := true
-			time2 := nexydayTime
-			if time2 > statRecord.Ts {
-				time2 = statRecord.Ts
-				isFullDay = false
-			} else if time == statRecord.Data.Atime {
-				isFullDay = false
-			}
-			downloaded := int64(0)
-			uploaded := int64(0)
-			if isFullDay {
-				downloaded = dailyDownloaded
-				uploaded = dailyUploaded
-			} else {
-				downloaded = (time2 - time) * aDownloadSpeed
-				uploaded = (time2 - time) * aUploadSpeed
-			}
-			// INSERT INTO torrent_traffics (client, day, site, downloaded, uploaded) VALUES (?,?,?,?,?)
-			//	ON CONFLICT(client, day, site) DO UPDATE SET downloaded = downloaded + ?, uploaded = uploaded + ?;
-			db.sqldb.Clauses(clause.OnConflict{
-				Columns: []clause.Column{{Name: "client"}, {Name: "day"}, {Name: "site"}},
-				DoUpdates: clause.Assignments(map[string]interface{}{
-					"downloaded": gorm.Expr("downloaded + ?", downloaded),
-					"uploaded":   gorm.Expr("uploaded + ?", uploaded),
-				}),
-			}).Create(&TorrentTraffic{
-				Client:     statRecord.Data.Client,
-				Day:        day,
-				Site:       statRecord.Data.Site,
-				Downloaded: downloaded,
-				Uploaded:   uploaded,
-			})
-			time = nexydayTime
-			day = util.FormatDate(time)
-			nexydayTime += 86400
+	db := &StatDb{sqldb: sqldb}
+	if legacyFilename != "" {
+		if err = db.importLegacyFile(legacyFilename); err != nil {
+			return nil, fmt.Errorf("failed to import legacy stats file %s: %w", legacyFilename, err)
 		}
 	}
 	return db, nil
 }
 
-func init() {
+// Import the deleted torrent records of legacy stats file. The legacy file only contains the total traffic
+// of each torrent when it's deleted, so the traffic is evenly spread over the torrent lifetime.
+func (db *StatDb) importLegacyFile(filename string) error {
+	meta := &StatMeta{}
+	if err := db.sqldb.Where("key = ?", META_LEGACY_IMPORTED).Limit(1).Find(meta).Error; err != nil {
+		return err
+	}
+	if meta.Key != "" {
+		return nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.sqldb.Transaction(func(tx *gorm.DB) error {
+		fileScanner := bufio.NewScanner(f)
+		fileScanner.Buffer(nil, 1024*1024)
+		flagMap := map[string]bool{}
+		for fileScanner.Scan() {
+			statRecord := Stat{}
+			err := json.Unmarshal(fileScanner.Bytes(), &statRecord)
+			if err != nil || statRecord.Event != EVENT_TORRENT_DELETED || statRecord.Data == nil {
+				continue
+			}
+			id := fmt.Sprint(statRecord.Data.Client, statRecord.Data.InfoHash, statRecord.Data.Atime)
+			if flagMap[id] {
+				continue // duplicate records
+			}
+			flagMap[id] = true
+			if err = addTraffic(tx, statRecord.Data.Client, statRecord.Data.Site, statRecord.Data.Atime,
+				statRecord.Ts, statRecord.Data.Downloaded, statRecord.Data.Uploaded); err != nil {
+				return err
+			}
+			if err = tx.Create(newBrushEvent(statRecord.Ts, statRecord.Event, statRecord.Data)).Error; err != nil {
+				return err
+			}
+		}
+		if err := fileScanner.Err(); err != nil {
+			return err
+		}
+		return tx.Create(&StatMeta{Key: META_LEGACY_IMPORTED, Value: filename}).Error
+	})
 }
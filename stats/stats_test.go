@@ -0,0 +1,133 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+)
+
+func TestSnapshotTraffic(t *testing.T) {
+	dir := t.TempDir()
+	legacyFilename := filepath.Join(dir, "ptool_stats.txt")
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local).Unix()
+	day2 := day1 + 86400
+	legacyRecord, _ := json.Marshal(&stats.Stat{Ts: day1, Event: stats.EVENT_TORRENT_DELETED,
+		Data: &stats.TorrentStat{Client: "local", Site: "old", InfoHash: "0", Atime: day1 - 3600, Uploaded: 100}})
+	// duplicate record is ignored
+	os.WriteFile(legacyFilename, append(append(legacyRecord, '\n'), append(legacyRecord, '\n')...), 0600)
+	db, err := stats.NewDb(filepath.Join(dir, "ptool_stats.db"), legacyFilename)
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+
+	torrent := &stats.TorrentStat{Client: "local", Site: "foo", InfoHash: "1", Atime: day1 - 100,
+		Downloaded: 1000, Uploaded: 100}
+	if err = db.AddTorrentSnapshots(day1, []*stats.TorrentStat{torrent}); err != nil {
+		t.Fatalf("failed to add snapshots: %v", err)
+	}
+	torrent.Uploaded = 600
+	db.AddTorrentSnapshots(day2, []*stats.TorrentStat{torrent})
+	torrent.Uploaded = 1000
+	db.AddTorrentStats(day2+60, stats.EVENT_TORRENT_DELETED, []*stats.TorrentStat{torrent})
+	db.AddTorrentStats(day2+60, stats.EVENT_TORRENT_DELETED, []*stats.TorrentStat{torrent})
+
+	total, _ := db.GetTrafficStats("local", "", "", "")
+	if total.Downloaded != 1000 || total.Uploaded != 1100 {
+		t.Errorf("unexpected total traffic: %+v", total)
+	}
+	old, _ := db.GetTrafficStats("", "old", "", "")
+	if old.Uploaded != 100 {
+		t.Errorf("expect legacy records imported once, got %+v", old)
+	}
+	foo, _ := db.GetTrafficStats("local", "foo", util.FormatDate(day2), util.FormatDate(day2))
+	if foo.Downloaded != 0 || foo.Uploaded != 250+400 {
+		t.Errorf("unexpected day2 traffic: %+v", foo)
+	}
+	db, err = stats.NewDb(filepath.Join(dir, "ptool_stats.db"), legacyFilename)
+	if err != nil {
+		t.Fatalf("failed to re-open db: %v", err)
+	}
+	if old, _ = db.GetTrafficStats("", "old", "", ""); old.Uploaded != 100 {
+		t.Errorf("expect legacy records not imported again, got %+v", old)
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	db, err := stats.NewDb(filepath.Join(t.TempDir(), "ptool_stats.db"), "")
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local).Unix()
+	deleted := &stats.TorrentStat{Client: "local", Site: "foo", InfoHash: "1", Atime: day1 - 100, Uploaded: 100}
+	seeding := &stats.TorrentStat{Client: "local", Site: "foo", InfoHash: "2", Atime: day1 - 100, Uploaded: 100}
+	db.AddTorrentSnapshots(day1, []*stats.TorrentStat{deleted, seeding})
+	db.AddTorrentStats(day1+60, stats.EVENT_TORRENT_DELETED, []*stats.TorrentStat{deleted})
+	// The snapshot of deleted torrent is still kept within DELETED_SNAPSHOT_RETENTION.
+	db.AddTorrentStats(day1+120, stats.EVENT_TORRENT_DELETED, []*stats.TorrentStat{deleted})
+	if total, _ := db.GetTrafficStats("local", "", "", ""); total.Uploaded != 200 {
+		t.Errorf("expect repeated deletion record deduped, got %+v", total)
+	}
+	// Snapshot of deleted torrent is pruned, while the one of torrent still in client is kept.
+	db.AddTorrentSnapshots(day1+stats.DELETED_SNAPSHOT_RETENTION+3600, []*stats.TorrentStat{seeding})
+	db.AddTorrentSnapshots(day1+stats.DELETED_SNAPSHOT_RETENTION+7200, []*stats.TorrentStat{deleted, seeding})
+	if total, _ := db.GetTrafficStats("local", "", "", ""); total.Uploaded != 300 {
+		t.Errorf("expect snapshot of deleted torrent pruned, got %+v", total)
+	}
+	// Snapshot that has not been updated for SNAPSHOT_RETENTION is pruned.
+	db.AddTorrentSnapshots(day1+stats.SNAPSHOT_RETENTION*2, nil)
+	db.AddTorrentSnapshots(day1+stats.SNAPSHOT_RETENTION*2+60, []*stats.TorrentStat{seeding})
+	if total, _ := db.GetTrafficStats("local", "", "", ""); total.Uploaded != 400 {
+		t.Errorf("expect outdated snapshot pruned, got %+v", total)
+	}
+}
+
+func TestSitePeriodStats(t *testing.T) {
+	db, err := stats.NewDb(filepath.Join(t.TempDir(), "ptool_stats.db"), "")
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local).Unix()
+	snapshots := []*stats.SiteSnapshot{
+		{Site: "foo", Ts: day1 - 86400*10, Uploaded: 100, Bonus: 1},
+		{Site: "foo", Ts: day1, Uploaded: 1000, Bonus: 10},
+		{Site: "foo", Ts: day1 + 3600, Uploaded: 1500, Bonus: 20},
+		{Site: "foo", Ts: day1 + 86400*2, Uploaded: 1600, Bonus: 25.5},
+		{Site: "bar", Ts: day1, Uploaded: 1},
+	}
+	if err = db.AddSiteSnapshots(snapshots); err != nil {
+		t.Fatalf("failed to add snapshots: %v", err)
+	}
+	snapshots, _ = db.GetSiteSnapshots("foo", 0)
+	if len(snapshots) != 4 {
+		t.Fatalf("expect 4 snapshots, got %d", len(snapshots))
+	}
+	periodStats := stats.GetSitePeriodStats("foo", snapshots, stats.PERIOD_DAY, 3, day1+86400*2)
+	if len(periodStats) != 3 {
+		t.Fatalf("expect 3 periods, got %d", len(periodStats))
+	}
+	if s := periodStats[0]; s.Start != "2024-01-01" || !s.Recorded || s.Uploaded != 1500 || s.UploadedDelta != 1400 ||
+		s.BonusDelta != 19 {
+		t.Errorf("unexpected day 1 stats: %+v", s)
+	}
+	if s := periodStats[1]; s.Recorded || s.UploadedDelta != 0 {
+		t.Errorf("unexpected day 2 stats: %+v", s)
+	}
+	if s := periodStats[2]; !s.Recorded || s.UploadedDelta != 100 || s.BonusDelta != 5.5 {
+		t.Errorf("unexpected day 3 stats: %+v", s)
+	}
+	monthStats := stats.GetSitePeriodStats("foo", snapshots, stats.PERIOD_MONTH, 2, day1)
+	if s := monthStats[0]; s.Start != "2023-12-01" || s.UploadedDelta != 0 || s.Uploaded != 100 {
+		t.Errorf("unexpected month 1 stats: %+v", s)
+	}
+	if s := monthStats[1]; s.UploadedDelta != 1500 {
+		t.Errorf("unexpected month 2 stats: %+v", s)
+	}
+	if sites, _ := db.GetSnapshotSites(); len(sites) != 2 || sites[0] != "bar" {
+		t.Errorf("unexpected snapshot sites: %v", sites)
+	}
+}
@@ -0,0 +1,52 @@
+package transmissionrpc
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+	Bandwidth Groups (RPC v17)
+	https://github.com/transmission/transmission/blob/4.0.0/docs/rpc-spec.md#48-bandwidth-groups
+*/
+
+// BandwidthGroupGet returns the bandwidth groups of the given names (all groups if names is empty).
+// https://github.com/transmission/transmission/blob/4.0.0/docs/rpc-spec.md#482-bandwidth-group-accessor-group-get
+func (c *Client) BandwidthGroupGet(ctx context.Context, names []string) (groups []BandwidthGroup, err error) {
+	payload := &bandwidthGroupGetPayload{Group: names}
+	var answer bandwidthGroupGetAnswer
+	if err = c.rpcCall(ctx, "group-get", payload, &answer); err != nil {
+		err = fmt.Errorf("'group-get' rpc method failed: %w", err)
+		return
+	}
+	groups = answer.Group
+	return
+}
+
+// BandwidthGroupSet creates or updates a bandwidth group.
+// https://github.com/transmission/transmission/blob/4.0.0/docs/rpc-spec.md#481-bandwidth-group-mutator-group-set
+func (c *Client) BandwidthGroupSet(ctx context.Context, group BandwidthGroup) (err error) {
+	if err = c.rpcCall(ctx, "group-set", group, nil); err != nil {
+		err = fmt.Errorf("'group-set' rpc method failed: %w", err)
+	}
+	return
+}
+
+type bandwidthGroupGetPayload struct {
+	Group []string `json:"group,omitempty"`
+}
+
+type bandwidthGroupGetAnswer struct {
+	Group []BandwidthGroup `json:"group"`
+}
+
+// BandwidthGroup represents a bandwidth group.
+// https://github.com/transmission/transmission/blob/4.0.0/docs/rpc-spec.md#481-bandwidth-group-mutator-group-set
+type BandwidthGroup struct {
+	HonorsSessionLimits   bool   `json:"honorsSessionLimits"`
+	Name                  string `json:"name"`
+	SpeedLimitDownEnabled bool   `json:"speed-limit-down-enabled"`
+	SpeedLimitDown        int64  `json:"speed-limit-down"` // KBps
+	SpeedLimitUpEnabled   bool   `json:"speed-limit-up-enabled"`
+	SpeedLimitUp          int64  `json:"speed-limit-up"` // KBps
+}
@@ -142,8 +142,10 @@ type Torrent struct {
 	ErrorString             *string            `json:"errorString"`
 	Eta                     *int64             `json:"eta"`
 	EtaIdle                 *int64             `json:"etaIdle"`
+	FileCount               *int64             `json:"file-count"` // RPC v17
 	Files                   []*TorrentFile     `json:"files"`
 	FileStats               []*TorrentFileStat `json:"fileStats"`
+	Group                   *string            `json:"group"` // RPC v17: bandwidth group name
 	HashString              *string            `json:"hashString"`
 	HaveUnchecked           *int64             `json:"haveUnchecked"`
 	HaveValid               *int64             `json:"haveValid"`
@@ -184,6 +186,7 @@ type Torrent struct {
 	StartDate               *time.Time         `json:"startDate"`
 	Status                  *TorrentStatus     `json:"status"`
 	Trackers                []*Tracker         `json:"trackers"`
+	TrackerList             *string            `json:"trackerList"` // RPC v17: announce urls, one per line, tiers separated by a blank line
 	TrackerStats            []*TrackerStats    `json:"trackerStats"`
 	TotalSize               *cunits.Bits       `json:"totalSize"`
 	TorrentFile             *string            `json:"torrentFile"`
@@ -217,17 +220,17 @@ func (t *Torrent) UnmarshalJSON(data []byte) (err error) {
 	// Shadow real type for regular unmarshalling
 	type RawTorrent Torrent
 	tmp := &struct {
-		ActivityDate   *int64  `json:"activityDate"`
-		AddedDate      *int64  `json:"addedDate"`
-		DateCreated    *int64  `json:"dateCreated"`
-		DoneDate       *int64  `json:"doneDate"`
-		EditDate       *int64  `json:"editDate"`
-		PieceSize      *int64  `json:"pieceSize"`
-		SecondsSeeding *int64  `json:"secondsSeeding"`
-		SizeWhenDone   *int64  `json:"sizeWhenDone"`
-		StartDate      *int64  `json:"startDate"`
-		TotalSize      *int64  `json:"totalSize"`
-		Wanted         []int64 `json:"wanted"` // boolean in number form
+		ActivityDate   *int64        `json:"activityDate"`
+		AddedDate      *int64        `json:"addedDate"`
+		DateCreated    *int64        `json:"dateCreated"`
+		DoneDate       *int64        `json:"doneDate"`
+		EditDate       *int64        `json:"editDate"`
+		PieceSize      *int64        `json:"pieceSize"`
+		SecondsSeeding *int64        `json:"secondsSeeding"`
+		SizeWhenDone   *int64        `json:"sizeWhenDone"`
+		StartDate      *int64        `json:"startDate"`
+		TotalSize      *int64        `json:"totalSize"`
+		Wanted         []interface{} `json:"wanted"` // boolean in number form (RPC v17: real boolean)
 		*RawTorrent
 	}{
 		RawTorrent: (*RawTorrent)(t),
@@ -277,14 +280,16 @@ func (t *Torrent) UnmarshalJSON(data []byte) (err error) {
 		ts := cunits.ImportInByte(float64(*tmp.TotalSize))
 		t.TotalSize = &ts
 	}
-	// Boolean slice in decimal form
+	// Boolean slice in decimal form (or in boolean form since RPC v17)
 	if tmp.Wanted != nil {
 		t.Wanted = make([]bool, len(tmp.Wanted))
 		for index, value := range tmp.Wanted {
-			if value == 1 {
+			switch value {
+			case true, float64(1):
 				t.Wanted[index] = true
-			} else if value != 0 {
-				return fmt.Errorf("can't convert wanted index %d value '%d' as boolean", index, value)
+			case false, float64(0):
+			default:
+				return fmt.Errorf("can't convert wanted index %d value '%v' as boolean", index, value)
 			}
 		}
 	}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package osutil
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Dummy (placeholder).
+func GetFreeDiskSpace(path string) (int64, error) {
+	return 0, fmt.Errorf("getting free disk space is NOT supported on current platform %s", runtime.GOOS)
+}
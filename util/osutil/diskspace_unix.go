@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package osutil
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Return the available (to non-root user) free space of the filesystem where path is in, in bytes.
+func GetFreeDiskSpace(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"io"
+	"math"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -79,6 +80,13 @@ func ParseInt(str string) int64 {
 	return v
 }
 
+// Parse float number string that may contain thousands separators (e.g. "1,234.5"). Return 0 if failed.
+func ParseFloat(str string) float64 {
+	str = strings.TrimSpace(strings.ReplaceAll(str, ",", ""))
+	v, _ := strconv.ParseFloat(str, 64)
+	return v
+}
+
 // Return prefix of str that is at most max bytes encoded in UTF-8
 func StringPrefixInBytes(str string, max int64) string {
 	if int64(len(str)) <= max {
@@ -246,3 +254,27 @@ var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
 func EscapeQuotes(s string) string {
 	return quoteEscaper.Replace(s)
 }
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Render values as a sparkline string (e.g. "▁▃█▅"), one block char for each value.
+// Values are scaled between the min and max of them.
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	min, max := values[0], values[0]
+	for _, value := range values {
+		min = math.Min(min, value)
+		max = math.Max(max, value)
+	}
+	sb := &strings.Builder{}
+	for _, value := range values {
+		level := 0
+		if max > min {
+			level = int((value - min) / (max - min) * float64(len(sparkBlocks)-1))
+		}
+		sb.WriteRune(sparkBlocks[level])
+	}
+	return sb.String()
+}